@@ -69,11 +69,28 @@ func DisconnectDB() error {
 func EnsureCollections() {
 	// Liste der Collections, die in der Datenbank existieren sollten
 	collections := []string{
-		"users",        // Benutzer
-		"articles",     // Artikel
-		"activities",   // Aktivitäten
-		"suppliers",    // Lieferanten (für zukünftige Erweiterung)
-		"transactions", // Bewegungen/Transaktionen (für zukünftige Erweiterung)
+		"users",             // Benutzer
+		"articles",          // Artikel
+		"activities",        // Aktivitäten
+		"suppliers",         // Lieferanten (für zukünftige Erweiterung)
+		"transactions",      // Bewegungen/Transaktionen (für zukünftige Erweiterung)
+		"locations",         // Lagerorte
+		"stock_levels",      // Bestände je Artikel und Lagerort
+		"lots",              // Chargen mit Mindesthaltbarkeit
+		"serial_numbers",    // Seriennummern mit Historie
+		"reservations",      // Reservierungen
+		"purchase_orders",   // Bestellungen bei Lieferanten
+		"goods_receipts",    // Wareneingänge zu Bestellungen
+		"customer_orders",   // Kundenaufträge
+		"cycle_counts",      // Inventurzählungen
+		"cost_layers",       // FIFO-Kostenschichten für die Lagerbewertung
+		"units",             // Einheitenkatalog
+		"assemblies",        // Montagen und Demontagen von Sets
+		"supplier_articles", // Bezugsquellen mit Preishistorie
+		"returns",           // Kunden- und Lieferantenretouren
+		"hazard_classes",    // Gefahrgutkatalog mit Zusammenlagerungsregeln
+		"settings",          // Systemeinstellungen
+		"counters",          // Zähler für fortlaufende Belegnummern
 	}
 
 	// Mit einfachen Anfragen sicherstellen, dass die Collections existieren
@@ -3,27 +3,39 @@ package handler
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ArticleHandler verwaltet alle Anfragen zu Artikeln
 type ArticleHandler struct {
-	articleRepo  *repository.ArticleRepository
-	supplierRepo *repository.SupplierRepository
+	articleRepo    *repository.ArticleRepository
+	supplierRepo   *repository.SupplierRepository
+	stockLevelRepo *repository.StockLevelRepository
+	unitRepo       *repository.UnitOfMeasureRepository
+	hazardRepo     *repository.HazardClassRepository
+	stockService   *service.StockService
+	hazardService  *service.HazardService
 }
 
 // NewArticleHandler erstellt einen neuen ArticleHandler
 func NewArticleHandler() *ArticleHandler {
 	return &ArticleHandler{
-		articleRepo:  repository.NewArticleRepository(),
-		supplierRepo: repository.NewSupplierRepository(),
+		articleRepo:    repository.NewArticleRepository(),
+		supplierRepo:   repository.NewSupplierRepository(),
+		stockLevelRepo: repository.NewStockLevelRepository(),
+		unitRepo:       repository.NewUnitOfMeasureRepository(),
+		hazardRepo:     repository.NewHazardClassRepository(),
+		stockService:   service.NewStockService(),
+		hazardService:  service.NewHazardService(),
 	}
 }
 
@@ -44,6 +56,12 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		return
 	}
 
+	// Offene Bestellmengen je Artikel
+	onOrder, err := repository.NewPurchaseOrderRepository().SumOnOrderByArticle()
+	if err != nil {
+		log.Printf("Fehler beim Ermitteln der Bestellmengen: %v", err)
+	}
+
 	// Daten an das Template übergeben
 	c.HTML(http.StatusOK, "articles.html", gin.H{
 		"title":         "Artikel",
@@ -53,6 +71,7 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		"year":          time.Now().Year(),
 		"articles":      articles,
 		"totalArticles": len(articles),
+		"onOrder":       onOrder,
 		"userRole":      c.GetString("userRole"),
 	})
 }
@@ -91,6 +110,9 @@ func (h *ArticleHandler) ShowAddArticleForm(c *gin.Context) {
 		"userRole":          c.GetString("userRole"),
 		"suppliers":         suppliers,
 		"locations":         locations,
+		"units":             unitOptions(h.unitRepo),
+		"hazardClasses":     hazardClassOptions(h.hazardRepo),
+		"bomArticles":       bomOptions(h.articleRepo, nil),
 		"nextArticleNumber": nextArticleNumber, // Automatisch generierte Artikelnummer
 	})
 }
@@ -137,7 +159,6 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 
 	// Numerische Werte parsen
 	stockCurrent, _ := strconv.ParseFloat(c.PostForm("stockCurrent"), 64)
-	stockReserved, _ := strconv.ParseFloat(c.PostForm("stockReserved"), 64)
 	minimumStock, _ := strconv.ParseFloat(c.PostForm("minimumStock"), 64)
 	purchasePriceNet, _ := strconv.ParseFloat(c.PostForm("purchasePriceNet"), 64)
 	salesPriceGross, _ := strconv.ParseFloat(c.PostForm("salesPriceGross"), 64)
@@ -146,8 +167,19 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 
 	// Boolean-Wert parsen
 	serialNumberRequired := c.PostForm("serialNumberRequired") == "on"
+	lotTracked := c.PostForm("lotTracked") == "on"
+
+	// Der Anfangsbestand wird gebucht; ohne Chargen- bzw. Seriennummern ist das nicht möglich
+	if stockCurrent != 0 && (lotTracked || serialNumberRequired) {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": service.ErrStockChangeTracked.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
 
-	// Neuen Artikel erstellen
+	// Neuen Artikel erstellen; der Bestand entsteht erst durch die Buchung des Anfangsbestands
 	article := &model.Article{
 		ArticleNumber:         articleNumber,
 		ShortName:             shortName,
@@ -155,8 +187,6 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 		EAN:                   ean,
 		Category:              category,
 		Unit:                  unit,
-		StockCurrent:          stockCurrent,
-		StockReserved:         stockReserved,
 		MinimumStock:          minimumStock,
 		PurchasePriceNet:      purchasePriceNet,
 		SalesPriceGross:       salesPriceGross,
@@ -167,6 +197,7 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 		WeightKg:              weightKg,
 		Dimensions:            c.PostForm("dimensions"),
 		SerialNumberRequired:  serialNumberRequired,
+		LotTracked:            lotTracked,
 		HazardClass:           c.PostForm("hazardClass"),
 		Notes:                 c.PostForm("notes"),
 		CreatedAt:             time.Now(),
@@ -180,6 +211,36 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 		LastStockTakeDate:     time.Time{},
 	}
 
+	// Einheiten und Umrechnungen übernehmen
+	if err := bindArticleUnits(c, article); err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Stückliste übernehmen
+	if err := bindBillOfMaterials(c, article, h.articleRepo); err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Gefahrgutklasse und Standardlagerort gegen den Gefahrgutkatalog prüfen
+	if err := h.checkArticleHazard(article); err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
 	// Artikel in der Datenbank speichern
 	err := h.articleRepo.Create(article)
 	if err != nil {
@@ -191,10 +252,25 @@ func (h *ArticleHandler) AddArticle(c *gin.Context) {
 		return
 	}
 
-	// Aktivität loggen
 	user, _ := c.Get("user")
 	userModel := user.(*model.User)
 
+	// Anfangsbestand am Standardlagerort buchen
+	if stockCurrent != 0 {
+		_, err := h.stockService.ChangeStock(article, article.StorageLocationID, stockCurrent, "Anfangsbestand",
+			userModel.ID, userModel.FirstName+" "+userModel.LastName)
+		if err != nil {
+			c.HTML(http.StatusUnprocessableEntity, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Der Artikel wurde angelegt, der Anfangsbestand konnte jedoch nicht gebucht werden: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
+	// Aktivität loggen
+
 	activityRepo := repository.NewActivityRepository()
 	_, _ = activityRepo.LogActivity(
 		model.ActivityTypeArticleAdded,
@@ -247,14 +323,31 @@ func (h *ArticleHandler) ShowStockOverview(c *gin.Context) {
 	// Alle Kategorien für Filter
 	categories, _ := h.articleRepo.GetAllCategories()
 
+	// Bestände der angezeigten Artikel je Lagerort
+	locationMap, _ := repository.NewLocationRepository().BuildLocationTree()
+	levels, err := h.stockLevelRepo.FindAll()
+	if err != nil {
+		levels = []*model.StockLevel{} // Leere Liste im Fehlerfall
+	}
+	stockLevels := buildStockLevelRows(levels, articleMap(articles), locationMap)
+
+	stockByArticle := make(map[string][]stockLevelRow)
+	for _, row := range stockLevels {
+		stockByArticle[row.ArticleID] = append(stockByArticle[row.ArticleID], row)
+	}
+
 	// Statistiken berechnen
 	var totalStock float64
 	var totalValue float64
 	var lowStockCount int
+	stockByStatus := make(map[model.StockStatus]float64) // Bestand je Bestandsstatus über alle Artikel
 
 	for _, article := range articles {
 		totalStock += article.StockCurrent
-		totalValue += article.StockCurrent * article.PurchasePriceNet
+		totalValue += article.GetStockValue()
+		for _, status := range model.GetStockStatuses() {
+			stockByStatus[status] += article.GetStockIn(status)
+		}
 
 		if article.IsBelowMinimum() {
 			lowStockCount++
@@ -268,6 +361,8 @@ func (h *ArticleHandler) ShowStockOverview(c *gin.Context) {
 		"email":          userModel.Email,
 		"year":           time.Now().Year(),
 		"articles":       articles,
+		"stockLevels":    stockLevels,
+		"stockByArticle": stockByArticle,
 		"categories":     categories,
 		"categoryFilter": categoryFilter,
 		"stockStatus":    stockStatus,
@@ -275,6 +370,8 @@ func (h *ArticleHandler) ShowStockOverview(c *gin.Context) {
 		"totalStock":     totalStock,
 		"totalValue":     totalValue,
 		"lowStockCount":  lowStockCount,
+		"stockByStatus":  stockByStatus,
+		"stockStatuses":  model.GetStockStatuses(),
 		"userRole":       c.GetString("userRole"),
 	})
 }
@@ -342,16 +439,101 @@ func (h *ArticleHandler) GetArticleDetails(c *gin.Context) {
 		locationPath = article.StorageLocation
 	}
 
+	// Bestände des Artikels je Lagerort
+	locationMap, _ := repository.NewLocationRepository().BuildLocationTree()
+	stockLevels := []stockLevelRow{}
+	levels, err := h.stockLevelRepo.FindByArticleID(article.ID)
+	if err == nil {
+		stockLevels = buildStockLevelRows(levels, articleMap([]*model.Article{article}), locationMap)
+	}
+
+	// Chargen des Artikels in FEFO-Reihenfolge
+	lots := []lotRow{}
+	if article.LotTracked {
+		articleLots, err := repository.NewLotRepository().FindByArticleID(article.ID)
+		if err == nil {
+			lots = buildLotRows(articleLots, articleMap([]*model.Article{article}), locationMap)
+		}
+	}
+
+	// Seriennummern der Stücke im Lager
+	serials := []*model.SerialNumber{}
+	if article.SerialNumberRequired {
+		available, err := repository.NewSerialNumberRepository().FindAvailableByArticleID(article.ID)
+		if err == nil {
+			serials = available
+		}
+	}
+
+	// Aktive Reservierungen, aus denen sich der reservierte Bestand ergibt
+	reservations, err := repository.NewReservationRepository().FindActiveByArticleID(article.ID)
+	if err != nil {
+		reservations = []*model.Reservation{} // Leere Liste im Fehlerfall
+	}
+
+	// Offene Bestellungen und die daraus noch erwartete Menge
+	purchaseOrders, err := repository.NewPurchaseOrderRepository().FindOpenByArticleID(article.ID)
+	if err != nil {
+		purchaseOrders = []*model.PurchaseOrder{} // Leere Liste im Fehlerfall
+	}
+	quantityOnOrder, _ := service.NewPurchaseOrderService().GetQuantityOnOrder(article.ID)
+
+	// Bezugsquellen, die bevorzugte zuerst
+	supplierArticles, err := repository.NewSupplierArticleRepository().FindByArticleID(article.ID)
+	if err != nil {
+		supplierArticles = []*model.SupplierArticle{} // Leere Liste im Fehlerfall
+	}
+
+	// Baubare Menge und letzte Montagen eines Sets
+	var buildability *service.Buildability
+	assemblies := []*model.Assembly{}
+	if article.IsKit() {
+		assemblyService := service.NewAssemblyService()
+		buildability, _ = assemblyService.GetBuildability(article)
+		if recent, err := assemblyService.FindByKitArticleID(article.ID, 5); err == nil {
+			assemblies = recent
+		}
+	}
+
+	// Gefahrgutklasse mit Bezeichnung aus dem Katalog
+	var hazardClass *model.HazardClass
+	if article.HazardClass != "" {
+		if catalog, err := h.hazardRepo.FindCatalog(); err == nil {
+			hazardClass = catalog[article.HazardClass]
+		}
+	}
+
+	// Letzte Lagerbewegungen des Artikels
+	transactionRepo := repository.NewTransactionRepository()
+	transactions, err := transactionRepo.FindRecentByArticleID(article.ID.Hex(), 10)
+	if err != nil {
+		transactions = []*model.Transaction{} // Leere Liste im Fehlerfall
+	}
+
 	// Daten an das Template übergeben
 	c.HTML(http.StatusOK, "article_detail.html", gin.H{
-		"title":        article.ShortName,
-		"active":       "articles",
-		"user":         userModel.FirstName + " " + userModel.LastName,
-		"email":        userModel.Email,
-		"year":         time.Now().Year(),
-		"article":      article,
-		"userRole":     c.GetString("userRole"),
-		"locationPath": locationPath,
+		"title":            article.ShortName,
+		"active":           "articles",
+		"user":             userModel.FirstName + " " + userModel.LastName,
+		"email":            userModel.Email,
+		"year":             time.Now().Year(),
+		"article":          article,
+		"userRole":         c.GetString("userRole"),
+		"locationPath":     locationPath,
+		"transactions":     transactions,
+		"stockLevels":      stockLevels,
+		"lots":             lots,
+		"serials":          serials,
+		"reservations":     reservations,
+		"purchaseOrders":   purchaseOrders,
+		"quantityOnOrder":  quantityOnOrder,
+		"supplierArticles": supplierArticles,
+		"buildability":     buildability,
+		"assemblies":       assemblies,
+		"hazardClass":      hazardClass,
+		"stockStatuses":    model.GetStockStatuses(),
+		"heldStatuses":     model.GetHeldStockStatuses(),
+		"now":              time.Now(),
 	})
 }
 
@@ -370,6 +552,12 @@ func (h *ArticleHandler) ShowEditArticleForm(c *gin.Context) {
 		return
 	}
 
+	h.renderArticleEditForm(c, http.StatusOK, article, nil)
+}
+
+// renderArticleEditForm zeigt das Bearbeitungsformular an; bei einem Versionskonflikt
+// werden die abweichenden Felder oberhalb des Formulars aufgelistet
+func (h *ArticleHandler) renderArticleEditForm(c *gin.Context, status int, article *model.Article, conflicts conflictList) {
 	// Aktuellen Benutzer aus dem Context abrufen
 	user, _ := c.Get("user")
 	userModel := user.(*model.User)
@@ -443,21 +631,26 @@ func (h *ArticleHandler) ShowEditArticleForm(c *gin.Context) {
 		}
 	}
 
-	c.HTML(http.StatusOK, "article_edit.html", gin.H{
-		"title":     "Artikel bearbeiten",
-		"active":    "articles",
-		"user":      userModel.FirstName + " " + userModel.LastName,
-		"email":     userModel.Email,
-		"year":      time.Now().Year(),
-		"article":   article,
-		"userRole":  c.GetString("userRole"),
-		"locations": locations,
+	c.HTML(status, "article_edit.html", gin.H{
+		"title":         "Artikel bearbeiten",
+		"active":        "articles",
+		"user":          userModel.FirstName + " " + userModel.LastName,
+		"email":         userModel.Email,
+		"year":          time.Now().Year(),
+		"article":       article,
+		"userRole":      c.GetString("userRole"),
+		"locations":     locations,
+		"units":         unitOptions(h.unitRepo, article.Unit),
+		"hazardClasses": hazardClassOptions(h.hazardRepo, article.HazardClass),
+		"bomArticles":   bomOptions(h.articleRepo, article),
 		"locationsByType": gin.H{
 			"warehouses": warehouses,
 			"areas":      areas,
 			"shelves":    shelves,
 		},
 		"selectedLocation": selectedLocationInfo,
+		"conflict":         status == http.StatusConflict,
+		"conflicts":        conflicts,
 	})
 }
 
@@ -476,6 +669,12 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 		return
 	}
 
+	// Gefahrgutklasse, Standardlagerort, Seriennummern- und Chargenpflicht vor der Änderung
+	previousHazardClass := article.HazardClass
+	previousLocationID := article.StorageLocationID
+	previousSerialRequired := article.SerialNumberRequired
+	previousLotTracked := article.LotTracked
+
 	// Formulardaten abrufen und Artikel aktualisieren
 	article.ArticleNumber = c.PostForm("articleNumber")
 	article.ShortName = c.PostForm("shortName")
@@ -496,8 +695,8 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	}
 
 	// Numerische Werte parsen
+	previousStock := article.StockCurrent
 	article.StockCurrent, _ = strconv.ParseFloat(c.PostForm("stockCurrent"), 64)
-	article.StockReserved, _ = strconv.ParseFloat(c.PostForm("stockReserved"), 64)
 	article.MinimumStock, _ = strconv.ParseFloat(c.PostForm("minimumStock"), 64)
 	article.PurchasePriceNet, _ = strconv.ParseFloat(c.PostForm("purchasePriceNet"), 64)
 	article.SalesPriceGross, _ = strconv.ParseFloat(c.PostForm("salesPriceGross"), 64)
@@ -507,18 +706,141 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	article.WeightKg, _ = strconv.ParseFloat(c.PostForm("weightKg"), 64)
 	article.Dimensions = c.PostForm("dimensions")
 	article.SerialNumberRequired = c.PostForm("serialNumberRequired") == "on"
+	article.LotTracked = c.PostForm("lotTracked") == "on"
 	article.HazardClass = c.PostForm("hazardClass")
 	article.Notes = c.PostForm("notes")
 	article.UpdatedAt = time.Now()
 
+	// Version, mit der das Formular geladen wurde
+	loadedVersion := article.Version
+	article.Version, _ = strconv.ParseInt(c.PostForm("version"), 10, 64)
+
+	// Einheiten und Umrechnungen übernehmen
+	if err := bindArticleUnits(c, article); err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Stückliste übernehmen
+	if err := bindBillOfMaterials(c, article, h.articleRepo); err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Bestandsänderungen werden gebucht; ohne Chargen- bzw. Seriennummern ist das nicht möglich
+	delta := article.StockCurrent - previousStock
+	if delta != 0 && (article.LotTracked || article.SerialNumberRequired) {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": service.ErrStockChangeTracked.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Für vorhandenen Bestand sind keine Seriennummern erfasst; er ließe sich nicht mehr bewegen
+	if article.SerialNumberRequired && !previousSerialRequired && previousStock > 0 {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": service.ErrSerialTrackingStock.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Eine geänderte Gefahrgutklasse oder ein neuer Standardlagerort muss zu den Gefahrgutregeln passen
+	if article.HazardClass != previousHazardClass || article.StorageLocationID != previousLocationID {
+		if err := h.checkArticleHazard(article); err != nil {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
 	// Optional: Weitere Felder wie MaximumStock, ReorderQuantity, etc. aktualisieren
 	// article.MaximumStock, _ = strconv.ParseFloat(c.PostForm("maximumStock"), 64)
 	// article.ReorderQuantity, _ = strconv.ParseFloat(c.PostForm("reorderQuantity"), 64)
 	// article.Bin = c.PostForm("bin")
 	// article.IsActive = c.PostForm("isActive") == "on"
 
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Geänderten Bestand am Standardlagerort vor den Stammdaten buchen, damit eine abgelehnte
+	// Buchung keinen halb gespeicherten Artikel hinterlässt
+	if delta != 0 {
+		if article.Version != loadedVersion {
+			h.showArticleConflict(c, article)
+			return
+		}
+		// Eine bereits vergebene Artikelnummer ließe das Speichern erst nach der Buchung scheitern
+		if existing, err := h.articleRepo.FindByArticleNumber(article.ArticleNumber); err == nil && existing.ID != article.ID {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Artikelnummer " + article.ArticleNumber + " ist bereits vergeben",
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+
+		_, err := h.stockService.ChangeStock(article, article.StorageLocationID, delta, "Bestandsänderung im Artikelformular",
+			userModel.ID, userModel.FirstName+" "+userModel.LastName)
+		if err != nil {
+			c.HTML(http.StatusUnprocessableEntity, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Bestandsänderung konnte nicht gebucht werden, der Artikel wurde nicht gespeichert: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+
+		// Die Buchung hat die Version des Artikels erhöht
+		current, err := h.articleRepo.FindByID(id)
+		if err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Bestandsänderung wurde gebucht, der Artikel konnte jedoch nicht erneut geladen werden: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+		article.Version = current.Version
+	}
+
+	// Beim Einführen der Chargenpflicht den vorhandenen Bestand in Eröffnungschargen erfassen,
+	// damit er weiter entnommen werden kann
+	if article.LotTracked && !previousLotTracked && previousStock > 0 {
+		if article.Version != loadedVersion {
+			h.showArticleConflict(c, article)
+			return
+		}
+		if err := h.stockService.OpenLots(article.ID); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Chargenpflicht konnte nicht eingeführt werden, der Artikel wurde nicht gespeichert: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
 	// Artikel in der Datenbank aktualisieren
 	err = h.articleRepo.Update(article)
+	if err == repository.ErrVersionConflict {
+		h.showArticleConflict(c, article)
+		return
+	}
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"title":   "Fehler",
@@ -529,8 +851,6 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	}
 
 	// Aktivität loggen
-	user, _ := c.Get("user")
-	userModel := user.(*model.User)
 
 	activityRepo := repository.NewActivityRepository()
 	_, _ = activityRepo.LogActivity(
@@ -548,6 +868,58 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/articles?success=updated")
 }
 
+// checkArticleHazard prüft, ob die Gefahrgutklasse eines Artikels im Katalog steht und sein
+// Standardlagerort sie freigibt, ohne gegen die Zusammenlagerungsregeln zu verstoßen
+func (h *ArticleHandler) checkArticleHazard(article *model.Article) error {
+	if err := h.hazardService.CheckClass(article.HazardClass); err != nil {
+		return err
+	}
+	return h.hazardService.CheckStorage(article, article.StorageLocationID)
+}
+
+// showArticleConflict zeigt das Formular mit den eigenen Eingaben erneut an und listet die
+// Felder auf, die ein anderer Benutzer inzwischen geändert hat
+func (h *ArticleHandler) showArticleConflict(c *gin.Context, article *model.Article) {
+	current, err := h.articleRepo.FindByID(article.ID.Hex())
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Artikel wurde zwischenzeitlich gelöscht",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	var conflicts conflictList
+	conflicts.compare("Artikelnummer", article.ArticleNumber, current.ArticleNumber)
+	conflicts.compare("Bezeichnung kurz", article.ShortName, current.ShortName)
+	conflicts.compare("Beschreibung", article.LongName, current.LongName)
+	conflicts.compare("EAN", article.EAN, current.EAN)
+	conflicts.compare("Warengruppe", article.Category, current.Category)
+	conflicts.compare("Lagereinheit", article.Unit, current.Unit)
+	conflicts.compare("Einkaufseinheit", article.GetPurchaseUnit(), current.GetPurchaseUnit())
+	conflicts.compare("Ausgabeeinheit", article.GetIssueUnit(), current.GetIssueUnit())
+	conflicts.compare("Umrechnungen", formatUnitConversions(article), formatUnitConversions(current))
+	conflicts.compare("Stückliste", formatBillOfMaterials(article), formatBillOfMaterials(current))
+	conflicts.compare("Aktueller Bestand", article.StockCurrent, current.StockCurrent)
+	conflicts.compare("Mindestbestand", article.MinimumStock, current.MinimumStock)
+	conflicts.compare("Lagerort", article.StorageLocationID.Hex(), current.StorageLocationID.Hex())
+	conflicts.compare("Lieferantennr.", article.SupplierNumber, current.SupplierNumber)
+	conflicts.compare("Lieferzeit (Tage)", article.DeliveryTimeInDays, current.DeliveryTimeInDays)
+	conflicts.compare("Einkaufspreis (netto)", article.PurchasePriceNet, current.PurchasePriceNet)
+	conflicts.compare("Verkaufspreis (brutto)", article.SalesPriceGross, current.SalesPriceGross)
+	conflicts.compare("Gewicht (kg)", article.WeightKg, current.WeightKg)
+	conflicts.compare("Abmessungen", article.Dimensions, current.Dimensions)
+	conflicts.compare("Seriennummernpflichtig", article.SerialNumberRequired, current.SerialNumberRequired)
+	conflicts.compare("Chargenpflichtig", article.LotTracked, current.LotTracked)
+	conflicts.compare("Gefahrgutklasse", article.HazardClass, current.HazardClass)
+	conflicts.compare("Bemerkungen", article.Notes, current.Notes)
+
+	// Mit der aktuellen Version kann der Benutzer seine Werte bewusst erneut speichern
+	article.Version = current.Version
+	h.renderArticleEditForm(c, http.StatusConflict, article, conflicts)
+}
+
 // DeleteArticle löscht einen Artikel
 func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 	id := c.Param("id")
@@ -566,6 +938,23 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 		return
 	}
 
+	// Bestände je Lagerort, Chargen, Seriennummern, Reservierungen und Bezugsquellen entfernen
+	if err := h.stockLevelRepo.DeleteByArticleID(article.ID); err != nil {
+		log.Printf("Lagerplatzbestände für Artikel %s konnten nicht gelöscht werden: %v", article.ID.Hex(), err)
+	}
+	if err := repository.NewLotRepository().DeleteByArticleID(article.ID); err != nil {
+		log.Printf("Chargen für Artikel %s konnten nicht gelöscht werden: %v", article.ID.Hex(), err)
+	}
+	if err := repository.NewSerialNumberRepository().DeleteByArticleID(article.ID); err != nil {
+		log.Printf("Seriennummern für Artikel %s konnten nicht gelöscht werden: %v", article.ID.Hex(), err)
+	}
+	if err := repository.NewReservationRepository().DeleteByArticleID(article.ID); err != nil {
+		log.Printf("Reservierungen für Artikel %s konnten nicht gelöscht werden: %v", article.ID.Hex(), err)
+	}
+	if err := repository.NewSupplierArticleRepository().DeleteByArticleID(article.ID); err != nil {
+		log.Printf("Bezugsquellen für Artikel %s konnten nicht gelöscht werden: %v", article.ID.Hex(), err)
+	}
+
 	// Aktivität loggen
 	currentUser, _ := c.Get("user")
 	currentUserModel := currentUser.(*model.User)
@@ -0,0 +1,168 @@
+// backend/handler/assemblyHandler.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AssemblyHandler verwaltet Montagen und Demontagen von Sets
+type AssemblyHandler struct {
+	articleRepo     *repository.ArticleRepository
+	locationRepo    *repository.LocationRepository
+	assemblyService *service.AssemblyService
+}
+
+// NewAssemblyHandler erstellt einen neuen AssemblyHandler
+func NewAssemblyHandler() *AssemblyHandler {
+	return &AssemblyHandler{
+		articleRepo:     repository.NewArticleRepository(),
+		locationRepo:    repository.NewLocationRepository(),
+		assemblyService: service.NewAssemblyService(),
+	}
+}
+
+// assemblyFormInput sind die Eingaben des Montageformulars
+type assemblyFormInput struct {
+	Type       string
+	Quantity   string
+	LocationID string
+	Notes      string
+}
+
+// ShowAssemblyForm zeigt das Formular für eine Montage oder Demontage eines Sets an
+func (h *AssemblyHandler) ShowAssemblyForm(c *gin.Context) {
+	kit, err := h.articleRepo.FindByID(c.Query("articleId"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Artikel nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	input := assemblyFormInput{Type: c.Query("type"), Quantity: "1"}
+	if input.Type != string(model.AssemblyTypeDisassembly) {
+		input.Type = string(model.AssemblyTypeAssembly)
+	}
+	if !kit.StorageLocationID.IsZero() {
+		input.LocationID = kit.StorageLocationID.Hex()
+	}
+
+	h.renderAssemblyForm(c, http.StatusOK, kit, input, "")
+}
+
+// PostAssembly bucht eine Montage oder Demontage
+func (h *AssemblyHandler) PostAssembly(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	kit, err := h.articleRepo.FindByID(c.PostForm("articleId"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Artikel nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	input := assemblyFormInput{
+		Type:       c.PostForm("type"),
+		Quantity:   strings.TrimSpace(c.PostForm("quantity")),
+		LocationID: c.PostForm("locationId"),
+		Notes:      strings.TrimSpace(c.PostForm("notes")),
+	}
+
+	quantity, err := strconv.ParseFloat(strings.Replace(input.Quantity, ",", ".", 1), 64)
+	if err != nil {
+		h.renderAssemblyForm(c, http.StatusBadRequest, kit, input, service.ErrAssemblyQuantity.Error())
+		return
+	}
+	locationID, _ := primitive.ObjectIDFromHex(input.LocationID)
+
+	assembly, err := h.assemblyService.PostAssembly(kit.ID.Hex(), model.AssemblyType(input.Type), quantity, locationID,
+		input.Notes, userModel.ID, userModel.FirstName+" "+userModel.LastName)
+	if err != nil {
+		h.renderAssemblyForm(c, http.StatusUnprocessableEntity, kit, input, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/assemblies/view/%s?success=posted", assembly.ID.Hex()))
+}
+
+// GetAssemblyDetails zeigt eine gebuchte Montage oder Demontage an
+func (h *AssemblyHandler) GetAssemblyDetails(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	assembly, err := h.assemblyService.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Montage nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "assembly_detail.html", gin.H{
+		"title":    assembly.Type.GetDisplayName() + " " + assembly.AssemblyNumber,
+		"active":   "articles",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"assembly": assembly,
+		"success":  c.Query("success"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// renderAssemblyForm zeigt das Montageformular mit der baubaren Menge des Sets an
+func (h *AssemblyHandler) renderAssemblyForm(c *gin.Context, status int, kit *model.Article, input assemblyFormInput, errorMessage string) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	buildability, err := h.assemblyService.GetBuildability(kit)
+	if err != nil {
+		c.HTML(http.StatusUnprocessableEntity, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	locationMap, err := h.locationRepo.BuildLocationTree()
+	if err != nil {
+		locationMap = map[primitive.ObjectID]*model.Location{} // Leere Auswahl im Fehlerfall
+	}
+
+	c.HTML(status, "assembly_form.html", gin.H{
+		"title":        "Montage " + kit.ShortName,
+		"active":       "articles",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"kit":          kit,
+		"buildability": buildability,
+		"locations":    buildReceivingLocations(locationMap),
+		"input":        input,
+		"error":        errorMessage,
+		"userRole":     c.GetString("userRole"),
+	})
+}
@@ -0,0 +1,121 @@
+// backend/handler/bomHelper.go
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bindBillOfMaterials übernimmt die Stückliste aus dem Artikelformular. Jede Komponente darf
+// nur einmal vorkommen und braucht eine Menge größer 0. Ein Set darf weder sich selbst noch
+// über die Stücklisten seiner Komponenten wieder sich selbst enthalten.
+func bindBillOfMaterials(c *gin.Context, article *model.Article, articleRepo *repository.ArticleRepository) error {
+	articleIDs := c.PostFormArray("bomArticleId")
+	quantities := c.PostFormArray("bomQuantity")
+
+	findArticle := func(id primitive.ObjectID) (*model.Article, error) {
+		return articleRepo.FindByID(id.Hex())
+	}
+
+	components := []model.BOMComponent{}
+	seen := make(map[primitive.ObjectID]bool)
+	for i, idStr := range articleIDs {
+		if idStr == "" {
+			continue
+		}
+		component, err := articleRepo.FindByID(idStr)
+		if err != nil {
+			return fmt.Errorf("Komponente der Stückliste nicht gefunden: %v", err)
+		}
+		if component.ID == article.ID {
+			return fmt.Errorf("Ein Artikel kann nicht Komponente seiner eigenen Stückliste sein")
+		}
+		if seen[component.ID] {
+			return fmt.Errorf("Die Komponente %s ist mehrfach in der Stückliste enthalten", component.ArticleNumber)
+		}
+		seen[component.ID] = true
+
+		var quantity float64
+		if i < len(quantities) {
+			quantity, _ = strconv.ParseFloat(strings.Replace(quantities[i], ",", ".", 1), 64)
+		}
+		if quantity <= 0 {
+			return fmt.Errorf("Bitte für die Komponente %s eine Menge größer 0 angeben", component.ArticleNumber)
+		}
+
+		if !article.ID.IsZero() && containsArticle(component, article.ID, findArticle, map[primitive.ObjectID]bool{}) {
+			return fmt.Errorf("Die Komponente %s enthält diesen Artikel bereits in ihrer Stückliste", component.ArticleNumber)
+		}
+
+		components = append(components, model.BOMComponent{
+			ArticleID:     component.ID,
+			ArticleNumber: component.ArticleNumber,
+			ArticleName:   component.ShortName,
+			Unit:          component.Unit,
+			Quantity:      quantity,
+		})
+	}
+	article.BillOfMaterials = components
+
+	return nil
+}
+
+// containsArticle prüft, ob die Stückliste eines Sets einen Artikel direkt oder über
+// mehrere Stufen enthält; find lädt die Komponenten
+func containsArticle(kit *model.Article, articleID primitive.ObjectID, find func(primitive.ObjectID) (*model.Article, error), visited map[primitive.ObjectID]bool) bool {
+	if visited[kit.ID] {
+		return false
+	}
+	visited[kit.ID] = true
+
+	for _, component := range kit.BillOfMaterials {
+		if component.ArticleID == articleID {
+			return true
+		}
+		sub, err := find(component.ArticleID)
+		if err != nil {
+			continue
+		}
+		if containsArticle(sub, articleID, find, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// bomOptions gibt die Artikel zurück, die als Komponente der Stückliste gewählt werden können
+func bomOptions(articleRepo *repository.ArticleRepository, article *model.Article) []*model.Article {
+	articles, err := articleRepo.FindAll()
+	if err != nil {
+		return []*model.Article{} // Leere Liste im Fehlerfall
+	}
+
+	options := make([]*model.Article, 0, len(articles))
+	for _, candidate := range articles {
+		if article != nil && candidate.ID == article.ID {
+			continue
+		}
+		options = append(options, candidate)
+	}
+	return options
+}
+
+// formatBillOfMaterials beschreibt die Stückliste eines Artikels für die Konfliktanzeige
+func formatBillOfMaterials(article *model.Article) string {
+	if len(article.BillOfMaterials) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, len(article.BillOfMaterials))
+	for i, component := range article.BillOfMaterials {
+		parts[i] = fmt.Sprintf("%g %s %s", component.Quantity, component.Unit, component.ArticleNumber)
+	}
+	return strings.Join(parts, ", ")
+}
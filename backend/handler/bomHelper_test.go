@@ -0,0 +1,66 @@
+// backend/handler/bomHelper_test.go
+package handler
+
+import (
+	"testing"
+
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestContainsArticle(t *testing.T) {
+	// Set A enthält B, B enthält C; D enthält A; E und F enthalten sich gegenseitig; G verweist
+	// auf einen gelöschten Artikel
+	a := &model.Article{ID: primitive.NewObjectID()}
+	b := &model.Article{ID: primitive.NewObjectID()}
+	c := &model.Article{ID: primitive.NewObjectID()}
+	d := &model.Article{ID: primitive.NewObjectID()}
+	e := &model.Article{ID: primitive.NewObjectID()}
+	f := &model.Article{ID: primitive.NewObjectID()}
+	g := &model.Article{ID: primitive.NewObjectID()}
+	unrelated := &model.Article{ID: primitive.NewObjectID()}
+
+	a.BillOfMaterials = []model.BOMComponent{{ArticleID: b.ID, Quantity: 2}}
+	b.BillOfMaterials = []model.BOMComponent{{ArticleID: c.ID, Quantity: 1}}
+	d.BillOfMaterials = []model.BOMComponent{{ArticleID: a.ID, Quantity: 1}}
+	e.BillOfMaterials = []model.BOMComponent{{ArticleID: f.ID, Quantity: 1}}
+	f.BillOfMaterials = []model.BOMComponent{{ArticleID: e.ID, Quantity: 1}}
+	g.BillOfMaterials = []model.BOMComponent{{ArticleID: primitive.NewObjectID(), Quantity: 1}, {ArticleID: c.ID, Quantity: 1}}
+
+	articles := make(map[primitive.ObjectID]*model.Article)
+	for _, article := range []*model.Article{a, b, c, d, e, f, g, unrelated} {
+		articles[article.ID] = article
+	}
+	find := func(id primitive.ObjectID) (*model.Article, error) {
+		if article, exists := articles[id]; exists {
+			return article, nil
+		}
+		return nil, mongo.ErrNoDocuments
+	}
+
+	tests := []struct {
+		name      string
+		kit       *model.Article
+		articleID primitive.ObjectID
+		want      bool
+	}{
+		{"direkte Komponente", a, b.ID, true},
+		{"Komponente einer Komponente", a, c.ID, true},
+		{"über drei Stufen", d, c.ID, true},
+		{"nicht enthalten", a, unrelated.ID, false},
+		{"Komponente enthält das Set nicht", c, a.ID, false},
+		{"Artikel ohne Stückliste", unrelated, a.ID, false},
+		{"bestehender Zyklus terminiert", e, unrelated.ID, false},
+		{"gelöschte Komponenten werden übersprungen", g, c.ID, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsArticle(tt.kit, tt.articleID, find, map[primitive.ObjectID]bool{}); got != tt.want {
+				t.Errorf("containsArticle() = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// backend/handler/conflictHelper.go
+package handler
+
+import (
+	"fmt"
+)
+
+// fieldConflict beschreibt ein Feld, dessen gespeicherter Wert von der eigenen Eingabe abweicht
+type fieldConflict struct {
+	Label  string // Feldbezeichnung für die Anzeige
+	Mine   string // Wert aus dem abgesendeten Formular
+	Theirs string // Aktuell gespeicherter Wert
+}
+
+// conflictList sammelt die Abweichungen zwischen Formulareingabe und gespeichertem Datensatz
+type conflictList []fieldConflict
+
+// compare fügt ein Feld hinzu, wenn sich eigener und gespeicherter Wert unterscheiden
+func (l *conflictList) compare(label string, mine, theirs interface{}) {
+	mineStr := fmt.Sprintf("%v", mine)
+	theirsStr := fmt.Sprintf("%v", theirs)
+	if mineStr != theirsStr {
+		*l = append(*l, fieldConflict{Label: label, Mine: mineStr, Theirs: theirsStr})
+	}
+}
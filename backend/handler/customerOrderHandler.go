@@ -0,0 +1,489 @@
+// backend/handler/customerOrderHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerOrderHandler verwaltet alle Anfragen zu Kundenaufträgen
+type CustomerOrderHandler struct {
+	customerOrderRepo    *repository.CustomerOrderRepository
+	articleRepo          *repository.ArticleRepository
+	customerOrderService *service.CustomerOrderService
+	returnService        *service.ReturnService
+}
+
+// NewCustomerOrderHandler erstellt einen neuen CustomerOrderHandler
+func NewCustomerOrderHandler() *CustomerOrderHandler {
+	return &CustomerOrderHandler{
+		customerOrderRepo:    repository.NewCustomerOrderRepository(),
+		articleRepo:          repository.NewArticleRepository(),
+		customerOrderService: service.NewCustomerOrderService(),
+		returnService:        service.NewReturnService(),
+	}
+}
+
+// customerOrderStatuses sind die Status in der Reihenfolge der Filterauswahl
+var customerOrderStatuses = []model.CustomerOrderStatus{
+	model.CustomerOrderStatusDraft,
+	model.CustomerOrderStatusConfirmed,
+	model.CustomerOrderStatusShipped,
+	model.CustomerOrderStatusCancelled,
+}
+
+// ListCustomerOrders zeigt die Liste aller Aufträge an, optional nach Status gefiltert
+func (h *CustomerOrderHandler) ListCustomerOrders(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	status := model.CustomerOrderStatus(c.Query("status"))
+
+	orders, err := h.customerOrderRepo.FindAll(status)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Abrufen der Aufträge: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	statusFilter := make([]gin.H, 0, len(customerOrderStatuses))
+	for _, s := range customerOrderStatuses {
+		statusFilter = append(statusFilter, gin.H{
+			"Value": string(s),
+			"Label": model.GetCustomerOrderStatusDisplay(s),
+		})
+	}
+
+	c.HTML(http.StatusOK, "customer_orders.html", gin.H{
+		"title":        "Aufträge",
+		"active":       "customer-orders",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"orders":       orders,
+		"status":       string(status),
+		"statusFilter": statusFilter,
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// GetCustomerOrderDetails zeigt die Details eines Auftrags an
+func (h *CustomerOrderHandler) GetCustomerOrderDetails(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.customerOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Auftrag nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Retouren zum Auftrag; ein Fehler blendet die Liste nur aus
+	returns, _ := h.returnService.FindBySource(order.ID)
+
+	c.HTML(http.StatusOK, "customer_order_detail.html", gin.H{
+		"title":    "Auftrag " + order.OrderNumber,
+		"active":   "customer-orders",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"order":    order,
+		"returns":  returns,
+		"success":  c.Query("success"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// ShowAddCustomerOrderForm zeigt das Formular zum Anlegen eines Auftrags an
+func (h *CustomerOrderHandler) ShowAddCustomerOrderForm(c *gin.Context) {
+	order := &model.CustomerOrder{}
+
+	// Vorbelegung aus der Artikelansicht
+	if articleID := c.Query("articleId"); articleID != "" {
+		if article, err := h.articleRepo.FindByID(articleID); err == nil {
+			order.Lines = []model.CustomerOrderLine{{
+				ArticleID: article.ID,
+				Quantity:  1,
+				UnitPrice: article.SalesPriceGross,
+			}}
+		}
+	}
+
+	h.renderCustomerOrderForm(c, http.StatusOK, order, "")
+}
+
+// AddCustomerOrder legt einen neuen Auftrag als Entwurf an
+func (h *CustomerOrderHandler) AddCustomerOrder(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order := &model.CustomerOrder{}
+	lines, err := parseCustomerOrderForm(c, order)
+	if err != nil {
+		h.renderCustomerOrderForm(c, http.StatusBadRequest, order, err.Error())
+		return
+	}
+
+	err = h.customerOrderService.CreateCustomerOrder(order, lines, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if isCustomerOrderInputError(err) {
+			status = http.StatusBadRequest
+		}
+		h.renderCustomerOrderForm(c, status, order, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/customer-orders/view/%s?success=added", order.ID.Hex()))
+}
+
+// ShowEditCustomerOrderForm zeigt das Formular zum Bearbeiten eines Auftrags im Entwurf an
+func (h *CustomerOrderHandler) ShowEditCustomerOrderForm(c *gin.Context) {
+	order, err := h.customerOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Auftrag nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if !order.IsEditable() {
+		c.Redirect(http.StatusFound, "/customer-orders/view/"+order.ID.Hex())
+		return
+	}
+
+	h.renderCustomerOrderForm(c, http.StatusOK, order, "")
+}
+
+// UpdateCustomerOrder speichert einen bearbeiteten Auftrag im Entwurf
+func (h *CustomerOrderHandler) UpdateCustomerOrder(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.customerOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Auftrag nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Version aus dem Formular verwenden, damit zwischenzeitliche Änderungen erkannt werden
+	if version, err := strconv.ParseInt(c.PostForm("version"), 10, 64); err == nil {
+		order.Version = version
+	}
+
+	lines, err := parseCustomerOrderForm(c, order)
+	if err != nil {
+		h.renderCustomerOrderForm(c, http.StatusBadRequest, order, err.Error())
+		return
+	}
+
+	err = h.customerOrderService.UpdateCustomerOrder(order, lines, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case isCustomerOrderInputError(err):
+			status = http.StatusBadRequest
+		case err == repository.ErrVersionConflict, err == service.ErrCustomerOrderNotEditable:
+			status = http.StatusConflict
+		}
+		h.renderCustomerOrderForm(c, status, order, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/customer-orders/view/%s?success=updated", order.ID.Hex()))
+}
+
+// ConfirmCustomerOrder bestätigt einen Auftrag und reserviert den Bestand seiner Positionen
+func (h *CustomerOrderHandler) ConfirmCustomerOrder(c *gin.Context) {
+	h.changeStatus(c, h.customerOrderService.ConfirmCustomerOrder, "confirmed")
+}
+
+// CancelCustomerOrder storniert einen Auftrag
+func (h *CustomerOrderHandler) CancelCustomerOrder(c *gin.Context) {
+	h.changeStatus(c, h.customerOrderService.CancelCustomerOrder, "cancelled")
+}
+
+// ShowPickingList zeigt die nach Lagerort sortierte Kommissionierliste eines bestätigten Auftrags an
+func (h *CustomerOrderHandler) ShowPickingList(c *gin.Context) {
+	order, err := h.customerOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Auftrag nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if !order.CanShip() {
+		c.Redirect(http.StatusFound, "/customer-orders/view/"+order.ID.Hex())
+		return
+	}
+
+	list, err := h.customerOrderService.BuildPickingList(order)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen der Kommissionierliste: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	h.renderPickingList(c, http.StatusOK, order, list, nil, "")
+}
+
+// ShipCustomerOrder bestätigt die Kommissionierung, bucht die Warenausgänge und markiert
+// den Auftrag als versendet
+func (h *CustomerOrderHandler) ShipCustomerOrder(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.customerOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Auftrag nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Entnahmen einlesen; die Eingaben bleiben für eine erneute Anzeige erhalten
+	list := &service.PickingList{}
+	serials := make(map[int]string)
+	var picks []service.PickInput
+	for _, rowStr := range c.PostFormArray("pick") {
+		if _, err := strconv.Atoi(rowStr); err != nil {
+			continue
+		}
+		suffix := "_" + rowStr
+
+		lineIndex, err := strconv.Atoi(c.PostForm("lineIndex" + suffix))
+		if err != nil || lineIndex < 0 || lineIndex >= len(order.Lines) {
+			continue
+		}
+		line := order.Lines[lineIndex]
+
+		pick := service.PickInput{LineIndex: lineIndex}
+		pick.LocationID, _ = primitive.ObjectIDFromHex(c.PostForm("locationId" + suffix))
+		pick.Quantity, _ = strconv.ParseFloat(c.PostForm("quantity"+suffix), 64)
+		serials[len(picks)] = c.PostForm("serialNumbers" + suffix)
+		pick.SerialNumbers = parseSerialNumbers(serials[len(picks)])
+		picks = append(picks, pick)
+
+		list.Entries = append(list.Entries, &service.PickListEntry{
+			LineIndex:            lineIndex,
+			ArticleID:            line.ArticleID,
+			ArticleNumber:        line.ArticleNumber,
+			ArticleName:          line.ArticleName,
+			Unit:                 line.Unit,
+			LocationID:           pick.LocationID,
+			LocationPath:         c.PostForm("locationPath" + suffix),
+			Quantity:             pick.Quantity,
+			SerialNumberRequired: c.PostForm("serialRequired"+suffix) == "true",
+		})
+	}
+
+	err = h.customerOrderService.ShipCustomerOrder(order.ID.Hex(), picks, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		switch {
+		case err == repository.ErrCustomerOrderStatus, err == service.ErrCustomerOrderNotConfirmed:
+			c.HTML(http.StatusConflict, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Der Auftrag wurde zwischenzeitlich geändert oder bereits versendet.",
+				"year":    time.Now().Year(),
+			})
+		case errors.Is(err, service.ErrCustomerOrderPick):
+			h.renderPickingList(c, http.StatusBadRequest, order, list, serials, err.Error())
+		default:
+			// Buchungsfehler, z.B. fehlender Bestand am Lagerort oder unbekannte Seriennummern
+			h.renderPickingList(c, http.StatusUnprocessableEntity, order, list, serials, err.Error())
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/customer-orders/view/%s?success=shipped", order.ID.Hex()))
+}
+
+// changeStatus führt einen Statuswechsel aus und leitet zur Detailseite zurück
+func (h *CustomerOrderHandler) changeStatus(
+	c *gin.Context,
+	change func(id string, userID primitive.ObjectID, userName string) error,
+	success string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	id := c.Param("id")
+	if err := change(id, userModel.ID, fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case err == repository.ErrCustomerOrderStatus, err == repository.ErrVersionConflict:
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrReservationExceedsStock):
+			status = http.StatusUnprocessableEntity
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/customer-orders/view/%s?success=%s", id, success))
+}
+
+// renderCustomerOrderForm zeigt das Auftragsformular zum Anlegen oder Bearbeiten an
+func (h *CustomerOrderHandler) renderCustomerOrderForm(c *gin.Context, status int, order *model.CustomerOrder, errorMessage string) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	articles, err := h.articleRepo.FindAll()
+	if err != nil {
+		articles = []*model.Article{} // Leere Liste im Fehlerfall
+	}
+
+	// Nur aktive Artikel können verkauft werden
+	activeArticles := make([]*model.Article, 0, len(articles))
+	for _, article := range articles {
+		if article.IsActive {
+			activeArticles = append(activeArticles, article)
+		}
+	}
+
+	title := "Auftrag anlegen"
+	action := "/customer-orders/add"
+	if !order.ID.IsZero() {
+		title = "Auftrag " + order.OrderNumber + " bearbeiten"
+		action = "/customer-orders/edit/" + order.ID.Hex()
+	}
+
+	c.HTML(status, "customer_order_form.html", gin.H{
+		"title":    title,
+		"active":   "customer-orders",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"order":    order,
+		"action":   action,
+		"articles": activeArticles,
+		"error":    errorMessage,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// renderPickingList zeigt die Kommissionierliste mit dem Formular zur Bestätigung an
+func (h *CustomerOrderHandler) renderPickingList(
+	c *gin.Context,
+	status int,
+	order *model.CustomerOrder,
+	list *service.PickingList,
+	serials map[int]string,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	c.HTML(status, "picking_list.html", gin.H{
+		"title":    "Kommissionierliste " + order.OrderNumber,
+		"active":   "customer-orders",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"order":    order,
+		"list":     list,
+		"serials":  serials,
+		"now":      time.Now(),
+		"error":    errorMessage,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// parseCustomerOrderForm übernimmt Kopfdaten aus dem Formular in den Auftrag und gibt die
+// erfassten Positionen zurück
+func parseCustomerOrderForm(c *gin.Context, order *model.CustomerOrder) ([]service.CustomerOrderLineInput, error) {
+	order.CustomerName = strings.TrimSpace(c.PostForm("customerName"))
+	order.CustomerReference = strings.TrimSpace(c.PostForm("customerReference"))
+	order.ShippingAddress = strings.TrimSpace(c.PostForm("shippingAddress"))
+	order.Notes = strings.TrimSpace(c.PostForm("notes"))
+
+	var err error
+	order.DeliveryDate = time.Time{}
+	if deliveryDate := c.PostForm("deliveryDate"); deliveryDate != "" {
+		order.DeliveryDate, err = time.ParseInLocation("2006-01-02", deliveryDate, time.Local)
+		if err != nil {
+			return nil, errors.New("Ungültiger Liefertermin")
+		}
+	}
+
+	articleIDs := c.PostFormArray("lineArticleId")
+	quantities := c.PostFormArray("lineQuantity")
+	unitPrices := c.PostFormArray("lineUnitPrice")
+
+	lines := make([]service.CustomerOrderLineInput, 0, len(articleIDs))
+	order.Lines = order.Lines[:0]
+	for i, articleID := range articleIDs {
+		line := service.CustomerOrderLineInput{ArticleID: articleID}
+		if i < len(quantities) {
+			line.Quantity, _ = strconv.ParseFloat(quantities[i], 64)
+		}
+		if i < len(unitPrices) {
+			line.UnitPrice, _ = strconv.ParseFloat(unitPrices[i], 64)
+		}
+		lines = append(lines, line)
+
+		// Eingaben für eine erneute Anzeige des Formulars festhalten
+		objID, _ := primitive.ObjectIDFromHex(articleID)
+		order.Lines = append(order.Lines, model.CustomerOrderLine{
+			ArticleID: objID,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		})
+	}
+
+	return lines, nil
+}
+
+// isCustomerOrderInputError prüft, ob ein Fehler auf ungültige Eingaben zurückgeht
+func isCustomerOrderInputError(err error) bool {
+	return err == service.ErrCustomerOrderCustomer ||
+		err == service.ErrCustomerOrderNoLines ||
+		err == service.ErrCustomerOrderLine
+}
@@ -0,0 +1,463 @@
+// backend/handler/cycleCountHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CycleCountHandler verwaltet alle Anfragen zu Inventurzählungen
+type CycleCountHandler struct {
+	cycleCountRepo    *repository.CycleCountRepository
+	articleRepo       *repository.ArticleRepository
+	locationRepo      *repository.LocationRepository
+	cycleCountService *service.CycleCountService
+}
+
+// NewCycleCountHandler erstellt einen neuen CycleCountHandler
+func NewCycleCountHandler() *CycleCountHandler {
+	return &CycleCountHandler{
+		cycleCountRepo:    repository.NewCycleCountRepository(),
+		articleRepo:       repository.NewArticleRepository(),
+		locationRepo:      repository.NewLocationRepository(),
+		cycleCountService: service.NewCycleCountService(),
+	}
+}
+
+// cycleCountStatuses sind die Status in der Reihenfolge der Filterauswahl
+var cycleCountStatuses = []model.CycleCountStatus{
+	model.CycleCountStatusCounting,
+	model.CycleCountStatusReview,
+	model.CycleCountStatusApproved,
+	model.CycleCountStatusCancelled,
+}
+
+// ListCycleCounts zeigt die Liste aller Zählungen an, optional nach Status gefiltert
+func (h *CycleCountHandler) ListCycleCounts(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	status := model.CycleCountStatus(c.Query("status"))
+
+	counts, err := h.cycleCountRepo.FindAll(status)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Abrufen der Zählungen: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	statusFilter := make([]gin.H, 0, len(cycleCountStatuses))
+	for _, s := range cycleCountStatuses {
+		statusFilter = append(statusFilter, gin.H{
+			"Value": string(s),
+			"Label": model.GetCycleCountStatusDisplay(s),
+		})
+	}
+
+	c.HTML(http.StatusOK, "cycle_counts.html", gin.H{
+		"title":        "Inventur",
+		"active":       "cycle-counts",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"counts":       counts,
+		"status":       string(status),
+		"statusFilter": statusFilter,
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// GetCycleCountDetails zeigt eine Zählung mit ihren Differenzen und deren Wertauswirkung an
+func (h *CycleCountHandler) GetCycleCountDetails(c *gin.Context) {
+	count, err := h.cycleCountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Zählung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	h.renderCycleCountDetails(c, http.StatusOK, count, nil, "")
+}
+
+// ShowAddCycleCountForm zeigt das Formular zum Anlegen einer Zählung an
+func (h *CycleCountHandler) ShowAddCycleCountForm(c *gin.Context) {
+	h.renderCycleCountForm(c, http.StatusOK, service.CycleCountInput{
+		Scope:      model.CycleCountScopeWarehouse,
+		BlindCount: true,
+	}, "")
+}
+
+// AddCycleCount legt eine Zählung an und hält die Sollbestände fest
+func (h *CycleCountHandler) AddCycleCount(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	input := service.CycleCountInput{
+		Scope:      model.CycleCountScope(c.PostForm("scope")),
+		Category:   strings.TrimSpace(c.PostForm("category")),
+		BlindCount: c.PostForm("blindCount") == "on",
+		Notes:      strings.TrimSpace(c.PostForm("notes")),
+	}
+	switch input.Scope {
+	case model.CycleCountScopeWarehouse:
+		input.LocationID, _ = primitive.ObjectIDFromHex(c.PostForm("warehouseId"))
+	case model.CycleCountScopeLocation:
+		input.LocationID, _ = primitive.ObjectIDFromHex(c.PostForm("locationId"))
+	}
+
+	count, err := h.cycleCountService.CreateCycleCount(input, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrCycleCountScope || err == service.ErrCycleCountEmpty {
+			status = http.StatusBadRequest
+		}
+		h.renderCycleCountForm(c, status, input, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/cycle-counts/view/%s?success=added", count.ID.Hex()))
+}
+
+// ShowCountSheet zeigt das Zählblatt einer laufenden Zählung an
+func (h *CycleCountHandler) ShowCountSheet(c *gin.Context) {
+	count, err := h.cycleCountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Zählung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if !count.IsCounting() {
+		c.Redirect(http.StatusFound, "/cycle-counts/view/"+count.ID.Hex())
+		return
+	}
+
+	h.renderCountSheet(c, http.StatusOK, count, nil, c.Query("success"), "")
+}
+
+// RecordCounts speichert die auf dem Zählblatt erfassten Mengen. Leere Felder gelten als
+// nicht gezählt.
+func (h *CycleCountHandler) RecordCounts(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	count, err := h.cycleCountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Zählung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Eingaben bleiben für eine erneute Anzeige erhalten
+	entered := make(map[int]string)
+	quantities := make(map[int]float64)
+	var parseErr error
+	for i := range count.Items {
+		value := strings.TrimSpace(c.PostForm("quantity_" + strconv.Itoa(i)))
+		if value == "" {
+			continue
+		}
+		entered[i] = value
+
+		quantity, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+		if err != nil {
+			parseErr = fmt.Errorf("%s (%s): %w", count.Items[i].ArticleNumber, count.Items[i].LocationPath, service.ErrCycleCountQuantity)
+			continue
+		}
+		quantities[i] = quantity
+	}
+	if parseErr != nil {
+		h.renderCountSheet(c, http.StatusBadRequest, count, entered, "", parseErr.Error())
+		return
+	}
+
+	err = h.cycleCountService.RecordCounts(count.ID.Hex(), quantities, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		switch {
+		case err == repository.ErrCycleCountStatus:
+			c.HTML(http.StatusConflict, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Zählung wurde zwischenzeitlich abgeschlossen.",
+				"year":    time.Now().Year(),
+			})
+		case err == service.ErrCycleCountNoCounts, errors.Is(err, service.ErrCycleCountQuantity):
+			h.renderCountSheet(c, http.StatusBadRequest, count, entered, "", err.Error())
+		default:
+			h.renderCountSheet(c, http.StatusInternalServerError, count, entered, "", err.Error())
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/cycle-counts/count/%s?success=saved", count.ID.Hex()))
+}
+
+// CloseCounting beendet die Erfassung und legt die Zählung zur Freigabe vor
+func (h *CycleCountHandler) CloseCounting(c *gin.Context) {
+	h.changeStatus(c, h.cycleCountService.CloseCounting, "closed")
+}
+
+// ReopenCounting öffnet eine Zählung für Nachzählungen wieder
+func (h *CycleCountHandler) ReopenCounting(c *gin.Context) {
+	h.changeStatus(c, h.cycleCountService.ReopenCounting, "reopened")
+}
+
+// CancelCycleCount bricht eine Zählung ab
+func (h *CycleCountHandler) CancelCycleCount(c *gin.Context) {
+	h.changeStatus(c, h.cycleCountService.CancelCycleCount, "cancelled")
+}
+
+// ApproveCycleCount gibt die Differenzen einer Zählung frei und bucht sie als Inventur
+func (h *CycleCountHandler) ApproveCycleCount(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	count, err := h.cycleCountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Zählung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Angaben zu Chargen und Seriennummern einlesen; die Eingaben bleiben für eine erneute Anzeige erhalten
+	entered := make(map[int]gin.H)
+	postings := make(map[int]service.CycleCountPostingInput)
+	for i := range count.Items {
+		suffix := "_" + strconv.Itoa(i)
+		lotNumber := strings.TrimSpace(c.PostForm("lotNumber" + suffix))
+		expiryDate := c.PostForm("expiryDate" + suffix)
+		serialNumbers := c.PostForm("serialNumbers" + suffix)
+		if lotNumber == "" && expiryDate == "" && serialNumbers == "" {
+			continue
+		}
+		entered[i] = gin.H{"LotNumber": lotNumber, "ExpiryDate": expiryDate, "SerialNumbers": serialNumbers}
+
+		posting := service.CycleCountPostingInput{
+			LotNumber:     lotNumber,
+			SerialNumbers: parseSerialNumbers(serialNumbers),
+		}
+		if expiryDate != "" {
+			posting.ExpiryDate, err = time.ParseInLocation("2006-01-02", expiryDate, time.Local)
+			if err != nil {
+				h.renderCycleCountDetails(c, http.StatusBadRequest, count, entered,
+					fmt.Sprintf("%s (%s): Ungültiges Mindesthaltbarkeitsdatum", count.Items[i].ArticleNumber, count.Items[i].LocationPath))
+				return
+			}
+		}
+		postings[i] = posting
+	}
+
+	err = h.cycleCountService.ApproveCycleCount(count.ID.Hex(), postings, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		if err == repository.ErrCycleCountStatus {
+			c.HTML(http.StatusConflict, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Zählung wurde zwischenzeitlich geändert oder bereits freigegeben.",
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+
+		// Bereits gebuchte Positionen neu laden, damit die Anzeige den aktuellen Stand zeigt
+		if current, findErr := h.cycleCountRepo.FindByID(count.ID.Hex()); findErr == nil {
+			count = current
+		}
+		h.renderCycleCountDetails(c, http.StatusUnprocessableEntity, count, entered, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/cycle-counts/view/%s?success=approved", count.ID.Hex()))
+}
+
+// changeStatus führt einen Statuswechsel aus und leitet zur Detailseite zurück
+func (h *CycleCountHandler) changeStatus(
+	c *gin.Context,
+	change func(id string, userID primitive.ObjectID, userName string) error,
+	success string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	id := c.Param("id")
+	if err := change(id, userModel.ID, fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName)); err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case repository.ErrCycleCountStatus:
+			status = http.StatusConflict
+		case service.ErrCycleCountNothingCounted:
+			status = http.StatusUnprocessableEntity
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/cycle-counts/view/%s?success=%s", id, success))
+}
+
+// renderCycleCountForm zeigt das Formular zum Anlegen einer Zählung an
+func (h *CycleCountHandler) renderCycleCountForm(c *gin.Context, status int, input service.CycleCountInput, errorMessage string) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	locationMap, err := h.locationRepo.BuildLocationTree()
+	if err != nil {
+		locationMap = map[primitive.ObjectID]*model.Location{} // Leere Auswahl im Fehlerfall
+	}
+
+	var warehouses, locations []gin.H
+	for _, location := range locationMap {
+		option := gin.H{"ID": location.ID.Hex(), "Path": location.GetFullPath(locationMap)}
+		if location.ParentID.IsZero() {
+			warehouses = append(warehouses, option)
+		}
+		locations = append(locations, option)
+	}
+	sortOptionsByPath(warehouses)
+	sortOptionsByPath(locations)
+
+	categories, err := h.articleRepo.GetAllCategories()
+	if err != nil {
+		categories = []string{}
+	}
+
+	c.HTML(status, "cycle_count_form.html", gin.H{
+		"title":      "Zählung anlegen",
+		"active":     "cycle-counts",
+		"user":       userModel.FirstName + " " + userModel.LastName,
+		"email":      userModel.Email,
+		"year":       time.Now().Year(),
+		"input":      input,
+		"locationId": input.LocationID.Hex(),
+		"warehouses": warehouses,
+		"locations":  locations,
+		"categories": categories,
+		"error":      errorMessage,
+		"userRole":   c.GetString("userRole"),
+	})
+}
+
+// renderCountSheet zeigt das Zählblatt mit dem Erfassungsformular an
+func (h *CycleCountHandler) renderCountSheet(
+	c *gin.Context,
+	status int,
+	count *model.CycleCount,
+	entered map[int]string,
+	success, errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	c.HTML(status, "cycle_count_sheet.html", gin.H{
+		"title":    "Zählblatt " + count.CountNumber,
+		"active":   "cycle-counts",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"count":    count,
+		"entered":  entered,
+		"now":      time.Now(),
+		"success":  success,
+		"error":    errorMessage,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// renderCycleCountDetails zeigt die Detailseite einer Zählung mit dem Freigabeformular an
+func (h *CycleCountHandler) renderCycleCountDetails(
+	c *gin.Context,
+	status int,
+	count *model.CycleCount,
+	entered map[int]gin.H,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Chargen- und Seriennummernpflicht der Artikel mit offener Differenz für das Freigabeformular
+	tracking := make(map[int]gin.H)
+	if count.CanApprove() {
+		articles := make(map[primitive.ObjectID]*model.Article)
+		for i := range count.Items {
+			item := &count.Items[i]
+			if !item.HasVariance() || item.IsPosted() {
+				continue
+			}
+			article, exists := articles[item.ArticleID]
+			if !exists {
+				article, _ = h.articleRepo.FindByID(item.ArticleID.Hex())
+				articles[item.ArticleID] = article
+			}
+			if article == nil || (!article.LotTracked && !article.SerialNumberRequired) {
+				continue
+			}
+			tracking[i] = gin.H{
+				"LotTracked":           article.LotTracked,
+				"SerialNumberRequired": article.SerialNumberRequired,
+			}
+		}
+	}
+
+	userRole := c.GetString("userRole")
+	c.HTML(status, "cycle_count_detail.html", gin.H{
+		"title":      "Zählung " + count.CountNumber,
+		"active":     "cycle-counts",
+		"user":       userModel.FirstName + " " + userModel.LastName,
+		"email":      userModel.Email,
+		"year":       time.Now().Year(),
+		"count":      count,
+		"tracking":   tracking,
+		"entered":    entered,
+		"canApprove": userRole == string(model.RoleAdmin) || userRole == string(model.RoleManager),
+		"success":    c.Query("success"),
+		"error":      errorMessage,
+		"userRole":   userRole,
+	})
+}
+
+// sortOptionsByPath sortiert Auswahloptionen für Lagerorte nach ihrem Pfad
+func sortOptionsByPath(options []gin.H) {
+	sort.Slice(options, func(i, j int) bool {
+		return options[i]["Path"].(string) < options[j]["Path"].(string)
+	})
+}
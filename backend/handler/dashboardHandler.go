@@ -4,6 +4,7 @@ package handler
 import (
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
 	"net/http"
 	"time"
 
@@ -16,6 +17,8 @@ type DashboardHandler struct {
 	supplierRepo    *repository.SupplierRepository
 	transactionRepo *repository.TransactionRepository
 	activityRepo    *repository.ActivityRepository
+
+	valuationService *service.ValuationService
 }
 
 // NewDashboardHandler erstellt einen neuen DashboardHandler
@@ -25,6 +28,8 @@ func NewDashboardHandler() *DashboardHandler {
 		supplierRepo:    repository.NewSupplierRepository(),
 		transactionRepo: repository.NewTransactionRepository(),
 		activityRepo:    repository.NewActivityRepository(),
+
+		valuationService: service.NewValuationService(),
 	}
 }
 
@@ -181,7 +186,7 @@ func (h *DashboardHandler) getWarehouseStats() (map[string]interface{}, error) {
 	}
 
 	// Wert des Gesamtbestands
-	totalStockValue, err := h.articleRepo.CalculateTotalStockValue()
+	totalStockValue, err := h.valuationService.CalculateTotalStockValue()
 	if err != nil {
 		return nil, err
 	}
@@ -229,6 +234,20 @@ func formatActivityMessage(activity *model.Activity) string {
 		return "Bestand für <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde angepasst"
 	case model.ActivityTypeStockTaking:
 		return "Inventur für <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde durchgeführt"
+	case model.ActivityTypeStockReversed:
+		return "Buchung für <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde storniert"
+	case model.ActivityTypeStockReserved:
+		return "Bestand für <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde reserviert"
+	case model.ActivityTypeReservationReleased:
+		return "Reservierung für <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde freigegeben"
+	case model.ActivityTypePurchaseOrderCreated:
+		return "Bestellung <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde angelegt"
+	case model.ActivityTypeGoodsReceived:
+		return "Wareneingang <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde gebucht"
+	case model.ActivityTypeCustomerOrderCreated:
+		return "Auftrag <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde angelegt"
+	case model.ActivityTypeCycleCountCreated:
+		return "Zählung <span class=\"font-medium text-gray-900\">" + activity.TargetName + "</span> wurde angelegt"
 	case model.ActivityTypeUserLogin:
 		return "<span class=\"font-medium text-gray-900\">" + activity.UserName + "</span> hat sich angemeldet"
 	default:
@@ -0,0 +1,329 @@
+// backend/handler/goodsReceiptHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoodsReceiptHandler verwaltet alle Anfragen zu Wareneingängen
+type GoodsReceiptHandler struct {
+	goodsReceiptRepo    *repository.GoodsReceiptRepository
+	purchaseOrderRepo   *repository.PurchaseOrderRepository
+	articleRepo         *repository.ArticleRepository
+	locationRepo        *repository.LocationRepository
+	goodsReceiptService *service.GoodsReceiptService
+	returnService       *service.ReturnService
+}
+
+// NewGoodsReceiptHandler erstellt einen neuen GoodsReceiptHandler
+func NewGoodsReceiptHandler() *GoodsReceiptHandler {
+	return &GoodsReceiptHandler{
+		goodsReceiptRepo:    repository.NewGoodsReceiptRepository(),
+		purchaseOrderRepo:   repository.NewPurchaseOrderRepository(),
+		articleRepo:         repository.NewArticleRepository(),
+		locationRepo:        repository.NewLocationRepository(),
+		goodsReceiptService: service.NewGoodsReceiptService(),
+		returnService:       service.NewReturnService(),
+	}
+}
+
+// goodsReceiptRow ist eine Zeile des Wareneingangsformulars: eine Bestellposition mit den
+// Eingaben zur Lieferung
+type goodsReceiptRow struct {
+	Index          int
+	Line           model.PurchaseOrderLine
+	LotTracked     bool
+	SerialRequired bool
+	Quantity       float64
+	Reason         string
+	LocationID     string
+	LotNumber      string
+	ExpiryDate     string
+	SerialNumbers  string
+}
+
+// locationOption ist ein Lagerplatz in einer Auswahlliste
+type locationOption struct {
+	ID   string
+	Path string
+}
+
+// ShowGoodsReceiptForm zeigt das Formular für einen Wareneingang zu einer Bestellung an.
+// Die Liefermengen sind mit den offenen Mengen der Bestellung vorbelegt.
+func (h *GoodsReceiptHandler) ShowGoodsReceiptForm(c *gin.Context) {
+	order, err := h.purchaseOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bestellung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	if !order.IsOpen() {
+		c.HTML(http.StatusConflict, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": service.ErrGoodsReceiptOrderNotOpen.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	articles := h.loadArticles(order)
+	rows := make([]goodsReceiptRow, 0, len(order.Lines))
+	for i, line := range order.Lines {
+		if line.GetOpenQuantity() == 0 {
+			continue
+		}
+		row := goodsReceiptRow{Index: i, Line: line, Quantity: line.GetOpenQuantity()}
+		if article, exists := articles[line.ArticleID]; exists {
+			row.LotTracked = article.LotTracked
+			row.SerialRequired = article.SerialNumberRequired
+			if !article.StorageLocationID.IsZero() {
+				row.LocationID = article.StorageLocationID.Hex()
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	h.renderGoodsReceiptForm(c, http.StatusOK, order, rows, "", "", false, "")
+}
+
+// PostGoodsReceipt bucht den erfassten Wareneingang zu einer Bestellung
+func (h *GoodsReceiptHandler) PostGoodsReceipt(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.purchaseOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bestellung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	input := service.GoodsReceiptInput{
+		DeliveryNote: strings.TrimSpace(c.PostForm("deliveryNote")),
+		Notes:        strings.TrimSpace(c.PostForm("notes")),
+		CloseOrder:   c.PostForm("closeOrder") == "on",
+	}
+
+	// Positionen einlesen; die Eingaben bleiben für eine erneute Anzeige erhalten
+	articles := h.loadArticles(order)
+	var rows []goodsReceiptRow
+	var inputError string
+	for _, indexStr := range c.PostFormArray("lineIndex") {
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 || index >= len(order.Lines) {
+			continue
+		}
+		suffix := "_" + indexStr
+
+		row := goodsReceiptRow{
+			Index:         index,
+			Line:          order.Lines[index],
+			Reason:        strings.TrimSpace(c.PostForm("reason" + suffix)),
+			LocationID:    c.PostForm("locationId" + suffix),
+			LotNumber:     strings.TrimSpace(c.PostForm("lotNumber" + suffix)),
+			ExpiryDate:    c.PostForm("expiryDate" + suffix),
+			SerialNumbers: c.PostForm("serialNumbers" + suffix),
+		}
+		if article, exists := articles[row.Line.ArticleID]; exists {
+			row.LotTracked = article.LotTracked
+			row.SerialRequired = article.SerialNumberRequired
+		}
+		rows = append(rows, row)
+
+		line := service.GoodsReceiptLineInput{
+			LineIndex:     index,
+			Reason:        row.Reason,
+			LotNumber:     row.LotNumber,
+			SerialNumbers: parseSerialNumbers(row.SerialNumbers),
+		}
+		if quantityStr := strings.TrimSpace(c.PostForm("quantity" + suffix)); quantityStr != "" {
+			line.Quantity, err = strconv.ParseFloat(quantityStr, 64)
+			if err != nil || line.Quantity < 0 {
+				inputError = fmt.Sprintf("Ungültige Liefermenge bei %s", row.Line.ArticleNumber)
+			}
+		}
+		rows[len(rows)-1].Quantity = line.Quantity
+		line.LocationID, _ = primitive.ObjectIDFromHex(row.LocationID)
+		if row.ExpiryDate != "" {
+			line.ExpiryDate, err = time.ParseInLocation("2006-01-02", row.ExpiryDate, time.Local)
+			if err != nil {
+				inputError = fmt.Sprintf("Ungültiges Mindesthaltbarkeitsdatum bei %s", row.Line.ArticleNumber)
+			}
+		}
+		input.Lines = append(input.Lines, line)
+	}
+
+	if inputError != "" {
+		h.renderGoodsReceiptForm(c, http.StatusBadRequest, order, rows, input.DeliveryNote, input.Notes, input.CloseOrder, inputError)
+		return
+	}
+
+	receipt, err := h.goodsReceiptService.PostGoodsReceipt(order.ID.Hex(), input, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		switch {
+		case isGoodsReceiptInputError(err):
+			h.renderGoodsReceiptForm(c, http.StatusBadRequest, order, rows, input.DeliveryNote, input.Notes, input.CloseOrder, err.Error())
+		case err == repository.ErrVersionConflict, err == service.ErrGoodsReceiptOrderNotOpen:
+			c.HTML(http.StatusConflict, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Bestellung wurde zwischenzeitlich geändert oder ein anderer Wareneingang gebucht. Bitte den Wareneingang erneut erfassen.",
+				"year":    time.Now().Year(),
+			})
+		default:
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Fehler beim Buchen des Wareneingangs: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/goods-receipts/view/%s?success=posted", receipt.ID.Hex()))
+}
+
+// GetGoodsReceiptDetails zeigt die Details eines Wareneingangs an
+func (h *GoodsReceiptHandler) GetGoodsReceiptDetails(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	receipt, err := h.goodsReceiptRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Wareneingang nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Rücksendungen zum Wareneingang; ein Fehler blendet die Liste nur aus
+	returns, _ := h.returnService.FindBySource(receipt.ID)
+
+	c.HTML(http.StatusOK, "goods_receipt_detail.html", gin.H{
+		"title":    "Wareneingang " + receipt.ReceiptNumber,
+		"active":   "purchase-orders",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"receipt":  receipt,
+		"returns":  returns,
+		"success":  c.Query("success"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// renderGoodsReceiptForm zeigt das Wareneingangsformular an
+func (h *GoodsReceiptHandler) renderGoodsReceiptForm(
+	c *gin.Context,
+	status int,
+	order *model.PurchaseOrder,
+	rows []goodsReceiptRow,
+	deliveryNote, notes string,
+	closeOrder bool,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	locationMap, err := h.locationRepo.BuildLocationTree()
+	if err != nil {
+		locationMap = map[primitive.ObjectID]*model.Location{} // Leere Auswahl im Fehlerfall
+	}
+
+	c.HTML(status, "goods_receipt_form.html", gin.H{
+		"title":        "Wareneingang zu " + order.OrderNumber,
+		"active":       "purchase-orders",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"order":        order,
+		"rows":         rows,
+		"locations":    buildReceivingLocations(locationMap),
+		"deliveryNote": deliveryNote,
+		"notes":        notes,
+		"closeOrder":   closeOrder,
+		"error":        errorMessage,
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// loadArticles lädt die Artikel der Bestellpositionen für Chargen- und Seriennummernpflicht
+func (h *GoodsReceiptHandler) loadArticles(order *model.PurchaseOrder) map[primitive.ObjectID]*model.Article {
+	articles := make(map[primitive.ObjectID]*model.Article, len(order.Lines))
+	for _, line := range order.Lines {
+		if _, exists := articles[line.ArticleID]; exists {
+			continue
+		}
+		if article, err := h.articleRepo.FindByID(line.ArticleID.Hex()); err == nil {
+			articles[line.ArticleID] = article
+		}
+	}
+	return articles
+}
+
+// buildReceivingLocations gibt die aktiven Lagerplätze ohne Unterebenen zurück, auf die
+// Wareneingänge gebucht werden können, sortiert nach ihrem Pfad
+func buildReceivingLocations(locationMap map[primitive.ObjectID]*model.Location) []locationOption {
+	hasChildren := make(map[primitive.ObjectID]bool, len(locationMap))
+	for _, location := range locationMap {
+		if !location.ParentID.IsZero() {
+			hasChildren[location.ParentID] = true
+		}
+	}
+
+	options := make([]locationOption, 0, len(locationMap))
+	for _, location := range locationMap {
+		if !location.IsActive || hasChildren[location.ID] {
+			continue
+		}
+		options = append(options, locationOption{
+			ID:   location.ID.Hex(),
+			Path: location.GetFullPath(locationMap),
+		})
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Path < options[j].Path
+	})
+
+	return options
+}
+
+// isGoodsReceiptInputError prüft, ob ein Fehler auf ungültige Eingaben im Wareneingang zurückgeht
+func isGoodsReceiptInputError(err error) bool {
+	return errors.Is(err, service.ErrGoodsReceiptEmpty) ||
+		errors.Is(err, service.ErrGoodsReceiptQuantity) ||
+		errors.Is(err, service.ErrGoodsReceiptReason) ||
+		errors.Is(err, service.ErrGoodsReceiptCloseReason) ||
+		errors.Is(err, service.ErrLocationRequired) ||
+		errors.Is(err, service.ErrLotRequired) ||
+		errors.Is(err, service.ErrSerialCount) ||
+		errors.Is(err, service.ErrSerialNotAvailable) ||
+		errors.Is(err, service.ErrSerialInStock) ||
+		errors.Is(err, service.ErrHazardNotAllowed) ||
+		errors.Is(err, service.ErrHazardSegregation)
+}
@@ -0,0 +1,48 @@
+// backend/handler/hazardHelper.go
+package handler
+
+import (
+	"strings"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hazardClassOptions gibt alle Gefahrgutklassen aus dem Katalog zurück, ergänzt um die
+// angegebenen Klassen, falls sie (noch) nicht im Katalog stehen
+func hazardClassOptions(hazardRepo *repository.HazardClassRepository, current ...string) []*model.HazardClass {
+	classes, err := hazardRepo.FindAll()
+	if err != nil {
+		classes = []*model.HazardClass{} // Leere Liste im Fehlerfall
+	}
+
+	known := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		known[class.Code] = true
+	}
+	for _, code := range current {
+		if code != "" && !known[code] {
+			classes = append(classes, &model.HazardClass{Code: code, Name: code})
+			known[code] = true
+		}
+	}
+
+	return classes
+}
+
+// bindHazardClasses liest die angehakten Gefahrgutklassen eines Formulars ohne Leerwerte und Duplikate
+func bindHazardClasses(c *gin.Context, field string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range c.PostFormArray(field) {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,92 @@
+// backend/handler/ledgerHandler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LedgerHandler verwaltet die Konsistenzprüfung des Buchungsjournals
+type LedgerHandler struct {
+	ledgerService *service.LedgerService
+}
+
+// NewLedgerHandler erstellt einen neuen LedgerHandler
+func NewLedgerHandler() *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: service.NewLedgerService(),
+	}
+}
+
+// ShowLedgerCheck prüft Artikelbestände gegen das Buchungsjournal und listet alle Abweichungen auf
+func (h *LedgerHandler) ShowLedgerCheck(c *gin.Context) {
+	h.renderLedgerCheck(c, http.StatusOK, "", "", nil)
+}
+
+// RepairLedger gleicht die Abweichung eines Artikels durch eine Journalkorrektur aus
+func (h *LedgerHandler) RepairLedger(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	articleID := c.Param("id")
+	entered := gin.H{
+		"Stock":  c.PostForm("stock"),
+		"Reason": c.PostForm("reason"),
+		"Notes":  c.PostForm("notes"),
+	}
+
+	stock, err := strconv.ParseFloat(strings.Replace(c.PostForm("stock"), ",", ".", 1), 64)
+	if err != nil {
+		h.renderLedgerCheck(c, http.StatusBadRequest, "Bitte einen gültigen Bestand angeben", articleID, entered)
+		return
+	}
+
+	_, err = h.ledgerService.Repair(articleID, stock, c.PostForm("reason"), strings.TrimSpace(c.PostForm("notes")),
+		userModel.ID, userModel.FirstName+" "+userModel.LastName)
+	if err != nil {
+		h.renderLedgerCheck(c, http.StatusUnprocessableEntity, err.Error(), articleID, entered)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/ledger-check?success=repaired")
+}
+
+// renderLedgerCheck führt die Prüfung aus und zeigt das Ergebnis an. Bei einer fehlgeschlagenen
+// Korrektur bleiben die Eingaben für den betroffenen Artikel erhalten.
+func (h *LedgerHandler) renderLedgerCheck(c *gin.Context, status int, errorMessage, articleID string, entered gin.H) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	result, err := h.ledgerService.CheckConsistency()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler bei der Prüfung des Buchungsjournals: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(status, "ledger_check.html", gin.H{
+		"title":     "Journalprüfung",
+		"active":    "ledger",
+		"user":      userModel.FirstName + " " + userModel.LastName,
+		"email":     userModel.Email,
+		"year":      time.Now().Year(),
+		"userRole":  c.GetString("userRole"),
+		"result":    result,
+		"success":   c.Query("success"),
+		"error":     errorMessage,
+		"articleID": articleID,
+		"entered":   entered,
+	})
+}
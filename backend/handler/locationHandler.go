@@ -4,6 +4,8 @@ package handler
 import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"StockFlow/backend/model"
@@ -15,13 +17,17 @@ import (
 
 // LocationHandler verwaltet alle Anfragen zu Lagerorten
 type LocationHandler struct {
-	locationRepo *repository.LocationRepository
+	locationRepo   *repository.LocationRepository
+	stockLevelRepo *repository.StockLevelRepository
+	hazardRepo     *repository.HazardClassRepository
 }
 
 // NewLocationHandler erstellt einen neuen LocationHandler
 func NewLocationHandler() *LocationHandler {
 	return &LocationHandler{
-		locationRepo: repository.NewLocationRepository(),
+		locationRepo:   repository.NewLocationRepository(),
+		stockLevelRepo: repository.NewStockLevelRepository(),
+		hazardRepo:     repository.NewHazardClassRepository(),
 	}
 }
 
@@ -56,17 +62,28 @@ func (h *LocationHandler) ListLocations(c *gin.Context) {
 		}
 	}
 
+	// Bestände je Lagerort für die Anzeige gruppieren
+	stockByLocation := make(map[string][]stockLevelRow)
+	levels, err := h.stockLevelRepo.FindAll()
+	if err == nil {
+		articles, _ := repository.NewArticleRepository().FindAll()
+		for _, row := range buildStockLevelRows(levels, articleMap(articles), locationMap) {
+			stockByLocation[row.LocationID] = append(stockByLocation[row.LocationID], row)
+		}
+	}
+
 	// Daten an das Template übergeben
 	c.HTML(http.StatusOK, "locations.html", gin.H{
-		"title":      "Lagerorte",
-		"active":     "locations",
-		"user":       userModel.FirstName + " " + userModel.LastName,
-		"email":      userModel.Email,
-		"year":       time.Now().Year(),
-		"locations":  locations,
-		"warehouses": warehouses,
-		"locMap":     locationMap,
-		"userRole":   c.GetString("userRole"),
+		"title":           "Lagerorte",
+		"active":          "locations",
+		"user":            userModel.FirstName + " " + userModel.LastName,
+		"email":           userModel.Email,
+		"year":            time.Now().Year(),
+		"locations":       locations,
+		"warehouses":      warehouses,
+		"locMap":          locationMap,
+		"stockByLocation": stockByLocation,
+		"userRole":        c.GetString("userRole"),
 	})
 }
 
@@ -92,15 +109,16 @@ func (h *LocationHandler) ShowAddLocationForm(c *gin.Context) {
 	parentID := c.Query("parent")
 
 	c.HTML(http.StatusOK, "location_add.html", gin.H{
-		"title":     "Lagerort hinzufügen",
-		"active":    "locations",
-		"user":      userModel.FirstName + " " + userModel.LastName,
-		"email":     userModel.Email,
-		"year":      time.Now().Year(),
-		"locations": locations,
-		"parentID":  parentID,
-		"locType":   locationType,
-		"userRole":  c.GetString("userRole"),
+		"title":         "Lagerort hinzufügen",
+		"active":        "locations",
+		"user":          userModel.FirstName + " " + userModel.LastName,
+		"email":         userModel.Email,
+		"year":          time.Now().Year(),
+		"locations":     locations,
+		"parentID":      parentID,
+		"locType":       locationType,
+		"hazardClasses": hazardClassOptions(h.hazardRepo),
+		"userRole":      c.GetString("userRole"),
 	})
 }
 
@@ -140,6 +158,8 @@ func (h *LocationHandler) AddLocation(c *gin.Context) {
 		Capacity:    capacity,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+
+		HazardClasses: bindHazardClasses(c, "hazardClasses"),
 	}
 
 	// Lagerort in der Datenbank speichern
@@ -199,14 +219,15 @@ func (h *LocationHandler) ShowEditLocationForm(c *gin.Context) {
 	userModel := user.(*model.User)
 
 	c.HTML(http.StatusOK, "location_edit.html", gin.H{
-		"title":     "Lagerort bearbeiten",
-		"active":    "locations",
-		"user":      userModel.FirstName + " " + userModel.LastName,
-		"email":     userModel.Email,
-		"year":      time.Now().Year(),
-		"location":  location,
-		"locations": locations,
-		"userRole":  c.GetString("userRole"),
+		"title":         "Lagerort bearbeiten",
+		"active":        "locations",
+		"user":          userModel.FirstName + " " + userModel.LastName,
+		"email":         userModel.Email,
+		"year":          time.Now().Year(),
+		"location":      location,
+		"locations":     locations,
+		"hazardClasses": hazardClassOptions(h.hazardRepo, location.HazardClasses...),
+		"userRole":      c.GetString("userRole"),
 	})
 }
 
@@ -243,10 +264,18 @@ func (h *LocationHandler) UpdateLocation(c *gin.Context) {
 	}
 
 	location.IsActive = c.PostForm("isActive") == "on"
+	location.HazardClasses = bindHazardClasses(c, "hazardClasses")
 	location.UpdatedAt = time.Now()
 
+	// Version, mit der das Formular geladen wurde
+	location.Version, _ = strconv.ParseInt(c.PostForm("version"), 10, 64)
+
 	// Lagerort in der Datenbank aktualisieren
 	err = h.locationRepo.Update(location)
+	if err == repository.ErrVersionConflict {
+		h.showLocationConflict(c, location)
+		return
+	}
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"title":   "Fehler",
@@ -276,6 +305,66 @@ func (h *LocationHandler) UpdateLocation(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/locations?success=updated")
 }
 
+// showLocationConflict zeigt das Formular mit den eigenen Eingaben erneut an und listet die
+// Felder auf, die ein anderer Benutzer inzwischen geändert hat
+func (h *LocationHandler) showLocationConflict(c *gin.Context, location *model.Location) {
+	current, err := h.locationRepo.FindByID(location.ID.Hex())
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Lagerort wurde zwischenzeitlich gelöscht",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Lagerortnamen für die Anzeige des übergeordneten Orts
+	locations, err := h.locationRepo.FindAll()
+	if err != nil {
+		locations = []*model.Location{} // Leere Liste im Fehlerfall
+	}
+	locationMap := make(map[primitive.ObjectID]*model.Location)
+	for _, loc := range locations {
+		locationMap[loc.ID] = loc
+	}
+	parentName := func(id primitive.ObjectID) string {
+		if parent, exists := locationMap[id]; exists {
+			return parent.Name
+		}
+		return ""
+	}
+
+	var conflicts conflictList
+	conflicts.compare("Typ", location.Type, current.Type)
+	conflicts.compare("Name", location.Name, current.Name)
+	conflicts.compare("Beschreibung", location.Description, current.Description)
+	conflicts.compare("Adresse", location.Address, current.Address)
+	conflicts.compare("Übergeordneter Ort", parentName(location.ParentID), parentName(current.ParentID))
+	conflicts.compare("Aktiv", location.IsActive, current.IsActive)
+	conflicts.compare("Gefahrgutklassen", strings.Join(location.HazardClasses, ", "), strings.Join(current.HazardClasses, ", "))
+
+	// Mit der aktuellen Version kann der Benutzer seine Werte bewusst erneut speichern
+	location.Version = current.Version
+
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	c.HTML(http.StatusConflict, "location_edit.html", gin.H{
+		"title":         "Lagerort bearbeiten",
+		"active":        "locations",
+		"user":          userModel.FirstName + " " + userModel.LastName,
+		"email":         userModel.Email,
+		"year":          time.Now().Year(),
+		"location":      location,
+		"locations":     locations,
+		"hazardClasses": hazardClassOptions(h.hazardRepo, location.HazardClasses...),
+		"userRole":      c.GetString("userRole"),
+		"conflict":      true,
+		"conflicts":     conflicts,
+	})
+}
+
 // DeleteLocation löscht einen Lagerort
 func (h *LocationHandler) DeleteLocation(c *gin.Context) {
 	id := c.Param("id")
@@ -287,6 +376,17 @@ func (h *LocationHandler) DeleteLocation(c *gin.Context) {
 		return
 	}
 
+	// Lagerorte mit Bestand dürfen nicht gelöscht werden
+	hasStock, err := h.stockLevelRepo.HasStockAtLocation(location.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fehler beim Prüfen des Bestands: " + err.Error()})
+		return
+	}
+	if hasStock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An diesem Lagerort liegt noch Bestand. Bitte zuerst umlagern."})
+		return
+	}
+
 	// Lagerort löschen
 	err = h.locationRepo.Delete(id)
 	if err != nil {
@@ -0,0 +1,398 @@
+// backend/handler/purchaseOrderHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PurchaseOrderHandler verwaltet alle Anfragen zu Bestellungen
+type PurchaseOrderHandler struct {
+	purchaseOrderRepo      *repository.PurchaseOrderRepository
+	articleRepo            *repository.ArticleRepository
+	supplierRepo           *repository.SupplierRepository
+	supplierArticleRepo    *repository.SupplierArticleRepository
+	purchaseOrderService   *service.PurchaseOrderService
+	supplierArticleService *service.SupplierArticleService
+}
+
+// NewPurchaseOrderHandler erstellt einen neuen PurchaseOrderHandler
+func NewPurchaseOrderHandler() *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{
+		purchaseOrderRepo:      repository.NewPurchaseOrderRepository(),
+		articleRepo:            repository.NewArticleRepository(),
+		supplierRepo:           repository.NewSupplierRepository(),
+		supplierArticleRepo:    repository.NewSupplierArticleRepository(),
+		purchaseOrderService:   service.NewPurchaseOrderService(),
+		supplierArticleService: service.NewSupplierArticleService(),
+	}
+}
+
+// purchaseOrderStatuses sind die Status in der Reihenfolge der Filterauswahl
+var purchaseOrderStatuses = []model.PurchaseOrderStatus{
+	model.PurchaseOrderStatusDraft,
+	model.PurchaseOrderStatusSent,
+	model.PurchaseOrderStatusPartiallyReceived,
+	model.PurchaseOrderStatusReceived,
+	model.PurchaseOrderStatusCancelled,
+}
+
+// ListPurchaseOrders zeigt die Liste aller Bestellungen an, optional nach Status gefiltert
+func (h *PurchaseOrderHandler) ListPurchaseOrders(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	status := model.PurchaseOrderStatus(c.Query("status"))
+
+	orders, err := h.purchaseOrderRepo.FindAll(status)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Abrufen der Bestellungen: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	statusFilter := make([]gin.H, 0, len(purchaseOrderStatuses))
+	for _, s := range purchaseOrderStatuses {
+		statusFilter = append(statusFilter, gin.H{
+			"Value": string(s),
+			"Label": model.GetPurchaseOrderStatusDisplay(s),
+		})
+	}
+
+	c.HTML(http.StatusOK, "purchase_orders.html", gin.H{
+		"title":        "Bestellungen",
+		"active":       "purchase-orders",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"orders":       orders,
+		"status":       string(status),
+		"statusFilter": statusFilter,
+		"created":      c.Query("created"),
+		"now":          time.Now(),
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// GetPurchaseOrderDetails zeigt die Details einer Bestellung an
+func (h *PurchaseOrderHandler) GetPurchaseOrderDetails(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.purchaseOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bestellung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Bisherige Wareneingänge zur Bestellung
+	receipts, err := repository.NewGoodsReceiptRepository().FindByPurchaseOrderID(order.ID)
+	if err != nil {
+		receipts = []*model.GoodsReceipt{} // Leere Liste im Fehlerfall
+	}
+
+	c.HTML(http.StatusOK, "purchase_order_detail.html", gin.H{
+		"title":    "Bestellung " + order.OrderNumber,
+		"active":   "purchase-orders",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"order":    order,
+		"receipts": receipts,
+		"now":      time.Now(),
+		"success":  c.Query("success"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// ShowAddPurchaseOrderForm zeigt das Formular zum Anlegen einer Bestellung an
+func (h *PurchaseOrderHandler) ShowAddPurchaseOrderForm(c *gin.Context) {
+	order := &model.PurchaseOrder{OrderDate: time.Now()}
+	order.SupplierID, _ = primitive.ObjectIDFromHex(c.Query("supplierId"))
+
+	// Vorbelegung aus der Artikelansicht, bevorzugt mit den Bedingungen einer Bezugsquelle
+	if articleID := c.Query("articleId"); articleID != "" {
+		if article, err := h.articleRepo.FindByID(articleID); err == nil {
+			line := model.PurchaseOrderLine{
+				ArticleID: article.ID,
+				Quantity:  article.ReorderQuantity,
+				UnitPrice: article.PurchasePriceNet,
+			}
+			options, _ := h.supplierArticleService.GetSupplyOptions(article, article.ReorderQuantity, service.SourceStrategyPreferred)
+			for _, option := range options {
+				if order.SupplierID.IsZero() || option.SupplierArticle.SupplierID == order.SupplierID {
+					order.SupplierID = option.SupplierArticle.SupplierID
+					line.Quantity = option.Quantity
+					line.UnitPrice = option.UnitPrice
+					break
+				}
+			}
+			if order.SupplierID.IsZero() {
+				order.SupplierID = article.SupplierID
+			}
+			order.Lines = []model.PurchaseOrderLine{line}
+		}
+	}
+
+	h.renderPurchaseOrderForm(c, http.StatusOK, order, "")
+}
+
+// AddPurchaseOrder legt eine neue Bestellung als Entwurf an
+func (h *PurchaseOrderHandler) AddPurchaseOrder(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order := &model.PurchaseOrder{}
+	lines, err := parsePurchaseOrderForm(c, order)
+	if err != nil {
+		h.renderPurchaseOrderForm(c, http.StatusBadRequest, order, err.Error())
+		return
+	}
+
+	err = h.purchaseOrderService.CreatePurchaseOrder(order, lines, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if isPurchaseOrderInputError(err) {
+			status = http.StatusBadRequest
+		}
+		h.renderPurchaseOrderForm(c, status, order, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/purchase-orders/view/%s?success=added", order.ID.Hex()))
+}
+
+// ShowEditPurchaseOrderForm zeigt das Formular zum Bearbeiten einer Bestellung im Entwurf an
+func (h *PurchaseOrderHandler) ShowEditPurchaseOrderForm(c *gin.Context) {
+	order, err := h.purchaseOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bestellung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if !order.IsEditable() {
+		c.Redirect(http.StatusFound, "/purchase-orders/view/"+order.ID.Hex())
+		return
+	}
+
+	h.renderPurchaseOrderForm(c, http.StatusOK, order, "")
+}
+
+// UpdatePurchaseOrder speichert eine bearbeitete Bestellung im Entwurf
+func (h *PurchaseOrderHandler) UpdatePurchaseOrder(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	order, err := h.purchaseOrderRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bestellung nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Version aus dem Formular verwenden, damit zwischenzeitliche Änderungen erkannt werden
+	if version, err := strconv.ParseInt(c.PostForm("version"), 10, 64); err == nil {
+		order.Version = version
+	}
+
+	lines, err := parsePurchaseOrderForm(c, order)
+	if err != nil {
+		h.renderPurchaseOrderForm(c, http.StatusBadRequest, order, err.Error())
+		return
+	}
+
+	err = h.purchaseOrderService.UpdatePurchaseOrder(order, lines, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case isPurchaseOrderInputError(err):
+			status = http.StatusBadRequest
+		case err == repository.ErrVersionConflict, err == service.ErrPurchaseOrderNotEditable:
+			status = http.StatusConflict
+		}
+		h.renderPurchaseOrderForm(c, status, order, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/purchase-orders/view/%s?success=updated", order.ID.Hex()))
+}
+
+// SendPurchaseOrder markiert eine Bestellung als an den Lieferanten versendet
+func (h *PurchaseOrderHandler) SendPurchaseOrder(c *gin.Context) {
+	h.changeStatus(c, h.purchaseOrderService.SendPurchaseOrder, "sent")
+}
+
+// CancelPurchaseOrder storniert eine Bestellung
+func (h *PurchaseOrderHandler) CancelPurchaseOrder(c *gin.Context) {
+	h.changeStatus(c, h.purchaseOrderService.CancelPurchaseOrder, "cancelled")
+}
+
+// changeStatus führt einen Statuswechsel aus und leitet zur Detailseite zurück
+func (h *PurchaseOrderHandler) changeStatus(
+	c *gin.Context,
+	change func(id string, userID primitive.ObjectID, userName string) error,
+	success string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	id := c.Param("id")
+	if err := change(id, userModel.ID, fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName)); err != nil {
+		status := http.StatusInternalServerError
+		if err == repository.ErrPurchaseOrderStatus {
+			status = http.StatusConflict
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/purchase-orders/view/%s?success=%s", id, success))
+}
+
+// renderPurchaseOrderForm zeigt das Bestellformular zum Anlegen oder Bearbeiten an
+func (h *PurchaseOrderHandler) renderPurchaseOrderForm(c *gin.Context, status int, order *model.PurchaseOrder, errorMessage string) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	suppliers, err := h.supplierRepo.FindActive()
+	if err != nil {
+		suppliers = []*model.Supplier{} // Leere Liste im Fehlerfall
+	}
+	articles, err := h.articleRepo.FindAll()
+	if err != nil {
+		articles = []*model.Article{} // Leere Liste im Fehlerfall
+	}
+
+	// Bestelleinheit, Preis und Lieferzeit je Artikel und Lieferant für die Vorgaben im Formular
+	supplierSources := make(map[string]map[string]gin.H)
+	if supplierArticles, err := h.supplierArticleRepo.FindAll(); err == nil {
+		for _, supplierArticle := range supplierArticles {
+			articleID := supplierArticle.ArticleID.Hex()
+			if supplierSources[articleID] == nil {
+				supplierSources[articleID] = make(map[string]gin.H)
+			}
+			supplierSources[articleID][supplierArticle.SupplierID.Hex()] = gin.H{
+				"price":    supplierArticle.GetCurrentPrice(),
+				"unit":     supplierArticle.Unit,
+				"delivery": supplierArticle.LeadTimeDays,
+			}
+		}
+	}
+
+	title := "Bestellung anlegen"
+	action := "/purchase-orders/add"
+	if !order.ID.IsZero() {
+		title = "Bestellung " + order.OrderNumber + " bearbeiten"
+		action = "/purchase-orders/edit/" + order.ID.Hex()
+	}
+
+	c.HTML(status, "purchase_order_form.html", gin.H{
+		"title":     title,
+		"active":    "purchase-orders",
+		"user":      userModel.FirstName + " " + userModel.LastName,
+		"email":     userModel.Email,
+		"year":      time.Now().Year(),
+		"order":     order,
+		"action":    action,
+		"suppliers": suppliers,
+		"articles":  articles,
+		"sources":   supplierSources,
+		"error":     errorMessage,
+		"userRole":  c.GetString("userRole"),
+	})
+}
+
+// parsePurchaseOrderForm übernimmt Kopfdaten aus dem Formular in die Bestellung und gibt die
+// erfassten Positionen zurück. Ein leerer Liefertermin wird beim Speichern vorbelegt.
+func parsePurchaseOrderForm(c *gin.Context, order *model.PurchaseOrder) ([]service.PurchaseOrderLineInput, error) {
+	order.SupplierID, _ = primitive.ObjectIDFromHex(c.PostForm("supplierId"))
+	order.Notes = strings.TrimSpace(c.PostForm("notes"))
+
+	var err error
+	order.OrderDate = time.Time{}
+	if orderDate := c.PostForm("orderDate"); orderDate != "" {
+		order.OrderDate, err = time.ParseInLocation("2006-01-02", orderDate, time.Local)
+		if err != nil {
+			return nil, errors.New("Ungültiges Bestelldatum")
+		}
+	}
+	order.ExpectedDate = time.Time{}
+	if expectedDate := c.PostForm("expectedDate"); expectedDate != "" {
+		order.ExpectedDate, err = time.ParseInLocation("2006-01-02", expectedDate, time.Local)
+		if err != nil {
+			return nil, errors.New("Ungültiger Liefertermin")
+		}
+	}
+
+	articleIDs := c.PostFormArray("lineArticleId")
+	quantities := c.PostFormArray("lineQuantity")
+	unitPrices := c.PostFormArray("lineUnitPrice")
+
+	lines := make([]service.PurchaseOrderLineInput, 0, len(articleIDs))
+	order.Lines = order.Lines[:0]
+	for i, articleID := range articleIDs {
+		line := service.PurchaseOrderLineInput{ArticleID: articleID}
+		if i < len(quantities) {
+			line.Quantity, _ = strconv.ParseFloat(quantities[i], 64)
+		}
+		if i < len(unitPrices) {
+			line.UnitPrice, _ = strconv.ParseFloat(unitPrices[i], 64)
+		}
+		lines = append(lines, line)
+
+		// Eingaben für eine erneute Anzeige des Formulars festhalten
+		objID, _ := primitive.ObjectIDFromHex(articleID)
+		order.Lines = append(order.Lines, model.PurchaseOrderLine{
+			ArticleID: objID,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		})
+	}
+
+	return lines, nil
+}
+
+// isPurchaseOrderInputError prüft, ob ein Fehler auf ungültige Eingaben zurückgeht
+func isPurchaseOrderInputError(err error) bool {
+	return err == service.ErrPurchaseOrderSupplier ||
+		err == service.ErrPurchaseOrderNoLines ||
+		err == service.ErrPurchaseOrderLine ||
+		errors.Is(err, service.ErrPurchaseOrderQuantity)
+}
@@ -0,0 +1,121 @@
+// backend/handler/replenishmentHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplenishmentHandler verwaltet die Anfragen zu Bestellvorschlägen
+type ReplenishmentHandler struct {
+	replenishmentService *service.ReplenishmentService
+}
+
+// NewReplenishmentHandler erstellt einen neuen ReplenishmentHandler
+func NewReplenishmentHandler() *ReplenishmentHandler {
+	return &ReplenishmentHandler{
+		replenishmentService: service.NewReplenishmentService(),
+	}
+}
+
+// ShowReorderProposals zeigt die Bestellvorschläge gruppiert nach Lieferanten an. Die
+// Bezugsquelle wird nach der Strategie im Parameter "source" gewählt.
+func (h *ReplenishmentHandler) ShowReorderProposals(c *gin.Context) {
+	h.renderReorderProposals(c, http.StatusOK, service.ParseSourceStrategy(c.Query("source")), nil, nil, "")
+}
+
+// CreatePurchaseOrders legt aus den ausgewählten Bestellvorschlägen Bestellungen im Entwurf an
+func (h *ReplenishmentHandler) CreatePurchaseOrders(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	strategy := service.ParseSourceStrategy(c.PostForm("source"))
+
+	// Ausgewählte Artikel mit den gegebenenfalls geänderten Mengen und Bezugsquellen
+	quantities := make(map[string]string)
+	sources := make(map[string]string)
+	var selections []service.ProposalSelection
+	for _, articleID := range c.PostFormArray("articleId") {
+		quantityStr := strings.TrimSpace(c.PostForm("quantity_" + articleID))
+		quantities[articleID] = quantityStr
+		sources[articleID] = c.PostForm("supplierArticleId_" + articleID)
+
+		quantity, err := strconv.ParseFloat(quantityStr, 64)
+		if err != nil || quantity < 0 {
+			h.renderReorderProposals(c, http.StatusBadRequest, strategy, quantities, sources, "Ungültige Bestellmenge")
+			return
+		}
+		selections = append(selections, service.ProposalSelection{
+			ArticleID:         articleID,
+			SupplierArticleID: sources[articleID],
+			Quantity:          quantity,
+		})
+	}
+
+	orders, err := h.replenishmentService.CreatePurchaseOrders(selections, strategy, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := err.Error()
+		switch {
+		case err == service.ErrProposalNoSelection, errors.Is(err, service.ErrProposalWithoutSupplier),
+			errors.Is(err, service.ErrSupplierArticleUnit), isPurchaseOrderInputError(err):
+			status = http.StatusBadRequest
+		}
+		if len(orders) > 0 {
+			message = fmt.Sprintf("%s (%d Bestellungen wurden bereits angelegt)", message, len(orders))
+		}
+		h.renderReorderProposals(c, status, strategy, quantities, sources, message)
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/purchase-orders?status=%s&created=%d", model.PurchaseOrderStatusDraft, len(orders)))
+}
+
+// renderReorderProposals berechnet die Vorschläge und zeigt sie an. Bereits erfasste Mengen
+// und Bezugsquellen ersetzen nach einem Fehler die vorgeschlagenen.
+func (h *ReplenishmentHandler) renderReorderProposals(
+	c *gin.Context,
+	status int,
+	strategy service.SourceStrategy,
+	quantities, sources map[string]string,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	groups, err := h.replenishmentService.ComputeProposals(strategy)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Berechnen der Bestellvorschläge: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(status, "reorder_proposals.html", gin.H{
+		"title":      "Bestellvorschläge",
+		"active":     "purchase-orders",
+		"user":       userModel.FirstName + " " + userModel.LastName,
+		"email":      userModel.Email,
+		"year":       time.Now().Year(),
+		"groups":     groups,
+		"quantities": quantities,
+		"sources":    sources,
+		"strategy":   string(strategy),
+		"error":      errorMessage,
+		"userRole":   c.GetString("userRole"),
+	})
+}
@@ -0,0 +1,478 @@
+// backend/handler/reportHandler.go
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler verwaltet alle Anfragen zu Lagerberichten
+type ReportHandler struct {
+	reportService       *service.ReportService
+	valuationService    *service.ValuationService
+	stockHistoryService *service.StockHistoryService
+	scorecardService    *service.SupplierScorecardService
+	hazardService       *service.HazardService
+}
+
+// NewReportHandler erstellt einen neuen ReportHandler
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{
+		reportService:       service.NewReportService(),
+		valuationService:    service.NewValuationService(),
+		stockHistoryService: service.NewStockHistoryService(),
+		scorecardService:    service.NewSupplierScorecardService(),
+		hazardService:       service.NewHazardService(),
+	}
+}
+
+// ShowExpiringLotsReport zeigt die Chargen an, die in Kürze ablaufen oder bereits abgelaufen sind
+func (h *ReportHandler) ShowExpiringLotsReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Betrachtungszeitraum in Tagen (Standard: 30)
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 {
+		days = 30
+	}
+
+	report, err := h.reportService.GenerateExpiringLotsReport(days)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "report_expiring_lots.html", gin.H{
+		"title":    "Ablaufende Chargen",
+		"active":   "reports",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"report":   report,
+		"days":     days,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// ShowValuationReport zeigt den Lagerwert zu einem Stichtag nach dem gewählten Bewertungsverfahren an
+func (h *ReportHandler) ShowValuationReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Stichtag (Standard: heute); bewertet wird der Bestand am Ende des Tages
+	date, err := time.ParseInLocation("2006-01-02", c.Query("date"), time.Local)
+	if err != nil {
+		date = time.Now()
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	cutoff := date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	// Bewertungsverfahren (Standard: eingestelltes Verfahren)
+	configured, err := h.valuationService.GetValuationMethod()
+	if err != nil {
+		configured = model.ValuationMethodMovingAverage
+	}
+	method := model.ValuationMethod(c.Query("method"))
+	if !method.IsValid() {
+		method = configured
+	}
+
+	report, err := h.valuationService.GenerateValuationReport(cutoff, method)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "report_valuation.html", gin.H{
+		"title":            "Lagerbewertung",
+		"active":           "reports",
+		"user":             userModel.FirstName + " " + userModel.LastName,
+		"email":            userModel.Email,
+		"year":             time.Now().Year(),
+		"report":           report,
+		"date":             date.Format("2006-01-02"),
+		"method":           string(method),
+		"methodName":       method.GetDisplayName(),
+		"configuredMethod": configured.GetDisplayName(),
+		"methods":          model.ValuationMethods,
+		"userRole":         c.GetString("userRole"),
+	})
+}
+
+// parseReportPeriod liest den Berichtszeitraum aus den Parametern start und end (jeweils
+// JJJJ-MM-TT, beide Tage eingeschlossen). Ohne Angabe gilt der angegebene Zeitraum bis heute.
+func parseReportPeriod(c *gin.Context, defaultDays int) (time.Time, time.Time) {
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+
+	end, err := time.ParseInLocation("2006-01-02", c.Query("end"), time.Local)
+	if err != nil {
+		end = today
+	}
+	start, err := time.ParseInLocation("2006-01-02", c.Query("start"), time.Local)
+	if err != nil || start.After(end) {
+		start = end.AddDate(0, 0, -defaultDays)
+	}
+
+	return start, end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// ShowTurnoverReport zeigt Lagerumschlag, durchschnittlichen Bestand und Reichweite je Artikel
+// und Warengruppe für einen Zeitraum an
+func (h *ReportHandler) ShowTurnoverReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	start, end := parseReportPeriod(c, 90)
+	report, err := h.reportService.GenerateInventoryTurnoverReport(start, end)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "report_turnover.html", gin.H{
+		"title":    "Lagerumschlag",
+		"active":   "reports",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"report":   report,
+		"start":    start.Format("2006-01-02"),
+		"end":      end.Format("2006-01-02"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// GetTurnoverReport liefert den Bericht zum Lagerumschlag als JSON
+func (h *ReportHandler) GetTurnoverReport(c *gin.Context) {
+	start, end := parseReportPeriod(c, 90)
+	report, err := h.reportService.GenerateInventoryTurnoverReport(start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fehler beim Erstellen des Berichts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// supplierReportDays ist der Standardzeitraum der Lieferantenbewertung in Tagen
+const supplierReportDays = 365
+
+// ShowSupplierReport zeigt die Rangliste der Lieferanten nach Lieferzeit, Termintreue,
+// Mengentreue und Preisänderungen für einen Zeitraum an
+func (h *ReportHandler) ShowSupplierReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	start, end := parseReportPeriod(c, supplierReportDays)
+	report, err := h.scorecardService.GenerateSupplierReport(start, end)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "report_suppliers.html", gin.H{
+		"title":    "Lieferantenbewertung",
+		"active":   "reports",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"report":   report,
+		"start":    start.Format("2006-01-02"),
+		"end":      end.Format("2006-01-02"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// GetSupplierReport liefert die Lieferantenbewertung als JSON
+func (h *ReportHandler) GetSupplierReport(c *gin.Context) {
+	start, end := parseReportPeriod(c, supplierReportDays)
+	report, err := h.scorecardService.GenerateSupplierReport(start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fehler beim Erstellen des Berichts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ShowHazardReport zeigt die aktuellen Verstöße gegen die Gefahrgutregeln an: Bestand auf
+// nicht freigegebenen Lagerorten, unverträgliche Klassen am selben Lagerort und
+// Standardlagerorte, die die Klasse ihres Artikels nicht freigeben
+func (h *ReportHandler) ShowHazardReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	report, err := h.hazardService.GenerateComplianceReport()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "report_hazards.html", gin.H{
+		"title":    "Gefahrgut",
+		"active":   "reports",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"report":   report,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// GetHazardReport liefert die Verstöße gegen die Gefahrgutregeln als JSON
+func (h *ReportHandler) GetHazardReport(c *gin.Context) {
+	report, err := h.hazardService.GenerateComplianceReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fehler beim Erstellen des Berichts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// movementDimensions sind die Gruppierungen des Bewegungsberichts mit Anzeigename und Schlüssel im Bericht
+var movementDimensions = []struct {
+	Dimension service.MovementDimension
+	Name      string
+	ReportKey string
+}{
+	{service.MovementByType, "Typ", "byType"},
+	{service.MovementByArticle, "Artikel", "byArticle"},
+	{service.MovementByCategory, "Warengruppe", "byCategory"},
+	{service.MovementByUser, "Benutzer", "byUser"},
+	{service.MovementByLocation, "Lagerort", "byLocation"},
+}
+
+// movementDrillDownLimit begrenzt die Anzahl der Buchungen in der Detailansicht
+const movementDrillDownLimit = 500
+
+// ShowMovementReport zeigt die Summen der Lagerbewegungen eines Zeitraums nach der gewählten
+// Gruppierung an; jede Summe führt zu den zugehörigen Buchungen
+func (h *ReportHandler) ShowMovementReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	start, end := parseReportPeriod(c, 30)
+	report, err := h.reportService.GenerateStockMovementReport(start, end)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Gruppierung (Standard: Typ)
+	dimension := movementDimensions[0]
+	for _, candidate := range movementDimensions {
+		if string(candidate.Dimension) == c.Query("by") {
+			dimension = candidate
+		}
+	}
+
+	c.HTML(http.StatusOK, "report_movements.html", gin.H{
+		"title":      "Lagerbewegungen",
+		"active":     "reports",
+		"user":       userModel.FirstName + " " + userModel.LastName,
+		"email":      userModel.Email,
+		"year":       time.Now().Year(),
+		"report":     report,
+		"totals":     report[dimension.ReportKey],
+		"by":         string(dimension.Dimension),
+		"byName":     dimension.Name,
+		"dimensions": movementDimensions,
+		"start":      start.Format("2006-01-02"),
+		"end":        end.Format("2006-01-02"),
+		"userRole":   c.GetString("userRole"),
+	})
+}
+
+// ShowMovementTransactions zeigt die Buchungen hinter einer Summe des Bewegungsberichts an
+func (h *ReportHandler) ShowMovementTransactions(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	start, end := parseReportPeriod(c, 30)
+	dimension := service.MovementDimension(c.Query("by"))
+	key := c.Query("key")
+
+	transactions, total, label, err := h.reportService.FindMovementTransactions(start, end, dimension, key, movementDrillDownLimit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == service.ErrInvalidMovementDimension {
+			status = http.StatusBadRequest
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Laden der Buchungen: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	byName := string(dimension)
+	for _, candidate := range movementDimensions {
+		if candidate.Dimension == dimension {
+			byName = candidate.Name
+		}
+	}
+
+	c.HTML(http.StatusOK, "report_movement_transactions.html", gin.H{
+		"title":        "Lagerbewegungen: " + label,
+		"active":       "reports",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"transactions": transactions,
+		"total":        total,
+		"limited":      total > int64(len(transactions)),
+		"label":        label,
+		"by":           string(dimension),
+		"byName":       byName,
+		"start":        start.Format("2006-01-02"),
+		"end":          end.Format("2006-01-02"),
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// parseReportTimestamp liest einen Zeitpunkt aus dem Datumsparameter (JJJJ-MM-TT) und dem
+// optionalen Uhrzeitparameter (HH:MM). Ohne Uhrzeit gilt das Ende des Tages, ohne gültiges
+// Datum gibt ok false zurück.
+func parseReportTimestamp(c *gin.Context, dateParam, timeParam string) (at time.Time, ok bool) {
+	date, err := time.ParseInLocation("2006-01-02", c.Query(dateParam), time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if clock, err := time.Parse("15:04", c.Query(timeParam)); err == nil {
+		at = date.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+		return at.Add(time.Minute - time.Nanosecond), true
+	}
+
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), true
+}
+
+// ShowStockAsOfReport zeigt den aus dem Buchungsjournal rekonstruierten Bestand zu einem
+// Zeitpunkt an. Mit einem Vergleichsdatum werden die Bestände beider Zeitpunkte gegenübergestellt.
+func (h *ReportHandler) ShowStockAsOfReport(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Zeitpunkt (Standard: jetzt)
+	at, ok := parseReportTimestamp(c, "date", "time")
+	if !ok {
+		at = time.Now()
+	}
+
+	data := gin.H{
+		"title":       "Bestand zum Stichtag",
+		"active":      "reports",
+		"user":        userModel.FirstName + " " + userModel.LastName,
+		"email":       userModel.Email,
+		"year":        time.Now().Year(),
+		"date":        at.Format("2006-01-02"),
+		"time":        c.Query("time"),
+		"compareDate": c.Query("compareDate"),
+		"userRole":    c.GetString("userRole"),
+	}
+
+	var report map[string]interface{}
+	var err error
+	if compareAt, ok := parseReportTimestamp(c, "compareDate", "compareTime"); ok {
+		from, to := compareAt, at
+		if from.After(to) {
+			from, to = to, from
+		}
+		report, err = h.stockHistoryService.CompareStockAsOf(from, to)
+		data["compare"] = true
+		data["compareTime"] = c.Query("compareTime")
+	} else {
+		report, err = h.stockHistoryService.GetStockAsOf(at)
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Erstellen des Berichts: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	data["report"] = report
+	c.HTML(http.StatusOK, "report_stock_as_of.html", data)
+}
+
+// GetStockAsOf liefert den Bestand zu einem Zeitpunkt als JSON: für einen Artikel, wenn
+// articleId angegeben ist, sonst für alle Artikel. Mit compareDate wird stattdessen die
+// Gegenüberstellung zweier Zeitpunkte geliefert.
+func (h *ReportHandler) GetStockAsOf(c *gin.Context) {
+	at, ok := parseReportTimestamp(c, "date", "time")
+	if !ok {
+		at = time.Now()
+	}
+
+	if articleID := c.Query("articleId"); articleID != "" {
+		entry, err := h.stockHistoryService.GetArticleStockAsOf(articleID, at)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"at": at, "article": entry})
+		return
+	}
+
+	var report map[string]interface{}
+	var err error
+	if compareAt, ok := parseReportTimestamp(c, "compareDate", "compareTime"); ok {
+		from, to := compareAt, at
+		if from.After(to) {
+			from, to = to, from
+		}
+		report, err = h.stockHistoryService.CompareStockAsOf(from, to)
+	} else {
+		report, err = h.stockHistoryService.GetStockAsOf(at)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fehler beim Erstellen des Berichts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,199 @@
+// backend/handler/reservationHandler.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReservationHandler verwaltet alle Anfragen zu Reservierungen
+type ReservationHandler struct {
+	reservationRepo    *repository.ReservationRepository
+	articleRepo        *repository.ArticleRepository
+	reservationService *service.ReservationService
+}
+
+// NewReservationHandler erstellt einen neuen ReservationHandler
+func NewReservationHandler() *ReservationHandler {
+	return &ReservationHandler{
+		reservationRepo:    repository.NewReservationRepository(),
+		articleRepo:        repository.NewArticleRepository(),
+		reservationService: service.NewReservationService(),
+	}
+}
+
+// ListReservations zeigt die aktiven und die zuletzt beendeten Reservierungen an
+func (h *ReservationHandler) ListReservations(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Abgelaufene Reservierungen vor der Anzeige freigeben
+	if _, err := h.reservationService.ReleaseExpired(); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Freigeben abgelaufener Reservierungen: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	active, err := h.reservationRepo.FindActive()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Abrufen der Reservierungen: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	closed, err := h.reservationRepo.FindClosed(50)
+	if err != nil {
+		closed = []*model.Reservation{} // Leere Liste im Fehlerfall
+	}
+
+	// Einheiten der Artikel für die Mengenanzeige
+	articles, err := h.articleRepo.FindAll()
+	if err != nil {
+		articles = []*model.Article{} // Leere Liste im Fehlerfall
+	}
+	units := make(map[string]string, len(articles))
+	for _, article := range articles {
+		units[article.ID.Hex()] = article.Unit
+	}
+
+	c.HTML(http.StatusOK, "reservations.html", gin.H{
+		"title":    "Reservierungen",
+		"active":   "reservations",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"reserved": active,
+		"closed":   closed,
+		"units":    units,
+		"success":  c.Query("success"),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// ShowAddReservationForm zeigt das Formular zum Reservieren von Bestand an
+func (h *ReservationHandler) ShowAddReservationForm(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	articles, err := h.articleRepo.FindAll()
+	if err != nil {
+		articles = []*model.Article{} // Leere Liste im Fehlerfall
+	}
+
+	c.HTML(http.StatusOK, "reservation_add.html", gin.H{
+		"title":             "Bestand reservieren",
+		"active":            "reservations",
+		"user":              userModel.FirstName + " " + userModel.LastName,
+		"email":             userModel.Email,
+		"year":              time.Now().Year(),
+		"articles":          articles,
+		"selectedArticleId": c.Query("articleId"),
+		"userRole":          c.GetString("userRole"),
+	})
+}
+
+// AddReservation legt eine neue Reservierung an
+func (h *ReservationHandler) AddReservation(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	articleID := c.PostForm("articleId")
+
+	quantity, err := strconv.ParseFloat(c.PostForm("quantity"), 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Ungültige Menge. Bitte geben Sie eine positive Zahl ein.",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Ablaufdatum ist optional; ohne Angabe gilt die Reservierung unbefristet
+	var expiresAt time.Time
+	if expiresAtStr := c.PostForm("expiresAt"); expiresAtStr != "" {
+		expiresAt, err = time.ParseInLocation("2006-01-02T15:04", expiresAtStr, time.Local)
+		if err != nil {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Ungültiges Ablaufdatum",
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
+	_, err = h.reservationService.CreateReservation(
+		articleID,
+		quantity,
+		strings.TrimSpace(c.PostForm("reason")),
+		strings.TrimSpace(c.PostForm("reference")),
+		expiresAt,
+		userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case service.ErrInvalidReservationQuantity, service.ErrReservationExpiry:
+			status = http.StatusBadRequest
+		case service.ErrReservationExceedsStock:
+			status = http.StatusConflict
+		}
+
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/reservations?success=added")
+}
+
+// ReleaseReservation gibt eine aktive Reservierung frei
+func (h *ReservationHandler) ReleaseReservation(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	err := h.reservationService.ReleaseReservation(
+		c.Param("id"),
+		userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == repository.ErrReservationNotActive {
+			status = http.StatusConflict
+		}
+
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/reservations?success=released")
+}
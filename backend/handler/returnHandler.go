@@ -0,0 +1,526 @@
+// backend/handler/returnHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReturnHandler verwaltet Kundenretouren und Rücksendungen an Lieferanten
+type ReturnHandler struct {
+	articleRepo   *repository.ArticleRepository
+	locationRepo  *repository.LocationRepository
+	supplierRepo  *repository.SupplierRepository
+	returnService *service.ReturnService
+}
+
+// NewReturnHandler erstellt einen neuen ReturnHandler
+func NewReturnHandler() *ReturnHandler {
+	return &ReturnHandler{
+		articleRepo:   repository.NewArticleRepository(),
+		locationRepo:  repository.NewLocationRepository(),
+		supplierRepo:  repository.NewSupplierRepository(),
+		returnService: service.NewReturnService(),
+	}
+}
+
+// returnStatuses sind die Status in der Reihenfolge der Filterauswahl
+var returnStatuses = []model.ReturnStatus{
+	model.ReturnStatusOpen,
+	model.ReturnStatusCompleted,
+	model.ReturnStatusCancelled,
+}
+
+// returnSource ist der Beleg, zu dem eine Retoure angelegt wird
+type returnSource struct {
+	CustomerOrderID string
+	TransactionID   string
+	ReceiptID       string
+}
+
+// returnFormRow ist eine Zeile des Retourenformulars: eine Position des Originalbelegs mit
+// der erfassten Rückgabemenge
+type returnFormRow struct {
+	Index     int
+	Candidate *service.ReturnCandidate
+	Quantity  float64
+	Reason    string
+}
+
+// returnAssessmentRow ist eine Zeile der Zustandsprüfung
+type returnAssessmentRow struct {
+	Index          int
+	Line           model.ReturnLine
+	LotTracked     bool
+	SerialRequired bool
+	Condition      string
+	LocationID     string
+	StockStatus    string
+	LotNumber      string
+	ExpiryDate     string
+	SerialNumbers  string
+}
+
+// ListReturns zeigt die Liste aller Retouren an, optional nach Art und Status gefiltert
+func (h *ReturnHandler) ListReturns(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	returnType := model.ReturnType(c.Query("type"))
+	status := model.ReturnStatus(c.Query("status"))
+
+	returns, err := h.returnService.FindAll(returnType, status)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Abrufen der Retouren: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	typeFilter := []gin.H{
+		{"Value": string(model.ReturnTypeCustomer), "Label": model.ReturnTypeCustomer.GetDisplayName()},
+		{"Value": string(model.ReturnTypeSupplier), "Label": model.ReturnTypeSupplier.GetDisplayName()},
+	}
+	statusFilter := make([]gin.H, 0, len(returnStatuses))
+	for _, s := range returnStatuses {
+		statusFilter = append(statusFilter, gin.H{
+			"Value": string(s),
+			"Label": model.GetReturnStatusDisplay(s),
+		})
+	}
+
+	c.HTML(http.StatusOK, "returns.html", gin.H{
+		"title":        "Retouren",
+		"active":       "returns",
+		"user":         userModel.FirstName + " " + userModel.LastName,
+		"email":        userModel.Email,
+		"year":         time.Now().Year(),
+		"returns":      returns,
+		"type":         string(returnType),
+		"status":       string(status),
+		"typeFilter":   typeFilter,
+		"statusFilter": statusFilter,
+		"userRole":     c.GetString("userRole"),
+	})
+}
+
+// ShowReturnForm zeigt das Formular für eine Retoure zu einem Kundenauftrag, einem einzelnen
+// Warenausgang oder einem Wareneingang an
+func (h *ReturnHandler) ShowReturnForm(c *gin.Context) {
+	source := returnSource{
+		CustomerOrderID: c.Query("customerOrderId"),
+		TransactionID:   c.Query("transactionId"),
+		ReceiptID:       c.Query("receiptId"),
+	}
+
+	header, candidates, err := h.prepare(source)
+	if err != nil {
+		h.renderPrepareError(c, err)
+		return
+	}
+
+	rows := make([]returnFormRow, len(candidates))
+	for i, candidate := range candidates {
+		rows[i] = returnFormRow{Index: i, Candidate: candidate}
+	}
+	// Ein einzelner Warenausgang kommt in der Regel vollständig zurück
+	if source.TransactionID != "" && len(rows) == 1 {
+		rows[0].Quantity = candidates[0].GetReturnable()
+	}
+
+	h.renderReturnForm(c, http.StatusOK, source, header, rows, service.ReturnInput{
+		PartnerName:       header.PartnerName,
+		ExternalReference: header.ExternalReference,
+	}, "")
+}
+
+// AddReturn legt eine Retoure mit den erfassten Rückgabemengen an
+func (h *ReturnHandler) AddReturn(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	source := returnSource{
+		CustomerOrderID: c.PostForm("customerOrderId"),
+		TransactionID:   c.PostForm("transactionId"),
+		ReceiptID:       c.PostForm("receiptId"),
+	}
+
+	header, candidates, err := h.prepare(source)
+	if err != nil {
+		h.renderPrepareError(c, err)
+		return
+	}
+
+	input := service.ReturnInput{
+		PartnerName:       strings.TrimSpace(c.PostForm("partnerName")),
+		ExternalReference: strings.TrimSpace(c.PostForm("externalReference")),
+		Notes:             strings.TrimSpace(c.PostForm("notes")),
+	}
+
+	// Positionen einlesen; die Eingaben bleiben für eine erneute Anzeige erhalten
+	rows := make([]returnFormRow, len(candidates))
+	for i, candidate := range candidates {
+		rows[i] = returnFormRow{Index: i, Candidate: candidate}
+	}
+	var inputError string
+	for _, indexStr := range c.PostFormArray("row") {
+		index, err := strconv.Atoi(indexStr)
+		if err != nil || index < 0 || index >= len(candidates) {
+			continue
+		}
+		suffix := "_" + indexStr
+
+		row := &rows[index]
+		row.Reason = strings.TrimSpace(c.PostForm("reason" + suffix))
+		if quantityStr := strings.TrimSpace(c.PostForm("quantity" + suffix)); quantityStr != "" {
+			row.Quantity, err = strconv.ParseFloat(quantityStr, 64)
+			if err != nil || row.Quantity < 0 {
+				inputError = fmt.Sprintf("Ungültige Rückgabemenge bei %s", row.Candidate.Line.ArticleNumber)
+			}
+		}
+
+		input.Lines = append(input.Lines, service.ReturnLineInput{
+			CandidateIndex: index,
+			Quantity:       row.Quantity,
+			Reason:         row.Reason,
+		})
+	}
+
+	if inputError != "" {
+		h.renderReturnForm(c, http.StatusBadRequest, source, header, rows, input, inputError)
+		return
+	}
+
+	returnCase, err := h.returnService.CreateReturn(header, candidates, input, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		if isReturnInputError(err) {
+			h.renderReturnForm(c, http.StatusBadRequest, source, header, rows, input, err.Error())
+			return
+		}
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Anlegen der Retoure: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/returns/view/%s?success=created", returnCase.ID.Hex()))
+}
+
+// GetReturnDetails zeigt die Details einer Retoure an; bei offenen Retouren mit dem Formular
+// für die Zustandsprüfung
+func (h *ReturnHandler) GetReturnDetails(c *gin.Context) {
+	returnCase, err := h.returnService.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Retoure nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	var rows []returnAssessmentRow
+	if returnCase.IsOpen() {
+		rows = h.buildAssessmentRows(returnCase)
+	}
+
+	h.renderReturnDetail(c, http.StatusOK, returnCase, rows, "")
+}
+
+// PostReturn bucht das Ergebnis der Zustandsprüfung einer offenen Retoure
+func (h *ReturnHandler) PostReturn(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	returnCase, err := h.returnService.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Retoure nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Prüfergebnisse einlesen; die Eingaben bleiben für eine erneute Anzeige erhalten
+	rows := h.buildAssessmentRows(returnCase)
+	assessments := make([]service.ReturnAssessmentInput, len(rows))
+	var inputError string
+	for i := range rows {
+		row := &rows[i]
+		suffix := "_" + strconv.Itoa(i)
+
+		row.Condition = c.PostForm("condition" + suffix)
+		row.LocationID = c.PostForm("locationId" + suffix)
+		row.StockStatus = c.PostForm("stockStatus" + suffix)
+		row.LotNumber = strings.TrimSpace(c.PostForm("lotNumber" + suffix))
+		row.ExpiryDate = c.PostForm("expiryDate" + suffix)
+		row.SerialNumbers = c.PostForm("serialNumbers" + suffix)
+
+		assessment := service.ReturnAssessmentInput{
+			Condition:     model.ReturnCondition(row.Condition),
+			StockStatus:   model.StockStatus(row.StockStatus),
+			LotNumber:     row.LotNumber,
+			SerialNumbers: parseSerialNumbers(row.SerialNumbers),
+		}
+		assessment.LocationID, _ = primitive.ObjectIDFromHex(row.LocationID)
+		if row.ExpiryDate != "" {
+			assessment.ExpiryDate, err = time.ParseInLocation("2006-01-02", row.ExpiryDate, time.Local)
+			if err != nil {
+				inputError = fmt.Sprintf("Ungültiges Mindesthaltbarkeitsdatum bei %s", row.Line.ArticleNumber)
+			}
+		}
+		assessments[i] = assessment
+	}
+
+	if inputError != "" {
+		h.renderReturnDetail(c, http.StatusBadRequest, returnCase, rows, inputError)
+		return
+	}
+
+	_, err = h.returnService.PostReturn(returnCase.ID.Hex(), assessments, userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		switch {
+		case err == repository.ErrReturnStatus, err == service.ErrReturnNotOpen:
+			c.HTML(http.StatusConflict, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Die Retoure wurde zwischenzeitlich geprüft oder storniert.",
+				"year":    time.Now().Year(),
+			})
+		case isReturnInputError(err):
+			h.renderReturnDetail(c, http.StatusBadRequest, returnCase, rows, err.Error())
+		default:
+			// Buchungsfehler, z.B. fehlender Bestand am Lagerplatz oder unbekannte Seriennummern
+			h.renderReturnDetail(c, http.StatusUnprocessableEntity, returnCase, rows, err.Error())
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/returns/view/%s?success=posted", returnCase.ID.Hex()))
+}
+
+// CancelReturn storniert eine offene Retoure
+func (h *ReturnHandler) CancelReturn(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	id := c.Param("id")
+	if err := h.returnService.CancelReturn(id, userModel.ID, fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName)); err != nil {
+		status := http.StatusInternalServerError
+		if err == repository.ErrReturnStatus || err == service.ErrReturnNotOpen {
+			status = http.StatusConflict
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/returns/view/%s?success=cancelled", id))
+}
+
+// ShowReturnDocument zeigt den druckbaren Rücksendeschein einer gebuchten Rücksendung an
+// einen Lieferanten an
+func (h *ReturnHandler) ShowReturnDocument(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	returnCase, err := h.returnService.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Retoure nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if !returnCase.HasDocument() {
+		c.Redirect(http.StatusFound, "/returns/view/"+returnCase.ID.Hex())
+		return
+	}
+
+	// Der Lieferant kann inzwischen gelöscht sein; der Schein zeigt dann nur den Namen
+	var supplier *model.Supplier
+	if !returnCase.SupplierID.IsZero() {
+		supplier, _ = h.supplierRepo.FindByID(returnCase.SupplierID.Hex())
+	}
+
+	c.HTML(http.StatusOK, "return_document.html", gin.H{
+		"title":    "Rücksendeschein " + returnCase.ReturnNumber,
+		"active":   "returns",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"return":   returnCase,
+		"supplier": supplier,
+		"now":      time.Now(),
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// prepare lädt Kopf und Positionen einer neuen Retoure zum angegebenen Beleg
+func (h *ReturnHandler) prepare(source returnSource) (*model.ReturnCase, []*service.ReturnCandidate, error) {
+	switch {
+	case source.ReceiptID != "":
+		return h.returnService.PrepareSupplierReturn(source.ReceiptID)
+	case source.CustomerOrderID != "" || source.TransactionID != "":
+		return h.returnService.PrepareCustomerReturn(source.CustomerOrderID, source.TransactionID)
+	default:
+		return nil, nil, service.ErrReturnSource
+	}
+}
+
+// renderPrepareError zeigt einen Fehler beim Laden des Belegs einer neuen Retoure an
+func (h *ReturnHandler) renderPrepareError(c *gin.Context, err error) {
+	status := http.StatusNotFound
+	if err == service.ErrReturnSource {
+		status = http.StatusBadRequest
+	}
+	c.HTML(status, "error.html", gin.H{
+		"title":   "Fehler",
+		"message": err.Error(),
+		"year":    time.Now().Year(),
+	})
+}
+
+// buildAssessmentRows belegt die Zustandsprüfung mit den Vorschlägen der Positionen vor
+func (h *ReturnHandler) buildAssessmentRows(returnCase *model.ReturnCase) []returnAssessmentRow {
+	conditions := returnCase.GetConditions()
+
+	rows := make([]returnAssessmentRow, len(returnCase.Lines))
+	for i, line := range returnCase.Lines {
+		row := returnAssessmentRow{
+			Index:         i,
+			Line:          line,
+			Condition:     string(conditions[0]),
+			LotNumber:     line.LotNumber,
+			SerialNumbers: strings.Join(line.SerialNumbers, "\n"),
+		}
+		if !line.LocationID.IsZero() {
+			row.LocationID = line.LocationID.Hex()
+		}
+		if !line.ExpiryDate.IsZero() {
+			row.ExpiryDate = line.ExpiryDate.Format("2006-01-02")
+		}
+		if article, err := h.articleRepo.FindByID(line.ArticleID.Hex()); err == nil {
+			row.LotTracked = article.LotTracked
+			row.SerialRequired = article.SerialNumberRequired
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// renderReturnForm zeigt das Formular zum Anlegen einer Retoure an
+func (h *ReturnHandler) renderReturnForm(
+	c *gin.Context,
+	status int,
+	source returnSource,
+	header *model.ReturnCase,
+	rows []returnFormRow,
+	input service.ReturnInput,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	backURL := "/returns"
+	switch {
+	case source.ReceiptID != "":
+		backURL = "/goods-receipts/view/" + source.ReceiptID
+	case !header.CustomerOrderID.IsZero():
+		backURL = "/customer-orders/view/" + header.CustomerOrderID.Hex()
+	}
+
+	c.HTML(status, "return_form.html", gin.H{
+		"title":    header.GetDisplayType() + " anlegen",
+		"active":   "returns",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"source":   source,
+		"header":   header,
+		"rows":     rows,
+		"input":    input,
+		"backURL":  backURL,
+		"error":    errorMessage,
+		"userRole": c.GetString("userRole"),
+	})
+}
+
+// renderReturnDetail zeigt die Detailseite einer Retoure an
+func (h *ReturnHandler) renderReturnDetail(
+	c *gin.Context,
+	status int,
+	returnCase *model.ReturnCase,
+	rows []returnAssessmentRow,
+	errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	var locations []locationOption
+	if returnCase.IsOpen() {
+		locationMap, err := h.locationRepo.BuildLocationTree()
+		if err != nil {
+			locationMap = map[primitive.ObjectID]*model.Location{} // Leere Auswahl im Fehlerfall
+		}
+		locations = buildReceivingLocations(locationMap)
+	}
+
+	c.HTML(status, "return_detail.html", gin.H{
+		"title":         "Retoure " + returnCase.ReturnNumber,
+		"active":        "returns",
+		"user":          userModel.FirstName + " " + userModel.LastName,
+		"email":         userModel.Email,
+		"year":          time.Now().Year(),
+		"return":        returnCase,
+		"rows":          rows,
+		"locations":     locations,
+		"stockStatuses": model.GetStockStatuses(),
+		"success":       c.Query("success"),
+		"error":         errorMessage,
+		"userRole":      c.GetString("userRole"),
+	})
+}
+
+// isReturnInputError prüft, ob ein Fehler auf ungültige Eingaben in einer Retoure zurückgeht
+func isReturnInputError(err error) bool {
+	return errors.Is(err, service.ErrReturnEmpty) ||
+		errors.Is(err, service.ErrReturnQuantity) ||
+		errors.Is(err, service.ErrReturnPartner) ||
+		errors.Is(err, service.ErrReturnCondition) ||
+		errors.Is(err, service.ErrLocationRequired) ||
+		errors.Is(err, service.ErrLotRequired) ||
+		errors.Is(err, service.ErrSerialCount) ||
+		errors.Is(err, service.ErrInvalidStockStatus) ||
+		errors.Is(err, service.ErrHazardNotAllowed) ||
+		errors.Is(err, service.ErrHazardSegregation)
+}
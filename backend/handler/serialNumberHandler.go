@@ -0,0 +1,59 @@
+// backend/handler/serialNumberHandler.go
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SerialNumberHandler verwaltet alle Anfragen zu Seriennummern
+type SerialNumberHandler struct {
+	serialRepo *repository.SerialNumberRepository
+}
+
+// NewSerialNumberHandler erstellt einen neuen SerialNumberHandler
+func NewSerialNumberHandler() *SerialNumberHandler {
+	return &SerialNumberHandler{
+		serialRepo: repository.NewSerialNumberRepository(),
+	}
+}
+
+// LookupSerialNumber sucht Seriennummern und zeigt an, wo sich das Stück befindet und wer es ausgegeben hat
+func (h *SerialNumberHandler) LookupSerialNumber(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	query := strings.TrimSpace(c.Query("q"))
+
+	serials := []*model.SerialNumber{}
+	if query != "" {
+		var err error
+		serials, err = h.serialRepo.Search(query, 50)
+		if err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Fehler bei der Suche nach Seriennummern: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
+	c.HTML(http.StatusOK, "serial_lookup.html", gin.H{
+		"title":    "Seriennummern",
+		"active":   "serials",
+		"user":     userModel.FirstName + " " + userModel.LastName,
+		"email":    userModel.Email,
+		"year":     time.Now().Year(),
+		"query":    query,
+		"serials":  serials,
+		"userRole": c.GetString("userRole"),
+	})
+}
@@ -0,0 +1,139 @@
+// backend/handler/stockLevelHelper.go
+package handler
+
+import (
+	"sort"
+	"time"
+
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// stockLevelRow ist eine Zeile der Bestandsanzeige: ein Artikel an einem Lagerort
+type stockLevelRow struct {
+	ArticleID     string
+	ArticleNumber string
+	ArticleName   string
+	Unit          string
+	LocationID    string
+	LocationPath  string
+	Quantity      float64
+
+	// Aufteilung nach Bestandsstatus
+	Available  float64
+	Quarantine float64
+	Blocked    float64
+	Damaged    float64
+}
+
+// HasHeld prüft, ob am Lagerort Ware in Quarantäne, gesperrt oder beschädigt ist
+func (r stockLevelRow) HasHeld() bool {
+	return r.Quarantine != 0 || r.Blocked != 0 || r.Damaged != 0
+}
+
+// buildStockLevelRows ergänzt Bestände um Artikel- und Lagerortbezeichnungen und sortiert
+// sie nach Lagerortpfad und Artikelnummer. Bestände gelöschter Artikel werden ausgelassen.
+func buildStockLevelRows(
+	levels []*model.StockLevel,
+	articles map[primitive.ObjectID]*model.Article,
+	locationMap map[primitive.ObjectID]*model.Location,
+) []stockLevelRow {
+	rows := make([]stockLevelRow, 0, len(levels))
+	for _, level := range levels {
+		article, exists := articles[level.ArticleID]
+		if !exists {
+			continue
+		}
+
+		row := stockLevelRow{
+			ArticleID:     article.ID.Hex(),
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Unit:          article.Unit,
+			LocationPath:  model.UnassignedLocationName,
+			Quantity:      level.Quantity,
+			Available:     level.GetAvailableQuantity(),
+			Quarantine:    level.Quarantine,
+			Blocked:       level.Blocked,
+			Damaged:       level.Damaged,
+		}
+		if location, exists := locationMap[level.LocationID]; exists {
+			row.LocationID = location.ID.Hex()
+			row.LocationPath = location.GetFullPath(locationMap)
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].LocationPath != rows[j].LocationPath {
+			return rows[i].LocationPath < rows[j].LocationPath
+		}
+		return rows[i].ArticleNumber < rows[j].ArticleNumber
+	})
+
+	return rows
+}
+
+// articleMap erstellt eine Map für schnellen Zugriff auf Artikel anhand ihrer ID
+func articleMap(articles []*model.Article) map[primitive.ObjectID]*model.Article {
+	result := make(map[primitive.ObjectID]*model.Article, len(articles))
+	for _, article := range articles {
+		result[article.ID] = article
+	}
+	return result
+}
+
+// lotRow ist eine Zeile der Chargenanzeige
+type lotRow struct {
+	ArticleID     string
+	ArticleNumber string
+	ArticleName   string
+	Unit          string
+	LocationPath  string
+	LotNumber     string
+	ExpiryDate    time.Time
+	DaysLeft      int
+	Expired       bool
+	Quantity      float64
+	Value         float64
+}
+
+// buildLotRows ergänzt Chargen um Artikel- und Lagerortbezeichnungen; die Reihenfolge der
+// Chargen bleibt erhalten. Chargen gelöschter Artikel werden ausgelassen.
+func buildLotRows(
+	lots []*model.Lot,
+	articles map[primitive.ObjectID]*model.Article,
+	locationMap map[primitive.ObjectID]*model.Location,
+) []lotRow {
+	now := time.Now()
+	rows := make([]lotRow, 0, len(lots))
+	for _, lot := range lots {
+		article, exists := articles[lot.ArticleID]
+		if !exists {
+			continue
+		}
+
+		row := lotRow{
+			ArticleID:     article.ID.Hex(),
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Unit:          article.Unit,
+			LocationPath:  model.UnassignedLocationName,
+			LotNumber:     lot.LotNumber,
+			ExpiryDate:    lot.ExpiryDate,
+			Expired:       lot.IsExpired(now),
+			Quantity:      lot.Quantity,
+			Value:         lot.Quantity * article.GetAverageCost(),
+		}
+		if lot.HasExpiry() {
+			row.DaysLeft = lot.DaysUntilExpiry(now)
+		}
+		if location, exists := locationMap[lot.LocationID]; exists {
+			row.LocationPath = location.GetFullPath(locationMap)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
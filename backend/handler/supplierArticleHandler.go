@@ -0,0 +1,246 @@
+// backend/handler/supplierArticleHandler.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SupplierArticleHandler verwaltet die Bezugsquellen der Artikel
+type SupplierArticleHandler struct {
+	supplierArticleRepo    *repository.SupplierArticleRepository
+	articleRepo            *repository.ArticleRepository
+	supplierRepo           *repository.SupplierRepository
+	supplierArticleService *service.SupplierArticleService
+}
+
+// NewSupplierArticleHandler erstellt einen neuen SupplierArticleHandler
+func NewSupplierArticleHandler() *SupplierArticleHandler {
+	return &SupplierArticleHandler{
+		supplierArticleRepo:    repository.NewSupplierArticleRepository(),
+		articleRepo:            repository.NewArticleRepository(),
+		supplierRepo:           repository.NewSupplierRepository(),
+		supplierArticleService: service.NewSupplierArticleService(),
+	}
+}
+
+// supplierPriceInput ist ein im Formular erfasster neuer Preis einer Bezugsquelle
+type supplierPriceInput struct {
+	Price     string
+	ValidFrom string
+	Notes     string
+}
+
+// ShowAddSupplierArticleForm zeigt das Formular zum Anlegen einer Bezugsquelle an, vorbelegt
+// mit dem Artikel bzw. Lieferanten aus der Artikel- oder Lieferantenansicht
+func (h *SupplierArticleHandler) ShowAddSupplierArticleForm(c *gin.Context) {
+	supplierArticle := &model.SupplierArticle{}
+	supplierArticle.SupplierID, _ = primitive.ObjectIDFromHex(c.Query("supplierId"))
+
+	if article, err := h.articleRepo.FindByID(c.Query("articleId")); err == nil {
+		supplierArticle.ArticleID = article.ID
+		supplierArticle.Unit = article.GetPurchaseUnit()
+		supplierArticle.LeadTimeDays = article.DeliveryTimeInDays
+
+		// Die erste Bezugsquelle eines Artikels ist bevorzugt
+		if existing, err := h.supplierArticleRepo.FindByArticleID(article.ID); err == nil && len(existing) == 0 {
+			supplierArticle.IsPreferred = true
+		}
+	}
+
+	input := supplierPriceInput{ValidFrom: time.Now().Format("2006-01-02")}
+	h.renderSupplierArticleForm(c, http.StatusOK, supplierArticle, input, c.Query("from"), "")
+}
+
+// AddSupplierArticle legt eine neue Bezugsquelle an
+func (h *SupplierArticleHandler) AddSupplierArticle(c *gin.Context) {
+	supplierArticle := &model.SupplierArticle{}
+	supplierArticle.SupplierID, _ = primitive.ObjectIDFromHex(c.PostForm("supplierId"))
+	supplierArticle.ArticleID, _ = primitive.ObjectIDFromHex(c.PostForm("articleId"))
+
+	h.saveSupplierArticle(c, supplierArticle)
+}
+
+// ShowEditSupplierArticleForm zeigt das Formular zum Bearbeiten einer Bezugsquelle mit ihrer
+// Preishistorie an
+func (h *SupplierArticleHandler) ShowEditSupplierArticleForm(c *gin.Context) {
+	supplierArticle, err := h.supplierArticleRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bezugsquelle nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	input := supplierPriceInput{ValidFrom: time.Now().Format("2006-01-02")}
+	h.renderSupplierArticleForm(c, http.StatusOK, supplierArticle, input, c.Query("from"), "")
+}
+
+// UpdateSupplierArticle speichert eine geänderte Bezugsquelle. Lieferant und Artikel bleiben
+// unverändert.
+func (h *SupplierArticleHandler) UpdateSupplierArticle(c *gin.Context) {
+	supplierArticle, err := h.supplierArticleRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bezugsquelle nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	h.saveSupplierArticle(c, supplierArticle)
+}
+
+// DeleteSupplierArticle löscht eine Bezugsquelle mit ihrer Preishistorie
+func (h *SupplierArticleHandler) DeleteSupplierArticle(c *gin.Context) {
+	supplierArticle, err := h.supplierArticleRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bezugsquelle nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if err := h.supplierArticleRepo.Delete(supplierArticle.ID); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Löschen der Bezugsquelle: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, supplierArticleReturnURL(supplierArticle, c.PostForm("from")))
+}
+
+// saveSupplierArticle übernimmt die Formulareingaben in die Bezugsquelle und speichert sie
+func (h *SupplierArticleHandler) saveSupplierArticle(c *gin.Context, supplierArticle *model.SupplierArticle) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	from := c.PostForm("from")
+	input := supplierPriceInput{
+		Price:     strings.TrimSpace(c.PostForm("price")),
+		ValidFrom: c.PostForm("validFrom"),
+		Notes:     strings.TrimSpace(c.PostForm("priceNotes")),
+	}
+
+	supplierArticle.SupplierArticleNumber = strings.TrimSpace(c.PostForm("supplierArticleNumber"))
+	supplierArticle.Unit = c.PostForm("unit")
+	supplierArticle.MinimumOrderQuantity, _ = strconv.ParseFloat(c.PostForm("minimumOrderQuantity"), 64)
+	supplierArticle.PackSize, _ = strconv.ParseFloat(c.PostForm("packSize"), 64)
+	supplierArticle.LeadTimeDays, _ = strconv.Atoi(c.PostForm("leadTimeDays"))
+	supplierArticle.IsPreferred = c.PostForm("isPreferred") == "on"
+
+	var price float64
+	var validFrom time.Time
+	if input.Price != "" {
+		var err error
+		price, err = strconv.ParseFloat(strings.Replace(input.Price, ",", ".", 1), 64)
+		if err != nil {
+			h.renderSupplierArticleForm(c, http.StatusBadRequest, supplierArticle, input, from, "Ungültiger Preis")
+			return
+		}
+		if input.ValidFrom != "" {
+			validFrom, err = time.ParseInLocation("2006-01-02", input.ValidFrom, time.Local)
+			if err != nil {
+				h.renderSupplierArticleForm(c, http.StatusBadRequest, supplierArticle, input, from, "Ungültiges Datum für den Preis")
+				return
+			}
+		}
+	}
+
+	err := h.supplierArticleService.SaveSupplierArticle(supplierArticle, price, validFrom, input.Notes,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case err == repository.ErrSupplierArticleExists:
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrSupplierArticleSupplier), errors.Is(err, service.ErrSupplierArticleArticle),
+			errors.Is(err, service.ErrSupplierArticleUnit), errors.Is(err, service.ErrSupplierArticleQuantity),
+			errors.Is(err, service.ErrSupplierPriceDate), errors.Is(err, service.ErrSupplierPriceInvalid):
+			status = http.StatusBadRequest
+		}
+		h.renderSupplierArticleForm(c, status, supplierArticle, input, from, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, supplierArticleReturnURL(supplierArticle, from))
+}
+
+// renderSupplierArticleForm zeigt das Formular für eine Bezugsquelle an
+func (h *SupplierArticleHandler) renderSupplierArticleForm(
+	c *gin.Context,
+	status int,
+	supplierArticle *model.SupplierArticle,
+	input supplierPriceInput,
+	from, errorMessage string,
+) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	suppliers, err := h.supplierRepo.FindActive()
+	if err != nil {
+		suppliers = []*model.Supplier{} // Leere Liste im Fehlerfall
+	}
+	articles, err := h.articleRepo.FindAll()
+	if err != nil {
+		articles = []*model.Article{} // Leere Liste im Fehlerfall
+	}
+
+	title := "Bezugsquelle hinzufügen"
+	action := "/supplier-articles/add"
+	if !supplierArticle.ID.IsZero() {
+		title = "Bezugsquelle " + supplierArticle.ArticleNumber + " bei " + supplierArticle.SupplierName
+		action = "/supplier-articles/edit/" + supplierArticle.ID.Hex()
+	}
+
+	c.HTML(status, "supplier_article_form.html", gin.H{
+		"title":           title,
+		"active":          "suppliers",
+		"user":            userModel.FirstName + " " + userModel.LastName,
+		"email":           userModel.Email,
+		"year":            time.Now().Year(),
+		"supplierArticle": supplierArticle,
+		"input":           input,
+		"action":          action,
+		"from":            from,
+		"backURL":         supplierArticleReturnURL(supplierArticle, from),
+		"suppliers":       suppliers,
+		"articles":        articles,
+		"now":             time.Now(),
+		"error":           errorMessage,
+		"userRole":        c.GetString("userRole"),
+	})
+}
+
+// supplierArticleReturnURL gibt die Seite zurück, von der aus die Bezugsquelle bearbeitet wurde:
+// die Lieferantenansicht oder, als Vorgabe, die Artikelansicht
+func supplierArticleReturnURL(supplierArticle *model.SupplierArticle, from string) string {
+	if from == "supplier" && !supplierArticle.SupplierID.IsZero() {
+		return "/suppliers/view/" + supplierArticle.SupplierID.Hex()
+	}
+	if !supplierArticle.ArticleID.IsZero() {
+		return "/articles/view/" + supplierArticle.ArticleID.Hex()
+	}
+	return "/suppliers"
+}
@@ -4,8 +4,10 @@ package handler
 import (
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,15 +15,19 @@ import (
 
 // SupplierHandler verwaltet alle Anfragen zu Lieferanten
 type SupplierHandler struct {
-	supplierRepo *repository.SupplierRepository
-	articleRepo  *repository.ArticleRepository
+	supplierRepo        *repository.SupplierRepository
+	articleRepo         *repository.ArticleRepository
+	supplierArticleRepo *repository.SupplierArticleRepository
+	scorecardService    *service.SupplierScorecardService
 }
 
 // NewSupplierHandler erstellt einen neuen SupplierHandler
 func NewSupplierHandler() *SupplierHandler {
 	return &SupplierHandler{
-		supplierRepo: repository.NewSupplierRepository(),
-		articleRepo:  repository.NewArticleRepository(),
+		supplierRepo:        repository.NewSupplierRepository(),
+		articleRepo:         repository.NewArticleRepository(),
+		supplierArticleRepo: repository.NewSupplierArticleRepository(),
+		scorecardService:    service.NewSupplierScorecardService(),
 	}
 }
 
@@ -154,20 +160,38 @@ func (h *SupplierHandler) GetSupplierDetails(c *gin.Context) {
 		articles = []*model.Article{} // Leere Liste im Fehlerfall
 	}
 
+	// Bezugsquellen des Lieferanten mit Konditionen und Preishistorie
+	supplierArticles, err := h.supplierArticleRepo.FindBySupplierID(supplier.ID)
+	if err != nil {
+		supplierArticles = []*model.SupplierArticle{} // Leere Liste im Fehlerfall
+	}
+
+	// Bewertung der Lieferungen im gewählten Zeitraum (Standard: ein Jahr)
+	start, end := parseReportPeriod(c, supplierReportDays)
+	scorecard, err := h.scorecardService.GetScorecard(supplier.ID, start, end)
+	if err != nil {
+		scorecard = nil // Keine Bewertung im Fehlerfall
+	}
+
 	// Aktuellen Benutzer aus dem Context abrufen
 	user, _ := c.Get("user")
 	userModel := user.(*model.User)
 
 	// Daten an das Template übergeben
 	c.HTML(http.StatusOK, "supplier_detail.html", gin.H{
-		"title":    supplier.Name,
-		"active":   "suppliers",
-		"user":     userModel.FirstName + " " + userModel.LastName,
-		"email":    userModel.Email,
-		"year":     time.Now().Year(),
-		"supplier": supplier,
-		"articles": articles,
-		"userRole": c.GetString("userRole"),
+		"title":            supplier.Name,
+		"active":           "suppliers",
+		"user":             userModel.FirstName + " " + userModel.LastName,
+		"email":            userModel.Email,
+		"year":             time.Now().Year(),
+		"supplier":         supplier,
+		"articles":         articles,
+		"supplierArticles": supplierArticles,
+		"scorecard":        scorecard,
+		"start":            start.Format("2006-01-02"),
+		"end":              end.Format("2006-01-02"),
+		"now":              time.Now(),
+		"userRole":         c.GetString("userRole"),
 	})
 }
 
@@ -230,8 +254,15 @@ func (h *SupplierHandler) UpdateSupplier(c *gin.Context) {
 	supplier.IsActive = c.PostForm("isActive") == "on"
 	supplier.UpdatedAt = time.Now()
 
+	// Version, mit der das Formular geladen wurde
+	supplier.Version, _ = strconv.ParseInt(c.PostForm("version"), 10, 64)
+
 	// Lieferanten in der Datenbank aktualisieren
 	err = h.supplierRepo.Update(supplier)
+	if err == repository.ErrVersionConflict {
+		h.showSupplierConflict(c, supplier)
+		return
+	}
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"title":   "Fehler",
@@ -261,6 +292,52 @@ func (h *SupplierHandler) UpdateSupplier(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/suppliers?success=updated")
 }
 
+// showSupplierConflict zeigt das Formular mit den eigenen Eingaben erneut an und listet die
+// Felder auf, die ein anderer Benutzer inzwischen geändert hat
+func (h *SupplierHandler) showSupplierConflict(c *gin.Context, supplier *model.Supplier) {
+	current, err := h.supplierRepo.FindByID(supplier.ID.Hex())
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Lieferant wurde zwischenzeitlich gelöscht",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	var conflicts conflictList
+	conflicts.compare("Lieferantennummer", supplier.SupplierCode, current.SupplierCode)
+	conflicts.compare("Name", supplier.Name, current.Name)
+	conflicts.compare("Ansprechpartner", supplier.ContactPerson, current.ContactPerson)
+	conflicts.compare("E-Mail", supplier.Email, current.Email)
+	conflicts.compare("Telefon", supplier.Phone, current.Phone)
+	conflicts.compare("Adresse", supplier.Address, current.Address)
+	conflicts.compare("Website", supplier.Website, current.Website)
+	conflicts.compare("Steuernummer", supplier.TaxID, current.TaxID)
+	conflicts.compare("Zahlungsbedingungen", supplier.PaymentTerms, current.PaymentTerms)
+	conflicts.compare("Bemerkungen", supplier.Notes, current.Notes)
+	conflicts.compare("Aktiv", supplier.IsActive, current.IsActive)
+
+	// Mit der aktuellen Version kann der Benutzer seine Werte bewusst erneut speichern
+	supplier.Version = current.Version
+
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	c.HTML(http.StatusConflict, "supplier_edit.html", gin.H{
+		"title":     "Lieferant bearbeiten",
+		"active":    "suppliers",
+		"user":      userModel.FirstName + " " + userModel.LastName,
+		"email":     userModel.Email,
+		"year":      time.Now().Year(),
+		"supplier":  supplier,
+		"userRole":  c.GetString("userRole"),
+		"conflict":  true,
+		"conflicts": conflicts,
+	})
+}
+
 // DeleteSupplier löscht einen Lieferanten
 func (h *SupplierHandler) DeleteSupplier(c *gin.Context) {
 	id := c.Param("id")
@@ -281,6 +358,14 @@ func (h *SupplierHandler) DeleteSupplier(c *gin.Context) {
 		return
 	}
 
+	// Prüfen, ob der Lieferant Bezugsquelle von Artikeln ist
+	if count, err := h.supplierArticleRepo.CountBySupplierID(supplier.ID); err == nil && count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Dieser Lieferant ist Bezugsquelle von %d Artikeln und kann nicht gelöscht werden", count),
+		})
+		return
+	}
+
 	// Lieferanten löschen
 	err = h.supplierRepo.Delete(id)
 	if err != nil {
@@ -4,9 +4,12 @@ package handler
 import (
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +20,7 @@ import (
 type TransactionHandler struct {
 	transactionRepo *repository.TransactionRepository
 	articleRepo     *repository.ArticleRepository
+	stockService    *service.StockService
 }
 
 // NewTransactionHandler erstellt einen neuen TransactionHandler
@@ -24,6 +28,7 @@ func NewTransactionHandler() *TransactionHandler {
 	return &TransactionHandler{
 		transactionRepo: repository.NewTransactionRepository(),
 		articleRepo:     repository.NewArticleRepository(),
+		stockService:    service.NewStockService(),
 	}
 }
 
@@ -115,6 +120,42 @@ func (h *TransactionHandler) ShowAddTransactionForm(c *gin.Context) {
 		articles = []*model.Article{} // Leere Liste im Fehlerfall
 	}
 
+	// Lagerorte für die Auswahl des Lagerplatzes und für Umlagerungen laden
+	locationRepo := repository.NewLocationRepository()
+	locations, err := locationRepo.FindAll()
+	if err != nil {
+		locations = []*model.Location{} // Leere Liste im Fehlerfall
+	}
+
+	// Aktuelle Bestände, Chargen, Seriennummern und Reservierungen des gewählten Artikels
+	stockLevels := []stockLevelRow{}
+	lots := []lotRow{}
+	serials := []*model.SerialNumber{}
+	reservations := []*model.Reservation{}
+	if article != nil {
+		locationMap, _ := locationRepo.BuildLocationTree()
+		levels, err := repository.NewStockLevelRepository().FindByArticleID(article.ID)
+		if err == nil {
+			stockLevels = buildStockLevelRows(levels, articleMap([]*model.Article{article}), locationMap)
+		}
+		if article.LotTracked {
+			articleLots, err := repository.NewLotRepository().FindByArticleID(article.ID)
+			if err == nil {
+				lots = buildLotRows(articleLots, articleMap([]*model.Article{article}), locationMap)
+			}
+		}
+		if article.SerialNumberRequired {
+			available, err := repository.NewSerialNumberRepository().FindAvailableByArticleID(article.ID)
+			if err == nil {
+				serials = available
+			}
+		}
+		active, err := repository.NewReservationRepository().FindActiveByArticleID(article.ID)
+		if err == nil {
+			reservations = active
+		}
+	}
+
 	c.HTML(http.StatusOK, "transaction_add.html", gin.H{
 		"title":           "Lagerbewegung erfassen",
 		"active":          "transactions",
@@ -123,6 +164,12 @@ func (h *TransactionHandler) ShowAddTransactionForm(c *gin.Context) {
 		"year":            time.Now().Year(),
 		"articles":        articles,
 		"selectedArticle": article,
+		"locations":       locations,
+		"stockLevels":     stockLevels,
+		"lots":            lots,
+		"serials":         serials,
+		"reservations":    reservations,
+		"reservationId":   c.Query("reservationId"),
 		"type":            transactionType,
 		"userRole":        c.GetString("userRole"),
 	})
@@ -172,120 +219,110 @@ func (h *TransactionHandler) AddTransaction(c *gin.Context) {
 		return
 	}
 
-	// Stückpreis als Float parsen (falls vorhanden)
+	// Stückpreis als Float parsen (falls vorhanden). Ohne Angabe bewertet PostTransaction
+	// Zugänge zum Einkaufspreis und alle übrigen Buchungen zum Durchschnittspreis.
 	var unitPrice float64
 	if unitPriceStr != "" {
 		unitPrice, _ = strconv.ParseFloat(unitPriceStr, 64)
+	}
+
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	// Neue Transaktion erstellen; Bestände werden beim Buchen ermittelt
+	transaction := &model.Transaction{
+		Type:      model.TransactionType(transactionType),
+		ArticleID: articleID,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Reason:    reason,
+		Reference: reference,
+		UserID:    userModel.ID,
+		UserName:  fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
+		Notes:     notes,
+	}
+
+	// Menge und Preis in einer weiteren Einheit des Artikels, z.B. Karton statt Stück
+	if unit := c.PostForm("unit"); unit != "" && unit != article.Unit {
+		transaction.InputUnit = unit
+		transaction.InputQuantity, transaction.Quantity = quantity, 0
+		transaction.InputUnitPrice, transaction.UnitPrice = unitPrice, 0
+	}
+
+	// Quell- und Ziellagerort bei Umlagerungen, sonst der betroffene Lagerplatz
+	if transaction.Type == model.TransactionTypeTransfer {
+		transaction.SourceLocationID, _ = primitive.ObjectIDFromHex(c.PostForm("sourceLocationId"))
+		transaction.TargetLocationID, _ = primitive.ObjectIDFromHex(c.PostForm("targetLocationId"))
+	} else if locationIDStr := c.PostForm("locationId"); locationIDStr != "" {
+		transaction.LocationID, _ = primitive.ObjectIDFromHex(locationIDStr)
 	} else {
-		// Standardmäßig den Einkaufspreis des Artikels verwenden
-		unitPrice = article.PurchasePriceNet
+		// Ohne Auswahl wird der Standardlagerort des Artikels verwendet
+		transaction.LocationID = article.StorageLocationID
 	}
 
-	// Alten Bestand speichern
-	oldStock := article.StockCurrent
-
-	// Neuen Bestand berechnen basierend auf dem Transaktionstyp
-	var newStock float64
-	switch transactionType {
-	case string(model.TransactionTypeStockIn):
-		newStock = oldStock + quantity
-	case string(model.TransactionTypeStockOut):
-		newStock = oldStock - quantity
-		// Prüfen, ob genügend Bestand vorhanden ist
-		if newStock < 0 {
+	// Charge beim Wareneingang bzw. manuell gewählte Charge bei Entnahmen (sonst FEFO)
+	transaction.LotNumber = strings.TrimSpace(c.PostForm("lotNumber"))
+	if expiryDateStr := c.PostForm("expiryDate"); expiryDateStr != "" {
+		transaction.ExpiryDate, err = time.ParseInLocation("2006-01-02", expiryDateStr, time.Local)
+		if err != nil {
 			c.HTML(http.StatusBadRequest, "error.html", gin.H{
 				"title":   "Fehler",
-				"message": "Nicht genügend Bestand vorhanden.",
+				"message": "Ungültiges Mindesthaltbarkeitsdatum",
 				"year":    time.Now().Year(),
 			})
 			return
 		}
-	case string(model.TransactionTypeAdjust):
-		// Bei einer Anpassung ist die eingegebene Menge bereits der neue Bestand
-		newStock = quantity
-		// Anpassen der Menge für die Transaktion (Differenz zum alten Bestand)
-		quantity = newStock - oldStock
-	case string(model.TransactionTypeInventory):
-		// Bei einer Inventur ist die eingegebene Menge der gezählte Bestand
-		newStock = quantity
-		// Anpassen der Menge für die Transaktion (Differenz zum alten Bestand)
-		quantity = newStock - oldStock
-	default:
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"title":   "Fehler",
-			"message": "Ungültiger Transaktionstyp",
-			"year":    time.Now().Year(),
-		})
-		return
 	}
 
-	// Aktuellen Benutzer aus dem Context abrufen
-	user, _ := c.Get("user")
-	userModel := user.(*model.User)
-
-	// Neue Transaktion erstellen
-	transaction := &model.Transaction{
-		ID:          primitive.NewObjectID(),
-		Type:        model.TransactionType(transactionType),
-		ArticleID:   articleID,
-		ArticleName: article.ShortName,
-		Quantity:    quantity,
-		OldStock:    oldStock,
-		NewStock:    newStock,
-		UnitPrice:   unitPrice,
-		Reason:      reason,
-		Reference:   reference,
-		UserID:      userModel.ID,
-		UserName:    fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
-		Timestamp:   time.Now(),
-		Notes:       notes,
-	}
+	// Seriennummern (eine je Stück, getrennt durch Zeilenumbruch, Komma oder Semikolon)
+	transaction.SerialNumbers = parseSerialNumbers(c.PostForm("serialNumbers"))
 
-	// Transaktion speichern
-	err = h.transactionRepo.Create(transaction)
-	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"title":   "Fehler",
-			"message": "Fehler beim Speichern der Transaktion: " + err.Error(),
-			"year":    time.Now().Year(),
-		})
-		return
+	// Bestandsstatus der bewegten Ware (leer = verfügbar), bei Statuswechseln auch der neue Status
+	transaction.StockStatus = model.StockStatus(c.PostForm("stockStatus"))
+	if transaction.Type == model.TransactionTypeStatusChange {
+		transaction.TargetStockStatus = model.StockStatus(c.PostForm("targetStockStatus"))
+		transaction.SerialNumbers = nil
 	}
 
-	// Artikelbestand aktualisieren
-	article.StockCurrent = newStock
-	if transactionType == string(model.TransactionTypeInventory) {
-		article.LastStockTakeDate = time.Now()
+	// Reservierung, die ein Warenausgang erfüllt
+	if reservationIDStr := c.PostForm("reservationId"); reservationIDStr != "" && transaction.Type == model.TransactionTypeStockOut {
+		transaction.ReservationID, _ = primitive.ObjectIDFromHex(reservationIDStr)
 	}
 
-	err = h.articleRepo.Update(article)
+	// Bestand und Journal gemeinsam buchen
+	err = h.stockService.PostTransaction(transaction)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+		status := http.StatusInternalServerError
+		message := err.Error()
+		switch {
+		case err == repository.ErrInsufficientStock:
+			status = http.StatusBadRequest
+			message = "Nicht genügend Bestand vorhanden."
+		case err == repository.ErrStockReserved, err == repository.ErrStockHeld, err == repository.ErrReservationNotActive,
+			err == repository.ErrReservationConflict:
+			status = http.StatusConflict
+		case err == service.ErrInvalidStockStatus, err == service.ErrStockBelowHeld:
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrHazardNotAllowed):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrHazardSegregation):
+			status = http.StatusConflict
+		case err == service.ErrInvalidTransactionType, err == service.ErrInvalidTransferLocations,
+			err == service.ErrLocationRequired, err == service.ErrLotRequired, err == service.ErrUnknownUnit,
+			err == repository.ErrInsufficientLotStock, err == service.ErrSerialCount,
+			errors.Is(err, service.ErrSerialNotAvailable), errors.Is(err, service.ErrSerialInStock):
+			status = http.StatusBadRequest
+		}
+
+		c.HTML(status, "error.html", gin.H{
 			"title":   "Fehler",
-			"message": "Fehler beim Aktualisieren des Artikelbestands: " + err.Error(),
+			"message": message,
 			"year":    time.Now().Year(),
 		})
 		return
 	}
 
-	// Aktivität loggen
-	activityRepo := repository.NewActivityRepository()
-	activityType := model.ActivityTypeStockAdjusted
-	if transactionType == string(model.TransactionTypeInventory) {
-		activityType = model.ActivityTypeStockTaking
-	}
-
-	_, _ = activityRepo.LogActivity(
-		activityType,
-		userModel.ID,
-		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
-		articleID,
-		"article",
-		article.ShortName,
-		fmt.Sprintf("%s: %g %s (neu: %g)", transaction.GetDisplayType(), quantity, article.Unit, newStock),
-		quantity,
-	)
-
 	// Weiterleitungsziel: zurück zur Artikel-Detailseite oder zur Transaktionsliste
 	redirectURL := fmt.Sprintf("/articles/view/%s?success=transaction", articleIDStr)
 	if c.PostForm("returnToList") == "true" {
@@ -315,14 +352,90 @@ func (h *TransactionHandler) GetTransactionDetails(c *gin.Context) {
 	user, _ := c.Get("user")
 	userModel := user.(*model.User)
 
+	// Verknüpfte Storno- bzw. Originalbuchung laden
+	var reversal, original *model.Transaction
+	if transaction.IsReversed() {
+		reversal, _ = h.transactionRepo.FindByID(transaction.ReversedBy.Hex())
+	}
+	if !transaction.ReversalOf.IsZero() {
+		original, _ = h.transactionRepo.FindByID(transaction.ReversalOf.Hex())
+	}
+
+	// Prüfen, ob die Buchung storniert werden kann
+	var reverseBlockedReason string
+	if err := h.stockService.CheckReversible(transaction); err != nil {
+		reverseBlockedReason = err.Error()
+	}
+
 	// Daten an das Template übergeben
 	c.HTML(http.StatusOK, "transaction_detail.html", gin.H{
-		"title":       "Transaktionsdetails",
-		"active":      "transactions",
-		"user":        userModel.FirstName + " " + userModel.LastName,
-		"email":       userModel.Email,
-		"year":        time.Now().Year(),
-		"transaction": transaction,
-		"userRole":    c.GetString("userRole"),
+		"title":                "Transaktionsdetails",
+		"active":               "transactions",
+		"user":                 userModel.FirstName + " " + userModel.LastName,
+		"email":                userModel.Email,
+		"year":                 time.Now().Year(),
+		"transaction":          transaction,
+		"reversal":             reversal,
+		"original":             original,
+		"isReversed":           transaction.IsReversed(),
+		"canReverse":           reverseBlockedReason == "",
+		"reverseBlockedReason": reverseBlockedReason,
+		"userRole":             c.GetString("userRole"),
 	})
 }
+
+// ReverseTransaction storniert eine Buchung durch eine Gegenbuchung
+func (h *TransactionHandler) ReverseTransaction(c *gin.Context) {
+	id := c.Param("id")
+	reason := c.PostForm("reason")
+
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	reversal, err := h.stockService.ReverseTransaction(
+		id,
+		reason,
+		userModel.ID,
+		fmt.Sprintf("%s %s", userModel.FirstName, userModel.LastName),
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case err == repository.ErrAlreadyReversed, err == service.ErrReversalNotAllowed,
+			err == service.ErrReversalSuperseded, errors.Is(err, service.ErrSerialMoved),
+			err == service.ErrReversalReturned, err == service.ErrGoodsReceiptNotReversible,
+			err == service.ErrShipmentNotReversible,
+			err == repository.ErrStockReserved, err == repository.ErrStockHeld:
+			status = http.StatusConflict
+		case err == repository.ErrInsufficientStock, err == service.ErrInvalidTransferLocations,
+			err == repository.ErrInsufficientLotStock:
+			status = http.StatusBadRequest
+		}
+
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Storno nicht möglich: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	// Weiterleitung zur Stornobuchung mit Erfolgsmeldung
+	c.Redirect(http.StatusFound, fmt.Sprintf("/transactions/view/%s?success=reversed", reversal.ID.Hex()))
+}
+
+// parseSerialNumbers zerlegt die Eingabe der Seriennummern in einzelne Werte
+func parseSerialNumbers(input string) []string {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == ';'
+	})
+
+	var serials []string
+	for _, field := range fields {
+		if serial := strings.TrimSpace(field); serial != "" {
+			serials = append(serials, serial)
+		}
+	}
+	return serials
+}
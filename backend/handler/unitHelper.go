@@ -0,0 +1,111 @@
+// backend/handler/unitHelper.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errUnitRequired wird zurückgegeben, wenn ein Artikel ohne Lagereinheit gespeichert werden soll
+var errUnitRequired = errors.New("Bitte eine Lagereinheit angeben")
+
+// unitOptions gibt die Namen aller Einheiten aus dem Katalog zurück, ergänzt um die
+// angegebenen Einheiten, falls sie (noch) nicht im Katalog stehen
+func unitOptions(unitRepo *repository.UnitOfMeasureRepository, current ...string) []string {
+	units, err := unitRepo.FindAll()
+	if err != nil {
+		units = []*model.UnitOfMeasure{} // Leere Liste im Fehlerfall
+	}
+
+	names := make([]string, 0, len(units)+len(current))
+	known := make(map[string]bool, len(units))
+	for _, unit := range units {
+		names = append(names, unit.Name)
+		known[unit.Name] = true
+	}
+	for _, name := range current {
+		if name != "" && !known[name] {
+			names = append(names, name)
+			known[name] = true
+		}
+	}
+
+	return names
+}
+
+// bindArticleUnits übernimmt Einkaufs- und Ausgabeeinheit sowie die Umrechnungen aus dem
+// Artikelformular. Jede Umrechnung braucht einen Faktor größer 0 und eine eigene Einheit,
+// die nicht die Lagereinheit ist; Einkaufs- und Ausgabeeinheit müssen umrechenbar sein.
+func bindArticleUnits(c *gin.Context, article *model.Article) error {
+	if article.Unit == "" {
+		return errUnitRequired
+	}
+
+	units := c.PostFormArray("conversionUnit")
+	factors := c.PostFormArray("conversionFactor")
+	purchasePrices := c.PostFormArray("conversionPurchasePrice")
+	salesPrices := c.PostFormArray("conversionSalesPrice")
+
+	conversions := []model.UnitConversion{}
+	seen := make(map[string]bool)
+	for i, unit := range units {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			continue
+		}
+		if unit == article.Unit {
+			return fmt.Errorf("Die Lagereinheit %s braucht keine Umrechnung", unit)
+		}
+		if seen[unit] {
+			return fmt.Errorf("Für die Einheit %s ist mehr als eine Umrechnung angegeben", unit)
+		}
+		seen[unit] = true
+
+		conversion := model.UnitConversion{Unit: unit}
+		if i < len(factors) {
+			conversion.Factor, _ = strconv.ParseFloat(factors[i], 64)
+		}
+		if conversion.Factor <= 0 {
+			return fmt.Errorf("Bitte für die Einheit %s einen Umrechnungsfaktor größer 0 angeben", unit)
+		}
+		if i < len(purchasePrices) {
+			conversion.PurchasePriceNet, _ = strconv.ParseFloat(purchasePrices[i], 64)
+		}
+		if i < len(salesPrices) {
+			conversion.SalesPriceGross, _ = strconv.ParseFloat(salesPrices[i], 64)
+		}
+		conversions = append(conversions, conversion)
+	}
+	article.UnitConversions = conversions
+
+	article.PurchaseUnit = c.PostForm("purchaseUnit")
+	if _, ok := article.GetUnitFactor(article.PurchaseUnit); !ok {
+		return fmt.Errorf("Für die Einkaufseinheit %s ist keine Umrechnung angegeben", article.PurchaseUnit)
+	}
+	article.IssueUnit = c.PostForm("issueUnit")
+	if _, ok := article.GetUnitFactor(article.IssueUnit); !ok {
+		return fmt.Errorf("Für die Ausgabeeinheit %s ist keine Umrechnung angegeben", article.IssueUnit)
+	}
+
+	return nil
+}
+
+// formatUnitConversions beschreibt die Umrechnungen eines Artikels für die Konfliktanzeige
+func formatUnitConversions(article *model.Article) string {
+	if len(article.UnitConversions) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, len(article.UnitConversions))
+	for i, conversion := range article.UnitConversions {
+		parts[i] = fmt.Sprintf("1 %s = %g %s", conversion.Unit, conversion.Factor, article.Unit)
+	}
+	return strings.Join(parts, ", ")
+}
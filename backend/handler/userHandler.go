@@ -2,24 +2,37 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 // UserHandler verwaltet alle Anfragen zu Benutzern
 type UserHandler struct {
-	userRepo *repository.UserRepository
+	userRepo         *repository.UserRepository
+	unitRepo         *repository.UnitOfMeasureRepository
+	hazardRepo       *repository.HazardClassRepository
+	articleRepo      *repository.ArticleRepository
+	locationRepo     *repository.LocationRepository
+	valuationService *service.ValuationService
 }
 
 // NewUserHandler erstellt einen neuen UserHandler
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		userRepo: repository.NewUserRepository(),
+		userRepo:         repository.NewUserRepository(),
+		unitRepo:         repository.NewUnitOfMeasureRepository(),
+		hazardRepo:       repository.NewHazardClassRepository(),
+		articleRepo:      repository.NewArticleRepository(),
+		locationRepo:     repository.NewLocationRepository(),
+		valuationService: service.NewValuationService(),
 	}
 }
 
@@ -389,7 +402,253 @@ func (h *UserHandler) ShowSettings(c *gin.Context) {
 		}
 		data["users"] = users
 		data["totalUsers"] = len(users)
+
+		valuationMethod, err := h.valuationService.GetValuationMethod()
+		if err != nil {
+			valuationMethod = model.ValuationMethodMovingAverage
+		}
+		data["valuationMethod"] = valuationMethod
+		data["valuationMethods"] = model.ValuationMethods
+
+		units, err := h.unitRepo.FindAll()
+		if err != nil {
+			units = []*model.UnitOfMeasure{}
+		}
+		data["units"] = units
+
+		hazardClasses, err := h.hazardRepo.FindAll()
+		if err != nil {
+			hazardClasses = []*model.HazardClass{}
+		}
+		data["hazardClasses"] = hazardClasses
 	}
 
 	c.HTML(http.StatusOK, "settings.html", data)
 }
+
+// UpdateValuationMethod speichert das Bewertungsverfahren für den Lagerwert (nur für Admins)
+func (h *UserHandler) UpdateValuationMethod(c *gin.Context) {
+	// Aktuellen Benutzer aus dem Context abrufen
+	user, _ := c.Get("user")
+	userModel := user.(*model.User)
+
+	method := model.ValuationMethod(c.PostForm("valuationMethod"))
+	err := h.valuationService.SetValuationMethod(method, userModel.ID, userModel.FirstName+" "+userModel.LastName)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Speichern des Bewertungsverfahrens: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=valuation")
+}
+
+// CreateUnit nimmt eine neue Einheit in den Einheitenkatalog auf (nur für Admins)
+func (h *UserHandler) CreateUnit(c *gin.Context) {
+	unit := &model.UnitOfMeasure{
+		Name:   strings.TrimSpace(c.PostForm("name")),
+		Symbol: strings.TrimSpace(c.PostForm("symbol")),
+	}
+	if unit.Name == "" {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bitte einen Namen für die Einheit angeben",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	if unit.Symbol == "" {
+		unit.Symbol = unit.Name
+	}
+
+	if err := h.unitRepo.Create(unit); err != nil {
+		status := http.StatusInternalServerError
+		if err == repository.ErrUnitExists {
+			status = http.StatusConflict
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Anlegen der Einheit: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=unit_added")
+}
+
+// DeleteUnit entfernt eine Einheit aus dem Einheitenkatalog, sofern kein Artikel sie verwendet (nur für Admins)
+func (h *UserHandler) DeleteUnit(c *gin.Context) {
+	unit, err := h.unitRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Einheit nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	count, err := h.articleRepo.CountByUnit(unit.Name)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Prüfen der Verwendung: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	if count > 0 {
+		c.HTML(http.StatusConflict, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": fmt.Sprintf("Die Einheit %s wird von %d Artikeln verwendet und kann nicht gelöscht werden", unit.Name, count),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if err := h.unitRepo.Delete(unit.ID.Hex()); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Löschen der Einheit: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=unit_deleted")
+}
+
+// CreateHazardClass nimmt eine neue Gefahrgutklasse in den Gefahrgutkatalog auf (nur für Admins)
+func (h *UserHandler) CreateHazardClass(c *gin.Context) {
+	class := &model.HazardClass{
+		Code: strings.TrimSpace(c.PostForm("code")),
+		Name: strings.TrimSpace(c.PostForm("name")),
+	}
+	if class.Code == "" {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Bitte eine Klassennummer für die Gefahrgutklasse angeben",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	if class.Name == "" {
+		class.Name = class.Code
+	}
+
+	if err := h.hazardRepo.Create(class); err != nil {
+		status := http.StatusInternalServerError
+		if err == repository.ErrHazardClassExists {
+			status = http.StatusConflict
+		}
+		c.HTML(status, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Anlegen der Gefahrgutklasse: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=hazard_added")
+}
+
+// DeleteHazardClass entfernt eine Gefahrgutklasse aus dem Gefahrgutkatalog, sofern weder ein
+// Artikel noch ein Lagerort sie verwendet (nur für Admins)
+func (h *UserHandler) DeleteHazardClass(c *gin.Context) {
+	class, err := h.hazardRepo.FindByID(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Gefahrgutklasse nicht gefunden",
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	articleCount, err := h.articleRepo.CountByHazardClass(class.Code)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Prüfen der Verwendung: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	locationCount, err := h.locationRepo.CountByHazardClass(class.Code)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Prüfen der Verwendung: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+	if articleCount > 0 || locationCount > 0 {
+		c.HTML(http.StatusConflict, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": fmt.Sprintf("Die Gefahrgutklasse %s wird von %d Artikeln und %d Lagerorten verwendet und kann nicht gelöscht werden", class.Code, articleCount, locationCount),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	if err := h.hazardRepo.Delete(class); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Löschen der Gefahrgutklasse: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=hazard_deleted")
+}
+
+// UpdateHazardRules speichert die Zusammenlagerungsregeln aus der Matrix der Einstellungsseite
+// (nur für Admins). Jedes angehakte Paar wird bei beiden Klassen als unverträglich hinterlegt.
+func (h *UserHandler) UpdateHazardRules(c *gin.Context) {
+	classes, err := h.hazardRepo.FindAll()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"title":   "Fehler",
+			"message": "Fehler beim Laden des Gefahrgutkatalogs: " + err.Error(),
+			"year":    time.Now().Year(),
+		})
+		return
+	}
+
+	incompatible := make(map[string][]string, len(classes))
+	for _, class := range classes {
+		incompatible[class.Code] = []string{}
+	}
+	for _, pair := range c.PostFormArray("incompatible") {
+		codes := strings.SplitN(pair, "|", 2)
+		if len(codes) != 2 || codes[0] == codes[1] {
+			continue
+		}
+		first, firstKnown := incompatible[codes[0]]
+		second, secondKnown := incompatible[codes[1]]
+		if !firstKnown || !secondKnown {
+			continue
+		}
+		incompatible[codes[0]] = append(first, codes[1])
+		incompatible[codes[1]] = append(second, codes[0])
+	}
+
+	for _, class := range classes {
+		if err := h.hazardRepo.SetIncompatible(class.Code, incompatible[class.Code]); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"title":   "Fehler",
+				"message": "Fehler beim Speichern der Zusammenlagerungsregeln: " + err.Error(),
+				"year":    time.Now().Year(),
+			})
+			return
+		}
+	}
+
+	c.Redirect(http.StatusFound, "/settings?success=hazard_rules")
+}
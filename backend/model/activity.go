@@ -17,6 +17,10 @@ const (
 	ActivityTypeArticleDeleted ActivityType = "article_deleted"
 	ActivityTypeStockAdjusted  ActivityType = "stock_adjusted" // Neu: Für Bestandsanpassungen
 	ActivityTypeStockTaking    ActivityType = "stock_taking"   // Neu: Für Inventur
+	ActivityTypeStockReversed  ActivityType = "stock_reversed" // Storno einer Lagerbuchung
+
+	ActivityTypeStockReserved       ActivityType = "stock_reserved"       // Bestand reserviert
+	ActivityTypeReservationReleased ActivityType = "reservation_released" // Reservierung freigegeben oder abgelaufen
 
 	// System-bezogene Aktivitäten
 	ActivityTypeUserAdded       ActivityType = "user_added"
@@ -26,6 +30,26 @@ const (
 	ActivityTypeSupplierAdded   ActivityType = "supplier_added"
 	ActivityTypeSupplierUpdated ActivityType = "supplier_updated"
 	ActivityTypeSupplierDeleted ActivityType = "supplier_deleted"
+
+	// Einkauf
+	ActivityTypePurchaseOrderCreated ActivityType = "purchase_order_created"
+	ActivityTypePurchaseOrderUpdated ActivityType = "purchase_order_updated" // Bearbeitet, versendet oder storniert
+	ActivityTypeGoodsReceived        ActivityType = "goods_received"
+
+	// Verkauf
+	ActivityTypeCustomerOrderCreated ActivityType = "customer_order_created"
+	ActivityTypeCustomerOrderUpdated ActivityType = "customer_order_updated" // Bearbeitet, bestätigt, versendet oder storniert
+
+	// Inventur
+	ActivityTypeCycleCountCreated ActivityType = "cycle_count_created"
+	ActivityTypeCycleCountUpdated ActivityType = "cycle_count_updated" // Abgeschlossen, wieder geöffnet, freigegeben oder abgebrochen
+
+	// Sets
+	ActivityTypeAssemblyPosted ActivityType = "assembly_posted" // Montage oder Demontage gebucht
+
+	// Retouren
+	ActivityTypeReturnCreated ActivityType = "return_created"
+	ActivityTypeReturnUpdated ActivityType = "return_updated" // Geprüft und gebucht oder storniert
 )
 
 // Activity repräsentiert eine Aktivität im System
@@ -45,14 +69,16 @@ type Activity struct {
 // GetIconClass gibt die CSS-Klasse für das Icon basierend auf dem Aktivitätstyp zurück
 func (a *Activity) GetIconClass() string {
 	switch a.Type {
-	case ActivityTypeArticleAdded, ActivityTypeStockAdjusted:
+	case ActivityTypeArticleAdded, ActivityTypeStockAdjusted, ActivityTypeGoodsReceived, ActivityTypeAssemblyPosted:
 		return "bg-green-500"
-	case ActivityTypeArticleUpdated, ActivityTypeStockTaking:
+	case ActivityTypeArticleUpdated, ActivityTypeStockTaking, ActivityTypeCycleCountCreated, ActivityTypeCycleCountUpdated:
 		return "bg-blue-500"
-	case ActivityTypeArticleDeleted:
+	case ActivityTypeArticleDeleted, ActivityTypeStockReversed:
 		return "bg-red-500"
 	case ActivityTypeUserLogin:
 		return "bg-yellow-500"
+	case ActivityTypeStockReserved, ActivityTypeReservationReleased:
+		return "bg-indigo-500"
 	default:
 		return "bg-gray-500"
 	}
@@ -67,9 +93,9 @@ func (a *Activity) GetIconSVG() string {
 		return "<svg class=\"h-5 w-5 text-white\" viewBox=\"0 0 20 20\" fill=\"currentColor\"><path d=\"M7 3a1 1 0 000 2h6a1 1 0 100-2H7zM4 7a1 1 0 011-1h10a1 1 0 110 2H5a1 1 0 01-1-1zM2 11a2 2 0 012-2h12a2 2 0 012 2v4a2 2 0 01-2 2H4a2 2 0 01-2-2v-4z\" /></svg>"
 	case ActivityTypeArticleDeleted:
 		return "<svg class=\"h-5 w-5 text-white\" viewBox=\"0 0 20 20\" fill=\"currentColor\"><path fill-rule=\"evenodd\" d=\"M9 2a1 1 0 00-.894.553L7.382 4H4a1 1 0 000 2v10a2 2 0 002 2h8a2 2 0 002-2V6a1 1 0 100-2h-3.382l-.724-1.447A1 1 0 0011 2H9zM7 8a1 1 0 012 0v6a1 1 0 11-2 0V8zm5-1a1 1 0 00-1 1v6a1 1 0 102 0V8a1 1 0 00-1-1z\" clip-rule=\"evenodd\" /></svg>"
-	case ActivityTypeStockAdjusted:
+	case ActivityTypeStockAdjusted, ActivityTypeStockReversed, ActivityTypeAssemblyPosted:
 		return "<svg class=\"h-5 w-5 text-white\" viewBox=\"0 0 20 20\" fill=\"currentColor\"><path fill-rule=\"evenodd\" d=\"M10 3a1 1 0 01.707.293l3 3a1 1 0 01-1.414 1.414L10 5.414 7.707 7.707a1 1 0 01-1.414-1.414l3-3A1 1 0 0110 3zm-3.707 9.293a1 1 0 011.414 0L10 14.586l2.293-2.293a1 1 0 011.414 1.414l-3 3a1 1 0 01-1.414 0l-3-3a1 1 0 010-1.414z\" clip-rule=\"evenodd\" /></svg>"
-	case ActivityTypeStockTaking:
+	case ActivityTypeStockTaking, ActivityTypeCycleCountCreated, ActivityTypeCycleCountUpdated:
 		return "<svg class=\"h-5 w-5 text-white\" viewBox=\"0 0 20 20\" fill=\"currentColor\"><path d=\"M9 2a1 1 0 000 2h2a1 1 0 100-2H9z\" /><path fill-rule=\"evenodd\" d=\"M4 5a2 2 0 012-2 3 3 0 003 3h2a3 3 0 003-3 2 2 0 012 2v11a2 2 0 01-2 2H6a2 2 0 01-2-2V5zm3 4a1 1 0 000 2h.01a1 1 0 100-2H7zm3 0a1 1 0 000 2h3a1 1 0 100-2h-3zm-3 4a1 1 0 100 2h.01a1 1 0 100-2H7zm3 0a1 1 0 100 2h3a1 1 0 100-2h-3z\" clip-rule=\"evenodd\" /></svg>"
 	case ActivityTypeUserLogin:
 		return "<svg class=\"h-5 w-5 text-white\" viewBox=\"0 0 20 20\" fill=\"currentColor\"><path fill-rule=\"evenodd\" d=\"M3 3a1 1 0 011 1v12a1 1 0 11-2 0V4a1 1 0 011-1zm7.707 3.293a1 1 0 010 1.414L9.414 9H17a1 1 0 110 2H9.414l1.293 1.293a1 1 0 01-1.414 1.414l-3-3a1 1 0 010-1.414l3-3a1 1 0 011.414 0z\" clip-rule=\"evenodd\" /></svg>"
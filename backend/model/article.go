@@ -3,6 +3,7 @@ package model
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
 	"time"
 )
 
@@ -17,10 +18,14 @@ type Article struct {
 	Unit                  string             `bson:"unit" json:"unit"`                                   // Lagereinheit (z.B. Stück, kg)
 	StockCurrent          float64            `bson:"stockCurrent" json:"stockCurrent"`                   // Aktueller Lagerbestand
 	StockReserved         float64            `bson:"stockReserved" json:"stockReserved"`                 // Reservierte Menge
+	StockQuarantine       float64            `bson:"stockQuarantine,omitempty" json:"stockQuarantine"`   // Davon in Quarantäne
+	StockBlocked          float64            `bson:"stockBlocked,omitempty" json:"stockBlocked"`         // Davon gesperrt
+	StockDamaged          float64            `bson:"stockDamaged,omitempty" json:"stockDamaged"`         // Davon beschädigt
 	MinimumStock          float64            `bson:"minimumStock" json:"minimumStock"`                   // Mindestbestand/Bestellpunkt
 	MaximumStock          float64            `bson:"maximumStock" json:"maximumStock"`                   // Maximalbestand (neu)
 	ReorderQuantity       float64            `bson:"reorderQuantity" json:"reorderQuantity"`             // Bestellmenge (neu)
 	PurchasePriceNet      float64            `bson:"purchasePriceNet" json:"purchasePriceNet"`           // Einkaufspreis (netto)
+	AverageCost           float64            `bson:"averageCost" json:"averageCost"`                     // Gleitender Durchschnittspreis aus den Wareneingängen
 	SalesPriceGross       float64            `bson:"salesPriceGross" json:"salesPriceGross"`             // Verkaufspreis (brutto)
 	SupplierID            primitive.ObjectID `bson:"supplierId,omitempty" json:"supplierId,omitempty"`   // Referenz zum Lieferanten (neu)
 	SupplierNumber        string             `bson:"supplierNumber" json:"supplierNumber"`               // Lieferantennummer
@@ -31,6 +36,7 @@ type Article struct {
 	WeightKg              float64            `bson:"weightKg" json:"weightKg"`                           // Gewicht in kg
 	Dimensions            string             `bson:"dimensions" json:"dimensions"`                       // Abmessungen (LxBxH) in cm
 	SerialNumberRequired  bool               `bson:"serialNumberRequired" json:"serialNumberRequired"`   // Seriennummernpflicht
+	LotTracked            bool               `bson:"lotTracked" json:"lotTracked"`                       // Chargenpflicht (mit Mindesthaltbarkeit)
 	HazardClass           string             `bson:"hazardClass" json:"hazardClass"`                     // Gefahrgutklasse
 	Notes                 string             `bson:"notes" json:"notes"`                                 // Bemerkungen
 	Images                []string           `bson:"images,omitempty" json:"images,omitempty"`           // Bilder (neu)
@@ -39,29 +45,167 @@ type Article struct {
 	CreatedAt             time.Time          `bson:"createdAt" json:"createdAt"`                         // Erstellungsdatum
 	UpdatedAt             time.Time          `bson:"updatedAt" json:"updatedAt"`                         // Aktualisierungsdatum
 	StorageLocationID     primitive.ObjectID `bson:"storageLocationId,omitempty" json:"storageLocationId,omitempty"`
+	Version               int64              `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+
+	// Einheiten: Bestände, Buchungen und Preise werden in der Lagereinheit (Unit) geführt.
+	// Einkauf und Ausgabe können in weiteren Einheiten erfolgen, z.B. Karton zu 12 Stück.
+	PurchaseUnit    string           `bson:"purchaseUnit,omitempty" json:"purchaseUnit,omitempty"`       // Einkaufseinheit (leer = Lagereinheit)
+	IssueUnit       string           `bson:"issueUnit,omitempty" json:"issueUnit,omitempty"`             // Verkaufs-/Ausgabeeinheit (leer = Lagereinheit)
+	UnitConversions []UnitConversion `bson:"unitConversions,omitempty" json:"unitConversions,omitempty"` // Weitere Einheiten mit Umrechnung
+
+	// Stückliste: Ein Artikel mit Komponenten ist ein Set, das durch eine Montage aus den
+	// Komponenten entsteht und durch eine Demontage wieder in sie zerlegt wird
+	BillOfMaterials []BOMComponent `bson:"billOfMaterials,omitempty" json:"billOfMaterials,omitempty"`
 }
 
-// GetStockStatus gibt den Bestandsstatus zurück (zu niedrig, optimal, zu hoch)
+// GetStockStatus gibt den Bestandsstatus zurück (zu niedrig, optimal, zu hoch). Maßgeblich
+// ist der frei verwendbare Bestand ohne Quarantäne, gesperrte und beschädigte Ware.
 func (a *Article) GetStockStatus() string {
-	if a.StockCurrent <= a.MinimumStock {
+	stock := a.GetUnrestrictedStock()
+	if stock <= a.MinimumStock {
 		return "low"
-	} else if a.MaximumStock > 0 && a.StockCurrent >= a.MaximumStock {
+	} else if a.MaximumStock > 0 && stock >= a.MaximumStock {
 		return "high"
 	}
 	return "ok"
 }
 
-// GetAvailableStock gibt den verfügbaren Bestand zurück (aktuell - reserviert)
+// GetHeldStock gibt den zurückgehaltenen Bestand zurück (Quarantäne, gesperrt, beschädigt)
+func (a *Article) GetHeldStock() float64 {
+	return a.StockQuarantine + a.StockBlocked + a.StockDamaged
+}
+
+// GetUnrestrictedStock gibt den frei verwendbaren Bestand einschließlich Reservierungen zurück
+func (a *Article) GetUnrestrictedStock() float64 {
+	return a.StockCurrent - a.GetHeldStock()
+}
+
+// GetStockIn gibt den Bestand in einem Bestandsstatus zurück; verfügbar ist der frei
+// verwendbare Bestand einschließlich Reservierungen
+func (a *Article) GetStockIn(status StockStatus) float64 {
+	switch status {
+	case StockStatusQuarantine:
+		return a.StockQuarantine
+	case StockStatusBlocked:
+		return a.StockBlocked
+	case StockStatusDamaged:
+		return a.StockDamaged
+	default:
+		return a.GetUnrestrictedStock()
+	}
+}
+
+// GetAvailableStock gibt den verfügbaren Bestand zurück (aktuell - zurückgehalten - reserviert)
 func (a *Article) GetAvailableStock() float64 {
-	return a.StockCurrent - a.StockReserved
+	return a.GetUnrestrictedStock() - a.StockReserved
 }
 
-// IsBelowMinimum prüft, ob der Bestand unter dem Mindestbestand ist
+// IsBelowMinimum prüft, ob der frei verwendbare Bestand unter dem Mindestbestand ist
 func (a *Article) IsBelowMinimum() bool {
-	return a.StockCurrent <= a.MinimumStock
+	return a.GetUnrestrictedStock() <= a.MinimumStock
 }
 
-// GetStockValue gibt den aktuellen Warenwert zurück (Bestand * Einkaufspreis)
+// GetAverageCost gibt den gleitenden Durchschnittspreis zurück. Solange noch kein
+// Wareneingang bewertet wurde, gilt der Einkaufspreis.
+func (a *Article) GetAverageCost() float64 {
+	if a.AverageCost > 0 {
+		return a.AverageCost
+	}
+	return a.PurchasePriceNet
+}
+
+// MovingAverageCost berechnet den gleitenden Durchschnittspreis nach einem bewerteten Zugang
+// (oder mit negativer Menge nach dessen Storno). Negativer Bestand vor dem Zugang zählt nicht
+// mit; fällt der Bestand auf null, bleibt der bisherige Preis erhalten.
+func MovingAverageCost(stockBefore, averageBefore, quantity, unitPrice float64) float64 {
+	stockBefore = math.Max(stockBefore, 0)
+	if stockBefore+quantity <= 0 {
+		return averageBefore
+	}
+	return (stockBefore*averageBefore + quantity*unitPrice) / (stockBefore + quantity)
+}
+
+// GetStockValue gibt den aktuellen Warenwert zum gleitenden Durchschnittspreis zurück
 func (a *Article) GetStockValue() float64 {
-	return a.StockCurrent * a.PurchasePriceNet
+	return a.StockCurrent * a.GetAverageCost()
+}
+
+// GetUnitConversion gibt die Umrechnung einer weiteren Einheit des Artikels zurück
+func (a *Article) GetUnitConversion(unit string) *UnitConversion {
+	for i := range a.UnitConversions {
+		if a.UnitConversions[i].Unit == unit {
+			return &a.UnitConversions[i]
+		}
+	}
+	return nil
+}
+
+// GetUnitFactor gibt die Anzahl Lagereinheiten je Einheit zurück. Für die Lagereinheit (oder
+// eine leere Angabe) ist der Faktor 1; für Einheiten ohne Umrechnung wird false zurückgegeben.
+func (a *Article) GetUnitFactor(unit string) (float64, bool) {
+	if unit == "" || unit == a.Unit {
+		return 1, true
+	}
+	if conversion := a.GetUnitConversion(unit); conversion != nil && conversion.Factor > 0 {
+		return conversion.Factor, true
+	}
+	return 0, false
+}
+
+// GetUnits gibt alle Einheiten zurück, in denen Mengen des Artikels erfasst werden können
+func (a *Article) GetUnits() []string {
+	units := []string{a.Unit}
+	for _, conversion := range a.UnitConversions {
+		units = append(units, conversion.Unit)
+	}
+	return units
+}
+
+// GetPurchaseUnit gibt die Einheit zurück, in der der Artikel bestellt wird
+func (a *Article) GetPurchaseUnit() string {
+	if _, ok := a.GetUnitFactor(a.PurchaseUnit); ok && a.PurchaseUnit != "" {
+		return a.PurchaseUnit
+	}
+	return a.Unit
+}
+
+// GetIssueUnit gibt die Einheit zurück, in der der Artikel verkauft bzw. ausgegeben wird
+func (a *Article) GetIssueUnit() string {
+	if _, ok := a.GetUnitFactor(a.IssueUnit); ok && a.IssueUnit != "" {
+		return a.IssueUnit
+	}
+	return a.Unit
+}
+
+// GetPurchasePriceFor gibt den Einkaufspreis netto je Einheit zurück
+func (a *Article) GetPurchasePriceFor(unit string) float64 {
+	if conversion := a.GetUnitConversion(unit); conversion != nil && conversion.PurchasePriceNet > 0 {
+		return conversion.PurchasePriceNet
+	}
+	factor, _ := a.GetUnitFactor(unit)
+	return a.PurchasePriceNet * factor
+}
+
+// GetSalesPriceFor gibt den Verkaufspreis brutto je Einheit zurück
+func (a *Article) GetSalesPriceFor(unit string) float64 {
+	if conversion := a.GetUnitConversion(unit); conversion != nil && conversion.SalesPriceGross > 0 {
+		return conversion.SalesPriceGross
+	}
+	factor, _ := a.GetUnitFactor(unit)
+	return a.SalesPriceGross * factor
+}
+
+// IsKit prüft, ob der Artikel eine Stückliste besitzt
+func (a *Article) IsKit() bool {
+	return len(a.BillOfMaterials) > 0
+}
+
+// HasComponent prüft, ob ein Artikel in der Stückliste enthalten ist
+func (a *Article) HasComponent(articleID primitive.ObjectID) bool {
+	for i := range a.BillOfMaterials {
+		if a.BillOfMaterials[i].ArticleID == articleID {
+			return true
+		}
+	}
+	return false
 }
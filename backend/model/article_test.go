@@ -0,0 +1,36 @@
+// backend/model/article_test.go
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMovingAverageCost(t *testing.T) {
+	tests := []struct {
+		name          string
+		stockBefore   float64
+		averageBefore float64
+		quantity      float64
+		unitPrice     float64
+		want          float64
+	}{
+		{"erster Zugang", 0, 0, 10, 4, 4},
+		{"Zugang zum gleichen Preis", 10, 4, 10, 4, 4},
+		{"Zugang zu höherem Preis", 10, 4, 30, 8, 7},
+		{"negativer Bestand zählt nicht", -5, 4, 10, 6, 6},
+		{"Storno eines Zugangs", 40, 7, -30, 8, 4},
+		{"Storno auf null behält den Preis", 10, 4, -10, 4, 4},
+		{"Storno unter null behält den Preis", 5, 4, -10, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MovingAverageCost(tt.stockBefore, tt.averageBefore, tt.quantity, tt.unitPrice)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MovingAverageCost(%v, %v, %v, %v) = %v, erwartet %v",
+					tt.stockBefore, tt.averageBefore, tt.quantity, tt.unitPrice, got, tt.want)
+			}
+		})
+	}
+}
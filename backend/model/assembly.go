@@ -0,0 +1,77 @@
+// backend/model/assembly.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// BOMComponent ist eine Position der Stückliste eines Sets
+type BOMComponent struct {
+	ArticleID     primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName   string             `bson:"articleName" json:"articleName"`
+	Unit          string             `bson:"unit" json:"unit"`
+	Quantity      float64            `bson:"quantity" json:"quantity"` // Menge je Set in der Lagereinheit der Komponente
+}
+
+// AssemblyType unterscheidet Montage und Demontage
+type AssemblyType string
+
+const (
+	AssemblyTypeAssembly    AssemblyType = "assembly"    // Komponenten werden zu Sets montiert
+	AssemblyTypeDisassembly AssemblyType = "disassembly" // Sets werden in ihre Komponenten zerlegt
+)
+
+// GetDisplayName gibt die Bezeichnung des Vorgangs zurück
+func (t AssemblyType) GetDisplayName() string {
+	switch t {
+	case AssemblyTypeAssembly:
+		return "Montage"
+	case AssemblyTypeDisassembly:
+		return "Demontage"
+	default:
+		return string(t)
+	}
+}
+
+// AssemblyLine ist eine bei einer Montage oder Demontage bewegte Komponente
+type AssemblyLine struct {
+	ArticleID      primitive.ObjectID   `bson:"articleId" json:"articleId"`
+	ArticleNumber  string               `bson:"articleNumber" json:"articleNumber"`
+	ArticleName    string               `bson:"articleName" json:"articleName"`
+	Unit           string               `bson:"unit" json:"unit"`
+	Quantity       float64              `bson:"quantity" json:"quantity"`   // Bewegte Menge insgesamt
+	UnitPrice      float64              `bson:"unitPrice" json:"unitPrice"` // Stückpreis der Bewertung
+	TransactionIDs []primitive.ObjectID `bson:"transactionIds" json:"transactionIds"`
+}
+
+// GetTotal gibt den Wert der bewegten Menge zurück
+func (l *AssemblyLine) GetTotal() float64 {
+	return l.Quantity * l.UnitPrice
+}
+
+// Assembly ist eine gebuchte Montage oder Demontage. Alle Buchungen des Vorgangs verweisen
+// über ihre AssemblyID auf ihn.
+type Assembly struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AssemblyNumber string             `bson:"assemblyNumber" json:"assemblyNumber"` // Fortlaufende Nummer
+	Type           AssemblyType       `bson:"type" json:"type"`
+	Kit            AssemblyLine       `bson:"kit" json:"kit"`               // Montiertes bzw. zerlegtes Set
+	Components     []AssemblyLine     `bson:"components" json:"components"` // Verbrauchte bzw. zurückgewonnene Komponenten
+	LocationID     primitive.ObjectID `bson:"locationId" json:"locationId"` // Lagerplatz für Zugänge
+	LocationName   string             `bson:"locationName" json:"locationName"`
+	Notes          string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	UserID         primitive.ObjectID `bson:"userId" json:"userId"`
+	UserName       string             `bson:"userName" json:"userName"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// GetComponentTotal gibt den Wert aller Komponenten zurück
+func (a *Assembly) GetComponentTotal() float64 {
+	var total float64
+	for i := range a.Components {
+		total += a.Components[i].GetTotal()
+	}
+	return total
+}
@@ -0,0 +1,43 @@
+// backend/model/costLayer.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// CostLayerConsumption hält fest, welche Menge eine Buchung aus einer Kostenschicht entnommen
+// hat. Stornos tragen die zurückgegebene Menge negativ ein, damit die Historie erhalten bleibt.
+type CostLayerConsumption struct {
+	TransactionID primitive.ObjectID `bson:"transactionId" json:"transactionId"`
+	Quantity      float64            `bson:"quantity" json:"quantity"`
+	Timestamp     time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// CostLayer ist eine FIFO-Kostenschicht: ein Zugang eines Artikels mit seinem Stückpreis.
+// Abgänge verbrauchen die ältesten Schichten zuerst.
+type CostLayer struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ArticleID     primitive.ObjectID     `bson:"articleId" json:"articleId"`
+	TransactionID primitive.ObjectID     `bson:"transactionId,omitempty" json:"transactionId,omitempty"` // Leer beim Eröffnungsbestand
+	ReceivedAt    time.Time              `bson:"receivedAt" json:"receivedAt"`
+	Quantity      float64                `bson:"quantity" json:"quantity"`   // Ursprüngliche Menge des Zugangs
+	Remaining     float64                `bson:"remaining" json:"remaining"` // Noch nicht verbrauchte Menge
+	UnitCost      float64                `bson:"unitCost" json:"unitCost"`
+	Consumptions  []CostLayerConsumption `bson:"consumptions,omitempty" json:"consumptions,omitempty"`
+}
+
+// GetRemainingAt gibt die zum angegebenen Zeitpunkt noch nicht verbrauchte Menge zurück
+func (l *CostLayer) GetRemainingAt(cutoff time.Time) float64 {
+	if l.ReceivedAt.After(cutoff) {
+		return 0
+	}
+
+	remaining := l.Quantity
+	for _, consumption := range l.Consumptions {
+		if !consumption.Timestamp.After(cutoff) {
+			remaining -= consumption.Quantity
+		}
+	}
+	return remaining
+}
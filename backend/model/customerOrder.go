@@ -0,0 +1,119 @@
+// backend/model/customerOrder.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// CustomerOrderStatus repräsentiert den Zustand eines Kundenauftrags
+type CustomerOrderStatus string
+
+const (
+	CustomerOrderStatusDraft     CustomerOrderStatus = "draft"     // Erfasst, noch nicht bestätigt
+	CustomerOrderStatusConfirmed CustomerOrderStatus = "confirmed" // Bestätigt, Bestand reserviert
+	CustomerOrderStatusShipped   CustomerOrderStatus = "shipped"   // Kommissioniert und versendet
+	CustomerOrderStatusCancelled CustomerOrderStatus = "cancelled" // Storniert
+)
+
+// CustomerOrderLine ist eine Position eines Kundenauftrags
+type CustomerOrderLine struct {
+	ArticleID     primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName   string             `bson:"articleName" json:"articleName"`
+	Unit          string             `bson:"unit" json:"unit"`
+	Quantity      float64            `bson:"quantity" json:"quantity"`
+	UnitPrice     float64            `bson:"unitPrice" json:"unitPrice"`                             // Verkaufspreis brutto je Einheit
+	ReservationID primitive.ObjectID `bson:"reservationId,omitempty" json:"reservationId,omitempty"` // Reservierung ab der Bestätigung
+}
+
+// GetTotal gibt den Bruttowert der Position zurück
+func (l *CustomerOrderLine) GetTotal() float64 {
+	return l.Quantity * l.UnitPrice
+}
+
+// CustomerOrder repräsentiert einen Kundenauftrag
+type CustomerOrder struct {
+	ID                primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrderNumber       string              `bson:"orderNumber" json:"orderNumber"` // Fortlaufende Auftragsnummer
+	CustomerName      string              `bson:"customerName" json:"customerName"`
+	CustomerReference string              `bson:"customerReference,omitempty" json:"customerReference,omitempty"` // Bestellzeichen des Kunden
+	ShippingAddress   string              `bson:"shippingAddress" json:"shippingAddress"`
+	DeliveryDate      time.Time           `bson:"deliveryDate,omitempty" json:"deliveryDate,omitempty"` // Gewünschter Liefertermin
+	Status            CustomerOrderStatus `bson:"status" json:"status"`
+	Lines             []CustomerOrderLine `bson:"lines" json:"lines"`
+	Notes             string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedByID       primitive.ObjectID  `bson:"createdById" json:"createdById"`
+	CreatedByName     string              `bson:"createdByName" json:"createdByName"`
+	ConfirmedAt       time.Time           `bson:"confirmedAt,omitempty" json:"confirmedAt,omitempty"`
+	ShippedAt         time.Time           `bson:"shippedAt,omitempty" json:"shippedAt,omitempty"`
+	CreatedAt         time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time           `bson:"updatedAt" json:"updatedAt"`
+	Version           int64               `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+}
+
+// GetTotal gibt den Bruttowert des Auftrags zurück
+func (o *CustomerOrder) GetTotal() float64 {
+	var total float64
+	for i := range o.Lines {
+		total += o.Lines[i].GetTotal()
+	}
+	return total
+}
+
+// IsEditable prüft, ob Positionen und Kopfdaten noch geändert werden dürfen
+func (o *CustomerOrder) IsEditable() bool {
+	return o.Status == CustomerOrderStatusDraft
+}
+
+// CanShip prüft, ob der Auftrag kommissioniert und versendet werden kann
+func (o *CustomerOrder) CanShip() bool {
+	return o.Status == CustomerOrderStatusConfirmed
+}
+
+// CanReturn prüft, ob zum Auftrag eine Kundenretoure angelegt werden kann
+func (o *CustomerOrder) CanReturn() bool {
+	return o.Status == CustomerOrderStatusShipped
+}
+
+// CanCancel prüft, ob der Auftrag storniert werden kann
+func (o *CustomerOrder) CanCancel() bool {
+	return o.Status == CustomerOrderStatusDraft || o.Status == CustomerOrderStatusConfirmed
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (o *CustomerOrder) GetStatusClass() string {
+	switch o.Status {
+	case CustomerOrderStatusDraft:
+		return "bg-gray-100 text-gray-800"
+	case CustomerOrderStatusConfirmed:
+		return "bg-blue-100 text-blue-800"
+	case CustomerOrderStatusShipped:
+		return "bg-green-100 text-green-800"
+	case CustomerOrderStatusCancelled:
+		return "bg-red-100 text-red-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (o *CustomerOrder) GetDisplayStatus() string {
+	return GetCustomerOrderStatusDisplay(o.Status)
+}
+
+// GetCustomerOrderStatusDisplay gibt den Anzeigenamen eines Auftragsstatus zurück
+func GetCustomerOrderStatusDisplay(status CustomerOrderStatus) string {
+	switch status {
+	case CustomerOrderStatusDraft:
+		return "Entwurf"
+	case CustomerOrderStatusConfirmed:
+		return "Bestätigt"
+	case CustomerOrderStatusShipped:
+		return "Versendet"
+	case CustomerOrderStatusCancelled:
+		return "Storniert"
+	default:
+		return string(status)
+	}
+}
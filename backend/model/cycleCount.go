@@ -0,0 +1,247 @@
+// backend/model/cycleCount.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"time"
+)
+
+// CycleCountScope legt fest, welche Bestände eine Zählung umfasst
+type CycleCountScope string
+
+const (
+	CycleCountScopeWarehouse CycleCountScope = "warehouse" // Alle Lagerplätze eines Lagers
+	CycleCountScopeLocation  CycleCountScope = "location"  // Ein Lagerort mit allen Unterebenen
+	CycleCountScopeCategory  CycleCountScope = "category"  // Alle Artikel einer Warengruppe
+)
+
+// CycleCountStatus repräsentiert den Zustand einer Zählung
+type CycleCountStatus string
+
+const (
+	CycleCountStatusCounting  CycleCountStatus = "counting"  // Zählung läuft, Zählungen werden erfasst
+	CycleCountStatusReview    CycleCountStatus = "review"    // Zählung abgeschlossen, Differenzen warten auf Freigabe
+	CycleCountStatusApproved  CycleCountStatus = "approved"  // Differenzen freigegeben und gebucht
+	CycleCountStatusCancelled CycleCountStatus = "cancelled" // Abgebrochen, nichts gebucht
+)
+
+// cycleCountEpsilon gleicht Rundungsfehler beim Vergleich von Zähl- und Sollmengen aus
+const cycleCountEpsilon = 1e-9
+
+// CycleCountEntry ist eine einzelne Zählung einer Position durch einen Zähler
+type CycleCountEntry struct {
+	CounterID   primitive.ObjectID `bson:"counterId" json:"counterId"`
+	CounterName string             `bson:"counterName" json:"counterName"`
+	Quantity    float64            `bson:"quantity" json:"quantity"`
+	CountedAt   time.Time          `bson:"countedAt" json:"countedAt"`
+}
+
+// CycleCountItem ist eine zu zählende Position: ein Artikel an einem Lagerort
+type CycleCountItem struct {
+	ArticleID        primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber    string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName      string             `bson:"articleName" json:"articleName"`
+	Unit             string             `bson:"unit" json:"unit"`
+	LocationID       primitive.ObjectID `bson:"locationId,omitempty" json:"locationId,omitempty"` // Leer bei Bestand ohne Lagerort
+	LocationPath     string             `bson:"locationPath" json:"locationPath"`
+	ExpectedQuantity float64            `bson:"expectedQuantity" json:"expectedQuantity"` // Sollbestand beim Anlegen der Zählung
+	UnitPrice        float64            `bson:"unitPrice" json:"unitPrice"`               // Durchschnittspreis für die Bewertung
+	Counts           []CycleCountEntry  `bson:"counts,omitempty" json:"counts,omitempty"`
+	PostedAt         time.Time          `bson:"postedAt,omitempty" json:"postedAt,omitempty"`           // Zeitpunkt der Freigabe
+	TransactionID    primitive.ObjectID `bson:"transactionId,omitempty" json:"transactionId,omitempty"` // Inventurbuchung der Differenz
+}
+
+// IsCounted prüft, ob die Position mindestens einmal gezählt wurde
+func (i *CycleCountItem) IsCounted() bool {
+	return len(i.Counts) > 0
+}
+
+// IsPosted prüft, ob die Zählung der Position bereits freigegeben wurde
+func (i *CycleCountItem) IsPosted() bool {
+	return !i.PostedAt.IsZero()
+}
+
+// GetCountedQuantity gibt die zuletzt erfasste Zählmenge zurück
+func (i *CycleCountItem) GetCountedQuantity() float64 {
+	if len(i.Counts) == 0 {
+		return 0
+	}
+	return i.Counts[len(i.Counts)-1].Quantity
+}
+
+// GetCountedAt gibt den Zeitpunkt der zuletzt erfassten Zählung zurück
+func (i *CycleCountItem) GetCountedAt() time.Time {
+	if len(i.Counts) == 0 {
+		return time.Time{}
+	}
+	return i.Counts[len(i.Counts)-1].CountedAt
+}
+
+// GetVariance gibt die Differenz zwischen gezählter und erwarteter Menge zurück
+func (i *CycleCountItem) GetVariance() float64 {
+	if !i.IsCounted() {
+		return 0
+	}
+	return i.GetCountedQuantity() - i.ExpectedQuantity
+}
+
+// HasVariance prüft, ob die gezählte Menge vom Sollbestand abweicht
+func (i *CycleCountItem) HasVariance() bool {
+	return math.Abs(i.GetVariance()) > cycleCountEpsilon
+}
+
+// GetVarianceValue gibt die Wertauswirkung der Differenz zum Durchschnittspreis zurück
+func (i *CycleCountItem) GetVarianceValue() float64 {
+	return i.GetVariance() * i.UnitPrice
+}
+
+// HasConflictingCounts prüft, ob mehrere Zähler zu unterschiedlichen Ergebnissen kamen.
+// Verglichen wird jeweils die letzte Zählung jedes Zählers.
+func (i *CycleCountItem) HasConflictingCounts() bool {
+	latest := make(map[primitive.ObjectID]float64)
+	for _, entry := range i.Counts {
+		latest[entry.CounterID] = entry.Quantity
+	}
+
+	first := true
+	var reference float64
+	for _, quantity := range latest {
+		if first {
+			reference, first = quantity, false
+			continue
+		}
+		if math.Abs(quantity-reference) > cycleCountEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// CycleCount repräsentiert eine Inventurzählung für einen Ausschnitt des Lagers
+type CycleCount struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CountNumber   string             `bson:"countNumber" json:"countNumber"` // Fortlaufende Zählnummer
+	Scope         CycleCountScope    `bson:"scope" json:"scope"`
+	LocationID    primitive.ObjectID `bson:"locationId,omitempty" json:"locationId,omitempty"` // Lager bzw. Lagerort bei ortsbezogenen Zählungen
+	Category      string             `bson:"category,omitempty" json:"category,omitempty"`     // Warengruppe bei Zählung nach Warengruppe
+	ScopeName     string             `bson:"scopeName" json:"scopeName"`                       // Pfad des Lagerorts bzw. Warengruppe für die Anzeige
+	BlindCount    bool               `bson:"blindCount" json:"blindCount"`                     // Zähler sehen den Sollbestand nicht
+	Status        CycleCountStatus   `bson:"status" json:"status"`
+	Items         []CycleCountItem   `bson:"items" json:"items"`
+	Notes         string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedByID   primitive.ObjectID `bson:"createdById" json:"createdById"`
+	CreatedByName string             `bson:"createdByName" json:"createdByName"`
+	ApprovedByID  primitive.ObjectID `bson:"approvedById,omitempty" json:"approvedById,omitempty"`
+	ApprovedBy    string             `bson:"approvedBy,omitempty" json:"approvedBy,omitempty"`
+	ApprovedAt    time.Time          `bson:"approvedAt,omitempty" json:"approvedAt,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version       int64              `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+}
+
+// IsCounting prüft, ob noch Zählungen erfasst werden können
+func (c *CycleCount) IsCounting() bool {
+	return c.Status == CycleCountStatusCounting
+}
+
+// CanApprove prüft, ob die Differenzen der Zählung freigegeben werden können
+func (c *CycleCount) CanApprove() bool {
+	return c.Status == CycleCountStatusReview
+}
+
+// CanCancel prüft, ob die Zählung abgebrochen werden kann
+func (c *CycleCount) CanCancel() bool {
+	if c.Status != CycleCountStatusCounting && c.Status != CycleCountStatusReview {
+		return false
+	}
+	// Teilweise freigegebene Zählungen müssen abgeschlossen werden
+	for i := range c.Items {
+		if c.Items[i].IsPosted() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCountedItems gibt die Anzahl der gezählten Positionen zurück
+func (c *CycleCount) GetCountedItems() int {
+	counted := 0
+	for i := range c.Items {
+		if c.Items[i].IsCounted() {
+			counted++
+		}
+	}
+	return counted
+}
+
+// GetVarianceItems gibt die Anzahl der Positionen mit Differenz zurück
+func (c *CycleCount) GetVarianceItems() int {
+	variances := 0
+	for i := range c.Items {
+		if c.Items[i].HasVariance() {
+			variances++
+		}
+	}
+	return variances
+}
+
+// GetVarianceValue gibt die Wertauswirkung aller Differenzen zurück
+func (c *CycleCount) GetVarianceValue() float64 {
+	var total float64
+	for i := range c.Items {
+		total += c.Items[i].GetVarianceValue()
+	}
+	return total
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (c *CycleCount) GetStatusClass() string {
+	switch c.Status {
+	case CycleCountStatusCounting:
+		return "bg-blue-100 text-blue-800"
+	case CycleCountStatusReview:
+		return "bg-yellow-100 text-yellow-800"
+	case CycleCountStatusApproved:
+		return "bg-green-100 text-green-800"
+	case CycleCountStatusCancelled:
+		return "bg-red-100 text-red-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (c *CycleCount) GetDisplayStatus() string {
+	return GetCycleCountStatusDisplay(c.Status)
+}
+
+// GetDisplayScope gibt den Umfang der Zählung für die Anzeige zurück
+func (c *CycleCount) GetDisplayScope() string {
+	switch c.Scope {
+	case CycleCountScopeWarehouse:
+		return "Lager " + c.ScopeName
+	case CycleCountScopeLocation:
+		return "Lagerort " + c.ScopeName
+	case CycleCountScopeCategory:
+		return "Warengruppe " + c.ScopeName
+	default:
+		return c.ScopeName
+	}
+}
+
+// GetCycleCountStatusDisplay gibt den Anzeigenamen eines Zählstatus zurück
+func GetCycleCountStatusDisplay(status CycleCountStatus) string {
+	switch status {
+	case CycleCountStatusCounting:
+		return "Zählung läuft"
+	case CycleCountStatusReview:
+		return "Freigabe ausstehend"
+	case CycleCountStatusApproved:
+		return "Freigegeben"
+	case CycleCountStatusCancelled:
+		return "Abgebrochen"
+	default:
+		return string(status)
+	}
+}
@@ -0,0 +1,67 @@
+// backend/model/goodsReceipt.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// GoodsReceiptLine ist eine gelieferte Position eines Wareneingangs
+type GoodsReceiptLine struct {
+	LineIndex        int                `bson:"lineIndex" json:"lineIndex"` // Position in der Bestellung
+	ArticleID        primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber    string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName      string             `bson:"articleName" json:"articleName"`
+	Unit             string             `bson:"unit" json:"unit"`
+	ExpectedQuantity float64            `bson:"expectedQuantity" json:"expectedQuantity"` // Offene Menge laut Bestellung
+	Quantity         float64            `bson:"quantity" json:"quantity"`                 // Tatsächlich gelieferte Menge
+	Reason           string             `bson:"reason,omitempty" json:"reason,omitempty"` // Grund für eine abweichende Menge
+	LocationID       primitive.ObjectID `bson:"locationId" json:"locationId"`
+	LocationName     string             `bson:"locationName" json:"locationName"`
+	LotNumber        string             `bson:"lotNumber,omitempty" json:"lotNumber,omitempty"`
+	ExpiryDate       time.Time          `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	SerialNumbers    []string           `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"`
+	TransactionID    primitive.ObjectID `bson:"transactionId" json:"transactionId"` // Gebuchter Wareneingang
+}
+
+// GetDeviation gibt die Abweichung der gelieferten von der erwarteten Menge zurück
+func (l *GoodsReceiptLine) GetDeviation() float64 {
+	return l.Quantity - l.ExpectedQuantity
+}
+
+// IsOverDelivery prüft, ob mehr als erwartet geliefert wurde
+func (l *GoodsReceiptLine) IsOverDelivery() bool {
+	return l.GetDeviation() > 0
+}
+
+// IsUnderDelivery prüft, ob weniger als erwartet geliefert wurde
+func (l *GoodsReceiptLine) IsUnderDelivery() bool {
+	return l.GetDeviation() < 0
+}
+
+// GoodsReceipt repräsentiert einen Wareneingang zu einer Bestellung
+type GoodsReceipt struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReceiptNumber   string             `bson:"receiptNumber" json:"receiptNumber"` // Fortlaufende Wareneingangsnummer
+	PurchaseOrderID primitive.ObjectID `bson:"purchaseOrderId" json:"purchaseOrderId"`
+	OrderNumber     string             `bson:"orderNumber" json:"orderNumber"`
+	SupplierID      primitive.ObjectID `bson:"supplierId" json:"supplierId"`
+	SupplierName    string             `bson:"supplierName" json:"supplierName"`
+	DeliveryNote    string             `bson:"deliveryNote,omitempty" json:"deliveryNote,omitempty"` // Lieferscheinnummer
+	Lines           []GoodsReceiptLine `bson:"lines" json:"lines"`
+	Notes           string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	ClosedOrder     bool               `bson:"closedOrder" json:"closedOrder"` // Bestellung trotz offener Mengen abgeschlossen
+	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	UserName        string             `bson:"userName" json:"userName"`
+	ReceivedAt      time.Time          `bson:"receivedAt" json:"receivedAt"`
+}
+
+// HasDeviations prüft, ob eine Position von der erwarteten Menge abweicht
+func (r *GoodsReceipt) HasDeviations() bool {
+	for i := range r.Lines {
+		if r.Lines[i].GetDeviation() != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// backend/model/hazardClass.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// HazardClass ist eine Gefahrgutklasse aus dem Gefahrgutkatalog, z.B. eine ADR-Klasse.
+// Die Zusammenlagerungsregeln nennen die Klassen, mit denen Ware dieser Klasse nicht am
+// selben Lagerplatz liegen darf; eine Regel gilt in beide Richtungen.
+type HazardClass struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code         string             `bson:"code" json:"code"`                                     // Klassennummer, z.B. 3 oder 4.1 (eindeutig)
+	Name         string             `bson:"name" json:"name"`                                     // Bezeichnung, z.B. Entzündbare flüssige Stoffe
+	Incompatible []string           `bson:"incompatible,omitempty" json:"incompatible,omitempty"` // Unverträgliche Klassen
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// GetDisplayName gibt Klassennummer und Bezeichnung zurück
+func (h *HazardClass) GetDisplayName() string {
+	if h.Name == "" || h.Name == h.Code {
+		return h.Code
+	}
+	return h.Code + " – " + h.Name
+}
+
+// IsIncompatibleWith prüft, ob die Klasse laut ihren eigenen Regeln nicht mit der
+// angegebenen Klasse zusammengelagert werden darf
+func (h *HazardClass) IsIncompatibleWith(code string) bool {
+	for _, incompatible := range h.Incompatible {
+		if incompatible == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HazardClassesIncompatible prüft, ob zwei Klassen getrennt gelagert werden müssen. Es genügt,
+// wenn eine der beiden Klassen die andere als unverträglich nennt.
+func HazardClassesIncompatible(catalog map[string]*HazardClass, a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return false
+	}
+	if class, exists := catalog[a]; exists && class.IsIncompatibleWith(b) {
+		return true
+	}
+	if class, exists := catalog[b]; exists && class.IsIncompatibleWith(a) {
+		return true
+	}
+	return false
+}
+
+// DefaultHazardClasses sind die ADR-Klassen, mit denen der Gefahrgutkatalog angelegt wird.
+// Die Zusammenlagerungsregeln sind eine vereinfachte Voreinstellung und sollten an die
+// betrieblichen Vorgaben angepasst werden.
+var DefaultHazardClasses = []HazardClass{
+	{Code: "1", Name: "Explosive Stoffe und Gegenstände", Incompatible: []string{"2", "3", "4.1", "4.2", "4.3", "5.1", "5.2", "6.1", "6.2", "7", "8", "9"}},
+	{Code: "2", Name: "Gase", Incompatible: []string{"3", "4.2", "5.1", "5.2"}},
+	{Code: "3", Name: "Entzündbare flüssige Stoffe", Incompatible: []string{"5.1", "5.2", "7"}},
+	{Code: "4.1", Name: "Entzündbare feste Stoffe", Incompatible: []string{"5.1", "5.2"}},
+	{Code: "4.2", Name: "Selbstentzündliche Stoffe", Incompatible: []string{"5.1", "5.2"}},
+	{Code: "4.3", Name: "Stoffe, die mit Wasser entzündbare Gase bilden", Incompatible: []string{"5.1", "8"}},
+	{Code: "5.1", Name: "Entzündend (oxidierend) wirkende Stoffe", Incompatible: []string{"5.2", "8"}},
+	{Code: "5.2", Name: "Organische Peroxide", Incompatible: []string{"6.1", "8"}},
+	{Code: "6.1", Name: "Giftige Stoffe"},
+	{Code: "6.2", Name: "Ansteckungsgefährliche Stoffe", Incompatible: []string{"3", "4.1", "4.2", "4.3", "5.1", "5.2", "8"}},
+	{Code: "7", Name: "Radioaktive Stoffe", Incompatible: []string{"4.1", "4.2", "5.1", "5.2", "8"}},
+	{Code: "8", Name: "Ätzende Stoffe"},
+	{Code: "9", Name: "Verschiedene gefährliche Stoffe und Gegenstände"},
+}
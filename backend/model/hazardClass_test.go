@@ -0,0 +1,56 @@
+// backend/model/hazardClass_test.go
+package model
+
+import "testing"
+
+func TestHazardClassesIncompatible(t *testing.T) {
+	catalog := map[string]*HazardClass{
+		"3":   {Code: "3", Incompatible: []string{"5.1"}},
+		"5.1": {Code: "5.1"},
+		"8":   {Code: "8", Incompatible: []string{"4.3"}},
+		"9":   {Code: "9"},
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"Regel der ersten Klasse", "3", "5.1", true},
+		{"Regel gilt in beide Richtungen", "5.1", "3", true},
+		{"Regel einer Klasse, die im Katalog fehlt", "4.3", "8", true},
+		{"verträgliche Klassen", "3", "9", false},
+		{"gleiche Klasse", "3", "3", false},
+		{"kein Gefahrgut", "3", "", false},
+		{"beide nicht im Katalog", "1", "2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HazardClassesIncompatible(catalog, tt.a, tt.b); got != tt.want {
+				t.Errorf("HazardClassesIncompatible(%q, %q) = %v, erwartet %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultHazardClassesReferenceCatalog(t *testing.T) {
+	// Die Voreinstellungen dürfen nur auf Klassen verweisen, die selbst angelegt werden
+	codes := make(map[string]bool)
+	for _, class := range DefaultHazardClasses {
+		if codes[class.Code] {
+			t.Errorf("Klasse %s ist mehrfach enthalten", class.Code)
+		}
+		codes[class.Code] = true
+	}
+	for _, class := range DefaultHazardClasses {
+		for _, code := range class.Incompatible {
+			if !codes[code] {
+				t.Errorf("Klasse %s verweist auf unbekannte Klasse %s", class.Code, code)
+			}
+			if code == class.Code {
+				t.Errorf("Klasse %s ist mit sich selbst unverträglich", class.Code)
+			}
+		}
+	}
+}
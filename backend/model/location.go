@@ -27,6 +27,11 @@ type Location struct {
 	Capacity    float64            `bson:"capacity" json:"capacity"`           // Optionale Kapazitätsangabe
 	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version     int64              `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+
+	// Gefahrgutklassen, die hier gelagert werden dürfen; ohne Angabe gilt die Freigabe des
+	// übergeordneten Lagerorts
+	HazardClasses []string `bson:"hazardClasses,omitempty" json:"hazardClasses,omitempty"`
 }
 
 // GetFullPath gibt den vollständigen Pfad des Lagerorts zurück
@@ -42,3 +47,43 @@ func (l *Location) GetFullPath(locations map[primitive.ObjectID]*Location) strin
 
 	return parent.GetFullPath(locations) + " > " + l.Name
 }
+
+// GetAllowedHazardClasses gibt die Gefahrgutklassen zurück, die an diesem Lagerort gelagert
+// werden dürfen. Ohne eigene Angabe gilt die Freigabe des nächsten übergeordneten Lagerorts,
+// der eine nennt; ist nirgends eine hinterlegt, ist kein Gefahrgut zugelassen.
+func (l *Location) GetAllowedHazardClasses(locations map[primitive.ObjectID]*Location) []string {
+	current := l
+	for depth := 0; current != nil && depth < len(locations)+1; depth++ {
+		if len(current.HazardClasses) > 0 {
+			return current.HazardClasses
+		}
+		if current.ParentID.IsZero() {
+			break
+		}
+		current = locations[current.ParentID]
+	}
+	return nil
+}
+
+// AllowsHazardClass prüft, ob Ware der angegebenen Gefahrgutklasse hier gelagert werden darf
+func (l *Location) AllowsHazardClass(code string, locations map[primitive.ObjectID]*Location) bool {
+	if code == "" {
+		return true
+	}
+	for _, allowed := range l.GetAllowedHazardClasses(locations) {
+		if allowed == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HasHazardClass prüft, ob der Lagerort selbst die Gefahrgutklasse freigibt
+func (l *Location) HasHazardClass(code string) bool {
+	for _, allowed := range l.HazardClasses {
+		if allowed == code {
+			return true
+		}
+	}
+	return false
+}
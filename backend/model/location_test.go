@@ -0,0 +1,61 @@
+// backend/model/location_test.go
+package model
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLocationAllowsHazardClass(t *testing.T) {
+	// Lager A gibt 3 und 8 frei, Bereich A1 erbt, Fach A1a schränkt auf 8 ein; Lager B hat
+	// keine Freigabe; Fach C1 hängt an einem gelöschten Lagerort
+	warehouseA := &Location{ID: primitive.NewObjectID(), HazardClasses: []string{"3", "8"}}
+	areaA1 := &Location{ID: primitive.NewObjectID(), ParentID: warehouseA.ID}
+	shelfA1a := &Location{ID: primitive.NewObjectID(), ParentID: areaA1.ID, HazardClasses: []string{"8"}}
+	shelfA1b := &Location{ID: primitive.NewObjectID(), ParentID: areaA1.ID}
+	warehouseB := &Location{ID: primitive.NewObjectID()}
+	shelfC1 := &Location{ID: primitive.NewObjectID(), ParentID: primitive.NewObjectID(), HazardClasses: []string{"2"}}
+
+	locations := make(map[primitive.ObjectID]*Location)
+	for _, location := range []*Location{warehouseA, areaA1, shelfA1a, shelfA1b, warehouseB, shelfC1} {
+		locations[location.ID] = location
+	}
+
+	tests := []struct {
+		name     string
+		location *Location
+		code     string
+		want     bool
+	}{
+		{"eigene Freigabe", warehouseA, "3", true},
+		{"nicht freigegebene Klasse", warehouseA, "5.1", false},
+		{"geerbte Freigabe", areaA1, "8", true},
+		{"über zwei Stufen geerbt", shelfA1b, "3", true},
+		{"eigene Freigabe schränkt die geerbte ein", shelfA1a, "3", false},
+		{"eingeschränkte Freigabe", shelfA1a, "8", true},
+		{"ohne Freigabe kein Gefahrgut", warehouseB, "3", false},
+		{"kein Gefahrgut ist überall erlaubt", warehouseB, "", true},
+		{"fehlender übergeordneter Lagerort", shelfC1, "2", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.location.AllowsHazardClass(tt.code, locations); got != tt.want {
+				t.Errorf("AllowsHazardClass(%q) = %v, erwartet %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocationGetAllowedHazardClassesCycle(t *testing.T) {
+	// Fehlerhafte Daten mit zyklischen Verweisen dürfen nicht endlos laufen
+	a := &Location{ID: primitive.NewObjectID()}
+	b := &Location{ID: primitive.NewObjectID(), ParentID: a.ID}
+	a.ParentID = b.ID
+	locations := map[primitive.ObjectID]*Location{a.ID: a, b.ID: b}
+
+	if got := a.GetAllowedHazardClasses(locations); got != nil {
+		t.Errorf("GetAllowedHazardClasses() = %v, erwartet nil", got)
+	}
+}
@@ -0,0 +1,45 @@
+// backend/model/lot.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// OpeningLotNumber ist die Chargennummer, unter der vorhandener Bestand beim Einführen der
+// Chargenpflicht erfasst wird
+const OpeningLotNumber = "ALTBESTAND"
+
+// Lot repräsentiert eine Charge eines Artikels an einem Lagerort
+type Lot struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID  primitive.ObjectID `bson:"articleId" json:"articleId"`
+	LocationID primitive.ObjectID `bson:"locationId" json:"locationId"`
+	LotNumber  string             `bson:"lotNumber" json:"lotNumber"`   // Chargennummer
+	ExpiryDate time.Time          `bson:"expiryDate" json:"expiryDate"` // Mindesthaltbarkeitsdatum (leer, wenn nicht begrenzt)
+	Quantity   float64            `bson:"quantity" json:"quantity"`     // Menge der Charge an diesem Lagerort
+	ReceivedAt time.Time          `bson:"receivedAt" json:"receivedAt"` // Erster Wareneingang der Charge
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// HasExpiry prüft, ob die Charge ein Ablaufdatum besitzt
+func (l *Lot) HasExpiry() bool {
+	return !l.ExpiryDate.IsZero()
+}
+
+// IsExpired prüft, ob die Charge zum angegebenen Zeitpunkt abgelaufen ist
+func (l *Lot) IsExpired(at time.Time) bool {
+	return l.HasExpiry() && l.ExpiryDate.Before(at)
+}
+
+// DaysUntilExpiry gibt die Anzahl der Tage bis zum Ablauf zurück (negativ, wenn bereits abgelaufen)
+func (l *Lot) DaysUntilExpiry(at time.Time) int {
+	return int(l.ExpiryDate.Sub(at).Hours() / 24)
+}
+
+// LotAllocation beschreibt, welche Menge einer Charge durch eine Buchung bewegt wurde
+type LotAllocation struct {
+	LotNumber  string    `bson:"lotNumber" json:"lotNumber"`
+	ExpiryDate time.Time `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	Quantity   float64   `bson:"quantity" json:"quantity"` // Immer positiv; die Richtung ergibt sich aus der Buchung
+}
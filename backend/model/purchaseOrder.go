@@ -0,0 +1,177 @@
+// backend/model/purchaseOrder.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// PurchaseOrderStatus repräsentiert den Zustand einer Bestellung
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderStatusDraft             PurchaseOrderStatus = "draft"              // Entwurf, noch nicht versendet
+	PurchaseOrderStatusSent              PurchaseOrderStatus = "sent"               // An den Lieferanten versendet
+	PurchaseOrderStatusPartiallyReceived PurchaseOrderStatus = "partially_received" // Teilweise geliefert
+	PurchaseOrderStatusReceived          PurchaseOrderStatus = "received"           // Vollständig geliefert
+	PurchaseOrderStatusCancelled         PurchaseOrderStatus = "cancelled"          // Storniert
+)
+
+// PurchaseOrderLine ist eine Position einer Bestellung
+type PurchaseOrderLine struct {
+	ArticleID             primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber         string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName           string             `bson:"articleName" json:"articleName"`
+	SupplierArticleNumber string             `bson:"supplierArticleNumber,omitempty" json:"supplierArticleNumber,omitempty"`
+	Unit                  string             `bson:"unit" json:"unit"`
+	Quantity              float64            `bson:"quantity" json:"quantity"`                 // Bestellte Menge
+	UnitPrice             float64            `bson:"unitPrice" json:"unitPrice"`               // Einkaufspreis netto je Einheit
+	ReceivedQuantity      float64            `bson:"receivedQuantity" json:"receivedQuantity"` // Bereits gelieferte Menge
+
+	// Lagereinheiten je Bestelleinheit, z.B. 12 bei Bestellung in Kartons zu 12 Stück (0 = 1)
+	UnitFactor float64 `bson:"unitFactor,omitempty" json:"unitFactor,omitempty"`
+	BaseUnit   string  `bson:"baseUnit,omitempty" json:"baseUnit,omitempty"` // Lagereinheit des Artikels
+}
+
+// GetUnitFactor gibt die Anzahl der Lagereinheiten je Bestelleinheit zurück
+func (l *PurchaseOrderLine) GetUnitFactor() float64 {
+	if l.UnitFactor <= 0 {
+		return 1
+	}
+	return l.UnitFactor
+}
+
+// HasUnitFactor prüft, ob die Position in einer anderen Einheit als der Lagereinheit bestellt ist
+func (l *PurchaseOrderLine) HasUnitFactor() bool {
+	return l.GetUnitFactor() != 1
+}
+
+// GetOpenQuantity gibt die noch nicht gelieferte Menge der Position zurück
+func (l *PurchaseOrderLine) GetOpenQuantity() float64 {
+	if l.ReceivedQuantity >= l.Quantity {
+		return 0
+	}
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// GetOpenBaseQuantity gibt die noch nicht gelieferte Menge der Position in Lagereinheiten zurück
+func (l *PurchaseOrderLine) GetOpenBaseQuantity() float64 {
+	return l.GetOpenQuantity() * l.GetUnitFactor()
+}
+
+// GetTotal gibt den Nettowert der Position zurück
+func (l *PurchaseOrderLine) GetTotal() float64 {
+	return l.Quantity * l.UnitPrice
+}
+
+// PurchaseOrder repräsentiert eine Bestellung bei einem Lieferanten
+type PurchaseOrder struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrderNumber   string              `bson:"orderNumber" json:"orderNumber"` // Fortlaufende Bestellnummer
+	SupplierID    primitive.ObjectID  `bson:"supplierId" json:"supplierId"`
+	SupplierName  string              `bson:"supplierName" json:"supplierName"`
+	Status        PurchaseOrderStatus `bson:"status" json:"status"`
+	OrderDate     time.Time           `bson:"orderDate" json:"orderDate"`       // Bestelldatum
+	ExpectedDate  time.Time           `bson:"expectedDate" json:"expectedDate"` // Erwarteter Liefertermin
+	Lines         []PurchaseOrderLine `bson:"lines" json:"lines"`
+	Notes         string              `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedByID   primitive.ObjectID  `bson:"createdById" json:"createdById"`
+	CreatedByName string              `bson:"createdByName" json:"createdByName"`
+	SentAt        time.Time           `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+	CreatedAt     time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time           `bson:"updatedAt" json:"updatedAt"`
+	Version       int64               `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+}
+
+// GetTotal gibt den Nettowert der Bestellung zurück
+func (o *PurchaseOrder) GetTotal() float64 {
+	var total float64
+	for i := range o.Lines {
+		total += o.Lines[i].GetTotal()
+	}
+	return total
+}
+
+// IsEditable prüft, ob Positionen und Kopfdaten noch geändert werden dürfen
+func (o *PurchaseOrder) IsEditable() bool {
+	return o.Status == PurchaseOrderStatusDraft
+}
+
+// IsOpen prüft, ob noch Lieferungen zu der Bestellung erwartet werden
+func (o *PurchaseOrder) IsOpen() bool {
+	return o.Status == PurchaseOrderStatusSent || o.Status == PurchaseOrderStatusPartiallyReceived
+}
+
+// CanCancel prüft, ob die Bestellung storniert werden kann
+func (o *PurchaseOrder) CanCancel() bool {
+	return o.IsEditable() || o.IsOpen()
+}
+
+// GetReceiptStatus ermittelt den Status einer versendeten Bestellung aus den gelieferten Mengen
+func (o *PurchaseOrder) GetReceiptStatus() PurchaseOrderStatus {
+	complete, received := true, false
+	for i := range o.Lines {
+		if o.Lines[i].GetOpenQuantity() > 0 {
+			complete = false
+		}
+		if o.Lines[i].ReceivedQuantity > 0 {
+			received = true
+		}
+	}
+
+	switch {
+	case complete:
+		return PurchaseOrderStatusReceived
+	case received:
+		return PurchaseOrderStatusPartiallyReceived
+	default:
+		return PurchaseOrderStatusSent
+	}
+}
+
+// IsOverdue prüft, ob der erwartete Liefertermin einer offenen Bestellung überschritten ist
+func (o *PurchaseOrder) IsOverdue(at time.Time) bool {
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	return o.IsOpen() && !o.ExpectedDate.IsZero() && o.ExpectedDate.Before(today)
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (o *PurchaseOrder) GetStatusClass() string {
+	switch o.Status {
+	case PurchaseOrderStatusDraft:
+		return "bg-gray-100 text-gray-800"
+	case PurchaseOrderStatusSent:
+		return "bg-blue-100 text-blue-800"
+	case PurchaseOrderStatusPartiallyReceived:
+		return "bg-yellow-100 text-yellow-800"
+	case PurchaseOrderStatusReceived:
+		return "bg-green-100 text-green-800"
+	case PurchaseOrderStatusCancelled:
+		return "bg-red-100 text-red-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (o *PurchaseOrder) GetDisplayStatus() string {
+	return GetPurchaseOrderStatusDisplay(o.Status)
+}
+
+// GetPurchaseOrderStatusDisplay gibt den Anzeigenamen eines Bestellstatus zurück
+func GetPurchaseOrderStatusDisplay(status PurchaseOrderStatus) string {
+	switch status {
+	case PurchaseOrderStatusDraft:
+		return "Entwurf"
+	case PurchaseOrderStatusSent:
+		return "Bestellt"
+	case PurchaseOrderStatusPartiallyReceived:
+		return "Teilweise geliefert"
+	case PurchaseOrderStatusReceived:
+		return "Geliefert"
+	case PurchaseOrderStatusCancelled:
+		return "Storniert"
+	default:
+		return string(status)
+	}
+}
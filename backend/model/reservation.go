@@ -0,0 +1,86 @@
+// backend/model/reservation.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ReservationStatus repräsentiert den Zustand einer Reservierung
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "active"    // Menge ist reserviert
+	ReservationStatusFulfilled ReservationStatus = "fulfilled" // Vollständig durch Warenausgänge erfüllt
+	ReservationStatusReleased  ReservationStatus = "released"  // Manuell freigegeben
+	ReservationStatusExpired   ReservationStatus = "expired"   // Nach Ablauf automatisch freigegeben
+)
+
+// Reservation reserviert eine Menge eines Artikels, z.B. für einen Auftrag. Die offene Menge
+// aller aktiven Reservierungen ergibt den reservierten Bestand des Artikels.
+type Reservation struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID         primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleName       string             `bson:"articleName" json:"articleName"`
+	Quantity          float64            `bson:"quantity" json:"quantity"`                   // Reservierte Menge
+	FulfilledQuantity float64            `bson:"fulfilledQuantity" json:"fulfilledQuantity"` // Bereits ausgegebene Menge
+	Reason            string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	Reference         string             `bson:"reference,omitempty" json:"reference,omitempty"` // Referenz (z.B. Auftrag, Projekt)
+	OwnerID           primitive.ObjectID `bson:"ownerId" json:"ownerId"`                         // Benutzer, der reserviert hat
+	OwnerName         string             `bson:"ownerName" json:"ownerName"`
+	ExpiresAt         time.Time          `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"` // Ablauf (leer = unbefristet)
+	Status            ReservationStatus  `bson:"status" json:"status"`
+	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ClosedAt          time.Time          `bson:"closedAt,omitempty" json:"closedAt,omitempty"` // Zeitpunkt der Erfüllung bzw. Freigabe
+}
+
+// GetOpenQuantity gibt die noch reservierte, nicht ausgegebene Menge zurück
+func (r *Reservation) GetOpenQuantity() float64 {
+	return r.Quantity - r.FulfilledQuantity
+}
+
+// IsActive prüft, ob die Reservierung noch Bestand bindet
+func (r *Reservation) IsActive() bool {
+	return r.Status == ReservationStatusActive
+}
+
+// HasExpiry prüft, ob die Reservierung ein Ablaufdatum hat
+func (r *Reservation) HasExpiry() bool {
+	return !r.ExpiresAt.IsZero()
+}
+
+// IsExpired prüft, ob die Reservierung zum angegebenen Zeitpunkt abgelaufen ist
+func (r *Reservation) IsExpired(at time.Time) bool {
+	return r.HasExpiry() && !r.ExpiresAt.After(at)
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (r *Reservation) GetStatusClass() string {
+	switch r.Status {
+	case ReservationStatusActive:
+		return "bg-green-100 text-green-800"
+	case ReservationStatusFulfilled:
+		return "bg-blue-100 text-blue-800"
+	case ReservationStatusExpired:
+		return "bg-yellow-100 text-yellow-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (r *Reservation) GetDisplayStatus() string {
+	switch r.Status {
+	case ReservationStatusActive:
+		return "Aktiv"
+	case ReservationStatusFulfilled:
+		return "Erfüllt"
+	case ReservationStatusReleased:
+		return "Freigegeben"
+	case ReservationStatusExpired:
+		return "Abgelaufen"
+	default:
+		return string(r.Status)
+	}
+}
@@ -0,0 +1,204 @@
+// backend/model/returnCase.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ReturnType unterscheidet Kundenretouren und Rücksendungen an Lieferanten
+type ReturnType string
+
+const (
+	ReturnTypeCustomer ReturnType = "customer" // Ware kommt vom Kunden zurück
+	ReturnTypeSupplier ReturnType = "supplier" // Ware geht an den Lieferanten zurück
+)
+
+// GetDisplayName gibt die Bezeichnung der Retourenart zurück
+func (t ReturnType) GetDisplayName() string {
+	switch t {
+	case ReturnTypeCustomer:
+		return "Kundenretoure"
+	case ReturnTypeSupplier:
+		return "Lieferantenrücksendung"
+	default:
+		return string(t)
+	}
+}
+
+// ReturnStatus repräsentiert den Zustand einer Retoure
+type ReturnStatus string
+
+const (
+	ReturnStatusOpen      ReturnStatus = "open"      // Angelegt, Zustandsprüfung ausstehend
+	ReturnStatusCompleted ReturnStatus = "completed" // Geprüft und gebucht
+	ReturnStatusCancelled ReturnStatus = "cancelled" // Storniert, ohne Buchung
+)
+
+// GetReturnStatusDisplay gibt den Anzeigenamen eines Retourenstatus zurück
+func GetReturnStatusDisplay(status ReturnStatus) string {
+	switch status {
+	case ReturnStatusOpen:
+		return "Offen"
+	case ReturnStatusCompleted:
+		return "Abgeschlossen"
+	case ReturnStatusCancelled:
+		return "Storniert"
+	default:
+		return string(status)
+	}
+}
+
+// ReturnCondition ist das Ergebnis der Zustandsprüfung einer Position und legt fest, wie sie
+// gebucht wird
+type ReturnCondition string
+
+const (
+	ReturnConditionRestock    ReturnCondition = "restock"    // Einwandfrei, zurück in den Bestand
+	ReturnConditionQuarantine ReturnCondition = "quarantine" // Klärungsbedürftig, auf einen Quarantäneplatz
+	ReturnConditionScrap      ReturnCondition = "scrap"      // Unbrauchbar, wird verschrottet
+	ReturnConditionSupplier   ReturnCondition = "supplier"   // Geht an den Lieferanten zurück
+)
+
+// GetDisplayName gibt die Bezeichnung des Prüfergebnisses zurück
+func (c ReturnCondition) GetDisplayName() string {
+	switch c {
+	case ReturnConditionRestock:
+		return "Wieder einlagern"
+	case ReturnConditionQuarantine:
+		return "Quarantäne"
+	case ReturnConditionScrap:
+		return "Verschrotten"
+	case ReturnConditionSupplier:
+		return "An Lieferanten zurück"
+	default:
+		return string(c)
+	}
+}
+
+// GetReturnConditions gibt die für eine Retourenart zulässigen Prüfergebnisse zurück.
+// Kundenretouren werden eingelagert, in Quarantäne genommen oder verschrottet; Ware für den
+// Lieferanten geht an ihn zurück oder wird in Absprache mit ihm verschrottet.
+func GetReturnConditions(returnType ReturnType) []ReturnCondition {
+	if returnType == ReturnTypeSupplier {
+		return []ReturnCondition{ReturnConditionSupplier, ReturnConditionScrap}
+	}
+	return []ReturnCondition{ReturnConditionRestock, ReturnConditionQuarantine, ReturnConditionScrap}
+}
+
+// IsAllowedFor prüft, ob das Prüfergebnis für die Retourenart zulässig ist
+func (c ReturnCondition) IsAllowedFor(returnType ReturnType) bool {
+	for _, allowed := range GetReturnConditions(returnType) {
+		if c == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnLine ist eine zurückgegebene Position einer Retoure
+type ReturnLine struct {
+	ArticleID             primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber         string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName           string             `bson:"articleName" json:"articleName"`
+	Unit                  string             `bson:"unit" json:"unit"`
+	Factor                float64            `bson:"factor" json:"factor"`                               // Lagereinheiten je Einheit der Position
+	OriginalTransactionID primitive.ObjectID `bson:"originalTransactionId" json:"originalTransactionId"` // Warenausgang bzw. Wareneingang, auf den sich die Position bezieht
+	OriginalQuantity      float64            `bson:"originalQuantity" json:"originalQuantity"`           // Menge der Originalbuchung
+	Quantity              float64            `bson:"quantity" json:"quantity"`                           // Zurückgegebene Menge
+	Reason                string             `bson:"reason,omitempty" json:"reason,omitempty"`           // Grund der Rückgabe
+
+	// Ergebnis der Zustandsprüfung und gebuchte Bewegungen
+	Condition      ReturnCondition      `bson:"condition,omitempty" json:"condition,omitempty"`
+	StockStatus    StockStatus          `bson:"stockStatus,omitempty" json:"stockStatus,omitempty"` // Bestandsstatus, aus dem Ware an den Lieferanten zurückgeht
+	LocationID     primitive.ObjectID   `bson:"locationId,omitempty" json:"locationId,omitempty"`
+	LocationName   string               `bson:"locationName,omitempty" json:"locationName,omitempty"`
+	LotNumber      string               `bson:"lotNumber,omitempty" json:"lotNumber,omitempty"`
+	ExpiryDate     time.Time            `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	SerialNumbers  []string             `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"`
+	TransactionIDs []primitive.ObjectID `bson:"transactionIds,omitempty" json:"transactionIds,omitempty"`
+}
+
+// GetBaseQuantity gibt die zurückgegebene Menge in der Lagereinheit zurück
+func (l *ReturnLine) GetBaseQuantity() float64 {
+	if l.Factor <= 0 {
+		return l.Quantity
+	}
+	return l.Quantity * l.Factor
+}
+
+// ReturnCase ist eine Retoure (RMA): die Rücknahme von Ware vom Kunden oder die Rücksendung
+// an einen Lieferanten. Jede Position verweist auf die Buchung, mit der die Ware ausgeliefert
+// bzw. geliefert wurde; alle Buchungen der Retoure verweisen über ihre ReturnID auf sie.
+type ReturnCase struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReturnNumber string             `bson:"returnNumber" json:"returnNumber"` // Fortlaufende Retourennummer
+	Type         ReturnType         `bson:"type" json:"type"`
+	Status       ReturnStatus       `bson:"status" json:"status"`
+
+	// Kunde bzw. Lieferant und Beleg, auf den sich die Retoure bezieht
+	PartnerName       string             `bson:"partnerName" json:"partnerName"`
+	SupplierID        primitive.ObjectID `bson:"supplierId,omitempty" json:"supplierId,omitempty"`
+	CustomerOrderID   primitive.ObjectID `bson:"customerOrderId,omitempty" json:"customerOrderId,omitempty"`
+	GoodsReceiptID    primitive.ObjectID `bson:"goodsReceiptId,omitempty" json:"goodsReceiptId,omitempty"`
+	SourceNumber      string             `bson:"sourceNumber,omitempty" json:"sourceNumber,omitempty"`           // Auftrags- bzw. Wareneingangsnummer
+	ExternalReference string             `bson:"externalReference,omitempty" json:"externalReference,omitempty"` // RMA-Nummer des Lieferanten bzw. Zeichen des Kunden
+
+	Lines           []ReturnLine       `bson:"lines" json:"lines"`
+	Notes           string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedByID     primitive.ObjectID `bson:"createdById" json:"createdById"`
+	CreatedByName   string             `bson:"createdByName" json:"createdByName"`
+	CompletedByName string             `bson:"completedByName,omitempty" json:"completedByName,omitempty"`
+	CompletedAt     time.Time          `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version         int64              `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
+}
+
+// IsOpen prüft, ob die Zustandsprüfung noch aussteht
+func (r *ReturnCase) IsOpen() bool {
+	return r.Status == ReturnStatusOpen
+}
+
+// HasDocument prüft, ob für die Retoure ein Rücksendeschein an den Lieferanten vorliegt,
+// also ob nach der Prüfung Ware an ihn zurückgeht
+func (r *ReturnCase) HasDocument() bool {
+	if r.Type != ReturnTypeSupplier || r.Status != ReturnStatusCompleted {
+		return false
+	}
+	for i := range r.Lines {
+		if r.Lines[i].Condition == ReturnConditionSupplier {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDisplayType gibt die Bezeichnung der Retourenart zurück
+func (r *ReturnCase) GetDisplayType() string {
+	return r.Type.GetDisplayName()
+}
+
+// GetConditions gibt die für die Retoure zulässigen Prüfergebnisse zurück
+func (r *ReturnCase) GetConditions() []ReturnCondition {
+	return GetReturnConditions(r.Type)
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (r *ReturnCase) GetStatusClass() string {
+	switch r.Status {
+	case ReturnStatusOpen:
+		return "bg-yellow-100 text-yellow-800"
+	case ReturnStatusCompleted:
+		return "bg-green-100 text-green-800"
+	case ReturnStatusCancelled:
+		return "bg-red-100 text-red-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (r *ReturnCase) GetDisplayStatus() string {
+	return GetReturnStatusDisplay(r.Status)
+}
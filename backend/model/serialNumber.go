@@ -0,0 +1,109 @@
+// backend/model/serialNumber.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// SerialStatus repräsentiert den Status einer Seriennummer
+type SerialStatus string
+
+const (
+	SerialStatusInStock  SerialStatus = "in_stock" // Im Lager
+	SerialStatusIssued   SerialStatus = "issued"   // Ausgegeben
+	SerialStatusReturned SerialStatus = "returned" // Nach Ausgabe zurückgegeben und wieder im Lager
+	SerialStatusScrapped SerialStatus = "scrapped" // Verschrottet/ausgebucht
+)
+
+// SerialNumber repräsentiert ein einzelnes, per Seriennummer verfolgtes Stück eines Artikels
+type SerialNumber struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID         primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleName       string             `bson:"articleName" json:"articleName"`
+	SerialNumber      string             `bson:"serialNumber" json:"serialNumber"`
+	Status            SerialStatus       `bson:"status" json:"status"`
+	LocationID        primitive.ObjectID `bson:"locationId,omitempty" json:"locationId,omitempty"` // Lagerort bzw. Lagerort, aus dem das Stück zuletzt ausgebucht wurde
+	LocationName      string             `bson:"locationName,omitempty" json:"locationName,omitempty"`
+	LastTransactionID primitive.ObjectID `bson:"lastTransactionId" json:"lastTransactionId"`
+	History           []SerialEvent      `bson:"history" json:"history"`
+	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// SerialEvent ist ein Eintrag in der Historie einer Seriennummer und beschreibt den
+// Zustand nach einer Buchung
+type SerialEvent struct {
+	TransactionID   primitive.ObjectID `bson:"transactionId" json:"transactionId"`
+	TransactionType TransactionType    `bson:"transactionType" json:"transactionType"`
+	Status          SerialStatus       `bson:"status" json:"status"`
+	LocationID      primitive.ObjectID `bson:"locationId,omitempty" json:"locationId,omitempty"`
+	LocationName    string             `bson:"locationName,omitempty" json:"locationName,omitempty"`
+	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	UserName        string             `bson:"userName" json:"userName"`
+	Reference       string             `bson:"reference,omitempty" json:"reference,omitempty"`
+	Timestamp       time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// IsAvailable prüft, ob das Stück im Lager ist und ausgegeben werden kann
+func (s *SerialNumber) IsAvailable() bool {
+	return s.Status == SerialStatusInStock || s.Status == SerialStatusReturned
+}
+
+// LastIssue gibt die letzte Ausgabe des Stücks zurück oder nil, wenn es nie ausgegeben wurde
+func (s *SerialNumber) LastIssue() *SerialEvent {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Status == SerialStatusIssued {
+			return &s.History[i]
+		}
+	}
+	return nil
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status zurück
+func (s *SerialNumber) GetDisplayStatus() string {
+	return GetSerialStatusDisplay(s.Status)
+}
+
+// GetStatusClass gibt eine CSS-Klasse basierend auf dem Status zurück
+func (s *SerialNumber) GetStatusClass() string {
+	switch s.Status {
+	case SerialStatusInStock:
+		return "bg-green-100 text-green-800"
+	case SerialStatusIssued:
+		return "bg-blue-100 text-blue-800"
+	case SerialStatusReturned:
+		return "bg-yellow-100 text-yellow-800"
+	case SerialStatusScrapped:
+		return "bg-red-100 text-red-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// GetSerialStatusDisplay gibt einen benutzerfreundlichen Namen für einen Seriennummernstatus zurück
+func GetSerialStatusDisplay(status SerialStatus) string {
+	switch status {
+	case SerialStatusInStock:
+		return "Im Lager"
+	case SerialStatusIssued:
+		return "Ausgegeben"
+	case SerialStatusReturned:
+		return "Zurückgegeben"
+	case SerialStatusScrapped:
+		return "Verschrottet"
+	default:
+		return string(status)
+	}
+}
+
+// GetDisplayStatus gibt einen benutzerfreundlichen Namen für den Status nach dem Ereignis zurück
+func (e SerialEvent) GetDisplayStatus() string {
+	return GetSerialStatusDisplay(e.Status)
+}
+
+// GetDisplayType gibt einen benutzerfreundlichen Namen für den Typ der auslösenden Buchung zurück
+func (e SerialEvent) GetDisplayType() string {
+	transaction := Transaction{Type: e.TransactionType}
+	return transaction.GetDisplayType()
+}
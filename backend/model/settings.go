@@ -0,0 +1,44 @@
+// backend/model/settings.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ValuationMethod legt fest, wie der Lagerbestand bewertet wird
+type ValuationMethod string
+
+const (
+	ValuationMethodMovingAverage ValuationMethod = "moving_average" // Gleitender Durchschnittspreis
+	ValuationMethodFIFO          ValuationMethod = "fifo"           // First In, First Out über Kostenschichten
+)
+
+// ValuationMethods sind alle unterstützten Bewertungsverfahren in der Reihenfolge der Auswahl
+var ValuationMethods = []ValuationMethod{ValuationMethodMovingAverage, ValuationMethodFIFO}
+
+// IsValid prüft, ob das Bewertungsverfahren unterstützt wird
+func (m ValuationMethod) IsValid() bool {
+	return m == ValuationMethodMovingAverage || m == ValuationMethodFIFO
+}
+
+// GetDisplayName gibt einen benutzerfreundlichen Namen für das Bewertungsverfahren zurück
+func (m ValuationMethod) GetDisplayName() string {
+	switch m {
+	case ValuationMethodMovingAverage:
+		return "Gleitender Durchschnitt"
+	case ValuationMethodFIFO:
+		return "FIFO"
+	default:
+		return string(m)
+	}
+}
+
+// Settings enthält systemweite Einstellungen. Es gibt genau ein Dokument mit der ID "global".
+type Settings struct {
+	ID              string             `bson:"_id" json:"id"`
+	ValuationMethod ValuationMethod    `bson:"valuationMethod" json:"valuationMethod"` // Verfahren für den aktuellen Lagerwert und Berichte
+	UpdatedByID     primitive.ObjectID `bson:"updatedById,omitempty" json:"updatedById,omitempty"`
+	UpdatedByName   string             `bson:"updatedByName,omitempty" json:"updatedByName,omitempty"`
+	UpdatedAt       time.Time          `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
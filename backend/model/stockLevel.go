@@ -0,0 +1,124 @@
+// backend/model/stockLevel.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// UnassignedLocationName ist die Anzeige für Bestand, der keinem Lagerort zugeordnet ist
+const UnassignedLocationName = "Ohne Lagerort"
+
+// StockStatus ist der Verwendungsstatus von Bestand. Nur verfügbarer Bestand kann
+// reserviert, kommissioniert und ausgegeben werden; Bestand in den übrigen Status zählt zum
+// physischen Bestand, wird aber zurückgehalten, bis ein Statuswechsel ihn freigibt.
+type StockStatus string
+
+const (
+	StockStatusAvailable  StockStatus = "available"  // Frei verwendbar
+	StockStatusQuarantine StockStatus = "quarantine" // In Prüfung, z.B. nach Retoure oder Wareneingangsprüfung
+	StockStatusBlocked    StockStatus = "blocked"    // Gesperrt, z.B. wegen Rückruf oder Reklamation
+	StockStatusDamaged    StockStatus = "damaged"    // Beschädigt
+)
+
+// GetStockStatuses gibt alle Bestandsstatus in Anzeigereihenfolge zurück
+func GetStockStatuses() []StockStatus {
+	return []StockStatus{StockStatusAvailable, StockStatusQuarantine, StockStatusBlocked, StockStatusDamaged}
+}
+
+// GetHeldStockStatuses gibt die Bestandsstatus zurück, deren Bestand nicht verfügbar ist
+func GetHeldStockStatuses() []StockStatus {
+	return []StockStatus{StockStatusQuarantine, StockStatusBlocked, StockStatusDamaged}
+}
+
+// IsValid prüft, ob der Bestandsstatus bekannt ist
+func (s StockStatus) IsValid() bool {
+	for _, status := range GetStockStatuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHeld prüft, ob Bestand in diesem Status zurückgehalten wird. Ein leerer Status gilt als verfügbar.
+func (s StockStatus) IsHeld() bool {
+	return s != "" && s != StockStatusAvailable
+}
+
+// GetDisplayName gibt die Bezeichnung des Bestandsstatus zurück
+func (s StockStatus) GetDisplayName() string {
+	switch s {
+	case "", StockStatusAvailable:
+		return "Verfügbar"
+	case StockStatusQuarantine:
+		return "Quarantäne"
+	case StockStatusBlocked:
+		return "Gesperrt"
+	case StockStatusDamaged:
+		return "Beschädigt"
+	default:
+		return string(s)
+	}
+}
+
+// GetStatusClass gibt eine CSS-Klasse für die Anzeige des Bestandsstatus zurück
+func (s StockStatus) GetStatusClass() string {
+	switch s {
+	case "", StockStatusAvailable:
+		return "bg-green-100 text-green-800"
+	case StockStatusQuarantine:
+		return "bg-yellow-100 text-yellow-800"
+	case StockStatusBlocked:
+		return "bg-red-100 text-red-800"
+	case StockStatusDamaged:
+		return "bg-gray-200 text-gray-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// StockLevel repräsentiert den Bestand eines Artikels an einem Lagerort.
+// Der Gesamtbestand eines Artikels (Article.StockCurrent) ist die Summe seiner StockLevels.
+// Quantity ist der physische Bestand einschließlich der zurückgehaltenen Mengen.
+type StockLevel struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ArticleID  primitive.ObjectID `bson:"articleId" json:"articleId"`
+	LocationID primitive.ObjectID `bson:"locationId" json:"locationId"` // Leer bei Bestand ohne Lagerort (Altdaten)
+	Quantity   float64            `bson:"quantity" json:"quantity"`
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+
+	// Davon zurückgehaltene Mengen je Bestandsstatus
+	Quarantine float64 `bson:"quarantine,omitempty" json:"quarantine,omitempty"`
+	Blocked    float64 `bson:"blocked,omitempty" json:"blocked,omitempty"`
+	Damaged    float64 `bson:"damaged,omitempty" json:"damaged,omitempty"`
+}
+
+// IsUnassigned prüft, ob der Bestand keinem Lagerort zugeordnet ist
+func (s *StockLevel) IsUnassigned() bool {
+	return s.LocationID.IsZero()
+}
+
+// GetHeldQuantity gibt die am Lagerort zurückgehaltene Menge zurück
+func (s *StockLevel) GetHeldQuantity() float64 {
+	return s.Quarantine + s.Blocked + s.Damaged
+}
+
+// GetAvailableQuantity gibt die am Lagerort verfügbare Menge zurück
+func (s *StockLevel) GetAvailableQuantity() float64 {
+	return s.Quantity - s.GetHeldQuantity()
+}
+
+// GetQuantityIn gibt die Menge in einem Bestandsstatus zurück
+func (s *StockLevel) GetQuantityIn(status StockStatus) float64 {
+	switch status {
+	case StockStatusQuarantine:
+		return s.Quarantine
+	case StockStatusBlocked:
+		return s.Blocked
+	case StockStatusDamaged:
+		return s.Damaged
+	default:
+		return s.GetAvailableQuantity()
+	}
+}
@@ -22,4 +22,5 @@ type Supplier struct {
 	IsActive      bool               `bson:"isActive" json:"isActive"`               // Aktiv/Inaktiv
 	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version       int64              `bson:"version" json:"version"` // Versionszähler für optimistisches Sperren
 }
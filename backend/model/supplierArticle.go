@@ -0,0 +1,96 @@
+// backend/model/supplierArticle.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"time"
+)
+
+// SupplierPrice ist ein Einkaufspreis einer Bezugsquelle mit seinem Gültigkeitszeitraum
+type SupplierPrice struct {
+	PurchasePriceNet float64   `bson:"purchasePriceNet" json:"purchasePriceNet"`       // Einkaufspreis netto je Bestelleinheit
+	ValidFrom        time.Time `bson:"validFrom" json:"validFrom"`                     // Gültig ab (einschließlich)
+	ValidTo          time.Time `bson:"validTo,omitempty" json:"validTo,omitempty"`     // Gültig bis (ausschließlich), leer = unbefristet
+	ChangedBy        string    `bson:"changedBy,omitempty" json:"changedBy,omitempty"` // Benutzer, der den Preis erfasst hat
+	ChangedAt        time.Time `bson:"changedAt,omitempty" json:"changedAt,omitempty"` // Zeitpunkt der Erfassung
+	Notes            string    `bson:"notes,omitempty" json:"notes,omitempty"`         // z.B. Preisliste oder Angebot
+}
+
+// IsValidAt prüft, ob der Preis zum angegebenen Zeitpunkt gilt
+func (p *SupplierPrice) IsValidAt(t time.Time) bool {
+	return !t.Before(p.ValidFrom) && (p.ValidTo.IsZero() || t.Before(p.ValidTo))
+}
+
+// SupplierArticle ist eine Bezugsquelle: die Lieferbedingungen eines Lieferanten für einen
+// Artikel. Ein Artikel kann bei mehreren Lieferanten bezogen werden; höchstens eine
+// Bezugsquelle je Artikel ist bevorzugt.
+type SupplierArticle struct {
+	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SupplierID            primitive.ObjectID `bson:"supplierId" json:"supplierId"`
+	SupplierName          string             `bson:"supplierName" json:"supplierName"`
+	ArticleID             primitive.ObjectID `bson:"articleId" json:"articleId"`
+	ArticleNumber         string             `bson:"articleNumber" json:"articleNumber"`
+	ArticleName           string             `bson:"articleName" json:"articleName"`
+	SupplierArticleNumber string             `bson:"supplierArticleNumber" json:"supplierArticleNumber"` // Artikelnummer beim Lieferanten
+	Unit                  string             `bson:"unit" json:"unit"`                                   // Bestelleinheit, eine Einheit des Artikels
+	MinimumOrderQuantity  float64            `bson:"minimumOrderQuantity" json:"minimumOrderQuantity"`   // Mindestbestellmenge in der Bestelleinheit
+	PackSize              float64            `bson:"packSize" json:"packSize"`                           // Bestellt wird in Vielfachen davon (0 = beliebig)
+	LeadTimeDays          int                `bson:"leadTimeDays" json:"leadTimeDays"`                   // Lieferzeit in Tagen
+	IsPreferred           bool               `bson:"isPreferred" json:"isPreferred"`                     // Bevorzugte Bezugsquelle des Artikels
+	Prices                []SupplierPrice    `bson:"prices" json:"prices"`                               // Preishistorie, älteste zuerst
+	CreatedAt             time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt             time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// GetPriceAt gibt den zum angegebenen Zeitpunkt gültigen Preis zurück
+func (s *SupplierArticle) GetPriceAt(t time.Time) (*SupplierPrice, bool) {
+	for i := len(s.Prices) - 1; i >= 0; i-- {
+		if s.Prices[i].IsValidAt(t) {
+			return &s.Prices[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetCurrentPrice gibt den heute gültigen Einkaufspreis je Bestelleinheit zurück (0 = kein Preis)
+func (s *SupplierArticle) GetCurrentPrice() float64 {
+	if price, ok := s.GetPriceAt(time.Now()); ok {
+		return price.PurchasePriceNet
+	}
+	return 0
+}
+
+// GetLatestPrice gibt den zuletzt erfassten Preis zurück, auch wenn er erst künftig gilt
+func (s *SupplierArticle) GetLatestPrice() *SupplierPrice {
+	if len(s.Prices) == 0 {
+		return nil
+	}
+	return &s.Prices[len(s.Prices)-1]
+}
+
+// GetPriceHistory gibt die Preise in umgekehrter Reihenfolge zurück, den neuesten zuerst
+func (s *SupplierArticle) GetPriceHistory() []SupplierPrice {
+	history := make([]SupplierPrice, len(s.Prices))
+	for i := range s.Prices {
+		history[len(s.Prices)-1-i] = s.Prices[i]
+	}
+	return history
+}
+
+// RoundOrderQuantity rundet eine Menge in der Bestelleinheit auf die Mindestbestellmenge und
+// auf volle Verpackungseinheiten auf
+func (s *SupplierArticle) RoundOrderQuantity(quantity float64) float64 {
+	if quantity < s.MinimumOrderQuantity {
+		quantity = s.MinimumOrderQuantity
+	}
+	if s.PackSize > 0 {
+		quantity = math.Ceil(quantity/s.PackSize-1e-9) * s.PackSize
+	}
+	return quantity
+}
+
+// IsValidOrderQuantity prüft Mindestbestellmenge und Verpackungseinheit
+func (s *SupplierArticle) IsValidOrderQuantity(quantity float64) bool {
+	return math.Abs(s.RoundOrderQuantity(quantity)-quantity) < 1e-9
+}
@@ -14,6 +14,15 @@ const (
 	TransactionTypeStockOut  TransactionType = "stock_out" // Warenausgang
 	TransactionTypeAdjust    TransactionType = "adjust"    // Bestandskorrektur
 	TransactionTypeInventory TransactionType = "inventory" // Inventurzählung
+	TransactionTypeReversal  TransactionType = "reversal"  // Storno einer früheren Buchung
+	TransactionTypeTransfer  TransactionType = "transfer"  // Umlagerung zwischen Lagerorten
+
+	// Statuswechsel: verschiebt Bestand an einem Lagerort zwischen Bestandsstatus, z.B. von
+	// verfügbar nach Quarantäne, ohne den Gesamtbestand zu verändern
+	TransactionTypeStatusChange TransactionType = "status_change"
+
+	// Journalkorrektur: erfasst eine Bestandsdifferenz, die ohne Buchung entstanden ist
+	TransactionTypeCorrection TransactionType = "correction"
 )
 
 // Transaction repräsentiert eine Lager-Transaktion (Ein-/Ausgang/Korrektur)
@@ -22,16 +31,119 @@ type Transaction struct {
 	Type        TransactionType    `bson:"type" json:"type"`
 	ArticleID   primitive.ObjectID `bson:"articleId" json:"articleId"`
 	ArticleName string             `bson:"articleName" json:"articleName"`
-	Quantity    float64            `bson:"quantity" json:"quantity"`                       // Menge (positiv oder negativ)
-	OldStock    float64            `bson:"oldStock" json:"oldStock"`                       // Bestand vor der Transaktion
-	NewStock    float64            `bson:"newStock" json:"newStock"`                       // Bestand nach der Transaktion
-	UnitPrice   float64            `bson:"unitPrice,omitempty" json:"unitPrice,omitempty"` // Stückpreis für Bewertung
-	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`       // Grund der Transaktion
-	Reference   string             `bson:"reference,omitempty" json:"reference,omitempty"` // Referenz (z.B. Lieferschein, Bestellung)
-	UserID      primitive.ObjectID `bson:"userId" json:"userId"`                           // Benutzer, der die Transaktion durchgeführt hat
-	UserName    string             `bson:"userName" json:"userName"`                       // Name des Benutzers für die Anzeige
-	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`                     // Zeitpunkt der Transaktion
+	Quantity    float64            `bson:"quantity" json:"quantity"`                           // Menge (positiv oder negativ)
+	OldStock    float64            `bson:"oldStock" json:"oldStock"`                           // Bestand vor der Transaktion
+	NewStock    float64            `bson:"newStock" json:"newStock"`                           // Bestand nach der Transaktion
+	UnitPrice   float64            `bson:"unitPrice,omitempty" json:"unitPrice,omitempty"`     // Stückpreis für Bewertung
+	AverageCost float64            `bson:"averageCost,omitempty" json:"averageCost,omitempty"` // Gleitender Durchschnittspreis nach der Buchung
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`           // Grund der Transaktion
+	Reference   string             `bson:"reference,omitempty" json:"reference,omitempty"`     // Referenz (z.B. Lieferschein, Bestellung)
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`                               // Benutzer, der die Transaktion durchgeführt hat
+	UserName    string             `bson:"userName" json:"userName"`                           // Name des Benutzers für die Anzeige
+	Timestamp   time.Time          `bson:"timestamp" json:"timestamp"`                         // Zeitpunkt der Transaktion
 	Notes       string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	ReversalOf  primitive.ObjectID `bson:"reversalOf,omitempty" json:"reversalOf,omitempty"` // Stornierte Originalbuchung (nur bei Storno)
+	ReversedBy  primitive.ObjectID `bson:"reversedBy,omitempty" json:"reversedBy,omitempty"` // Stornobuchung, falls diese Buchung storniert wurde
+
+	// Betroffener Lagerort (Name als Pfad für die Anzeige)
+	LocationID   primitive.ObjectID `bson:"locationId,omitempty" json:"locationId,omitempty"`
+	LocationName string             `bson:"locationName,omitempty" json:"locationName,omitempty"`
+
+	// Lagerorte bei Umlagerungen (Namen als Pfad für die Anzeige)
+	SourceLocationID   primitive.ObjectID `bson:"sourceLocationId,omitempty" json:"sourceLocationId,omitempty"`
+	SourceLocationName string             `bson:"sourceLocationName,omitempty" json:"sourceLocationName,omitempty"`
+	TargetLocationID   primitive.ObjectID `bson:"targetLocationId,omitempty" json:"targetLocationId,omitempty"`
+	TargetLocationName string             `bson:"targetLocationName,omitempty" json:"targetLocationName,omitempty"`
+
+	// Chargen bei chargenpflichtigen Artikeln. LotNumber und ExpiryDate sind die Eingaben beim
+	// Wareneingang bzw. die manuell gewählte Charge bei Entnahmen; Lots enthält die tatsächlich
+	// bewegten Chargenmengen.
+	LotNumber  string          `bson:"lotNumber,omitempty" json:"lotNumber,omitempty"`
+	ExpiryDate time.Time       `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	Lots       []LotAllocation `bson:"lots,omitempty" json:"lots,omitempty"`
+
+	// Seriennummern der bewegten Stücke bei seriennummernpflichtigen Artikeln
+	SerialNumbers []string `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"`
+
+	// Reservierung, die ein Warenausgang erfüllt
+	ReservationID primitive.ObjectID `bson:"reservationId,omitempty" json:"reservationId,omitempty"`
+
+	// Wareneingang zu einer Bestellung, aus dem die Buchung stammt
+	GoodsReceiptID primitive.ObjectID `bson:"goodsReceiptId,omitempty" json:"goodsReceiptId,omitempty"`
+
+	// Kundenauftrag, dessen Versand die Buchung ausgelöst hat
+	CustomerOrderID primitive.ObjectID `bson:"customerOrderId,omitempty" json:"customerOrderId,omitempty"`
+
+	// Montage bzw. Demontage, zu der die Buchung gehört
+	AssemblyID primitive.ObjectID `bson:"assemblyId,omitempty" json:"assemblyId,omitempty"`
+
+	// Retoure, deren Zustandsprüfung die Buchung ausgelöst hat
+	ReturnID primitive.ObjectID `bson:"returnId,omitempty" json:"returnId,omitempty"`
+
+	// Bestandsstatus der bewegten Ware (leer = verfügbar), z.B. ein Zugang direkt in Quarantäne
+	// oder die Entnahme beschädigter Ware. Bei Statuswechseln ist StockStatus der bisherige und
+	// TargetStockStatus der neue Status.
+	StockStatus       StockStatus `bson:"stockStatus,omitempty" json:"stockStatus,omitempty"`
+	TargetStockStatus StockStatus `bson:"targetStockStatus,omitempty" json:"targetStockStatus,omitempty"`
+
+	// Erfasste Menge, falls nicht in der Lagereinheit gebucht wurde. Quantity und UnitPrice
+	// enthalten immer die in die Lagereinheit umgerechneten Werte.
+	InputUnit      string  `bson:"inputUnit,omitempty" json:"inputUnit,omitempty"`
+	InputQuantity  float64 `bson:"inputQuantity,omitempty" json:"inputQuantity,omitempty"`
+	InputUnitPrice float64 `bson:"inputUnitPrice,omitempty" json:"inputUnitPrice,omitempty"` // Stückpreis je erfasster Einheit
+	InputFactor    float64 `bson:"inputFactor,omitempty" json:"inputFactor,omitempty"`       // Lagereinheiten je erfasster Einheit
+}
+
+// IsReversed prüft, ob die Buchung bereits storniert wurde
+func (t *Transaction) IsReversed() bool {
+	return !t.ReversedBy.IsZero()
+}
+
+// HasInputUnit prüft, ob die Menge in einer anderen als der Lagereinheit erfasst wurde
+func (t *Transaction) HasInputUnit() bool {
+	return t.InputUnit != ""
+}
+
+// IsStatusChange prüft, ob die Buchung Bestand zwischen Bestandsstatus verschiebt (auch als Storno)
+func (t *Transaction) IsStatusChange() bool {
+	return t.TargetStockStatus != ""
+}
+
+// GetStockDelta gibt die tatsächliche Bestandsveränderung der Buchung zurück
+func (t *Transaction) GetStockDelta() float64 {
+	return t.NewStock - t.OldStock
+}
+
+// GetLocationDelta gibt die Bestandsveränderung der Buchung an einem Lagerort zurück.
+// Umlagerungen zählen als Abgang am Quell- und Zugang am Ziellagerort, Statuswechsel verändern
+// den Bestand am Lagerort nicht. Journalkorrekturen gleichen den Lagerplatzbestand ohne
+// Angabe der Menge aus und werden hier nicht berücksichtigt.
+func (t *Transaction) GetLocationDelta(locationID primitive.ObjectID) float64 {
+	switch {
+	case t.Type == TransactionTypeTransfer || (t.Type == TransactionTypeReversal && !t.SourceLocationID.IsZero()):
+		var delta float64
+		if t.TargetLocationID == locationID {
+			delta += t.Quantity
+		}
+		if t.SourceLocationID == locationID {
+			delta -= t.Quantity
+		}
+		return delta
+	case t.IsStatusChange(), t.LocationID != locationID:
+		return 0
+	}
+
+	switch t.Type {
+	case TransactionTypeStockIn:
+		return t.Quantity
+	case TransactionTypeStockOut:
+		return -t.Quantity
+	case TransactionTypeAdjust, TransactionTypeInventory, TransactionTypeReversal:
+		// Die Menge enthält bereits die Differenz zum bisherigen Bestand
+		return t.Quantity
+	default:
+		return 0
+	}
 }
 
 // GetStatusClass gibt eine CSS-Klasse basierend auf dem Transaktionstyp zurück
@@ -45,6 +157,14 @@ func (t *Transaction) GetStatusClass() string {
 		return "bg-yellow-100 text-yellow-800"
 	case TransactionTypeInventory:
 		return "bg-blue-100 text-blue-800"
+	case TransactionTypeReversal:
+		return "bg-purple-100 text-purple-800"
+	case TransactionTypeTransfer:
+		return "bg-indigo-100 text-indigo-800"
+	case TransactionTypeCorrection:
+		return "bg-orange-100 text-orange-800"
+	case TransactionTypeStatusChange:
+		return "bg-teal-100 text-teal-800"
 	default:
 		return "bg-gray-100 text-gray-800"
 	}
@@ -61,7 +181,57 @@ func (t *Transaction) GetDisplayType() string {
 		return "Bestandskorrektur"
 	case TransactionTypeInventory:
 		return "Inventur"
+	case TransactionTypeReversal:
+		return "Storno"
+	case TransactionTypeTransfer:
+		return "Umlagerung"
+	case TransactionTypeCorrection:
+		return "Journalkorrektur"
+	case TransactionTypeStatusChange:
+		return "Statuswechsel"
 	default:
 		return string(t.Type)
 	}
 }
+
+// MovementTotal fasst die Lagerbewegungen einer Gruppe (z.B. eines Artikels oder Lagerorts)
+// in einem Zeitraum zusammen. Zu- und Abgänge sind getrennt als positive Werte summiert.
+type MovementTotal struct {
+	Key         string  `bson:"_id" json:"key"`
+	Name        string  `bson:"name" json:"name"`
+	Count       int     `bson:"count" json:"count"`
+	QuantityIn  float64 `bson:"quantityIn" json:"quantityIn"`
+	QuantityOut float64 `bson:"quantityOut" json:"quantityOut"`
+	ValueIn     float64 `bson:"valueIn" json:"valueIn"`
+	ValueOut    float64 `bson:"valueOut" json:"valueOut"`
+}
+
+// GetNetQuantity gibt die Bestandsveränderung der Gruppe zurück
+func (m *MovementTotal) GetNetQuantity() float64 {
+	return m.QuantityIn - m.QuantityOut
+}
+
+// GetNetValue gibt die Wertveränderung der Gruppe zurück
+func (m *MovementTotal) GetNetValue() float64 {
+	return m.ValueIn - m.ValueOut
+}
+
+// LedgerSummary fasst das Buchungsjournal eines Artikels zusammen
+type LedgerSummary struct {
+	ArticleID     primitive.ObjectID `bson:"_id" json:"articleId"`
+	Count         int                `bson:"count" json:"count"`
+	MovementSum   float64            `bson:"movementSum" json:"movementSum"`     // Summe aller Bestandsveränderungen
+	CorrectionSum float64            `bson:"correctionSum" json:"correctionSum"` // Davon durch Journalkorrekturen
+	FirstOldStock float64            `bson:"firstOldStock" json:"firstOldStock"` // Bestand vor der ersten Buchung
+	LastNewStock  float64            `bson:"lastNewStock" json:"lastNewStock"`   // Bestand nach der letzten Buchung
+	LastTimestamp time.Time          `bson:"lastTimestamp" json:"lastTimestamp"`
+}
+
+// GetChainGap gibt die Bestandsänderungen zurück, die zwischen zwei Buchungen ohne Buchung
+// entstanden sind. In einem lückenlosen Journal ist der Bestand vor jeder Buchung der Bestand
+// nach der vorigen, sodass die Bewegungen genau die Differenz zwischen erstem und letztem
+// Bestand ergeben. Journalkorrekturen zählen nicht als Bewegung, da sie Lücken nachträglich
+// ausgleichen.
+func (s *LedgerSummary) GetChainGap() float64 {
+	return (s.LastNewStock - s.FirstOldStock) - (s.MovementSum - s.CorrectionSum)
+}
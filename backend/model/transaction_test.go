@@ -0,0 +1,57 @@
+// backend/model/transaction_test.go
+package model
+
+import "testing"
+
+func TestLedgerSummaryGetChainGap(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary LedgerSummary
+		want    float64
+	}{
+		{
+			// Zugang 0 → 10, Abgang 10 → 7, Zugang 7 → 12
+			name:    "lückenloses Journal",
+			summary: LedgerSummary{Count: 3, MovementSum: 12, FirstOldStock: 0, LastNewStock: 12},
+			want:    0,
+		},
+		{
+			// Zugang 20 → 30, Abgang 30 → 25
+			name:    "Anfangsbestand ohne Buchung ist keine Lücke",
+			summary: LedgerSummary{Count: 2, MovementSum: 5, FirstOldStock: 20, LastNewStock: 25},
+			want:    0,
+		},
+		{
+			// Zugang 0 → 10, Umlagerung 10 → 10, Abgang 10 → 4
+			name:    "Umlagerungen verändern den Bestand nicht",
+			summary: LedgerSummary{Count: 3, MovementSum: 4, FirstOldStock: 0, LastNewStock: 4},
+			want:    0,
+		},
+		{
+			// Zugang 0 → 10, Abgang 15 → 12
+			name:    "ungebuchter Zugang zwischen zwei Buchungen",
+			summary: LedgerSummary{Count: 2, MovementSum: 7, FirstOldStock: 0, LastNewStock: 12},
+			want:    5,
+		},
+		{
+			// Zugang 0 → 10, Abgang 8 → 5
+			name:    "ungebuchter Abgang zwischen zwei Buchungen",
+			summary: LedgerSummary{Count: 2, MovementSum: 7, FirstOldStock: 0, LastNewStock: 5},
+			want:    -2,
+		},
+		{
+			// Zugang 0 → 10, Korrektur 10 → 15, Abgang 15 → 12
+			name:    "Journalkorrektur zählt nicht als Bewegung",
+			summary: LedgerSummary{Count: 3, MovementSum: 12, CorrectionSum: 5, FirstOldStock: 0, LastNewStock: 12},
+			want:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.GetChainGap(); got != tt.want {
+				t.Errorf("GetChainGap() = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// backend/model/unitOfMeasure.go
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// UnitOfMeasure ist eine Mengeneinheit aus dem Einheitenkatalog
+type UnitOfMeasure struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`     // Bezeichnung, z.B. Stück oder Karton (eindeutig)
+	Symbol    string             `bson:"symbol" json:"symbol"` // Kurzzeichen, z.B. Stk oder Krt
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DefaultUnitsOfMeasure sind die Einheiten, mit denen der Katalog angelegt wird
+var DefaultUnitsOfMeasure = []UnitOfMeasure{
+	{Name: "Stück", Symbol: "Stk"},
+	{Name: "Packung", Symbol: "Pck"},
+	{Name: "Karton", Symbol: "Krt"},
+	{Name: "Palette", Symbol: "Pal"},
+	{Name: "Kilogramm", Symbol: "kg"},
+	{Name: "Gramm", Symbol: "g"},
+	{Name: "Liter", Symbol: "l"},
+	{Name: "Meter", Symbol: "m"},
+}
+
+// UnitConversion rechnet eine weitere Einheit eines Artikels in seine Lagereinheit um,
+// z.B. einen Karton zu 12 Stück. Preise je Einheit sind optional; ohne Angabe gilt der
+// Preis der Lagereinheit multipliziert mit dem Faktor.
+type UnitConversion struct {
+	Unit             string  `bson:"unit" json:"unit"`
+	Factor           float64 `bson:"factor" json:"factor"`                                         // Lagereinheiten je Einheit
+	PurchasePriceNet float64 `bson:"purchasePriceNet,omitempty" json:"purchasePriceNet,omitempty"` // Einkaufspreis netto je Einheit
+	SalesPriceGross  float64 `bson:"salesPriceGross,omitempty" json:"salesPriceGross,omitempty"`   // Verkaufspreis brutto je Einheit
+}
@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,6 +16,38 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrInsufficientStock wird zurückgegeben, wenn eine Buchung den Bestand unter null senken würde
+var ErrInsufficientStock = errors.New("Nicht genügend Bestand vorhanden")
+
+// ErrStockReserved wird zurückgegeben, wenn eine Entnahme in reservierten Bestand eingreifen würde
+var ErrStockReserved = errors.New("Der verfügbare Bestand reicht nicht aus, der Rest ist reserviert")
+
+// ErrStockHeld wird zurückgegeben, wenn eine Entnahme in Bestand eingreifen würde, der in
+// Quarantäne, gesperrt oder beschädigt ist
+var ErrStockHeld = errors.New("Der verfügbare Bestand reicht nicht aus, der Rest ist in Quarantäne, gesperrt oder beschädigt")
+
+// heldStockFields sind die Artikelfelder der zurückgehaltenen Bestände je Bestandsstatus
+var heldStockFields = map[model.StockStatus]string{
+	model.StockStatusQuarantine: "stockQuarantine",
+	model.StockStatusBlocked:    "stockBlocked",
+	model.StockStatusDamaged:    "stockDamaged",
+}
+
+// unrestrictedStock ist der Ausdruck für den frei verwendbaren Bestand eines Artikels: der
+// Gesamtbestand ohne die Mengen in Quarantäne, gesperrt oder beschädigt
+var unrestrictedStock = bson.M{"$subtract": bson.A{"$stockCurrent", bson.M{"$add": bson.A{
+	bson.M{"$ifNull": bson.A{"$stockQuarantine", 0}},
+	bson.M{"$ifNull": bson.A{"$stockBlocked", 0}},
+	bson.M{"$ifNull": bson.A{"$stockDamaged", 0}},
+}}}}
+
+// receiptCostAttempts begrenzt die Versuche, den Durchschnittspreis bei gleichzeitigen
+// Änderungen am Artikel zu speichern
+const receiptCostAttempts = 5
+
+// availableStock ist der Ausdruck für den verfügbaren Bestand: frei verwendbar und nicht reserviert
+var availableStock = bson.M{"$subtract": bson.A{unrestrictedStock, bson.M{"$ifNull": bson.A{"$stockReserved", 0}}}}
+
 // ArticleRepository enthält alle Datenbankoperationen für das Article-Modell
 type ArticleRepository struct {
 	collection *mongo.Collection
@@ -190,7 +223,16 @@ func (r *ArticleRepository) FindAll() ([]*model.Article, error) {
 	return articles, nil
 }
 
-// Update aktualisiert einen Artikel
+// ledgerFields sind die Artikelfelder, die sich aus dem Buchungsjournal ergeben und daher
+// von Update nicht überschrieben werden
+var ledgerFields = []string{"stockCurrent", "stockReserved", "stockQuarantine", "stockBlocked", "stockDamaged", "averageCost"}
+
+// optionalFields sind Stammdatenfelder, die leer nicht gespeichert werden. Update entfernt
+// sie, wenn sie geleert wurden, z.B. eine gelöschte Stückliste.
+var optionalFields = []string{"purchaseUnit", "issueUnit", "unitConversions", "billOfMaterials"}
+
+// Update aktualisiert die Stammdaten eines Artikels. Bestand, Reservierungen und
+// Durchschnittspreis bleiben unverändert; sie werden ausschließlich über Buchungen gepflegt.
 func (r *ArticleRepository) Update(article *model.Article) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -214,14 +256,385 @@ func (r *ArticleRepository) Update(article *model.Article) error {
 		return err
 	}
 
-	_, err = r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": article.ID},
-		bson.M{"$set": article},
-	)
+	// Bestand, Reservierungen und Durchschnittspreis werden nur über Buchungen verändert
+	data, err := bson.Marshal(article)
+	if err != nil {
+		return err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, field := range ledgerFields {
+		delete(fields, field)
+	}
+
+	// Nur speichern, wenn der Artikel seit dem Laden nicht verändert wurde
+	filter := versionFilter(article.ID, article.Version)
+	article.Version++
+	fields["version"] = article.Version
+
+	update := bson.M{"$set": fields}
+	unset := bson.M{}
+	for _, field := range optionalFields {
+		if _, exists := fields[field]; !exists {
+			unset[field] = ""
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		article.Version--
+		return err
+	}
+	if result.MatchedCount == 0 {
+		article.Version--
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// GetStockCurrent liest den aktuellen Gesamtbestand eines Artikels
+func (r *ArticleRepository) GetStockCurrent(articleID primitive.ObjectID) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetProjection(bson.M{"stockCurrent": 1})
+
+	var article model.Article
+	if err := r.collection.FindOne(ctx, bson.M{"_id": articleID}, opts).Decode(&article); err != nil {
+		return 0, err
+	}
+
+	return article.StockCurrent, nil
+}
+
+// IncrementStock verändert den Bestand eines Artikels atomar um delta und gibt den
+// Bestand vor und nach der Änderung zurück. Ohne allowNegative wird eine Entnahme
+// nur gebucht, wenn der Bestand danach nicht unter null fällt.
+func (r *ArticleRepository) IncrementStock(articleID primitive.ObjectID, delta float64, allowNegative bool) (float64, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": articleID}
+	if delta < 0 && !allowNegative {
+		// Der Filter greift nur, wenn genug Bestand vorhanden ist
+		filter["stockCurrent"] = bson.M{"$gte": -delta}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"stockCurrent": delta, "version": 1},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	// Dokument vor der Änderung zurückgeben, um den alten Bestand zu kennen
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var article model.Article
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&article)
+	if err == mongo.ErrNoDocuments && delta < 0 && !allowNegative {
+		// Unterscheiden, ob der Artikel fehlt oder nur der Bestand nicht reicht
+		count, countErr := r.collection.CountDocuments(ctx, bson.M{"_id": articleID})
+		if countErr != nil {
+			return 0, 0, countErr
+		}
+		if count > 0 {
+			return 0, 0, ErrInsufficientStock
+		}
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return article.StockCurrent, article.StockCurrent + delta, nil
+}
+
+// WithdrawAvailableStock entnimmt atomar eine Menge aus dem Bestand eines Artikels, ohne
+// reservierte oder zurückgehaltene Mengen anzutasten, und gibt den Bestand vor und nach der
+// Entnahme zurück. Reicht nur der reservierte Bestand, wird ErrStockReserved zurückgegeben,
+// reicht er nur mit Quarantäne, gesperrter oder beschädigter Ware, ErrStockHeld.
+func (r *ArticleRepository) WithdrawAvailableStock(articleID primitive.ObjectID, quantity float64) (float64, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Der Filter greift nur, wenn der verfügbare Bestand ausreicht
+	filter := bson.M{
+		"_id":   articleID,
+		"$expr": bson.M{"$gte": bson.A{availableStock, quantity}},
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"stockCurrent": -quantity, "version": 1},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var article model.Article
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&article)
+	if err == mongo.ErrNoDocuments {
+		// Unterscheiden, ob der Artikel fehlt, der Bestand nicht reicht, reserviert oder zurückgehalten ist
+		var current model.Article
+		if findErr := r.collection.FindOne(ctx, bson.M{"_id": articleID}).Decode(&current); findErr != nil {
+			return 0, 0, findErr
+		}
+		if current.GetUnrestrictedStock() >= quantity {
+			return 0, 0, ErrStockReserved
+		}
+		if current.StockCurrent >= quantity {
+			return 0, 0, ErrStockHeld
+		}
+		return 0, 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return article.StockCurrent, article.StockCurrent - quantity, nil
+}
+
+// IncrementReserved verändert den reservierten Bestand eines Artikels atomar um delta. Ohne
+// allowExceed wird eine zusätzliche Reservierung nur gebucht, wenn der verfügbare Bestand
+// dafür ausreicht; zurückgehaltene Ware kann nicht reserviert werden.
+func (r *ArticleRepository) IncrementReserved(articleID primitive.ObjectID, delta float64, allowExceed bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": articleID}
+	guarded := delta > 0 && !allowExceed
+	if guarded {
+		filter["$expr"] = bson.M{"$gte": bson.A{availableStock, delta}}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"stockReserved": delta, "version": 1},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if guarded {
+			count, countErr := r.collection.CountDocuments(ctx, bson.M{"_id": articleID})
+			if countErr != nil {
+				return countErr
+			}
+			if count > 0 {
+				return ErrInsufficientStock
+			}
+		}
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// SetReserved setzt den reservierten Bestand eines Artikels auf einen absoluten Wert
+func (r *ArticleRepository) SetReserved(articleID primitive.ObjectID, quantity float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{"stockReserved": quantity, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": articleID}, update)
+	return err
+}
+
+// IncrementHeldStock verändert den Bestand eines Artikels in einem zurückgehaltenen
+// Bestandsstatus und den Gesamtbestand atomar um delta und gibt den Gesamtbestand vor und
+// nach der Änderung zurück. Ohne allowNegative wird eine Entnahme nur gebucht, wenn in dem
+// Status genug Bestand vorhanden ist.
+func (r *ArticleRepository) IncrementHeldStock(articleID primitive.ObjectID, status model.StockStatus, delta float64, allowNegative bool) (float64, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	field, exists := heldStockFields[status]
+	if !exists {
+		return 0, 0, fmt.Errorf("Ungültiger Bestandsstatus: %s", status)
+	}
+
+	filter := bson.M{"_id": articleID}
+	guarded := delta < 0 && !allowNegative
+	if guarded {
+		filter[field] = bson.M{"$gte": -delta}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"stockCurrent": delta, field: delta, "version": 1},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var article model.Article
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&article)
+	if err == mongo.ErrNoDocuments && guarded {
+		count, countErr := r.collection.CountDocuments(ctx, bson.M{"_id": articleID})
+		if countErr != nil {
+			return 0, 0, countErr
+		}
+		if count > 0 {
+			return 0, 0, ErrInsufficientStock
+		}
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return article.StockCurrent, article.StockCurrent + delta, nil
+}
+
+// ChangeStockStatus verschiebt atomar eine Menge eines Artikels zwischen zwei
+// Bestandsstatus, ohne den Gesamtbestand zu verändern. Verfügbarer Bestand wird nur
+// verschoben, soweit er nicht reserviert ist; reicht nur der reservierte Bestand, wird
+// ErrStockReserved zurückgegeben. Zurückgegeben wird der unveränderte Gesamtbestand, wie
+// ihn die Änderung vorgefunden hat.
+func (r *ArticleRepository) ChangeStockStatus(articleID primitive.ObjectID, from, to model.StockStatus, quantity float64) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": articleID}
+	inc := bson.M{"version": 1}
+	if from.IsHeld() {
+		field, exists := heldStockFields[from]
+		if !exists {
+			return 0, fmt.Errorf("Ungültiger Bestandsstatus: %s", from)
+		}
+		filter[field] = bson.M{"$gte": quantity}
+		inc[field] = -quantity
+	} else {
+		filter["$expr"] = bson.M{"$gte": bson.A{availableStock, quantity}}
+	}
+	if to.IsHeld() {
+		field, exists := heldStockFields[to]
+		if !exists {
+			return 0, fmt.Errorf("Ungültiger Bestandsstatus: %s", to)
+		}
+		inc[field] = quantity
+	}
+
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	var article model.Article
+	err := r.collection.FindOneAndUpdate(ctx, filter, update).Decode(&article)
+	if err == mongo.ErrNoDocuments {
+		// Unterscheiden, ob der Artikel fehlt, der Bestand nicht reicht oder reserviert ist
+		var current model.Article
+		if findErr := r.collection.FindOne(ctx, bson.M{"_id": articleID}).Decode(&current); findErr != nil {
+			return 0, findErr
+		}
+		if !from.IsHeld() && current.GetUnrestrictedStock() >= quantity {
+			return 0, ErrStockReserved
+		}
+		return 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return article.StockCurrent, nil
+}
+
+// MarkStockTaken setzt das Datum der letzten Inventur eines Artikels
+func (r *ArticleRepository) MarkStockTaken(articleID primitive.ObjectID, date time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{"lastStockTakeDate": date, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": articleID}, update)
+	return err
+}
+
+// ClearSerialNumberRequired hebt die Seriennummernpflicht eines Artikels auf
+func (r *ArticleRepository) ClearSerialNumberRequired(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{"serialNumberRequired": false, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": articleID}, update)
 	return err
 }
 
+// ApplyReceiptCost verrechnet einen bewerteten Zugang (oder mit negativer Menge dessen Storno)
+// in den gleitenden Durchschnittspreis und gibt den neuen Durchschnittspreis zurück. Der
+// Bestand muss vorher gebucht sein. Der Preis wird nur gespeichert, wenn der Artikel seit dem
+// Lesen unverändert ist; andernfalls wird mit dem aktuellen Stand neu gerechnet.
+func (r *ArticleRepository) ApplyReceiptCost(articleID primitive.ObjectID, quantity, unitPrice float64) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for attempt := 0; attempt < receiptCostAttempts; attempt++ {
+		var article model.Article
+		if err := r.collection.FindOne(ctx, bson.M{"_id": articleID}).Decode(&article); err != nil {
+			return 0, err
+		}
+
+		averageCost := model.MovingAverageCost(article.StockCurrent-quantity, article.GetAverageCost(), quantity, unitPrice)
+
+		update := bson.M{
+			"$set": bson.M{"averageCost": averageCost},
+			"$inc": bson.M{"version": 1},
+		}
+		result, err := r.collection.UpdateOne(ctx, bson.M{"_id": articleID, "version": article.Version}, update)
+		if err != nil {
+			return 0, err
+		}
+		if result.MatchedCount > 0 {
+			return averageCost, nil
+		}
+	}
+
+	return 0, ErrVersionConflict
+}
+
+// SetAverageCost setzt den gleitenden Durchschnittspreis eines Artikels, z.B. um eine
+// fehlgeschlagene Buchung zurückzunehmen
+func (r *ArticleRepository) SetAverageCost(articleID primitive.ObjectID, averageCost float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": articleID}, bson.M{"$set": bson.M{"averageCost": averageCost}})
+	return err
+}
+
+// InitializeAverageCost übernimmt bei Artikeln ohne Durchschnittspreis den Einkaufspreis als
+// Ausgangswert und gibt die Anzahl der geänderten Artikel zurück
+func (r *ArticleRepository) InitializeAverageCost() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"averageCost": "$purchasePriceNet"}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, bson.M{"averageCost": bson.M{"$exists": false}}, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // Delete löscht einen Artikel
 func (r *ArticleRepository) Delete(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -300,15 +713,15 @@ func (r *ArticleRepository) SearchArticles(query string) ([]*model.Article, erro
 	return articles, nil
 }
 
-// FindLowStock findet Artikel, deren Bestand unter dem Mindestbestand liegt
+// FindLowStock findet Artikel, deren frei verwendbarer Bestand unter dem Mindestbestand liegt
 func (r *ArticleRepository) FindLowStock(limit int) ([]*model.Article, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Filter für Artikel mit Bestand unter oder gleich Mindestbestand
+	// Filter für Artikel mit frei verwendbarem Bestand unter oder gleich Mindestbestand
 	filter := bson.M{
 		"$expr": bson.M{
-			"$lte": []interface{}{"$stockCurrent", "$minimumStock"},
+			"$lte": []interface{}{unrestrictedStock, "$minimumStock"},
 		},
 		"minimumStock": bson.M{"$gt": 0}, // Nur Artikel mit definiertem Mindestbestand
 		"isActive":     true,             // Nur aktive Artikel
@@ -383,14 +796,14 @@ func (r *ArticleRepository) FindByStockStatus(status string) ([]*model.Article,
 	case "low":
 		filter = bson.M{
 			"$expr": bson.M{
-				"$lte": []interface{}{"$stockCurrent", "$minimumStock"},
+				"$lte": []interface{}{unrestrictedStock, "$minimumStock"},
 			},
 			"minimumStock": bson.M{"$gt": 0},
 		}
 	case "high":
 		filter = bson.M{
 			"$expr": bson.M{
-				"$gte": []interface{}{"$stockCurrent", "$maximumStock"},
+				"$gte": []interface{}{unrestrictedStock, "$maximumStock"},
 			},
 			"maximumStock": bson.M{"$gt": 0},
 		}
@@ -398,9 +811,9 @@ func (r *ArticleRepository) FindByStockStatus(status string) ([]*model.Article,
 		filter = bson.M{
 			"$expr": bson.M{
 				"$and": []bson.M{
-					{"$gt": []interface{}{"$stockCurrent", "$minimumStock"}},
+					{"$gt": []interface{}{unrestrictedStock, "$minimumStock"}},
 					{"$or": []bson.M{
-						{"$lt": []interface{}{"$stockCurrent", "$maximumStock"}},
+						{"$lt": []interface{}{unrestrictedStock, "$maximumStock"}},
 						{"$eq": []interface{}{"$maximumStock", 0}},
 					}},
 				},
@@ -446,14 +859,14 @@ func (r *ArticleRepository) FindByCategoryAndStockStatus(category, status string
 	case "low":
 		baseFilter = bson.M{
 			"$expr": bson.M{
-				"$lte": []interface{}{"$stockCurrent", "$minimumStock"},
+				"$lte": []interface{}{unrestrictedStock, "$minimumStock"},
 			},
 			"minimumStock": bson.M{"$gt": 0},
 		}
 	case "high":
 		baseFilter = bson.M{
 			"$expr": bson.M{
-				"$gte": []interface{}{"$stockCurrent", "$maximumStock"},
+				"$gte": []interface{}{unrestrictedStock, "$maximumStock"},
 			},
 			"maximumStock": bson.M{"$gt": 0},
 		}
@@ -461,9 +874,9 @@ func (r *ArticleRepository) FindByCategoryAndStockStatus(category, status string
 		baseFilter = bson.M{
 			"$expr": bson.M{
 				"$and": []bson.M{
-					{"$gt": []interface{}{"$stockCurrent", "$minimumStock"}},
+					{"$gt": []interface{}{unrestrictedStock, "$minimumStock"}},
 					{"$or": []bson.M{
-						{"$lt": []interface{}{"$stockCurrent", "$maximumStock"}},
+						{"$lt": []interface{}{unrestrictedStock, "$maximumStock"}},
 						{"$eq": []interface{}{"$maximumStock", 0}},
 					}},
 				},
@@ -516,7 +929,7 @@ func (r *ArticleRepository) CountLowStock() (int64, error) {
 
 	filter := bson.M{
 		"$expr": bson.M{
-			"$lte": []interface{}{"$stockCurrent", "$minimumStock"},
+			"$lte": []interface{}{unrestrictedStock, "$minimumStock"},
 		},
 		"minimumStock": bson.M{"$gt": 0},
 		"isActive":     true,
@@ -559,6 +972,70 @@ func (r *ArticleRepository) GetAllCategories() ([]string, error) {
 	return categories, nil
 }
 
+// GetAllUnits gibt alle bei Artikeln verwendeten Lagereinheiten zurück
+func (r *ArticleRepository) GetAllUnits() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	values, err := r.collection.Distinct(ctx, "unit", bson.M{"unit": bson.M{"$ne": ""}})
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]string, 0, len(values))
+	for _, value := range values {
+		if unit, ok := value.(string); ok {
+			units = append(units, unit)
+		}
+	}
+
+	return units, nil
+}
+
+// CountByUnit zählt die Artikel, die eine Einheit als Lager-, Einkaufs- oder Ausgabeeinheit
+// oder in einer Umrechnung verwenden
+func (r *ArticleRepository) CountByUnit(unit string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{
+		{"unit": unit},
+		{"purchaseUnit": unit},
+		{"issueUnit": unit},
+		{"unitConversions.unit": unit},
+	}}
+
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// GetAllHazardClasses gibt alle bei Artikeln verwendeten Gefahrgutklassen zurück
+func (r *ArticleRepository) GetAllHazardClasses() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	values, err := r.collection.Distinct(ctx, "hazardClass", bson.M{"hazardClass": bson.M{"$ne": ""}})
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]string, 0, len(values))
+	for _, value := range values {
+		if class, ok := value.(string); ok {
+			classes = append(classes, class)
+		}
+	}
+
+	return classes, nil
+}
+
+// CountByHazardClass zählt die Artikel einer Gefahrgutklasse
+func (r *ArticleRepository) CountByHazardClass(code string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"hazardClass": code})
+}
+
 // CountCategories zählt die Anzahl eindeutiger Kategorien
 func (r *ArticleRepository) CountCategories() (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -592,17 +1069,20 @@ func (r *ArticleRepository) CountCategories() (int64, error) {
 	return results[0].Count, nil
 }
 
-// CalculateTotalStockValue berechnet den Gesamtwert aller Artikel im Lager
+// CalculateTotalStockValue berechnet den Gesamtwert aller Artikel im Lager zum gleitenden
+// Durchschnittspreis
 func (r *ArticleRepository) CalculateTotalStockValue() (float64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Aggregation für den Gesamtwert (Bestand * Einkaufspreis)
+	// Aggregation für den Gesamtwert (Bestand * Durchschnittspreis, ersatzweise Einkaufspreis)
 	pipeline := []bson.M{
 		{
 			"$project": bson.M{
 				"value": bson.M{
-					"$multiply": []string{"$stockCurrent", "$purchasePriceNet"},
+					"$multiply": bson.A{"$stockCurrent", bson.M{"$cond": bson.A{
+						bson.M{"$gt": bson.A{"$averageCost", 0}}, "$averageCost", "$purchasePriceNet",
+					}}},
 				},
 			},
 		},
@@ -689,7 +1169,9 @@ func (r *ArticleRepository) GetCategorySummary() (map[string][]interface{}, erro
 				"count": bson.M{"$sum": 1},
 				"totalValue": bson.M{
 					"$sum": bson.M{
-						"$multiply": []string{"$stockCurrent", "$purchasePriceNet"},
+						"$multiply": bson.A{"$stockCurrent", bson.M{"$cond": bson.A{
+							bson.M{"$gt": bson.A{"$averageCost", 0}}, "$averageCost", "$purchasePriceNet",
+						}}},
 					},
 				},
 			},
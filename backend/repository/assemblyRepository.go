@@ -0,0 +1,115 @@
+// backend/repository/assemblyRepository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AssemblyRepository enthält alle Datenbankoperationen für Montagen und Demontagen
+type AssemblyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAssemblyRepository erstellt ein neues AssemblyRepository
+func NewAssemblyRepository() *AssemblyRepository {
+	return &AssemblyRepository{
+		collection: db.GetCollection("assemblies"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Montagenummer und den Index für die
+// Vorgänge eines Sets an
+func (r *AssemblyRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "assemblyNumber", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "kit.articleId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	})
+	return err
+}
+
+// NextAssemblyNumber vergibt die nächste fortlaufende Montagenummer (z.B. MO2026-0001)
+func (r *AssemblyRepository) NextAssemblyNumber() (string, error) {
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("assembly_%d", year))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("MO%d-%04d", year, number), nil
+}
+
+// Create speichert eine gebuchte Montage oder Demontage
+func (r *AssemblyRepository) Create(assembly *model.Assembly) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if assembly.ID.IsZero() {
+		assembly.ID = primitive.NewObjectID()
+	}
+	if assembly.CreatedAt.IsZero() {
+		assembly.CreatedAt = time.Now()
+	}
+
+	_, err := r.collection.InsertOne(ctx, assembly)
+	return err
+}
+
+// FindByID findet eine Montage oder Demontage anhand ihrer ID
+func (r *AssemblyRepository) FindByID(id string) (*model.Assembly, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var assembly model.Assembly
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&assembly); err != nil {
+		return nil, err
+	}
+
+	return &assembly, nil
+}
+
+// FindByKitArticleID findet die letzten Montagen und Demontagen eines Sets, die neuesten zuerst
+func (r *AssemblyRepository) FindByKitArticleID(articleID primitive.ObjectID, limit int64) ([]*model.Assembly, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"kit.articleId": articleID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var assemblies []*model.Assembly
+	if err := cursor.All(ctx, &assemblies); err != nil {
+		return nil, err
+	}
+
+	return assemblies, nil
+}
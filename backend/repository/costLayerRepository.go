@@ -0,0 +1,234 @@
+// backend/repository/costLayerRepository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CostLayerRepository enthält alle Datenbankoperationen für FIFO-Kostenschichten
+type CostLayerRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCostLayerRepository erstellt ein neues CostLayerRepository
+func NewCostLayerRepository() *CostLayerRepository {
+	return &CostLayerRepository{
+		collection: db.GetCollection("cost_layers"),
+	}
+}
+
+// EnsureIndexes legt die Indizes für die FIFO-Reihenfolge und die Suche nach Buchungen an
+func (r *CostLayerRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "articleId", Value: 1}, {Key: "receivedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "transactionId", Value: 1}}},
+		{Keys: bson.D{{Key: "consumptions.transactionId", Value: 1}}},
+	})
+	return err
+}
+
+// Create legt eine neue Kostenschicht an
+func (r *CostLayerRepository) Create(layer *model.CostLayer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if layer.ID.IsZero() {
+		layer.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, layer)
+	return err
+}
+
+// Delete löscht eine Kostenschicht
+func (r *CostLayerRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindOpen findet alle Kostenschichten eines Artikels mit Restmenge, die älteste zuerst
+func (r *CostLayerRepository) FindOpen(articleID primitive.ObjectID) ([]*model.CostLayer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "receivedAt", Value: 1}, {Key: "_id", Value: 1}})
+
+	return r.find(bson.M{"articleId": articleID, "remaining": bson.M{"$gt": 0}}, opts)
+}
+
+// FindByTransactionID findet die Kostenschicht, die durch eine Buchung angelegt wurde
+func (r *CostLayerRepository) FindByTransactionID(transactionID primitive.ObjectID) (*model.CostLayer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var layer model.CostLayer
+	if err := r.collection.FindOne(ctx, bson.M{"transactionId": transactionID}).Decode(&layer); err != nil {
+		return nil, err
+	}
+
+	return &layer, nil
+}
+
+// FindConsumedBy findet alle Kostenschichten, aus denen eine Buchung Menge entnommen hat
+func (r *CostLayerRepository) FindConsumedBy(transactionID primitive.ObjectID) ([]*model.CostLayer, error) {
+	return r.find(bson.M{"consumptions.transactionId": transactionID}, nil)
+}
+
+// FindReceivedUntil findet alle Kostenschichten, die bis zum angegebenen Zeitpunkt zugegangen sind
+func (r *CostLayerRepository) FindReceivedUntil(cutoff time.Time) ([]*model.CostLayer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "articleId", Value: 1}, {Key: "receivedAt", Value: 1}})
+
+	return r.find(bson.M{"receivedAt": bson.M{"$lte": cutoff}}, opts)
+}
+
+// Consume entnimmt einer Kostenschicht atomar eine Menge für eine Buchung. Reicht die
+// Restmenge nicht mehr aus, weil eine parallele Buchung schneller war, wird false zurückgegeben.
+func (r *CostLayerRepository) Consume(layerID, transactionID primitive.ObjectID, quantity float64, timestamp time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": layerID, "remaining": bson.M{"$gte": quantity}},
+		bson.M{
+			"$inc": bson.M{"remaining": -quantity},
+			"$push": bson.M{"consumptions": model.CostLayerConsumption{
+				TransactionID: transactionID,
+				Quantity:      quantity,
+				Timestamp:     timestamp,
+			}},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// Restore gibt einer Kostenschicht bei einem Storno Menge zurück. Die Rückgabe wird als
+// negativer Verbrauch der Stornobuchung eingetragen, damit Stichtagsbewertungen stimmen.
+func (r *CostLayerRepository) Restore(layerID, transactionID primitive.ObjectID, quantity float64, timestamp time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": layerID},
+		bson.M{
+			"$inc": bson.M{"remaining": quantity},
+			"$push": bson.M{"consumptions": model.CostLayerConsumption{
+				TransactionID: transactionID,
+				Quantity:      -quantity,
+				Timestamp:     timestamp,
+			}},
+		},
+	)
+	return err
+}
+
+// RemoveConsumption nimmt den Verbrauch einer Buchung aus einer Kostenschicht vollständig
+// zurück, z.B. wenn die Buchung nicht gespeichert werden konnte
+func (r *CostLayerRepository) RemoveConsumption(layerID, transactionID primitive.ObjectID, quantity float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": layerID},
+		bson.M{
+			"$inc":  bson.M{"remaining": quantity},
+			"$pull": bson.M{"consumptions": bson.M{"transactionId": transactionID}},
+		},
+	)
+	return err
+}
+
+// FindArticleIDs gibt die IDs aller Artikel zurück, für die Kostenschichten existieren
+func (r *CostLayerRepository) FindArticleIDs() (map[primitive.ObjectID]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	values, err := r.collection.Distinct(ctx, "articleId", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[primitive.ObjectID]bool, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids[id] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// SumRemainingValue berechnet den FIFO-Wert aller offenen Kostenschichten
+func (r *CostLayerRepository) SumRemainingValue() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"remaining": bson.M{"$gt": 0}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"total": bson.M{"$sum": bson.M{"$multiply": bson.A{"$remaining", "$unitCost"}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Total float64 `bson:"total"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return result[0].Total, nil
+}
+
+// find führt eine Abfrage aus und dekodiert die Kostenschichten
+func (r *CostLayerRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.CostLayer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var layers []*model.CostLayer
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var layer model.CostLayer
+		if err := cursor.Decode(&layer); err != nil {
+			return nil, err
+		}
+		layers = append(layers, &layer)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return layers, nil
+}
@@ -0,0 +1,215 @@
+// backend/repository/customerOrderRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCustomerOrderStatus wird zurückgegeben, wenn ein Auftrag nicht (mehr) den erwarteten Status hat
+var ErrCustomerOrderStatus = errors.New("Der Auftrag hat zwischenzeitlich seinen Status geändert")
+
+// CustomerOrderRepository enthält alle Datenbankoperationen für Kundenaufträge
+type CustomerOrderRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCustomerOrderRepository erstellt ein neues CustomerOrderRepository
+func NewCustomerOrderRepository() *CustomerOrderRepository {
+	return &CustomerOrderRepository{
+		collection: db.GetCollection("customer_orders"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Auftragsnummer an
+func (r *CustomerOrderRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "orderNumber", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt einen neuen Auftrag mit fortlaufender Auftragsnummer an (z.B. A2026-0001)
+func (r *CustomerOrderRepository) Create(order *model.CustomerOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("customer_order_%d", year))
+	if err != nil {
+		return err
+	}
+
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	order.OrderNumber = fmt.Sprintf("A%d-%04d", year, number)
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	order.Version = 0
+
+	_, err = r.collection.InsertOne(ctx, order)
+	return err
+}
+
+// FindByID findet einen Auftrag anhand seiner ID
+func (r *CustomerOrderRepository) FindByID(id string) (*model.CustomerOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var order model.CustomerOrder
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// FindAll findet alle Aufträge, die neuesten zuerst; ein leerer Status liefert alle
+func (r *CustomerOrderRepository) FindAll(status model.CustomerOrderStatus) ([]*model.CustomerOrder, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(filter, opts)
+}
+
+// Update speichert Kopfdaten und Positionen eines Auftrags im Entwurf. Die Änderung greift
+// nur, wenn der Auftrag seit dem Laden nicht verändert wurde und noch ein Entwurf ist.
+func (r *CustomerOrderRepository) Update(order *model.CustomerOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order.UpdatedAt = time.Now()
+
+	filter := versionFilter(order.ID, order.Version)
+	filter["status"] = model.CustomerOrderStatusDraft
+	order.Version++
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": order})
+	if err != nil {
+		order.Version--
+		return err
+	}
+	if result.MatchedCount == 0 {
+		order.Version--
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Confirm speichert die Reservierungen der Positionen und setzt den Auftrag auf bestätigt.
+// Die Änderung greift nur, wenn der Auftrag seit dem Laden nicht verändert wurde.
+func (r *CustomerOrderRepository) Confirm(order *model.CustomerOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	set := bson.M{
+		"status":      model.CustomerOrderStatusConfirmed,
+		"confirmedAt": now,
+		"updatedAt":   now,
+	}
+	for i := range order.Lines {
+		set[fmt.Sprintf("lines.%d.reservationId", i)] = order.Lines[i].ReservationID
+	}
+
+	filter := versionFilter(order.ID, order.Version)
+	filter["status"] = model.CustomerOrderStatusDraft
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	order.Status = model.CustomerOrderStatusConfirmed
+	order.ConfirmedAt = now
+	order.Version++
+	return nil
+}
+
+// UpdateStatus setzt den Status eines Auftrags, sofern er sich noch in einem der erwarteten
+// Status befindet. Zusätzliche Felder werden in derselben Änderung gesetzt.
+func (r *CustomerOrderRepository) UpdateStatus(
+	id primitive.ObjectID,
+	from []model.CustomerOrderStatus,
+	to model.CustomerOrderStatus,
+	fields bson.M,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$in": from}}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCustomerOrderStatus
+	}
+
+	return nil
+}
+
+// find führt eine Abfrage aus und dekodiert die Aufträge
+func (r *CustomerOrderRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.CustomerOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var orders []*model.CustomerOrder
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order model.CustomerOrder
+		if err := cursor.Decode(&order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
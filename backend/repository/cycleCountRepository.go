@@ -0,0 +1,199 @@
+// backend/repository/cycleCountRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCycleCountStatus wird zurückgegeben, wenn eine Zählung nicht (mehr) den erwarteten Status hat
+var ErrCycleCountStatus = errors.New("Die Zählung hat zwischenzeitlich ihren Status geändert")
+
+// CycleCountRepository enthält alle Datenbankoperationen für Inventurzählungen
+type CycleCountRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCycleCountRepository erstellt ein neues CycleCountRepository
+func NewCycleCountRepository() *CycleCountRepository {
+	return &CycleCountRepository{
+		collection: db.GetCollection("cycle_counts"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Zählnummer an
+func (r *CycleCountRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "countNumber", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt eine neue Zählung mit fortlaufender Zählnummer an (z.B. I2026-0001)
+func (r *CycleCountRepository) Create(count *model.CycleCount) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("cycle_count_%d", year))
+	if err != nil {
+		return err
+	}
+
+	if count.ID.IsZero() {
+		count.ID = primitive.NewObjectID()
+	}
+	count.CountNumber = fmt.Sprintf("I%d-%04d", year, number)
+	if count.CreatedAt.IsZero() {
+		count.CreatedAt = time.Now()
+	}
+	count.UpdatedAt = count.CreatedAt
+	count.Version = 0
+
+	_, err = r.collection.InsertOne(ctx, count)
+	return err
+}
+
+// FindByID findet eine Zählung anhand ihrer ID
+func (r *CycleCountRepository) FindByID(id string) (*model.CycleCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var count model.CycleCount
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&count); err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+// FindAll findet alle Zählungen, die neuesten zuerst; ein leerer Status liefert alle
+func (r *CycleCountRepository) FindAll(status model.CycleCountStatus) ([]*model.CycleCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	var counts []*model.CycleCount
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var count model.CycleCount
+		if err := cursor.Decode(&count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// AddCounts hängt Zählungen an Positionen einer laufenden Zählung an. Die Schlüssel sind die
+// Indizes der Positionen. Zählungen mehrerer Zähler werden nebeneinander gespeichert.
+func (r *CycleCountRepository) AddCounts(id primitive.ObjectID, entries map[int]model.CycleCountEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	push := bson.M{}
+	for index, entry := range entries {
+		push[fmt.Sprintf("items.%d.counts", index)] = entry
+	}
+	update := bson.M{
+		"$push": push,
+		"$set":  bson.M{"updatedAt": time.Now()},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": model.CycleCountStatusCounting}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCycleCountStatus
+	}
+
+	return nil
+}
+
+// UpdateStatus setzt den Status einer Zählung, sofern sie sich noch in einem der erwarteten
+// Status befindet. Zusätzliche Felder werden in derselben Änderung gesetzt.
+func (r *CycleCountRepository) UpdateStatus(
+	id primitive.ObjectID,
+	from []model.CycleCountStatus,
+	to model.CycleCountStatus,
+	fields bson.M,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$in": from}}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCycleCountStatus
+	}
+
+	return nil
+}
+
+// MarkItemPosted vermerkt die Freigabe einer Position und gegebenenfalls die Inventurbuchung
+// ihrer Differenz
+func (r *CycleCountRepository) MarkItemPosted(id primitive.ObjectID, index int, postedAt time.Time, transactionID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		fmt.Sprintf("items.%d.postedAt", index): postedAt,
+		"updatedAt":                             time.Now(),
+	}
+	if !transactionID.IsZero() {
+		set[fmt.Sprintf("items.%d.transactionId", index)] = transactionID
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	})
+	return err
+}
@@ -0,0 +1,28 @@
+// backend/repository/errors.go
+package repository
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrVersionConflict wird zurückgegeben, wenn ein Datensatz seit dem Laden von einem
+// anderen Benutzer geändert wurde und das Speichern dessen Änderungen überschreiben würde
+var ErrVersionConflict = errors.New("Der Datensatz wurde zwischenzeitlich von einem anderen Benutzer geändert")
+
+// versionFilter erzeugt den Filter für ein versioniertes Update. Dokumente, die vor
+// Einführung des Versionszählers angelegt wurden, haben kein version-Feld und gelten als Version 0.
+func versionFilter(id interface{}, version int64) bson.M {
+	if version == 0 {
+		return bson.M{
+			"_id": id,
+			"$or": []bson.M{
+				{"version": 0},
+				{"version": bson.M{"$exists": false}},
+			},
+		}
+	}
+
+	return bson.M{"_id": id, "version": version}
+}
@@ -0,0 +1,143 @@
+// backend/repository/goodsReceiptRepository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GoodsReceiptRepository enthält alle Datenbankoperationen für Wareneingänge
+type GoodsReceiptRepository struct {
+	collection *mongo.Collection
+}
+
+// NewGoodsReceiptRepository erstellt ein neues GoodsReceiptRepository
+func NewGoodsReceiptRepository() *GoodsReceiptRepository {
+	return &GoodsReceiptRepository{
+		collection: db.GetCollection("goods_receipts"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Wareneingangsnummer und den Index für
+// die Wareneingänge einer Bestellung an
+func (r *GoodsReceiptRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "receiptNumber", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "purchaseOrderId", Value: 1}},
+		},
+	})
+	return err
+}
+
+// NextReceiptNumber vergibt die nächste fortlaufende Wareneingangsnummer (z.B. WE2026-0001)
+func (r *GoodsReceiptRepository) NextReceiptNumber() (string, error) {
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("goods_receipt_%d", year))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("WE%d-%04d", year, number), nil
+}
+
+// Create speichert einen gebuchten Wareneingang
+func (r *GoodsReceiptRepository) Create(receipt *model.GoodsReceipt) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if receipt.ID.IsZero() {
+		receipt.ID = primitive.NewObjectID()
+	}
+	if receipt.ReceivedAt.IsZero() {
+		receipt.ReceivedAt = time.Now()
+	}
+
+	_, err := r.collection.InsertOne(ctx, receipt)
+	return err
+}
+
+// FindByID findet einen Wareneingang anhand seiner ID
+func (r *GoodsReceiptRepository) FindByID(id string) (*model.GoodsReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt model.GoodsReceipt
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&receipt); err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+// FindByPurchaseOrderID findet alle Wareneingänge zu einer Bestellung in zeitlicher Reihenfolge
+func (r *GoodsReceiptRepository) FindByPurchaseOrderID(orderID primitive.ObjectID) ([]*model.GoodsReceipt, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "receivedAt", Value: 1}})
+
+	return r.find(bson.M{"purchaseOrderId": orderID}, opts)
+}
+
+// FindRecent findet die letzten Wareneingänge, die neuesten zuerst
+func (r *GoodsReceiptRepository) FindRecent(limit int64) ([]*model.GoodsReceipt, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "receivedAt", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	return r.find(bson.M{}, opts)
+}
+
+// FindByIDs findet die Wareneingänge mit den angegebenen IDs
+func (r *GoodsReceiptRepository) FindByIDs(ids []primitive.ObjectID) ([]*model.GoodsReceipt, error) {
+	if len(ids) == 0 {
+		return []*model.GoodsReceipt{}, nil
+	}
+	return r.find(bson.M{"_id": bson.M{"$in": ids}}, options.Find())
+}
+
+// find führt eine Abfrage aus und dekodiert die Wareneingänge
+func (r *GoodsReceiptRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.GoodsReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var receipts []*model.GoodsReceipt
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var receipt model.GoodsReceipt
+		if err := cursor.Decode(&receipt); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &receipt)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
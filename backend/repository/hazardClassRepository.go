@@ -0,0 +1,162 @@
+// backend/repository/hazardClassRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrHazardClassExists wird zurückgegeben, wenn eine Gefahrgutklasse mit dieser Nummer bereits im Katalog steht
+var ErrHazardClassExists = errors.New("Eine Gefahrgutklasse mit dieser Nummer existiert bereits")
+
+// HazardClassRepository enthält alle Datenbankoperationen für den Gefahrgutkatalog
+type HazardClassRepository struct {
+	collection *mongo.Collection
+}
+
+// NewHazardClassRepository erstellt ein neues HazardClassRepository
+func NewHazardClassRepository() *HazardClassRepository {
+	return &HazardClassRepository{
+		collection: db.GetCollection("hazard_classes"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Klassennummer an
+func (r *HazardClassRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt eine neue Gefahrgutklasse an
+func (r *HazardClassRepository) Create(class *model.HazardClass) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if class.ID.IsZero() {
+		class.ID = primitive.NewObjectID()
+	}
+	if class.CreatedAt.IsZero() {
+		class.CreatedAt = time.Now()
+	}
+	class.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, class)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrHazardClassExists
+	}
+	return err
+}
+
+// FindAll gibt alle Gefahrgutklassen sortiert nach Klassennummer zurück
+func (r *HazardClassRepository) FindAll() ([]*model.HazardClass, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "code", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var classes []*model.HazardClass
+	if err := cursor.All(ctx, &classes); err != nil {
+		return nil, err
+	}
+
+	return classes, nil
+}
+
+// FindCatalog gibt den Gefahrgutkatalog mit der Klassennummer als Schlüssel zurück
+func (r *HazardClassRepository) FindCatalog() (map[string]*model.HazardClass, error) {
+	classes, err := r.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[string]*model.HazardClass, len(classes))
+	for _, class := range classes {
+		catalog[class.Code] = class
+	}
+
+	return catalog, nil
+}
+
+// FindByID findet eine Gefahrgutklasse anhand ihrer ID
+func (r *HazardClassRepository) FindByID(id string) (*model.HazardClass, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var class model.HazardClass
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&class); err != nil {
+		return nil, err
+	}
+
+	return &class, nil
+}
+
+// ExistsByCode prüft, ob eine Gefahrgutklasse mit dieser Nummer im Katalog steht
+func (r *HazardClassRepository) ExistsByCode(code string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"code": code})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// SetIncompatible ersetzt die Zusammenlagerungsregeln einer Gefahrgutklasse
+func (r *HazardClassRepository) SetIncompatible(code string, incompatible []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"incompatible": incompatible, "updatedAt": time.Now()}}
+	if len(incompatible) == 0 {
+		update = bson.M{
+			"$set":   bson.M{"updatedAt": time.Now()},
+			"$unset": bson.M{"incompatible": ""},
+		}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"code": code}, update)
+	return err
+}
+
+// Delete löscht eine Gefahrgutklasse und entfernt sie aus den Regeln der übrigen Klassen
+func (r *HazardClassRepository) Delete(class *model.HazardClass) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": class.ID}); err != nil {
+		return err
+	}
+
+	_, err := r.collection.UpdateMany(ctx, bson.M{"incompatible": class.Code}, bson.M{
+		"$pull": bson.M{"incompatible": class.Code},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	return err
+}
@@ -7,20 +7,23 @@ import (
 	"time"
 
 	"StockFlow/backend/db"
+	"StockFlow/backend/model"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 // InitRepository ist für die Initialisierung der Datenbank zuständig
 type InitRepository struct {
-	articleRepo *ArticleRepository
-	userRepo    *UserRepository
+	articleRepo    *ArticleRepository
+	userRepo       *UserRepository
+	stockLevelRepo *StockLevelRepository
 }
 
 // NewInitRepository erstellt ein neues InitRepository
 func NewInitRepository() *InitRepository {
 	return &InitRepository{
-		articleRepo: NewArticleRepository(),
-		userRepo:    NewUserRepository(),
+		articleRepo:    NewArticleRepository(),
+		userRepo:       NewUserRepository(),
+		stockLevelRepo: NewStockLevelRepository(),
 	}
 }
 
@@ -34,6 +37,451 @@ func (r *InitRepository) InitializeDatabase() error {
 		log.Println("Admin-Benutzer wurde überprüft/erstellt")
 	}
 
+	// Bestände je Lagerort vorbereiten
+	if err := r.stockLevelRepo.EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Lagerplatzbestände konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateStockLevels(); err != nil {
+		log.Printf("Warnung: Bestände konnten nicht auf Lagerorte übertragen werden: %v", err)
+	}
+	if err := NewLotRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Chargen konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateLotTracking(); err != nil {
+		log.Printf("Warnung: Eröffnungschargen konnten nicht angelegt werden: %v", err)
+	}
+	if err := NewSerialNumberRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Seriennummern konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateSerialTracking(); err != nil {
+		log.Printf("Warnung: Seriennummernpflicht konnte nicht geprüft werden: %v", err)
+	}
+	if err := NewReservationRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Reservierungen konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewPurchaseOrderRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Bestellungen konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewGoodsReceiptRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Wareneingänge konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewCustomerOrderRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Kundenaufträge konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewCycleCountRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Inventurzählungen konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewAssemblyRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Montagen konnte nicht erstellt werden: %v", err)
+	}
+	if err := NewReturnRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Retouren konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.syncReservedStock(); err != nil {
+		log.Printf("Warnung: Reservierte Bestände konnten nicht abgeglichen werden: %v", err)
+	}
+
+	// Einheitenkatalog vorbereiten
+	if err := NewUnitOfMeasureRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Einheiten konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateUnits(); err != nil {
+		log.Printf("Warnung: Einheitenkatalog konnte nicht angelegt werden: %v", err)
+	}
+
+	// Bezugsquellen vorbereiten
+	if err := NewSupplierArticleRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Bezugsquellen konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateSupplierArticles(); err != nil {
+		log.Printf("Warnung: Bezugsquellen konnten nicht übernommen werden: %v", err)
+	}
+
+	// Gefahrgutkatalog vorbereiten
+	if err := NewHazardClassRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Gefahrgutklassen konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateHazardClasses(); err != nil {
+		log.Printf("Warnung: Gefahrgutkatalog konnte nicht angelegt werden: %v", err)
+	}
+
+	// Lagerbewertung vorbereiten
+	if err := NewCostLayerRepository().EnsureIndexes(); err != nil {
+		log.Printf("Warnung: Index für Kostenschichten konnte nicht erstellt werden: %v", err)
+	}
+	if err := r.migrateValuation(); err != nil {
+		log.Printf("Warnung: Lagerbewertung konnte nicht vorbereitet werden: %v", err)
+	}
+
+	return nil
+}
+
+// migrateStockLevels überträgt den Bestand von Artikeln, die noch keine Bestände je
+// Lagerort besitzen, auf ihren Standardlagerort bzw. auf "Ohne Lagerort"
+func (r *InitRepository) migrateStockLevels() error {
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, article := range articles {
+		if article.StockCurrent == 0 {
+			continue
+		}
+
+		count, err := r.stockLevelRepo.CountByArticleID(article.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := r.stockLevelRepo.Set(article.ID, article.StorageLocationID, article.StockCurrent); err != nil {
+			return err
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("Bestand von %d Artikeln auf Lagerorte übertragen", migrated)
+	}
+
+	return nil
+}
+
+// migrateLotTracking erfasst den Bestand chargenpflichtiger Artikel, der keiner Charge
+// zugeordnet ist, je Lagerort in einer Eröffnungscharge. Solcher Bestand stammt aus der Zeit
+// vor der Chargenverwaltung und ließe sich sonst nicht entnehmen.
+func (r *InitRepository) migrateLotTracking() error {
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	lotRepo := NewLotRepository()
+	migrated := 0
+	for _, article := range articles {
+		if !article.LotTracked || article.StockCurrent <= 0 {
+			continue
+		}
+
+		levels, err := r.stockLevelRepo.FindByArticleID(article.ID)
+		if err != nil {
+			return err
+		}
+		opened, err := lotRepo.OpenStock(article.ID, levels)
+		if err != nil {
+			return err
+		}
+		if opened > 0 {
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		log.Printf("Bestand von %d chargenpflichtigen Artikeln in Eröffnungschargen erfasst", migrated)
+	}
+
+	return nil
+}
+
+// migrateSerialTracking hebt die Seriennummernpflicht von Artikeln auf, deren Bestand nicht
+// vollständig durch erfasste Seriennummern gedeckt ist. Solcher Bestand stammt aus der Zeit vor
+// der Seriennummernverwaltung und ließe sich sonst weder ausbuchen noch umlagern.
+func (r *InitRepository) migrateSerialTracking() error {
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	serialRepo := NewSerialNumberRepository()
+	cleared := 0
+	for _, article := range articles {
+		if !article.SerialNumberRequired || article.StockCurrent <= 0 {
+			continue
+		}
+
+		serials, err := serialRepo.FindAvailableByArticleID(article.ID)
+		if err != nil {
+			return err
+		}
+		if float64(len(serials)) >= article.StockCurrent {
+			continue
+		}
+
+		if err := r.articleRepo.ClearSerialNumberRequired(article.ID); err != nil {
+			return err
+		}
+		log.Printf("Seriennummernpflicht für Artikel %s aufgehoben: %g Stück im Bestand, %d Seriennummern erfasst",
+			article.ArticleNumber, article.StockCurrent, len(serials))
+		cleared++
+	}
+
+	if cleared > 0 {
+		log.Printf("Seriennummernpflicht bei %d Artikeln mit Bestand ohne Seriennummern aufgehoben", cleared)
+	}
+
+	return nil
+}
+
+// syncReservedStock gleicht den reservierten Bestand aller Artikel mit den offenen Mengen
+// ihrer aktiven Reservierungen ab. Von Hand eingetragene Werte ohne Reservierung entfallen.
+func (r *InitRepository) syncReservedStock() error {
+	totals, err := NewReservationRepository().SumActiveByArticle()
+	if err != nil {
+		return err
+	}
+
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	synced := 0
+	for _, article := range articles {
+		if article.StockReserved == totals[article.ID] {
+			continue
+		}
+		if err := r.articleRepo.SetReserved(article.ID, totals[article.ID]); err != nil {
+			return err
+		}
+		synced++
+	}
+
+	if synced > 0 {
+		log.Printf("Reservierter Bestand von %d Artikeln mit den Reservierungen abgeglichen", synced)
+	}
+
+	return nil
+}
+
+// migrateValuation übernimmt bei Artikeln ohne Durchschnittspreis den Einkaufspreis und legt
+// für vorhandenen Bestand ohne Kostenschichten einen Eröffnungsbestand zum Durchschnittspreis an
+func (r *InitRepository) migrateValuation() error {
+	initialized, err := r.articleRepo.InitializeAverageCost()
+	if err != nil {
+		return err
+	}
+	if initialized > 0 {
+		log.Printf("Durchschnittspreis von %d Artikeln aus dem Einkaufspreis übernommen", initialized)
+	}
+
+	costLayerRepo := NewCostLayerRepository()
+	layered, err := costLayerRepo.FindArticleIDs()
+	if err != nil {
+		return err
+	}
+
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	opened := 0
+	now := time.Now()
+	for _, article := range articles {
+		if article.StockCurrent <= 0 || layered[article.ID] {
+			continue
+		}
+
+		err := costLayerRepo.Create(&model.CostLayer{
+			ArticleID:  article.ID,
+			ReceivedAt: now,
+			Quantity:   article.StockCurrent,
+			Remaining:  article.StockCurrent,
+			UnitCost:   article.GetAverageCost(),
+		})
+		if err != nil {
+			return err
+		}
+		opened++
+	}
+
+	if opened > 0 {
+		log.Printf("Eröffnungsbestand für die FIFO-Bewertung von %d Artikeln angelegt", opened)
+	}
+
+	return nil
+}
+
+// migrateUnits legt einen leeren Einheitenkatalog mit den Standardeinheiten an und übernimmt
+// alle bei Artikeln verwendeten Lagereinheiten, die noch nicht im Katalog stehen
+func (r *InitRepository) migrateUnits() error {
+	unitRepo := NewUnitOfMeasureRepository()
+
+	existing, err := unitRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	var units []model.UnitOfMeasure
+	if len(existing) == 0 {
+		units = append(units, model.DefaultUnitsOfMeasure...)
+	}
+
+	used, err := r.articleRepo.GetAllUnits()
+	if err != nil {
+		return err
+	}
+	for _, name := range used {
+		units = append(units, model.UnitOfMeasure{Name: name, Symbol: name})
+	}
+
+	added := 0
+	for i := range units {
+		exists, err := unitRepo.ExistsByName(units[i].Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := unitRepo.Create(&units[i]); err != nil && err != ErrUnitExists {
+			return err
+		}
+		added++
+	}
+
+	if added > 0 {
+		log.Printf("%d Einheiten in den Einheitenkatalog übernommen", added)
+	}
+
+	return nil
+}
+
+// migrateSupplierArticles legt für Artikel mit Lieferant, aber ohne Bezugsquelle bei diesem
+// Lieferanten, eine bevorzugte Bezugsquelle aus den Lieferantendaten des Artikels an
+func (r *InitRepository) migrateSupplierArticles() error {
+	supplierArticleRepo := NewSupplierArticleRepository()
+	supplierRepo := NewSupplierRepository()
+
+	articles, err := r.articleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, article := range articles {
+		if article.SupplierID.IsZero() {
+			continue
+		}
+
+		existing, err := supplierArticleRepo.FindByArticleID(article.ID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue
+		}
+
+		supplier, err := supplierRepo.FindByID(article.SupplierID.Hex())
+		if err != nil {
+			continue // Lieferant existiert nicht mehr
+		}
+
+		unit := article.GetPurchaseUnit()
+		supplierArticle := &model.SupplierArticle{
+			SupplierID:            supplier.ID,
+			SupplierName:          supplier.Name,
+			ArticleID:             article.ID,
+			ArticleNumber:         article.ArticleNumber,
+			ArticleName:           article.ShortName,
+			SupplierArticleNumber: article.SupplierArticleNumber,
+			Unit:                  unit,
+			LeadTimeDays:          article.DeliveryTimeInDays,
+			IsPreferred:           true,
+		}
+		if price := article.GetPurchasePriceFor(unit); price > 0 {
+			validFrom := article.CreatedAt
+			if validFrom.IsZero() {
+				validFrom = time.Now()
+			}
+			supplierArticle.Prices = []model.SupplierPrice{{
+				PurchasePriceNet: price,
+				ValidFrom:        validFrom,
+				ChangedAt:        time.Now(),
+				Notes:            "Aus den Artikelstammdaten übernommen",
+			}}
+		}
+
+		if err := supplierArticleRepo.Create(supplierArticle); err != nil && err != ErrSupplierArticleExists {
+			return err
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("Bezugsquellen für %d Artikel aus den Artikelstammdaten übernommen", migrated)
+	}
+
+	return nil
+}
+
+// migrateHazardClasses legt einen leeren Gefahrgutkatalog mit den ADR-Klassen an und übernimmt
+// alle bei Artikeln erfassten Gefahrgutklassen, die noch nicht im Katalog stehen. Beim Anlegen
+// des Katalogs werden auf den bestehenden Hauptlagern alle Klassen freigegeben, damit bisher
+// erlaubte Buchungen nicht an den neuen Lagerregeln scheitern, bis ein Administrator die
+// Freigaben einschränkt.
+func (r *InitRepository) migrateHazardClasses() error {
+	hazardRepo := NewHazardClassRepository()
+
+	existing, err := hazardRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	var classes []model.HazardClass
+	if len(existing) == 0 {
+		classes = append(classes, model.DefaultHazardClasses...)
+	}
+
+	used, err := r.articleRepo.GetAllHazardClasses()
+	if err != nil {
+		return err
+	}
+	for _, code := range used {
+		classes = append(classes, model.HazardClass{Code: code, Name: code})
+	}
+
+	added := 0
+	for i := range classes {
+		exists, err := hazardRepo.ExistsByCode(classes[i].Code)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := hazardRepo.Create(&classes[i]); err != nil && err != ErrHazardClassExists {
+			return err
+		}
+		added++
+	}
+
+	if added > 0 {
+		log.Printf("%d Gefahrgutklassen in den Gefahrgutkatalog übernommen", added)
+	}
+
+	if len(existing) > 0 || added == 0 {
+		return nil
+	}
+
+	catalog, err := hazardRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	codes := make([]string, len(catalog))
+	for i, class := range catalog {
+		codes[i] = class.Code
+	}
+	released, err := NewLocationRepository().InitializeHazardClasses(codes)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		log.Printf("Alle Gefahrgutklassen auf %d bestehenden Hauptlagern freigegeben", released)
+	}
+
 	return nil
 }
 
@@ -180,12 +180,27 @@ func (r *LocationRepository) Update(location *model.Location) error {
 	// UpdatedAt-Zeitstempel aktualisieren
 	location.UpdatedAt = time.Now()
 
-	_, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": location.ID},
-		bson.M{"$set": location},
-	)
-	return err
+	// Nur speichern, wenn der Lagerort seit dem Laden nicht verändert wurde
+	filter := versionFilter(location.ID, location.Version)
+	location.Version++
+
+	// Eine geleerte Gefahrgutfreigabe wird entfernt, damit wieder die des übergeordneten Orts gilt
+	update := bson.M{"$set": location}
+	if len(location.HazardClasses) == 0 {
+		update["$unset"] = bson.M{"hazardClasses": ""}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		location.Version--
+		return err
+	}
+	if result.MatchedCount == 0 {
+		location.Version--
+		return ErrVersionConflict
+	}
+
+	return nil
 }
 
 // Delete löscht einen Lagerort
@@ -211,6 +226,41 @@ func (r *LocationRepository) Delete(id string) error {
 	return err
 }
 
+// CountByHazardClass zählt die Lagerorte, die eine Gefahrgutklasse freigeben
+func (r *LocationRepository) CountByHazardClass(code string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"hazardClasses": code})
+}
+
+// InitializeHazardClasses gibt auf allen Hauptlagern ohne eigene Gefahrgutfreigabe die
+// angegebenen Gefahrgutklassen frei und gibt die Anzahl der geänderten Lagerorte zurück
+func (r *LocationRepository) InitializeHazardClasses(codes []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"hazardClasses": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"parentId": bson.M{"$exists": false}},
+			{"parentId": nil},
+			{"parentId": primitive.NilObjectID},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"hazardClasses": codes, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // BuildLocationTree erstellt einen hierarchischen Baum aus Lagerorten
 func (r *LocationRepository) BuildLocationTree() (map[primitive.ObjectID]*model.Location, error) {
 	// Alle Lagerorte laden
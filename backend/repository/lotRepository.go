@@ -0,0 +1,214 @@
+// backend/repository/lotRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInsufficientLotStock wird zurückgegeben, wenn eine Charge nicht genug Bestand hat
+var ErrInsufficientLotStock = errors.New("Nicht genügend Bestand in der Charge vorhanden")
+
+// lotEpsilon gleicht Rundungsfehler beim Vergleich von Chargen- und Lagerplatzmengen aus
+const lotEpsilon = 1e-9
+
+// LotRepository enthält alle Datenbankoperationen für Chargen
+type LotRepository struct {
+	collection *mongo.Collection
+}
+
+// NewLotRepository erstellt ein neues LotRepository
+func NewLotRepository() *LotRepository {
+	return &LotRepository{
+		collection: db.GetCollection("lots"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf Artikel, Lagerort und Chargennummer an
+func (r *LotRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "articleId", Value: 1},
+			{Key: "locationId", Value: 1},
+			{Key: "lotNumber", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Increment verändert die Menge einer Charge an einem Lagerort atomar um delta und gibt die
+// Charge nach der Änderung zurück. Zugänge legen die Charge bei Bedarf mit dem angegebenen
+// Ablaufdatum an. Ohne allowNegative wird eine Entnahme nur gebucht, wenn die Charge genug
+// Bestand hat.
+func (r *LotRepository) Increment(
+	articleID, locationID primitive.ObjectID,
+	lotNumber string,
+	expiryDate time.Time,
+	delta float64,
+	allowNegative bool,
+) (*model.Lot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"articleId": articleID, "locationId": locationID, "lotNumber": lotNumber}
+	guarded := delta < 0 && !allowNegative
+	if guarded {
+		// Der Filter greift nur, wenn genug Bestand in der Charge vorhanden ist
+		filter["quantity"] = bson.M{"$gte": -delta}
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$inc": bson.M{"quantity": delta},
+		"$set": bson.M{"updatedAt": now},
+		"$setOnInsert": bson.M{
+			"expiryDate": expiryDate,
+			"receivedAt": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetUpsert(!guarded)
+
+	var lot model.Lot
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lot)
+	if err == mongo.ErrNoDocuments && guarded {
+		return nil, ErrInsufficientLotStock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &lot, nil
+}
+
+// OpenStock erfasst Bestand eines Artikels, der an einem Lagerort nicht durch Chargen gedeckt
+// ist, in einer Eröffnungscharge ohne Ablaufdatum und gibt die Anzahl der Lagerorte zurück,
+// an denen Bestand erfasst wurde
+func (r *LotRepository) OpenStock(articleID primitive.ObjectID, levels []*model.StockLevel) (int, error) {
+	opened := 0
+	for _, level := range levels {
+		lots, err := r.FindAvailable(articleID, level.LocationID)
+		if err != nil {
+			return opened, err
+		}
+		uncovered := level.Quantity
+		for _, lot := range lots {
+			uncovered -= lot.Quantity
+		}
+		if uncovered <= lotEpsilon {
+			continue
+		}
+
+		if _, err := r.Increment(articleID, level.LocationID, model.OpeningLotNumber, time.Time{}, uncovered, false); err != nil {
+			return opened, err
+		}
+		opened++
+	}
+
+	return opened, nil
+}
+
+// FindAvailable findet alle Chargen eines Artikels mit Bestand an einem Lagerort in
+// FEFO-Reihenfolge: zuerst ablaufende Chargen, Chargen ohne Ablaufdatum zuletzt
+func (r *LotRepository) FindAvailable(articleID, locationID primitive.ObjectID) ([]*model.Lot, error) {
+	lots, err := r.find(bson.M{
+		"articleId":  articleID,
+		"locationId": locationID,
+		"quantity":   bson.M{"$gt": 0},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sortLotsFEFO(lots)
+	return lots, nil
+}
+
+// FindByArticleID findet alle Chargen eines Artikels mit Bestand in FEFO-Reihenfolge
+func (r *LotRepository) FindByArticleID(articleID primitive.ObjectID) ([]*model.Lot, error) {
+	lots, err := r.find(bson.M{"articleId": articleID, "quantity": bson.M{"$gt": 0}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sortLotsFEFO(lots)
+	return lots, nil
+}
+
+// FindExpiringBefore findet alle Chargen mit Bestand, die vor dem angegebenen Datum ablaufen,
+// einschließlich bereits abgelaufener Chargen
+func (r *LotRepository) FindExpiringBefore(date time.Time) ([]*model.Lot, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expiryDate", Value: 1}})
+
+	return r.find(bson.M{
+		"quantity":   bson.M{"$gt": 0},
+		"expiryDate": bson.M{"$gt": time.Time{}, "$lt": date},
+	}, opts)
+}
+
+// DeleteByArticleID löscht alle Chargen eines Artikels
+func (r *LotRepository) DeleteByArticleID(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"articleId": articleID})
+	return err
+}
+
+// find führt eine Abfrage aus und dekodiert die Chargen
+func (r *LotRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.Lot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var lots []*model.Lot
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var lot model.Lot
+		if err := cursor.Decode(&lot); err != nil {
+			return nil, err
+		}
+		lots = append(lots, &lot)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return lots, nil
+}
+
+// sortLotsFEFO sortiert Chargen nach Ablaufdatum (first expired, first out); Chargen ohne
+// Ablaufdatum werden nach Eingangsdatum ans Ende gestellt
+func sortLotsFEFO(lots []*model.Lot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := lots[i], lots[j]
+		if a.HasExpiry() != b.HasExpiry() {
+			return a.HasExpiry()
+		}
+		if !a.ExpiryDate.Equal(b.ExpiryDate) {
+			return a.ExpiryDate.Before(b.ExpiryDate)
+		}
+		return a.ReceivedAt.Before(b.ReceivedAt)
+	})
+}
@@ -0,0 +1,291 @@
+// backend/repository/purchaseOrderRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrPurchaseOrderStatus wird zurückgegeben, wenn eine Bestellung nicht (mehr) den erwarteten Status hat
+var ErrPurchaseOrderStatus = errors.New("Die Bestellung hat zwischenzeitlich ihren Status geändert")
+
+// PurchaseOrderRepository enthält alle Datenbankoperationen für Bestellungen
+type PurchaseOrderRepository struct {
+	collection *mongo.Collection
+}
+
+// NewPurchaseOrderRepository erstellt ein neues PurchaseOrderRepository
+func NewPurchaseOrderRepository() *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{
+		collection: db.GetCollection("purchase_orders"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Bestellnummer an
+func (r *PurchaseOrderRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "orderNumber", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt eine neue Bestellung mit fortlaufender Bestellnummer an (z.B. B2026-0001)
+func (r *PurchaseOrderRepository) Create(order *model.PurchaseOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("purchase_order_%d", year))
+	if err != nil {
+		return err
+	}
+
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	order.OrderNumber = fmt.Sprintf("B%d-%04d", year, number)
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	order.Version = 0
+
+	_, err = r.collection.InsertOne(ctx, order)
+	return err
+}
+
+// FindByID findet eine Bestellung anhand ihrer ID
+func (r *PurchaseOrderRepository) FindByID(id string) (*model.PurchaseOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var order model.PurchaseOrder
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// FindAll findet alle Bestellungen, die neuesten zuerst; ein leerer Status liefert alle
+func (r *PurchaseOrderRepository) FindAll(status model.PurchaseOrderStatus) ([]*model.PurchaseOrder, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(filter, opts)
+}
+
+// FindBySupplierID findet alle Bestellungen bei einem Lieferanten, die neuesten zuerst
+func (r *PurchaseOrderRepository) FindBySupplierID(supplierID primitive.ObjectID) ([]*model.PurchaseOrder, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(bson.M{"supplierId": supplierID}, opts)
+}
+
+// FindOpenByArticleID findet alle offenen Bestellungen, die einen Artikel enthalten
+func (r *PurchaseOrderRepository) FindOpenByArticleID(articleID primitive.ObjectID) ([]*model.PurchaseOrder, error) {
+	filter := bson.M{
+		"lines.articleId": articleID,
+		"status": bson.M{"$in": []model.PurchaseOrderStatus{
+			model.PurchaseOrderStatusSent,
+			model.PurchaseOrderStatusPartiallyReceived,
+		}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "expectedDate", Value: 1}})
+
+	return r.find(filter, opts)
+}
+
+// Update speichert Kopfdaten und Positionen einer Bestellung im Entwurf. Die Änderung greift
+// nur, wenn die Bestellung seit dem Laden nicht verändert wurde und noch ein Entwurf ist.
+func (r *PurchaseOrderRepository) Update(order *model.PurchaseOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order.UpdatedAt = time.Now()
+
+	filter := versionFilter(order.ID, order.Version)
+	filter["status"] = model.PurchaseOrderStatusDraft
+	order.Version++
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": order})
+	if err != nil {
+		order.Version--
+		return err
+	}
+	if result.MatchedCount == 0 {
+		order.Version--
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// UpdateStatus setzt den Status einer Bestellung, sofern sie sich noch in einem der
+// erwarteten Status befindet. Zusätzliche Felder werden in derselben Änderung gesetzt.
+func (r *PurchaseOrderRepository) UpdateStatus(
+	id primitive.ObjectID,
+	from []model.PurchaseOrderStatus,
+	to model.PurchaseOrderStatus,
+	fields bson.M,
+) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$in": from}}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPurchaseOrderStatus
+	}
+
+	return nil
+}
+
+// UpdateReceipt speichert die gelieferten Mengen und den Status einer offenen Bestellung.
+// Die Änderung gelingt nur, wenn die Bestellung seit dem Laden nicht verändert wurde, sodass
+// gleichzeitige Wareneingänge keine Mengen überschreiben.
+func (r *PurchaseOrderRepository) UpdateReceipt(order *model.PurchaseOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": order.Status, "updatedAt": time.Now()}
+	for i := range order.Lines {
+		set[fmt.Sprintf("lines.%d.receivedQuantity", i)] = order.Lines[i].ReceivedQuantity
+	}
+
+	filter := versionFilter(order.ID, order.Version)
+	filter["status"] = bson.M{"$in": []model.PurchaseOrderStatus{
+		model.PurchaseOrderStatusSent,
+		model.PurchaseOrderStatusPartiallyReceived,
+		model.PurchaseOrderStatusReceived,
+	}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	order.Version++
+	return nil
+}
+
+// SumOnOrderByArticle summiert die noch nicht gelieferten Mengen offener Bestellungen je Artikel
+// in Lagereinheiten
+func (r *PurchaseOrderRepository) SumOnOrderByArticle() (map[primitive.ObjectID]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$in": []model.PurchaseOrderStatus{
+			model.PurchaseOrderStatusSent,
+			model.PurchaseOrderStatusPartiallyReceived,
+		}}}}},
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$lines.articleId",
+			"total": bson.M{"$sum": bson.M{"$multiply": bson.A{
+				bson.M{"$max": bson.A{
+					0,
+					bson.M{"$subtract": bson.A{"$lines.quantity", "$lines.receivedQuantity"}},
+				}},
+				bson.M{"$ifNull": bson.A{"$lines.unitFactor", 1}},
+			}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := make(map[primitive.ObjectID]float64)
+	for cursor.Next(ctx) {
+		var result struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Total float64            `bson:"total"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		totals[result.ID] = result.Total
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// FindByIDs findet die Bestellungen mit den angegebenen IDs
+func (r *PurchaseOrderRepository) FindByIDs(ids []primitive.ObjectID) ([]*model.PurchaseOrder, error) {
+	if len(ids) == 0 {
+		return []*model.PurchaseOrder{}, nil
+	}
+	return r.find(bson.M{"_id": bson.M{"$in": ids}}, options.Find())
+}
+
+// find führt eine Abfrage aus und dekodiert die Bestellungen
+func (r *PurchaseOrderRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.PurchaseOrder, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var orders []*model.PurchaseOrder
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order model.PurchaseOrder
+		if err := cursor.Decode(&order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,283 @@
+// backend/repository/reservationRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrReservationNotActive wird zurückgegeben, wenn eine Reservierung bereits erfüllt, freigegeben oder abgelaufen ist
+var ErrReservationNotActive = errors.New("Die Reservierung ist nicht mehr aktiv")
+
+// ErrReservationConflict wird zurückgegeben, wenn eine Reservierung zwischenzeitlich verändert wurde
+var ErrReservationConflict = errors.New("Die Reservierung wurde zwischenzeitlich verändert")
+
+// reservationEpsilon gleicht Rundungsfehler beim Vergleich von Mengen aus
+const reservationEpsilon = 1e-9
+
+// ReservationRepository enthält alle Datenbankoperationen für Reservierungen
+type ReservationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewReservationRepository erstellt ein neues ReservationRepository
+func NewReservationRepository() *ReservationRepository {
+	return &ReservationRepository{
+		collection: db.GetCollection("reservations"),
+	}
+}
+
+// EnsureIndexes legt den Index für die Suche nach aktiven Reservierungen eines Artikels an
+func (r *ReservationRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "articleId", Value: 1}, {Key: "status", Value: 1}},
+	})
+	return err
+}
+
+// Create legt eine neue Reservierung an
+func (r *ReservationRepository) Create(reservation *model.Reservation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if reservation.ID.IsZero() {
+		reservation.ID = primitive.NewObjectID()
+	}
+	reservation.CreatedAt = time.Now()
+	reservation.UpdatedAt = reservation.CreatedAt
+
+	_, err := r.collection.InsertOne(ctx, reservation)
+	return err
+}
+
+// FindByID findet eine Reservierung anhand ihrer ID
+func (r *ReservationRepository) FindByID(id string) (*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation model.Reservation
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&reservation); err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// FindActive findet alle aktiven Reservierungen, die zuerst ablaufenden zuerst
+func (r *ReservationRepository) FindActive() ([]*model.Reservation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expiresAt", Value: 1}, {Key: "createdAt", Value: 1}})
+
+	return r.find(bson.M{"status": model.ReservationStatusActive}, opts)
+}
+
+// FindClosed findet die zuletzt erfüllten, freigegebenen oder abgelaufenen Reservierungen
+func (r *ReservationRepository) FindClosed(limit int) ([]*model.Reservation, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "closedAt", Value: -1}}).
+		SetLimit(int64(limit))
+
+	return r.find(bson.M{"status": bson.M{"$ne": model.ReservationStatusActive}}, opts)
+}
+
+// FindActiveByArticleID findet alle aktiven Reservierungen eines Artikels
+func (r *ReservationRepository) FindActiveByArticleID(articleID primitive.ObjectID) ([]*model.Reservation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	return r.find(bson.M{"articleId": articleID, "status": model.ReservationStatusActive}, opts)
+}
+
+// FindExpired findet alle aktiven Reservierungen, deren Ablaufdatum erreicht ist. Ist
+// articleID gesetzt, werden nur Reservierungen dieses Artikels berücksichtigt.
+func (r *ReservationRepository) FindExpired(articleID primitive.ObjectID, at time.Time) ([]*model.Reservation, error) {
+	filter := bson.M{
+		"status":    model.ReservationStatusActive,
+		"expiresAt": bson.M{"$gt": time.Time{}, "$lte": at},
+	}
+	if !articleID.IsZero() {
+		filter["articleId"] = articleID
+	}
+
+	return r.find(filter, nil)
+}
+
+// Consume verbucht eine Ausgabe auf eine aktive Reservierung und gibt die davon gedeckte
+// Menge zurück (höchstens die offene Menge). Ist die Reservierung danach vollständig
+// ausgegeben, gilt sie als erfüllt.
+func (r *ReservationRepository) Consume(id, articleID primitive.ObjectID, quantity float64) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reservation model.Reservation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "articleId": articleID}).Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrReservationNotActive
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !reservation.IsActive() {
+		return 0, ErrReservationNotActive
+	}
+
+	taken := quantity
+	if open := reservation.GetOpenQuantity(); taken > open {
+		taken = open
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"fulfilledQuantity": reservation.FulfilledQuantity + taken,
+		"updatedAt":         now,
+	}
+	if reservation.GetOpenQuantity()-taken <= reservationEpsilon {
+		set["status"] = model.ReservationStatusFulfilled
+		set["closedAt"] = now
+	}
+
+	// Nur buchen, wenn die Reservierung seit dem Laden nicht verändert wurde
+	filter := bson.M{
+		"_id":               id,
+		"status":            model.ReservationStatusActive,
+		"fulfilledQuantity": reservation.FulfilledQuantity,
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		return 0, ErrReservationConflict
+	}
+
+	return taken, nil
+}
+
+// Reopen nimmt eine mit Consume verbuchte Ausgabe zurück und setzt die Reservierung wieder aktiv
+func (r *ReservationRepository) Reopen(id primitive.ObjectID, quantity float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$inc":   bson.M{"fulfilledQuantity": -quantity},
+		"$set":   bson.M{"status": model.ReservationStatusActive, "updatedAt": time.Now()},
+		"$unset": bson.M{"closedAt": ""},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// Close beendet eine aktive Reservierung mit dem angegebenen Status und gibt sie im Zustand
+// vor dem Schließen zurück
+func (r *ReservationRepository) Close(id primitive.ObjectID, status model.ReservationStatus) (*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"status": status, "closedAt": now, "updatedAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var reservation model.Reservation
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": model.ReservationStatusActive}, update, opts).
+		Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReservationNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// SumActiveByArticle summiert die offenen Mengen aller aktiven Reservierungen je Artikel
+func (r *ReservationRepository) SumActiveByArticle() (map[primitive.ObjectID]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": model.ReservationStatusActive}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$articleId",
+			"total": bson.M{"$sum": bson.M{"$subtract": bson.A{"$quantity", "$fulfilledQuantity"}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := make(map[primitive.ObjectID]float64)
+	for cursor.Next(ctx) {
+		var result struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Total float64            `bson:"total"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		totals[result.ID] = result.Total
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// DeleteByArticleID löscht alle Reservierungen eines Artikels
+func (r *ReservationRepository) DeleteByArticleID(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"articleId": articleID})
+	return err
+}
+
+// find führt eine Abfrage aus und dekodiert die Reservierungen
+func (r *ReservationRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reservations []*model.Reservation
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var reservation model.Reservation
+		if err := cursor.Decode(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &reservation)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
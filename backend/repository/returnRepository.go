@@ -0,0 +1,231 @@
+// backend/repository/returnRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrReturnStatus wird zurückgegeben, wenn eine Retoure nicht (mehr) den erwarteten Status hat
+var ErrReturnStatus = errors.New("Die Retoure hat zwischenzeitlich ihren Status geändert")
+
+// ReturnRepository enthält alle Datenbankoperationen für Retouren
+type ReturnRepository struct {
+	collection *mongo.Collection
+}
+
+// NewReturnRepository erstellt ein neues ReturnRepository
+func NewReturnRepository() *ReturnRepository {
+	return &ReturnRepository{
+		collection: db.GetCollection("returns"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf die Retourennummer und den Index für die
+// Retouren zu einer Originalbuchung an
+func (r *ReturnRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "returnNumber", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "lines.originalTransactionId", Value: 1}},
+		},
+	})
+	return err
+}
+
+// Create legt eine neue Retoure mit fortlaufender Retourennummer an (z.B. RMA2026-0001)
+func (r *ReturnRepository) Create(returnCase *model.ReturnCase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	year := time.Now().Year()
+	number, err := nextSequence(fmt.Sprintf("return_%d", year))
+	if err != nil {
+		return err
+	}
+
+	if returnCase.ID.IsZero() {
+		returnCase.ID = primitive.NewObjectID()
+	}
+	returnCase.ReturnNumber = fmt.Sprintf("RMA%d-%04d", year, number)
+	returnCase.Status = model.ReturnStatusOpen
+	returnCase.CreatedAt = time.Now()
+	returnCase.UpdatedAt = returnCase.CreatedAt
+	returnCase.Version = 0
+
+	_, err = r.collection.InsertOne(ctx, returnCase)
+	return err
+}
+
+// FindByID findet eine Retoure anhand ihrer ID
+func (r *ReturnRepository) FindByID(id string) (*model.ReturnCase, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var returnCase model.ReturnCase
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&returnCase); err != nil {
+		return nil, err
+	}
+
+	return &returnCase, nil
+}
+
+// FindAll findet alle Retouren, die neuesten zuerst; leere Angaben filtern nicht
+func (r *ReturnRepository) FindAll(returnType model.ReturnType, status model.ReturnStatus) ([]*model.ReturnCase, error) {
+	filter := bson.M{}
+	if returnType != "" {
+		filter["type"] = returnType
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(filter, opts)
+}
+
+// FindBySource findet die Retouren zu einem Auftrag oder Wareneingang, die neuesten zuerst
+func (r *ReturnRepository) FindBySource(sourceID primitive.ObjectID) ([]*model.ReturnCase, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"customerOrderId": sourceID},
+		{"goodsReceiptId": sourceID},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	return r.find(filter, opts)
+}
+
+// SumReturnedQuantities summiert je Originalbuchung die Mengen aller nicht stornierten
+// Retouren, optional ohne die angegebene Retoure
+func (r *ReturnRepository) SumReturnedQuantities(transactionIDs []primitive.ObjectID, excludeID primitive.ObjectID) (map[primitive.ObjectID]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := make(map[primitive.ObjectID]float64, len(transactionIDs))
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	match := bson.M{
+		"status":                      bson.M{"$ne": model.ReturnStatusCancelled},
+		"lines.originalTransactionId": bson.M{"$in": transactionIDs},
+	}
+	if !excludeID.IsZero() {
+		match["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$match", Value: bson.M{"lines.originalTransactionId": bson.M{"$in": transactionIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$lines.originalTransactionId",
+			"quantity": bson.M{"$sum": "$lines.quantity"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Quantity float64            `bson:"quantity"`
+		}
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		result[entry.ID] = entry.Quantity
+	}
+
+	return result, cursor.Err()
+}
+
+// UpdateStatus setzt den Status einer Retoure, sofern sie sich noch im erwarteten Status
+// befindet. Zusätzliche Felder werden in derselben Änderung gesetzt.
+func (r *ReturnRepository) UpdateStatus(id primitive.ObjectID, from, to model.ReturnStatus, fields bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": to, "updatedAt": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrReturnStatus
+	}
+
+	return nil
+}
+
+// UpdateLines speichert die Positionen einer Retoure mit dem Ergebnis der Zustandsprüfung
+func (r *ReturnRepository) UpdateLines(id primitive.ObjectID, lines []model.ReturnLine) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"lines": lines, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	})
+	return err
+}
+
+// find führt eine Abfrage aus und dekodiert die Retouren
+func (r *ReturnRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.ReturnCase, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var returns []*model.ReturnCase
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var returnCase model.ReturnCase
+		if err := cursor.Decode(&returnCase); err != nil {
+			return nil, err
+		}
+		returns = append(returns, &returnCase)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return returns, nil
+}
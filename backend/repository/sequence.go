@@ -0,0 +1,35 @@
+// backend/repository/sequence.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"StockFlow/backend/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// nextSequence gibt den nächsten Wert des benannten Zählers zurück. Der Zähler wird atomar
+// erhöht, sodass gleichzeitig angelegte Belege keine doppelten Nummern erhalten.
+func nextSequence(name string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	err := db.GetCollection("counters").
+		FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"value": 1}}, opts).
+		Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Value, nil
+}
@@ -0,0 +1,192 @@
+// backend/repository/serialNumberRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSerialConflict wird zurückgegeben, wenn eine Seriennummer zwischenzeitlich von einer anderen Buchung verändert wurde
+var ErrSerialConflict = errors.New("Die Seriennummer wurde zwischenzeitlich verändert")
+
+// SerialNumberRepository enthält alle Datenbankoperationen für Seriennummern
+type SerialNumberRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSerialNumberRepository erstellt ein neues SerialNumberRepository
+func NewSerialNumberRepository() *SerialNumberRepository {
+	return &SerialNumberRepository{
+		collection: db.GetCollection("serial_numbers"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf Artikel und Seriennummer an
+func (r *SerialNumberRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "articleId", Value: 1}, {Key: "serialNumber", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt eine neue Seriennummer an
+func (r *SerialNumberRepository) Create(serial *model.SerialNumber) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if serial.ID.IsZero() {
+		serial.ID = primitive.NewObjectID()
+	}
+	if serial.CreatedAt.IsZero() {
+		serial.CreatedAt = time.Now()
+	}
+	serial.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, serial)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSerialConflict
+	}
+	return err
+}
+
+// FindByArticleAndNumber findet eine Seriennummer eines Artikels; gibt nil zurück, wenn sie nicht existiert
+func (r *SerialNumberRepository) FindByArticleAndNumber(articleID primitive.ObjectID, serialNumber string) (*model.SerialNumber, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var serial model.SerialNumber
+	err := r.collection.FindOne(ctx, bson.M{"articleId": articleID, "serialNumber": serialNumber}).Decode(&serial)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &serial, nil
+}
+
+// Transition überführt eine Seriennummer in den Zustand des Ereignisses und ergänzt die
+// Historie. Die Änderung greift nur, wenn die Seriennummer seit dem Laden nicht verändert wurde.
+func (r *SerialNumberRepository) Transition(current *model.SerialNumber, event model.SerialEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":               current.ID,
+		"status":            current.Status,
+		"lastTransactionId": current.LastTransactionID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":            event.Status,
+			"locationId":        event.LocationID,
+			"locationName":      event.LocationName,
+			"lastTransactionId": event.TransactionID,
+			"updatedAt":         time.Now(),
+		},
+		"$push": bson.M{"history": event},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSerialConflict
+	}
+
+	return nil
+}
+
+// Restore stellt einen zuvor geladenen Zustand einer Seriennummer wieder her
+func (r *SerialNumberRepository) Restore(serial *model.SerialNumber) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": serial.ID}, serial, opts)
+	return err
+}
+
+// Delete löscht eine Seriennummer
+func (r *SerialNumberRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// Search findet Seriennummern, die mit dem Suchbegriff beginnen (ohne Beachtung der Groß-/Kleinschreibung)
+func (r *SerialNumberRepository) Search(query string, limit int) ([]*model.SerialNumber, error) {
+	filter := bson.M{
+		"serialNumber": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query), Options: "i"},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "serialNumber", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.find(filter, opts)
+}
+
+// FindAvailableByArticleID findet alle Seriennummern eines Artikels, die im Lager sind
+func (r *SerialNumberRepository) FindAvailableByArticleID(articleID primitive.ObjectID) ([]*model.SerialNumber, error) {
+	filter := bson.M{
+		"articleId": articleID,
+		"status":    bson.M{"$in": []model.SerialStatus{model.SerialStatusInStock, model.SerialStatusReturned}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "serialNumber", Value: 1}})
+
+	return r.find(filter, opts)
+}
+
+// DeleteByArticleID löscht alle Seriennummern eines Artikels
+func (r *SerialNumberRepository) DeleteByArticleID(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"articleId": articleID})
+	return err
+}
+
+// find führt eine Abfrage aus und dekodiert die Seriennummern
+func (r *SerialNumberRepository) find(filter bson.M, opts *options.FindOptions) ([]*model.SerialNumber, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var serials []*model.SerialNumber
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var serial model.SerialNumber
+		if err := cursor.Decode(&serial); err != nil {
+			return nil, err
+		}
+		serials = append(serials, &serial)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return serials, nil
+}
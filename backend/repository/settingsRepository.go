@@ -0,0 +1,59 @@
+// backend/repository/settingsRepository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// settingsID ist die ID des einzigen Einstellungsdokuments
+const settingsID = "global"
+
+// SettingsRepository enthält alle Datenbankoperationen für die Systemeinstellungen
+type SettingsRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSettingsRepository erstellt ein neues SettingsRepository
+func NewSettingsRepository() *SettingsRepository {
+	return &SettingsRepository{
+		collection: db.GetCollection("settings"),
+	}
+}
+
+// Get lädt die Einstellungen. Solange keine gespeichert wurden, gelten die Standardwerte.
+func (r *SettingsRepository) Get() (*model.Settings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	settings := model.Settings{ID: settingsID}
+	err := r.collection.FindOne(ctx, bson.M{"_id": settingsID}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if !settings.ValuationMethod.IsValid() {
+		settings.ValuationMethod = model.ValuationMethodMovingAverage
+	}
+
+	return &settings, nil
+}
+
+// Save speichert die Einstellungen
+func (r *SettingsRepository) Save(settings *model.Settings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	settings.ID = settingsID
+	settings.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": settingsID}, settings, options.Replace().SetUpsert(true))
+	return err
+}
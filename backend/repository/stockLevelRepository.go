@@ -0,0 +1,290 @@
+// backend/repository/stockLevelRepository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StockLevelRepository enthält alle Datenbankoperationen für Bestände je Lagerort
+type StockLevelRepository struct {
+	collection *mongo.Collection
+}
+
+// NewStockLevelRepository erstellt ein neues StockLevelRepository
+func NewStockLevelRepository() *StockLevelRepository {
+	return &StockLevelRepository{
+		collection: db.GetCollection("stock_levels"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf Artikel und Lagerort an
+func (r *StockLevelRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "articleId", Value: 1}, {Key: "locationId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// heldQuantity ist der Ausdruck für die an einem Lagerort zurückgehaltene Menge
+var heldQuantity = bson.M{"$add": bson.A{
+	bson.M{"$ifNull": bson.A{"$quarantine", 0}},
+	bson.M{"$ifNull": bson.A{"$blocked", 0}},
+	bson.M{"$ifNull": bson.A{"$damaged", 0}},
+}}
+
+// heldLevelFields sind die Felder der zurückgehaltenen Mengen je Bestandsstatus
+var heldLevelFields = map[model.StockStatus]string{
+	model.StockStatusQuarantine: "quarantine",
+	model.StockStatusBlocked:    "blocked",
+	model.StockStatusDamaged:    "damaged",
+}
+
+// Increment verändert den verfügbaren Bestand eines Artikels an einem Lagerort atomar um
+// delta und gibt den Bestand vor und nach der Änderung zurück. Ohne allowNegative wird eine
+// Entnahme nur gebucht, wenn am Lagerort genug verfügbarer Bestand vorhanden ist; Ware in
+// Quarantäne, gesperrte und beschädigte Ware bleibt unangetastet.
+func (r *StockLevelRepository) Increment(articleID, locationID primitive.ObjectID, delta float64, allowNegative bool) (float64, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"articleId": articleID, "locationId": locationID}
+	guarded := delta < 0 && !allowNegative
+	if guarded {
+		// Der Filter greift nur, wenn genug verfügbarer Bestand am Lagerort vorhanden ist
+		filter["$expr"] = bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$quantity", heldQuantity}}, -delta}}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"quantity": delta},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	// Bei Zugängen wird der Bestandseintrag bei Bedarf angelegt
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetUpsert(!guarded)
+
+	var level model.StockLevel
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&level)
+	if err == mongo.ErrNoDocuments && guarded {
+		return 0, 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return level.Quantity - delta, level.Quantity, nil
+}
+
+// IncrementHeld verändert den Bestand eines Artikels an einem Lagerort in einem
+// zurückgehaltenen Bestandsstatus atomar um delta; der Bestand am Lagerort ändert sich um
+// dieselbe Menge. Gibt den Bestand vor und nach der Änderung zurück. Ohne allowNegative wird
+// eine Entnahme nur gebucht, wenn in dem Status genug Bestand vorhanden ist.
+func (r *StockLevelRepository) IncrementHeld(articleID, locationID primitive.ObjectID, status model.StockStatus, delta float64, allowNegative bool) (float64, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	field, exists := heldLevelFields[status]
+	if !exists {
+		return 0, 0, fmt.Errorf("Ungültiger Bestandsstatus: %s", status)
+	}
+
+	filter := bson.M{"articleId": articleID, "locationId": locationID}
+	guarded := delta < 0 && !allowNegative
+	if guarded {
+		filter[field] = bson.M{"$gte": -delta}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"quantity": delta, field: delta},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetUpsert(!guarded)
+
+	var level model.StockLevel
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&level)
+	if err == mongo.ErrNoDocuments && guarded {
+		return 0, 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return level.Quantity - delta, level.Quantity, nil
+}
+
+// ChangeStatus verschiebt eine Menge eines Artikels an einem Lagerort atomar zwischen zwei
+// Bestandsstatus, ohne den Bestand am Lagerort zu verändern
+func (r *StockLevelRepository) ChangeStatus(articleID, locationID primitive.ObjectID, from, to model.StockStatus, quantity float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"articleId": articleID, "locationId": locationID}
+	inc := bson.M{}
+	if from.IsHeld() {
+		field, exists := heldLevelFields[from]
+		if !exists {
+			return fmt.Errorf("Ungültiger Bestandsstatus: %s", from)
+		}
+		filter[field] = bson.M{"$gte": quantity}
+		inc[field] = -quantity
+	} else {
+		filter["$expr"] = bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$quantity", heldQuantity}}, quantity}}
+	}
+	if to.IsHeld() {
+		field, exists := heldLevelFields[to]
+		if !exists {
+			return fmt.Errorf("Ungültiger Bestandsstatus: %s", to)
+		}
+		inc[field] = quantity
+	}
+
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInsufficientStock
+	}
+
+	return nil
+}
+
+// FindHeldQuantity gibt die an einem Lagerort zurückgehaltene Menge eines Artikels zurück
+func (r *StockLevelRepository) FindHeldQuantity(articleID, locationID primitive.ObjectID) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var level model.StockLevel
+	err := r.collection.FindOne(ctx, bson.M{"articleId": articleID, "locationId": locationID}).Decode(&level)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return level.GetHeldQuantity(), nil
+}
+
+// Set setzt den Bestand eines Artikels an einem Lagerort auf einen absoluten Wert und
+// gibt den vorherigen Bestand zurück
+func (r *StockLevelRepository) Set(articleID, locationID primitive.ObjectID, quantity float64) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"articleId": articleID, "locationId": locationID}
+	update := bson.M{
+		"$set": bson.M{"quantity": quantity, "updatedAt": time.Now()},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.Before).
+		SetUpsert(true)
+
+	var level model.StockLevel
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&level)
+	if err == mongo.ErrNoDocuments {
+		// Der Eintrag wurde neu angelegt, vorher war nichts vorhanden
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return level.Quantity, nil
+}
+
+// FindByArticleID findet alle Bestände eines Artikels
+func (r *StockLevelRepository) FindByArticleID(articleID primitive.ObjectID) ([]*model.StockLevel, error) {
+	return r.find(bson.M{"articleId": articleID, "quantity": bson.M{"$ne": 0}})
+}
+
+// FindByLocationIDs findet alle Bestände an den angegebenen Lagerorten
+func (r *StockLevelRepository) FindByLocationIDs(locationIDs []primitive.ObjectID) ([]*model.StockLevel, error) {
+	return r.find(bson.M{"locationId": bson.M{"$in": locationIDs}, "quantity": bson.M{"$ne": 0}})
+}
+
+// FindAll findet alle Bestände ungleich null
+func (r *StockLevelRepository) FindAll() ([]*model.StockLevel, error) {
+	return r.find(bson.M{"quantity": bson.M{"$ne": 0}})
+}
+
+// CountByArticleID zählt die Bestandseinträge eines Artikels, auch leere
+func (r *StockLevelRepository) CountByArticleID(articleID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"articleId": articleID})
+}
+
+// HasStockAtLocation prüft, ob an einem Lagerort noch Bestand liegt
+func (r *StockLevelRepository) HasStockAtLocation(locationID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"locationId": locationID, "quantity": bson.M{"$ne": 0}})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// DeleteByArticleID löscht alle Bestandseinträge eines Artikels
+func (r *StockLevelRepository) DeleteByArticleID(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"articleId": articleID})
+	return err
+}
+
+// find führt eine Abfrage aus und dekodiert die Bestandseinträge
+func (r *StockLevelRepository) find(filter bson.M) ([]*model.StockLevel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var levels []*model.StockLevel
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var level model.StockLevel
+		if err := cursor.Decode(&level); err != nil {
+			return nil, err
+		}
+		levels = append(levels, &level)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
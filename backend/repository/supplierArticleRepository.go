@@ -0,0 +1,188 @@
+// backend/repository/supplierArticleRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSupplierArticleExists wird zurückgegeben, wenn der Lieferant für den Artikel bereits als Bezugsquelle hinterlegt ist
+var ErrSupplierArticleExists = errors.New("Der Lieferant ist für diesen Artikel bereits als Bezugsquelle hinterlegt")
+
+// SupplierArticleRepository enthält alle Datenbankoperationen für Bezugsquellen
+type SupplierArticleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSupplierArticleRepository erstellt ein neues SupplierArticleRepository
+func NewSupplierArticleRepository() *SupplierArticleRepository {
+	return &SupplierArticleRepository{
+		collection: db.GetCollection("supplier_articles"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf Lieferant und Artikel sowie den Index für die
+// Bezugsquellen eines Artikels an
+func (r *SupplierArticleRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "supplierId", Value: 1}, {Key: "articleId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "articleId", Value: 1}},
+		},
+	})
+	return err
+}
+
+// Create legt eine neue Bezugsquelle an
+func (r *SupplierArticleRepository) Create(supplierArticle *model.SupplierArticle) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if supplierArticle.ID.IsZero() {
+		supplierArticle.ID = primitive.NewObjectID()
+	}
+	if supplierArticle.CreatedAt.IsZero() {
+		supplierArticle.CreatedAt = time.Now()
+	}
+	supplierArticle.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, supplierArticle)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSupplierArticleExists
+	}
+	return err
+}
+
+// Update speichert eine geänderte Bezugsquelle
+func (r *SupplierArticleRepository) Update(supplierArticle *model.SupplierArticle) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	supplierArticle.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": supplierArticle.ID}, supplierArticle)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSupplierArticleExists
+	}
+	return err
+}
+
+// FindByID findet eine Bezugsquelle anhand ihrer ID
+func (r *SupplierArticleRepository) FindByID(id string) (*model.SupplierArticle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var supplierArticle model.SupplierArticle
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&supplierArticle); err != nil {
+		return nil, err
+	}
+
+	return &supplierArticle, nil
+}
+
+// FindBySupplierAndArticle findet die Bezugsquelle eines Artikels bei einem Lieferanten
+func (r *SupplierArticleRepository) FindBySupplierAndArticle(supplierID, articleID primitive.ObjectID) (*model.SupplierArticle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var supplierArticle model.SupplierArticle
+	err := r.collection.FindOne(ctx, bson.M{"supplierId": supplierID, "articleId": articleID}).Decode(&supplierArticle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &supplierArticle, nil
+}
+
+// FindByArticleID gibt die Bezugsquellen eines Artikels zurück, die bevorzugte zuerst
+func (r *SupplierArticleRepository) FindByArticleID(articleID primitive.ObjectID) ([]*model.SupplierArticle, error) {
+	return r.find(bson.M{"articleId": articleID},
+		bson.D{{Key: "isPreferred", Value: -1}, {Key: "supplierName", Value: 1}})
+}
+
+// FindBySupplierID gibt die Bezugsquellen eines Lieferanten sortiert nach Artikelnummer zurück
+func (r *SupplierArticleRepository) FindBySupplierID(supplierID primitive.ObjectID) ([]*model.SupplierArticle, error) {
+	return r.find(bson.M{"supplierId": supplierID}, bson.D{{Key: "articleNumber", Value: 1}})
+}
+
+// FindAll gibt alle Bezugsquellen zurück
+func (r *SupplierArticleRepository) FindAll() ([]*model.SupplierArticle, error) {
+	return r.find(bson.M{}, bson.D{{Key: "articleNumber", Value: 1}, {Key: "supplierName", Value: 1}})
+}
+
+// CountBySupplierID zählt die Bezugsquellen eines Lieferanten
+func (r *SupplierArticleRepository) CountBySupplierID(supplierID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"supplierId": supplierID})
+}
+
+// ClearPreferred nimmt allen übrigen Bezugsquellen eines Artikels die Kennzeichnung als bevorzugt
+func (r *SupplierArticleRepository) ClearPreferred(articleID, exceptID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"articleId": articleID, "_id": bson.M{"$ne": exceptID}, "isPreferred": true},
+		bson.M{"$set": bson.M{"isPreferred": false, "updatedAt": time.Now()}},
+	)
+	return err
+}
+
+// Delete löscht eine Bezugsquelle
+func (r *SupplierArticleRepository) Delete(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// DeleteByArticleID löscht alle Bezugsquellen eines Artikels
+func (r *SupplierArticleRepository) DeleteByArticleID(articleID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"articleId": articleID})
+	return err
+}
+
+// find lädt Bezugsquellen mit dem angegebenen Filter und der angegebenen Sortierung
+func (r *SupplierArticleRepository) find(filter bson.M, sort bson.D) ([]*model.SupplierArticle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	supplierArticles := []*model.SupplierArticle{}
+	if err := cursor.All(ctx, &supplierArticles); err != nil {
+		return nil, err
+	}
+
+	return supplierArticles, nil
+}
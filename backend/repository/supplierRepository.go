@@ -185,12 +185,21 @@ func (r *SupplierRepository) Update(supplier *model.Supplier) error {
 		}
 	}
 
-	_, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": supplier.ID},
-		bson.M{"$set": supplier},
-	)
-	return err
+	// Nur speichern, wenn der Lieferant seit dem Laden nicht verändert wurde
+	filter := versionFilter(supplier.ID, supplier.Version)
+	supplier.Version++
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": supplier})
+	if err != nil {
+		supplier.Version--
+		return err
+	}
+	if result.MatchedCount == 0 {
+		supplier.Version--
+		return ErrVersionConflict
+	}
+
+	return nil
 }
 
 // Delete löscht einen Lieferanten
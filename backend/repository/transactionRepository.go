@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"StockFlow/backend/db"
@@ -14,6 +15,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrAlreadyReversed wird zurückgegeben, wenn eine Buchung bereits storniert wurde
+var ErrAlreadyReversed = errors.New("Die Buchung wurde bereits storniert")
+
 // TransactionRepository enthält alle Datenbankoperationen für das Transaction-Modell
 type TransactionRepository struct {
 	collection *mongo.Collection
@@ -187,6 +191,141 @@ func (r *TransactionRepository) FindByArticleID(articleID string) ([]*model.Tran
 	return transactions, nil
 }
 
+// FindByArticleInPeriod findet die Transaktionen eines Artikels im Zeitraum (start, end], die älteste zuerst
+func (r *TransactionRepository) FindByArticleInPeriod(articleID primitive.ObjectID, start, end time.Time) ([]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	var transactions []*model.Transaction
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"articleId": articleID,
+		"timestamp": bson.M{"$gt": start, "$lte": end},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var transaction model.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// FindRecentByArticleID findet die neuesten n Transaktionen eines Artikels
+func (r *TransactionRepository) FindRecentByArticleID(articleID string, limit int) ([]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Optionen für die Sortierung nach Zeitstempel (absteigend) und Limitierung
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(limit))
+
+	var transactions []*model.Transaction
+	cursor, err := r.collection.Find(ctx, bson.M{"articleId": objID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var transaction model.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// MarkReversed vermerkt an einer Buchung die zugehörige Stornobuchung. Die Markierung
+// erfolgt atomar nur, wenn die Buchung noch nicht storniert wurde.
+func (r *TransactionRepository) MarkReversed(transactionID, reversalID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": transactionID, "reversedBy": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"reversedBy": reversalID}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAlreadyReversed
+	}
+
+	return nil
+}
+
+// UnmarkReversed entfernt die Storno-Markierung wieder, falls die Stornobuchung fehlschlägt
+func (r *TransactionRepository) UnmarkReversed(transactionID, reversalID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": transactionID, "reversedBy": reversalID},
+		bson.M{"$unset": bson.M{"reversedBy": ""}},
+	)
+	return err
+}
+
+// HasInventoryAfter prüft, ob für einen Artikel an einem der Lagerorte nach einem Zeitpunkt eine
+// Inventur gebucht wurde. Eine leere Lagerort-ID steht für Bestand ohne Lagerort (Altdaten).
+func (r *TransactionRepository) HasInventoryAfter(articleID primitive.ObjectID, locationIDs []primitive.ObjectID, since time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if len(locationIDs) == 0 {
+		return false, nil
+	}
+
+	locations := make([]bson.M, 0, len(locationIDs))
+	for _, locationID := range locationIDs {
+		if locationID.IsZero() {
+			locations = append(locations, bson.M{"locationId": bson.M{"$exists": false}})
+			continue
+		}
+		locations = append(locations, bson.M{"locationId": locationID})
+	}
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"articleId": articleID,
+		"type":      model.TransactionTypeInventory,
+		"timestamp": bson.M{"$gt": since},
+		"$or":       locations,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 // FindRecent findet die neuesten n Transaktionen
 func (r *TransactionRepository) FindRecent(limit int) ([]*model.Transaction, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -219,6 +358,162 @@ func (r *TransactionRepository) FindRecent(limit int) ([]*model.Transaction, err
 	return transactions, nil
 }
 
+// FindInPeriod findet alle Transaktionen im Zeitraum [start, end], die älteste zuerst
+func (r *TransactionRepository) FindInPeriod(start, end time.Time) ([]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	var transactions []*model.Transaction
+	cursor, err := r.collection.Find(ctx, bson.M{"timestamp": bson.M{"$gte": start, "$lte": end}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var transaction model.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// SumMovements summiert die Lagerbewegungen im Zeitraum [start, end] je Wert des angegebenen
+// Feldes (type, articleId, userId oder locationId). Zu- und Abgänge ergeben sich aus der
+// Bestandsveränderung der Buchung und werden zu ihrem Stückpreis bewertet. Bei locationId
+// zählen Umlagerungen als Abgang am Quell- und Zugang am Ziellagerort.
+func (r *TransactionRepository) SumMovements(start, end time.Time, field string) ([]*model.MovementTotal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	delta := bson.M{"$subtract": bson.A{"$newStock", "$oldStock"}}
+	unitPrice := bson.M{"$ifNull": bson.A{"$unitPrice", 0}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"timestamp": bson.M{"$gte": start, "$lte": end}}}},
+	}
+
+	if field == "locationId" {
+		// Umlagerungen in Abgang und Zugang aufteilen
+		pipeline = append(pipeline,
+			bson.D{{Key: "$project", Value: bson.M{
+				"unitPrice": unitPrice,
+				"movements": bson.M{"$cond": bson.A{
+					bson.M{"$ifNull": bson.A{"$sourceLocationId", false}},
+					bson.A{
+						bson.M{"key": "$sourceLocationId", "name": "$sourceLocationName", "delta": bson.M{"$multiply": bson.A{"$quantity", -1}}},
+						bson.M{"key": "$targetLocationId", "name": "$targetLocationName", "delta": "$quantity"},
+					},
+					bson.A{
+						bson.M{"key": "$locationId", "name": "$locationName", "delta": delta},
+					},
+				}},
+			}}},
+			bson.D{{Key: "$unwind", Value: "$movements"}},
+			bson.D{{Key: "$project", Value: bson.M{
+				"key":   bson.M{"$toString": "$movements.key"},
+				"name":  "$movements.name",
+				"delta": "$movements.delta",
+				"value": bson.M{"$multiply": bson.A{"$movements.delta", "$unitPrice"}},
+			}}},
+		)
+	} else {
+		name := map[string]string{"articleId": "$articleName", "userId": "$userName"}[field]
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{
+			"key":   bson.M{"$toString": "$" + field},
+			"name":  bson.M{"$ifNull": bson.A{name, ""}},
+			"delta": delta,
+			"value": bson.M{"$multiply": bson.A{delta, unitPrice}},
+		}}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":         "$key",
+			"name":        bson.M{"$last": "$name"},
+			"count":       bson.M{"$sum": 1},
+			"quantityIn":  bson.M{"$sum": bson.M{"$max": bson.A{"$delta", 0}}},
+			"quantityOut": bson.M{"$sum": bson.M{"$max": bson.A{bson.M{"$multiply": bson.A{"$delta", -1}}, 0}}},
+			"valueIn":     bson.M{"$sum": bson.M{"$max": bson.A{"$value", 0}}},
+			"valueOut":    bson.M{"$sum": bson.M{"$max": bson.A{bson.M{"$multiply": bson.A{"$value", -1}}, 0}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*model.MovementTotal
+	for cursor.Next(ctx) {
+		var total model.MovementTotal
+		if err := cursor.Decode(&total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// FindMovements findet die Transaktionen im Zeitraum [start, end], die zusätzlich dem Filter
+// entsprechen, die neueste zuerst. limit begrenzt die Anzahl der geladenen Transaktionen;
+// zurückgegeben wird außerdem die Gesamtzahl der Treffer.
+func (r *TransactionRepository) FindMovements(start, end time.Time, filter bson.M, limit int) ([]*model.Transaction, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := bson.M{"timestamp": bson.M{"$gte": start, "$lte": end}}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(limit))
+
+	var transactions []*model.Transaction
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var transaction model.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return nil, 0, err
+		}
+		transactions = append(transactions, &transaction)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
 // CountSince zählt die Anzahl der Transaktionen seit einem bestimmten Zeitpunkt
 func (r *TransactionRepository) CountSince(since time.Time) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -231,6 +526,245 @@ func (r *TransactionRepository) CountSince(since time.Time) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
 
+// SumStockOutByArticle summiert die nicht stornierten Warenausgänge seit einem Zeitpunkt je Artikel
+func (r *TransactionRepository) SumStockOutByArticle(since time.Time) (map[primitive.ObjectID]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"type":       model.TransactionTypeStockOut,
+			"timestamp":  bson.M{"$gte": since},
+			"reversedBy": bson.M{"$exists": false},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$articleId",
+			"total": bson.M{"$sum": "$quantity"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := make(map[primitive.ObjectID]float64)
+	for cursor.Next(ctx) {
+		var result struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Total float64            `bson:"total"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		totals[result.ID] = result.Total
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// FindGoodsReceiptTransactionsInPeriod findet die nicht stornierten Zugangsbuchungen aus
+// Wareneingängen zu Bestellungen im Zeitraum (beide Grenzen eingeschlossen)
+func (r *TransactionRepository) FindGoodsReceiptTransactionsInPeriod(start, end time.Time) ([]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"type":           model.TransactionTypeStockIn,
+		"goodsReceiptId": bson.M{"$exists": true},
+		"reversedBy":     bson.M{"$exists": false},
+		"timestamp":      bson.M{"$gte": start, "$lte": end},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []*model.Transaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// FindShipmentsByCustomerOrderID findet die nicht stornierten Warenausgänge, mit denen ein
+// Kundenauftrag versendet wurde
+func (r *TransactionRepository) FindShipmentsByCustomerOrderID(orderID primitive.ObjectID) ([]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"type":            model.TransactionTypeStockOut,
+		"customerOrderId": orderID,
+		"reversedBy":      bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []*model.Transaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// FindLastPerArticleUntil gibt je Artikel die letzte Buchung bis einschließlich zum Stichtag zurück
+func (r *TransactionRepository) FindLastPerArticleUntil(cutoff time.Time) (map[primitive.ObjectID]*model.Transaction, error) {
+	return r.findFirstPerArticle(bson.M{"timestamp": bson.M{"$lte": cutoff}}, -1)
+}
+
+// FindFirstPerArticleAfter gibt je Artikel die erste Buchung nach dem Stichtag zurück
+func (r *TransactionRepository) FindFirstPerArticleAfter(cutoff time.Time) (map[primitive.ObjectID]*model.Transaction, error) {
+	return r.findFirstPerArticle(bson.M{"timestamp": bson.M{"$gt": cutoff}}, 1)
+}
+
+// FindLastByArticleUntil findet die letzte Buchung eines Artikels bis einschließlich zum
+// Stichtag und gibt nil zurück, wenn es keine gibt
+func (r *TransactionRepository) FindLastByArticleUntil(articleID primitive.ObjectID, cutoff time.Time) (*model.Transaction, error) {
+	return r.findOneByArticle(bson.M{"articleId": articleID, "timestamp": bson.M{"$lte": cutoff}}, -1)
+}
+
+// FindFirstByArticleAfter findet die erste Buchung eines Artikels nach dem Stichtag und gibt
+// nil zurück, wenn es keine gibt
+func (r *TransactionRepository) FindFirstByArticleAfter(articleID primitive.ObjectID, cutoff time.Time) (*model.Transaction, error) {
+	return r.findOneByArticle(bson.M{"articleId": articleID, "timestamp": bson.M{"$gt": cutoff}}, 1)
+}
+
+// findOneByArticle findet die erste passende Buchung in der angegebenen zeitlichen Sortierrichtung
+func (r *TransactionRepository) findOneByArticle(filter bson.M, direction int) (*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: direction}, {Key: "_id", Value: direction}})
+
+	var transaction model.Transaction
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&transaction)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// findFirstPerArticle gibt je Artikel die erste passende Buchung in der angegebenen
+// zeitlichen Sortierrichtung zurück
+func (r *TransactionRepository) findFirstPerArticle(filter bson.M, direction int) (map[primitive.ObjectID]*model.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: direction}, {Key: "_id", Value: direction}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$articleId",
+			"transaction": bson.M{"$first": "$$ROOT"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := make(map[primitive.ObjectID]*model.Transaction)
+	for cursor.Next(ctx) {
+		var result struct {
+			ID          primitive.ObjectID `bson:"_id"`
+			Transaction model.Transaction  `bson:"transaction"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		transactions[result.ID] = &result.Transaction
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// SummarizeLedger fasst das Buchungsjournal je Artikel zusammen
+func (r *TransactionRepository) SummarizeLedger() (map[primitive.ObjectID]*model.LedgerSummary, error) {
+	return r.summarizeLedger(bson.M{})
+}
+
+// SummarizeLedgerByArticle fasst das Buchungsjournal eines Artikels zusammen und gibt nil
+// zurück, wenn es keine Buchungen gibt
+func (r *TransactionRepository) SummarizeLedgerByArticle(articleID primitive.ObjectID) (*model.LedgerSummary, error) {
+	summaries, err := r.summarizeLedger(bson.M{"articleId": articleID})
+	if err != nil {
+		return nil, err
+	}
+	return summaries[articleID], nil
+}
+
+// summarizeLedger summiert die Bestandsveränderungen der passenden Buchungen je Artikel und
+// ermittelt den Bestand vor der ersten und nach der letzten Buchung
+func (r *TransactionRepository) summarizeLedger(filter bson.M) (map[primitive.ObjectID]*model.LedgerSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$articleId",
+			"count":       bson.M{"$sum": 1},
+			"movementSum": bson.M{"$sum": bson.M{"$subtract": bson.A{"$newStock", "$oldStock"}}},
+			"correctionSum": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$type", model.TransactionTypeCorrection}},
+				bson.M{"$subtract": bson.A{"$newStock", "$oldStock"}},
+				0,
+			}}},
+			"firstOldStock": bson.M{"$first": "$oldStock"},
+			"lastNewStock":  bson.M{"$last": "$newStock"},
+			"lastTimestamp": bson.M{"$last": "$timestamp"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	summaries := make(map[primitive.ObjectID]*model.LedgerSummary)
+	for cursor.Next(ctx) {
+		var summary model.LedgerSummary
+		if err := cursor.Decode(&summary); err != nil {
+			return nil, err
+		}
+		summaries[summary.ArticleID] = &summary
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
 // GetStockMovementSummary berechnet eine Zusammenfassung der Lagerbewegungen pro Monat
 func (r *TransactionRepository) GetStockMovementSummary() (map[string][]float64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -303,8 +837,8 @@ func (r *TransactionRepository) GetStockMovementSummary() (map[string][]float64,
 			stockIn[monthIndex] = result.Total
 		case model.TransactionTypeStockOut:
 			stockOut[monthIndex] = result.Total
-		case model.TransactionTypeAdjust, model.TransactionTypeInventory:
-			adjustment[monthIndex] = result.Total
+		case model.TransactionTypeAdjust, model.TransactionTypeInventory, model.TransactionTypeReversal:
+			adjustment[monthIndex] += result.Total
 		}
 	}
 
@@ -0,0 +1,128 @@
+// backend/repository/unitOfMeasureRepository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"StockFlow/backend/db"
+	"StockFlow/backend/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrUnitExists wird zurückgegeben, wenn eine Einheit mit diesem Namen bereits im Katalog steht
+var ErrUnitExists = errors.New("Eine Einheit mit diesem Namen existiert bereits")
+
+// UnitOfMeasureRepository enthält alle Datenbankoperationen für den Einheitenkatalog
+type UnitOfMeasureRepository struct {
+	collection *mongo.Collection
+}
+
+// NewUnitOfMeasureRepository erstellt ein neues UnitOfMeasureRepository
+func NewUnitOfMeasureRepository() *UnitOfMeasureRepository {
+	return &UnitOfMeasureRepository{
+		collection: db.GetCollection("units"),
+	}
+}
+
+// EnsureIndexes legt den eindeutigen Index auf den Namen der Einheit an
+func (r *UnitOfMeasureRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create legt eine neue Einheit an
+func (r *UnitOfMeasureRepository) Create(unit *model.UnitOfMeasure) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if unit.ID.IsZero() {
+		unit.ID = primitive.NewObjectID()
+	}
+	if unit.CreatedAt.IsZero() {
+		unit.CreatedAt = time.Now()
+	}
+	unit.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, unit)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrUnitExists
+	}
+	return err
+}
+
+// FindAll gibt alle Einheiten sortiert nach Namen zurück
+func (r *UnitOfMeasureRepository) FindAll() ([]*model.UnitOfMeasure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var units []*model.UnitOfMeasure
+	if err := cursor.All(ctx, &units); err != nil {
+		return nil, err
+	}
+
+	return units, nil
+}
+
+// FindByID findet eine Einheit anhand ihrer ID
+func (r *UnitOfMeasureRepository) FindByID(id string) (*model.UnitOfMeasure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var unit model.UnitOfMeasure
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&unit); err != nil {
+		return nil, err
+	}
+
+	return &unit, nil
+}
+
+// ExistsByName prüft, ob eine Einheit mit diesem Namen im Katalog steht
+func (r *UnitOfMeasureRepository) ExistsByName(name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"name": name})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Delete löscht eine Einheit
+func (r *UnitOfMeasureRepository) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	return err
+}
@@ -88,9 +88,9 @@ func InitializeRoutes(router *gin.Engine) {
 
 			for _, article := range allArticles {
 				totalStock += article.StockCurrent
-				totalStockValue += article.StockCurrent * article.PurchasePriceNet
+				totalStockValue += article.GetStockValue()
 
-				if article.StockCurrent < article.MinimumStock && article.MinimumStock > 0 {
+				if article.GetUnrestrictedStock() < article.MinimumStock && article.MinimumStock > 0 {
 					lowStockCount++
 				}
 
@@ -144,6 +144,15 @@ func InitializeRoutes(router *gin.Engine) {
 				case model.ActivityTypeStockTaking:
 					message = fmt.Sprintf("Inventur für <a href=\"/articles/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde durchgeführt",
 						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeStockReversed:
+					message = fmt.Sprintf("Buchung für <a href=\"/articles/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde storniert",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeStockReserved:
+					message = fmt.Sprintf("Bestand für <a href=\"/articles/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde reserviert",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeReservationReleased:
+					message = fmt.Sprintf("Reservierung für <a href=\"/articles/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde freigegeben",
+						activity.TargetID.Hex(), activity.TargetName)
 				case model.ActivityTypeUserAdded:
 					message = fmt.Sprintf("Benutzer <span class=\"font-medium text-gray-900\">%s</span> wurde hinzugefügt",
 						activity.TargetName)
@@ -162,6 +171,36 @@ func InitializeRoutes(router *gin.Engine) {
 				case model.ActivityTypeSupplierDeleted:
 					message = fmt.Sprintf("Lieferant <span class=\"font-medium text-gray-900\">%s</span> wurde entfernt",
 						activity.TargetName)
+				case model.ActivityTypePurchaseOrderCreated:
+					message = fmt.Sprintf("Bestellung <a href=\"/purchase-orders/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde angelegt",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypePurchaseOrderUpdated:
+					message = fmt.Sprintf("<a href=\"/purchase-orders/view/%s\" class=\"font-medium text-gray-900\">%s</a>",
+						activity.TargetID.Hex(), activity.Description)
+				case model.ActivityTypeGoodsReceived:
+					message = fmt.Sprintf("Wareneingang <a href=\"/goods-receipts/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde gebucht",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeAssemblyPosted:
+					message = fmt.Sprintf("<a href=\"/assemblies/view/%s\" class=\"font-medium text-gray-900\">%s</a>",
+						activity.TargetID.Hex(), activity.Description)
+				case model.ActivityTypeCustomerOrderCreated:
+					message = fmt.Sprintf("Auftrag <a href=\"/customer-orders/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde angelegt",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeCustomerOrderUpdated:
+					message = fmt.Sprintf("<a href=\"/customer-orders/view/%s\" class=\"font-medium text-gray-900\">%s</a>",
+						activity.TargetID.Hex(), activity.Description)
+				case model.ActivityTypeReturnCreated:
+					message = fmt.Sprintf("Retoure <a href=\"/returns/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde angelegt",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeReturnUpdated:
+					message = fmt.Sprintf("<a href=\"/returns/view/%s\" class=\"font-medium text-gray-900\">%s</a>",
+						activity.TargetID.Hex(), activity.Description)
+				case model.ActivityTypeCycleCountCreated:
+					message = fmt.Sprintf("Zählung <a href=\"/cycle-counts/view/%s\" class=\"font-medium text-gray-900\">%s</a> wurde angelegt",
+						activity.TargetID.Hex(), activity.TargetName)
+				case model.ActivityTypeCycleCountUpdated:
+					message = fmt.Sprintf("<a href=\"/cycle-counts/view/%s\" class=\"font-medium text-gray-900\">%s</a>",
+						activity.TargetID.Hex(), activity.Description)
 				default:
 					message = activity.Description
 				}
@@ -233,6 +272,12 @@ func InitializeRoutes(router *gin.Engine) {
 
 		// Einstellungsrouten (für alle Benutzer)
 		authorized.GET("/settings", userHandler.ShowSettings)
+		authorized.POST("/settings/valuation", middleware.RoleMiddleware(model.RoleAdmin), userHandler.UpdateValuationMethod)
+		authorized.POST("/settings/units", middleware.RoleMiddleware(model.RoleAdmin), userHandler.CreateUnit)
+		authorized.POST("/settings/units/delete/:id", middleware.RoleMiddleware(model.RoleAdmin), userHandler.DeleteUnit)
+		authorized.POST("/settings/hazard-classes", middleware.RoleMiddleware(model.RoleAdmin), userHandler.CreateHazardClass)
+		authorized.POST("/settings/hazard-classes/delete/:id", middleware.RoleMiddleware(model.RoleAdmin), userHandler.DeleteHazardClass)
+		authorized.POST("/settings/hazard-rules", middleware.RoleMiddleware(model.RoleAdmin), userHandler.UpdateHazardRules)
 
 		// Benutzerverwaltungsrouten (für Administratoren)
 		authorized.POST("/users/add", middleware.RoleMiddleware(model.RoleAdmin), userHandler.AddUser)
@@ -269,6 +314,7 @@ func InitializeRoutes(router *gin.Engine) {
 		authorized.GET("/transactions/add", transactionHandler.ShowAddTransactionForm)
 		authorized.POST("/transactions/add", transactionHandler.AddTransaction)
 		authorized.GET("/transactions/view/:id", transactionHandler.GetTransactionDetails)
+		authorized.POST("/transactions/reverse/:id", transactionHandler.ReverseTransaction)
 
 		// Lieferanten-Routen
 		supplierHandler := handler.NewSupplierHandler()
@@ -280,12 +326,115 @@ func InitializeRoutes(router *gin.Engine) {
 		authorized.POST("/suppliers/edit/:id", supplierHandler.UpdateSupplier)
 		authorized.DELETE("/suppliers/delete/:id", supplierHandler.DeleteSupplier)
 
+		// Bezugsquellen der Artikel mit Preishistorie
+		supplierArticleHandler := handler.NewSupplierArticleHandler()
+		authorized.GET("/supplier-articles/add", supplierArticleHandler.ShowAddSupplierArticleForm)
+		authorized.POST("/supplier-articles/add", supplierArticleHandler.AddSupplierArticle)
+		authorized.GET("/supplier-articles/edit/:id", supplierArticleHandler.ShowEditSupplierArticleForm)
+		authorized.POST("/supplier-articles/edit/:id", supplierArticleHandler.UpdateSupplierArticle)
+		authorized.POST("/supplier-articles/delete/:id", supplierArticleHandler.DeleteSupplierArticle)
+
+		// Reservierungen
+		reservationHandler := handler.NewReservationHandler()
+		authorized.GET("/reservations", reservationHandler.ListReservations)
+		authorized.GET("/reservations/add", reservationHandler.ShowAddReservationForm)
+		authorized.POST("/reservations/add", reservationHandler.AddReservation)
+		authorized.POST("/reservations/release/:id", reservationHandler.ReleaseReservation)
+
+		// Bestellungen
+		purchaseOrderHandler := handler.NewPurchaseOrderHandler()
+		authorized.GET("/purchase-orders", purchaseOrderHandler.ListPurchaseOrders)
+		authorized.GET("/purchase-orders/add", purchaseOrderHandler.ShowAddPurchaseOrderForm)
+		authorized.POST("/purchase-orders/add", purchaseOrderHandler.AddPurchaseOrder)
+		authorized.GET("/purchase-orders/view/:id", purchaseOrderHandler.GetPurchaseOrderDetails)
+		authorized.GET("/purchase-orders/edit/:id", purchaseOrderHandler.ShowEditPurchaseOrderForm)
+		authorized.POST("/purchase-orders/edit/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+		authorized.POST("/purchase-orders/send/:id", purchaseOrderHandler.SendPurchaseOrder)
+		authorized.POST("/purchase-orders/cancel/:id", purchaseOrderHandler.CancelPurchaseOrder)
+
+		// Bestellvorschläge
+		replenishmentHandler := handler.NewReplenishmentHandler()
+		authorized.GET("/purchase-orders/proposals", replenishmentHandler.ShowReorderProposals)
+		authorized.POST("/purchase-orders/proposals", replenishmentHandler.CreatePurchaseOrders)
+
+		// Wareneingänge zu Bestellungen
+		goodsReceiptHandler := handler.NewGoodsReceiptHandler()
+		authorized.GET("/purchase-orders/receive/:id", goodsReceiptHandler.ShowGoodsReceiptForm)
+		authorized.POST("/purchase-orders/receive/:id", goodsReceiptHandler.PostGoodsReceipt)
+		authorized.GET("/goods-receipts/view/:id", goodsReceiptHandler.GetGoodsReceiptDetails)
+
+		// Montage und Demontage von Sets
+		assemblyHandler := handler.NewAssemblyHandler()
+		authorized.GET("/assemblies/add", assemblyHandler.ShowAssemblyForm)
+		authorized.POST("/assemblies/add", assemblyHandler.PostAssembly)
+		authorized.GET("/assemblies/view/:id", assemblyHandler.GetAssemblyDetails)
+
+		// Kundenaufträge
+		customerOrderHandler := handler.NewCustomerOrderHandler()
+		authorized.GET("/customer-orders", customerOrderHandler.ListCustomerOrders)
+		authorized.GET("/customer-orders/add", customerOrderHandler.ShowAddCustomerOrderForm)
+		authorized.POST("/customer-orders/add", customerOrderHandler.AddCustomerOrder)
+		authorized.GET("/customer-orders/view/:id", customerOrderHandler.GetCustomerOrderDetails)
+		authorized.GET("/customer-orders/edit/:id", customerOrderHandler.ShowEditCustomerOrderForm)
+		authorized.POST("/customer-orders/edit/:id", customerOrderHandler.UpdateCustomerOrder)
+		authorized.POST("/customer-orders/confirm/:id", customerOrderHandler.ConfirmCustomerOrder)
+		authorized.POST("/customer-orders/cancel/:id", customerOrderHandler.CancelCustomerOrder)
+		authorized.GET("/customer-orders/pick/:id", customerOrderHandler.ShowPickingList)
+		authorized.POST("/customer-orders/pick/:id", customerOrderHandler.ShipCustomerOrder)
+
+		// Retouren von Kunden und an Lieferanten
+		returnHandler := handler.NewReturnHandler()
+		authorized.GET("/returns", returnHandler.ListReturns)
+		authorized.GET("/returns/add", returnHandler.ShowReturnForm)
+		authorized.POST("/returns/add", returnHandler.AddReturn)
+		authorized.GET("/returns/view/:id", returnHandler.GetReturnDetails)
+		authorized.POST("/returns/post/:id", returnHandler.PostReturn)
+		authorized.POST("/returns/cancel/:id", returnHandler.CancelReturn)
+		authorized.GET("/returns/document/:id", returnHandler.ShowReturnDocument)
+
+		// Inventurzählungen
+		cycleCountHandler := handler.NewCycleCountHandler()
+		authorized.GET("/cycle-counts", cycleCountHandler.ListCycleCounts)
+		authorized.GET("/cycle-counts/add", cycleCountHandler.ShowAddCycleCountForm)
+		authorized.POST("/cycle-counts/add", cycleCountHandler.AddCycleCount)
+		authorized.GET("/cycle-counts/view/:id", cycleCountHandler.GetCycleCountDetails)
+		authorized.GET("/cycle-counts/count/:id", cycleCountHandler.ShowCountSheet)
+		authorized.POST("/cycle-counts/count/:id", cycleCountHandler.RecordCounts)
+		authorized.POST("/cycle-counts/close/:id", cycleCountHandler.CloseCounting)
+		authorized.POST("/cycle-counts/reopen/:id", cycleCountHandler.ReopenCounting)
+		authorized.POST("/cycle-counts/cancel/:id", cycleCountHandler.CancelCycleCount)
+		authorized.POST("/cycle-counts/approve/:id", middleware.RoleMiddleware(model.RoleAdmin, model.RoleManager), cycleCountHandler.ApproveCycleCount)
+
+		// Seriennummern
+		serialNumberHandler := handler.NewSerialNumberHandler()
+		authorized.GET("/serials", serialNumberHandler.LookupSerialNumber)
+
+		// Berichte
+		reportHandler := handler.NewReportHandler()
+		authorized.GET("/reports/expiring-lots", reportHandler.ShowExpiringLotsReport)
+		authorized.GET("/reports/valuation", reportHandler.ShowValuationReport)
+		authorized.GET("/reports/turnover", reportHandler.ShowTurnoverReport)
+		authorized.GET("/reports/movements", reportHandler.ShowMovementReport)
+		authorized.GET("/reports/movements/transactions", reportHandler.ShowMovementTransactions)
+		authorized.GET("/reports/stock-as-of", reportHandler.ShowStockAsOfReport)
+		authorized.GET("/reports/suppliers", reportHandler.ShowSupplierReport)
+		authorized.GET("/reports/hazards", reportHandler.ShowHazardReport)
+
+		// Konsistenzprüfung des Buchungsjournals
+		ledgerHandler := handler.NewLedgerHandler()
+		authorized.GET("/ledger-check", middleware.RoleMiddleware(model.RoleAdmin), ledgerHandler.ShowLedgerCheck)
+		authorized.POST("/ledger-check/repair/:id", middleware.RoleMiddleware(model.RoleAdmin), ledgerHandler.RepairLedger)
+
 		// Optionale API-Endpoints für AJAX-Anfragen
 		api := router.Group("/api")
 		api.Use(middleware.AuthMiddleware())
 		{
 			api.DELETE("/articles/:id", articleHandler.DeleteArticle)
 			api.DELETE("/suppliers/:id", supplierHandler.DeleteSupplier)
+			api.GET("/reports/turnover", reportHandler.GetTurnoverReport)
+			api.GET("/reports/suppliers", reportHandler.GetSupplierReport)
+			api.GET("/reports/hazards", reportHandler.GetHazardReport)
+			api.GET("/stock/as-of", reportHandler.GetStockAsOf)
 		}
 	}
 }
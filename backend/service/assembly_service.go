@@ -0,0 +1,402 @@
+// backend/service/assembly_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrAssemblyNotKit wird zurückgegeben, wenn für einen Artikel ohne Stückliste montiert werden soll
+var ErrAssemblyNotKit = errors.New("Der Artikel hat keine Stückliste")
+
+// ErrAssemblyQuantity wird zurückgegeben, wenn keine gültige Menge angegeben wurde
+var ErrAssemblyQuantity = errors.New("Bitte eine Menge größer als 0 angeben")
+
+// ErrAssemblyType wird zurückgegeben, wenn weder Montage noch Demontage gewählt wurde
+var ErrAssemblyType = errors.New("Ungültiger Vorgang")
+
+// ErrAssemblySerial wird zurückgegeben, wenn Set oder Komponenten seriennummernpflichtig sind
+var ErrAssemblySerial = errors.New("Sets mit seriennummernpflichtigen Artikeln können nicht automatisch montiert werden")
+
+// ErrAssemblyNotReversible wird zurückgegeben, wenn eine einzelne Buchung einer Montage storniert werden soll
+var ErrAssemblyNotReversible = errors.New("Buchungen einer Montage oder Demontage können nicht einzeln storniert werden")
+
+// assemblyEpsilon gleicht Rundungsfehler bei der Berechnung baubarer Mengen aus
+const assemblyEpsilon = 1e-9
+
+// BuildableComponent beschreibt die Verfügbarkeit einer Komponente für die Montage
+type BuildableComponent struct {
+	Component model.BOMComponent
+	Available float64 // Verfügbarer Bestand der Komponente
+	Buildable float64 // Aus dieser Komponente baubare Sets
+}
+
+// IsLimiting prüft, ob die Komponente die baubare Menge begrenzt
+func (c *BuildableComponent) IsLimiting(buildable float64) bool {
+	return c.Buildable <= buildable+assemblyEpsilon
+}
+
+// Buildability ist die aus den verfügbaren Komponenten baubare Menge eines Sets
+type Buildability struct {
+	Quantity   float64 // Baubare Sets
+	Components []*BuildableComponent
+}
+
+// AssemblyService bucht Montagen und Demontagen von Sets
+type AssemblyService struct {
+	assemblyRepo   *repository.AssemblyRepository
+	articleRepo    *repository.ArticleRepository
+	stockLevelRepo *repository.StockLevelRepository
+	locationRepo   *repository.LocationRepository
+	activityRepo   *repository.ActivityRepository
+	stockService   *StockService
+}
+
+// NewAssemblyService erstellt einen neuen AssemblyService
+func NewAssemblyService() *AssemblyService {
+	return &AssemblyService{
+		assemblyRepo:   repository.NewAssemblyRepository(),
+		articleRepo:    repository.NewArticleRepository(),
+		stockLevelRepo: repository.NewStockLevelRepository(),
+		locationRepo:   repository.NewLocationRepository(),
+		activityRepo:   repository.NewActivityRepository(),
+		stockService:   NewStockService(),
+	}
+}
+
+// GetBuildability berechnet, wie viele Sets aus dem verfügbaren (nicht reservierten) Bestand
+// der Komponenten gebaut werden können. Teilmengen zählen nicht; ein Set ist erst baubar,
+// wenn alle Komponenten vollständig vorhanden sind.
+func (s *AssemblyService) GetBuildability(kit *model.Article) (*Buildability, error) {
+	if !kit.IsKit() {
+		return nil, ErrAssemblyNotKit
+	}
+
+	result := &Buildability{Quantity: math.Inf(1)}
+	for _, component := range kit.BillOfMaterials {
+		article, err := s.articleRepo.FindByID(component.ArticleID.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Komponente %s nicht gefunden: %v", component.ArticleNumber, err)
+		}
+
+		entry := &BuildableComponent{
+			Component: component,
+			Available: math.Max(article.GetAvailableStock(), 0),
+		}
+		if component.Quantity > 0 {
+			entry.Buildable = math.Floor(entry.Available/component.Quantity + assemblyEpsilon)
+		}
+		result.Components = append(result.Components, entry)
+		result.Quantity = math.Min(result.Quantity, entry.Buildable)
+	}
+
+	return result, nil
+}
+
+// FindByID gibt eine Montage zurück
+func (s *AssemblyService) FindByID(id string) (*model.Assembly, error) {
+	return s.assemblyRepo.FindByID(id)
+}
+
+// FindByKitArticleID gibt die letzten Montagen und Demontagen eines Sets zurück
+func (s *AssemblyService) FindByKitArticleID(articleID primitive.ObjectID, limit int64) ([]*model.Assembly, error) {
+	return s.assemblyRepo.FindByKitArticleID(articleID, limit)
+}
+
+// assemblyWithdrawal ist eine geplante Entnahme von einem Lagerort
+type assemblyWithdrawal struct {
+	locationID primitive.ObjectID
+	quantity   float64
+}
+
+// PostAssembly bucht eine Montage oder Demontage als einen zusammenhängenden Vorgang.
+//
+// Bei der Montage werden die Komponenten je Stückliste entnommen und das Set am angegebenen
+// Lagerplatz zugebucht. Das Set wird mit dem Wert der verbrauchten Komponenten bewertet. Bei
+// der Demontage wird das Set entnommen und die Komponenten am Lagerplatz zugebucht; der Wert
+// des Sets wird im Verhältnis ihrer Durchschnittspreise auf die Komponenten verteilt.
+//
+// Entnahmen erfolgen zuerst vom angegebenen Lagerplatz, danach von den Lagerorten mit dem
+// größten Bestand; Bestand ohne Lagerort kommt zuletzt. Chargenpflichtige Zugänge erhalten
+// die Montagenummer als Charge und das früheste Ablaufdatum der entnommenen Chargen.
+//
+// Alle Buchungen verweisen über ihre AssemblyID auf den Vorgang und können nicht einzeln
+// storniert werden. Schlägt eine Buchung fehl, werden die bereits gebuchten storniert.
+func (s *AssemblyService) PostAssembly(
+	kitID string,
+	assemblyType model.AssemblyType,
+	quantity float64,
+	locationID primitive.ObjectID,
+	notes string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Assembly, error) {
+	if assemblyType != model.AssemblyTypeAssembly && assemblyType != model.AssemblyTypeDisassembly {
+		return nil, ErrAssemblyType
+	}
+	if quantity <= 0 {
+		return nil, ErrAssemblyQuantity
+	}
+	if locationID.IsZero() {
+		return nil, ErrLocationRequired
+	}
+
+	kit, err := s.articleRepo.FindByID(kitID)
+	if err != nil {
+		return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
+	}
+	if !kit.IsKit() {
+		return nil, ErrAssemblyNotKit
+	}
+
+	// Komponenten laden und Verfügbarkeit vor der ersten Buchung prüfen
+	components := make([]*model.Article, len(kit.BillOfMaterials))
+	for i, component := range kit.BillOfMaterials {
+		article, err := s.articleRepo.FindByID(component.ArticleID.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Komponente %s nicht gefunden: %v", component.ArticleNumber, err)
+		}
+		components[i] = article
+	}
+	if kit.SerialNumberRequired {
+		return nil, ErrAssemblySerial
+	}
+	for _, article := range components {
+		if article.SerialNumberRequired {
+			return nil, fmt.Errorf("%s: %w", article.ArticleNumber, ErrAssemblySerial)
+		}
+	}
+
+	// Entnahmen planen
+	withdrawals := make(map[primitive.ObjectID][]assemblyWithdrawal)
+	if assemblyType == model.AssemblyTypeAssembly {
+		for i, component := range kit.BillOfMaterials {
+			planned, err := s.planWithdrawal(components[i], locationID, component.Quantity*quantity)
+			if err != nil {
+				return nil, err
+			}
+			withdrawals[component.ArticleID] = planned
+		}
+	} else {
+		planned, err := s.planWithdrawal(kit, locationID, quantity)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals[kit.ID] = planned
+	}
+
+	assembly := &model.Assembly{
+		ID:   primitive.NewObjectID(),
+		Type: assemblyType,
+		Kit: model.AssemblyLine{
+			ArticleID:     kit.ID,
+			ArticleNumber: kit.ArticleNumber,
+			ArticleName:   kit.ShortName,
+			Unit:          kit.Unit,
+			Quantity:      quantity,
+		},
+		LocationID: locationID,
+		Notes:      notes,
+		UserID:     userID,
+		UserName:   userName,
+		CreatedAt:  time.Now(),
+	}
+	for i, component := range kit.BillOfMaterials {
+		assembly.Components = append(assembly.Components, model.AssemblyLine{
+			ArticleID:     component.ArticleID,
+			ArticleNumber: components[i].ArticleNumber,
+			ArticleName:   components[i].ShortName,
+			Unit:          components[i].Unit,
+			Quantity:      component.Quantity * quantity,
+		})
+	}
+
+	assembly.AssemblyNumber, err = s.assemblyRepo.NextAssemblyNumber()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Vergeben der Montagenummer: %v", err)
+	}
+
+	// Rücknahme bei einem Fehler: bereits gebuchte Bewegungen stornieren
+	var posted []*model.Transaction
+	rollback := func() {
+		for i := len(posted) - 1; i >= 0; i-- {
+			transaction := posted[i]
+			if _, err := s.stockService.reverse(transaction, assemblyType.GetDisplayName()+" abgebrochen", userID, userName); err != nil {
+				log.Printf("%s %s: Buchung %s konnte nicht storniert werden: %v",
+					assemblyType.GetDisplayName(), assembly.AssemblyNumber, transaction.ID.Hex(), err)
+			}
+		}
+	}
+
+	newTransaction := func(transactionType model.TransactionType, articleID primitive.ObjectID) *model.Transaction {
+		return &model.Transaction{
+			Type:       transactionType,
+			ArticleID:  articleID,
+			Reason:     assemblyType.GetDisplayName(),
+			Reference:  fmt.Sprintf("%s %s", assembly.AssemblyNumber, kit.ArticleNumber),
+			Notes:      notes,
+			UserID:     userID,
+			UserName:   userName,
+			Timestamp:  assembly.CreatedAt,
+			AssemblyID: assembly.ID,
+		}
+	}
+
+	// withdraw bucht die geplanten Entnahmen eines Artikels und gibt Wert und frühestes
+	// Ablaufdatum der entnommenen Chargen zurück
+	withdraw := func(line *model.AssemblyLine) (float64, time.Time, error) {
+		var value float64
+		var expiry time.Time
+		for _, planned := range withdrawals[line.ArticleID] {
+			transaction := newTransaction(model.TransactionTypeStockOut, line.ArticleID)
+			transaction.Quantity = planned.quantity
+			transaction.LocationID = planned.locationID
+			if err := s.stockService.PostTransaction(transaction); err != nil {
+				return 0, time.Time{}, fmt.Errorf("%s: %w", line.ArticleNumber, err)
+			}
+			posted = append(posted, transaction)
+
+			line.TransactionIDs = append(line.TransactionIDs, transaction.ID)
+			value += transaction.Quantity * transaction.UnitPrice
+			for _, lot := range transaction.Lots {
+				if !lot.ExpiryDate.IsZero() && (expiry.IsZero() || lot.ExpiryDate.Before(expiry)) {
+					expiry = lot.ExpiryDate
+				}
+			}
+		}
+		line.UnitPrice = value / line.Quantity
+		return value, expiry, nil
+	}
+
+	// receive bucht den Zugang eines Artikels am Lagerplatz der Montage
+	receive := func(line *model.AssemblyLine, unitPrice float64, expiry time.Time) error {
+		transaction := newTransaction(model.TransactionTypeStockIn, line.ArticleID)
+		transaction.Quantity = line.Quantity
+		transaction.LocationID = locationID
+		transaction.UnitPrice = unitPrice
+		transaction.LotNumber = assembly.AssemblyNumber
+		transaction.ExpiryDate = expiry
+		if err := s.stockService.PostTransaction(transaction); err != nil {
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+		posted = append(posted, transaction)
+
+		line.TransactionIDs = append(line.TransactionIDs, transaction.ID)
+		line.UnitPrice = transaction.UnitPrice
+		assembly.LocationName = transaction.LocationName
+		return nil
+	}
+
+	if assemblyType == model.AssemblyTypeAssembly {
+		var total float64
+		var expiry time.Time
+		for i := range assembly.Components {
+			value, componentExpiry, err := withdraw(&assembly.Components[i])
+			if err != nil {
+				rollback()
+				return nil, err
+			}
+			total += value
+			if !componentExpiry.IsZero() && (expiry.IsZero() || componentExpiry.Before(expiry)) {
+				expiry = componentExpiry
+			}
+		}
+		if err := receive(&assembly.Kit, total/quantity, expiry); err != nil {
+			rollback()
+			return nil, err
+		}
+	} else {
+		total, expiry, err := withdraw(&assembly.Kit)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+
+		// Wert des Sets im Verhältnis der Durchschnittspreise auf die Komponenten verteilen
+		var componentCost float64
+		for i := range assembly.Components {
+			componentCost += assembly.Components[i].Quantity * components[i].GetAverageCost()
+		}
+		for i := range assembly.Components {
+			unitPrice := components[i].GetAverageCost()
+			if componentCost > 0 {
+				unitPrice = unitPrice * total / componentCost
+			}
+			if err := receive(&assembly.Components[i], unitPrice, expiry); err != nil {
+				rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.assemblyRepo.Create(assembly); err != nil {
+		rollback()
+		return nil, fmt.Errorf("Fehler beim Speichern der Montage: %v", err)
+	}
+
+	description := fmt.Sprintf("%s %s: %g %s %s", assemblyType.GetDisplayName(), assembly.AssemblyNumber,
+		quantity, kit.Unit, kit.ShortName)
+	_, _ = s.activityRepo.LogActivity(
+		model.ActivityTypeAssemblyPosted,
+		userID,
+		userName,
+		assembly.ID,
+		"assembly",
+		assembly.AssemblyNumber,
+		description,
+		quantity,
+	)
+
+	return assembly, nil
+}
+
+// planWithdrawal verteilt eine Entnahme auf die Lagerorte eines Artikels. Der Lagerplatz der
+// Montage wird zuerst ausgeschöpft, danach die Lagerorte mit dem größten Bestand; Bestand
+// ohne Lagerort kommt zuletzt. Reicht der verfügbare Bestand nicht, wird nichts gebucht.
+func (s *AssemblyService) planWithdrawal(article *model.Article, locationID primitive.ObjectID, quantity float64) ([]assemblyWithdrawal, error) {
+	if article.GetAvailableStock() < quantity-assemblyEpsilon {
+		return nil, fmt.Errorf("%s: Nicht genügend verfügbarer Bestand (benötigt %g %s, verfügbar %g %s)",
+			article.ArticleNumber, quantity, article.Unit, math.Max(article.GetAvailableStock(), 0), article.Unit)
+	}
+
+	levels, err := s.stockLevelRepo.FindByArticleID(article.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Bestände: %v", err)
+	}
+	sort.Slice(levels, func(a, b int) bool {
+		if (levels[a].LocationID == locationID) != (levels[b].LocationID == locationID) {
+			return levels[a].LocationID == locationID
+		}
+		if levels[a].IsUnassigned() != levels[b].IsUnassigned() {
+			return levels[b].IsUnassigned()
+		}
+		return levels[a].GetAvailableQuantity() > levels[b].GetAvailableQuantity()
+	})
+
+	var planned []assemblyWithdrawal
+	remaining := quantity
+	for _, level := range levels {
+		if remaining <= assemblyEpsilon {
+			break
+		}
+		if level.GetAvailableQuantity() <= assemblyEpsilon {
+			continue
+		}
+		take := math.Min(level.GetAvailableQuantity(), remaining)
+		planned = append(planned, assemblyWithdrawal{locationID: level.LocationID, quantity: take})
+		remaining -= take
+	}
+	if remaining > assemblyEpsilon {
+		return nil, fmt.Errorf("%s: %w", article.ArticleNumber, repository.ErrInsufficientStock)
+	}
+
+	return planned, nil
+}
@@ -0,0 +1,538 @@
+// backend/service/customer_order_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCustomerOrderCustomer wird zurückgegeben, wenn bei einem Auftrag der Kunde fehlt
+var ErrCustomerOrderCustomer = errors.New("Bitte den Kunden angeben")
+
+// ErrCustomerOrderNoLines wird zurückgegeben, wenn ein Auftrag keine Positionen enthält
+var ErrCustomerOrderNoLines = errors.New("Der Auftrag muss mindestens eine Position mit Menge größer als null enthalten")
+
+// ErrCustomerOrderLine wird zurückgegeben, wenn eine Position einen unbekannten oder inaktiven Artikel enthält
+var ErrCustomerOrderLine = errors.New("Der Auftrag enthält einen unbekannten oder inaktiven Artikel")
+
+// ErrCustomerOrderNotEditable wird zurückgegeben, wenn ein bereits bestätigter Auftrag geändert werden soll
+var ErrCustomerOrderNotEditable = errors.New("Nur Aufträge im Entwurf können bearbeitet werden")
+
+// ErrCustomerOrderNotConfirmed wird zurückgegeben, wenn ein nicht bestätigter Auftrag kommissioniert werden soll
+var ErrCustomerOrderNotConfirmed = errors.New("Nur bestätigte Aufträge können kommissioniert werden")
+
+// ErrCustomerOrderPick wird zurückgegeben, wenn die kommissionierten Mengen nicht zu den Positionen passen
+var ErrCustomerOrderPick = errors.New("Die kommissionierten Mengen müssen den bestellten Mengen entsprechen")
+
+// ErrShipmentNotReversible wird zurückgegeben, wenn eine einzelne Versandbuchung eines Auftrags storniert werden soll
+var ErrShipmentNotReversible = errors.New("Versandbuchungen eines Kundenauftrags können nicht einzeln storniert werden; zurückkommende Ware bitte über eine Kundenretoure buchen")
+
+// pickEpsilon gleicht Rundungsfehler beim Vergleich kommissionierter und bestellter Mengen aus
+const pickEpsilon = 1e-9
+
+// CustomerOrderLineInput ist eine erfasste Auftragsposition vor der Ergänzung um Artikeldaten
+type CustomerOrderLineInput struct {
+	ArticleID string
+	Quantity  float64
+	UnitPrice float64 // 0 = Verkaufspreis des Artikels
+}
+
+// PickListEntry ist eine Entnahme der Kommissionierliste: eine Menge einer Auftragsposition
+// an einem Lagerort
+type PickListEntry struct {
+	LineIndex            int
+	ArticleID            primitive.ObjectID
+	ArticleNumber        string
+	ArticleName          string
+	Unit                 string
+	LocationID           primitive.ObjectID // Leer bei Bestand ohne Lagerort
+	LocationPath         string
+	Quantity             float64
+	SerialNumberRequired bool
+}
+
+// PickShortage ist eine Menge einer Auftragsposition, für die an keinem Lagerort Bestand liegt
+type PickShortage struct {
+	LineIndex     int
+	ArticleNumber string
+	ArticleName   string
+	Unit          string
+	Quantity      float64
+}
+
+// PickingList ist die nach Lagerort-Pfad sortierte Kommissionierliste eines Auftrags
+type PickingList struct {
+	Entries   []*PickListEntry
+	Shortages []PickShortage
+}
+
+// IsComplete prüft, ob alle Positionen vollständig aus dem Lagerbestand entnommen werden können
+func (p *PickingList) IsComplete() bool {
+	return len(p.Shortages) == 0
+}
+
+// PickInput ist eine bestätigte Entnahme für eine Auftragsposition
+type PickInput struct {
+	LineIndex     int // Index der Position im Auftrag
+	LocationID    primitive.ObjectID
+	Quantity      float64
+	SerialNumbers []string
+}
+
+// CustomerOrderService verwaltet Kundenaufträge von der Erfassung bis zum Versand
+type CustomerOrderService struct {
+	customerOrderRepo  *repository.CustomerOrderRepository
+	articleRepo        *repository.ArticleRepository
+	locationRepo       *repository.LocationRepository
+	stockLevelRepo     *repository.StockLevelRepository
+	activityRepo       *repository.ActivityRepository
+	reservationService *ReservationService
+	stockService       *StockService
+}
+
+// NewCustomerOrderService erstellt einen neuen CustomerOrderService
+func NewCustomerOrderService() *CustomerOrderService {
+	return &CustomerOrderService{
+		customerOrderRepo:  repository.NewCustomerOrderRepository(),
+		articleRepo:        repository.NewArticleRepository(),
+		locationRepo:       repository.NewLocationRepository(),
+		stockLevelRepo:     repository.NewStockLevelRepository(),
+		activityRepo:       repository.NewActivityRepository(),
+		reservationService: NewReservationService(),
+		stockService:       NewStockService(),
+	}
+}
+
+// CreateCustomerOrder legt einen Auftrag als Entwurf an
+func (s *CustomerOrderService) CreateCustomerOrder(
+	order *model.CustomerOrder,
+	lines []CustomerOrderLineInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	if err := s.prepare(order, lines); err != nil {
+		return err
+	}
+
+	order.Status = model.CustomerOrderStatusDraft
+	order.CreatedByID = userID
+	order.CreatedByName = userName
+
+	if err := s.customerOrderRepo.Create(order); err != nil {
+		return fmt.Errorf("Fehler beim Speichern des Auftrags: %v", err)
+	}
+
+	s.logActivity(model.ActivityTypeCustomerOrderCreated, order, userID, userName,
+		fmt.Sprintf("Auftrag %s für %s angelegt", order.OrderNumber, order.CustomerName))
+
+	return nil
+}
+
+// UpdateCustomerOrder speichert geänderte Kopfdaten und Positionen eines Auftrags im Entwurf
+func (s *CustomerOrderService) UpdateCustomerOrder(
+	order *model.CustomerOrder,
+	lines []CustomerOrderLineInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	if !order.IsEditable() {
+		return ErrCustomerOrderNotEditable
+	}
+	if err := s.prepare(order, lines); err != nil {
+		return err
+	}
+
+	if err := s.customerOrderRepo.Update(order); err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeCustomerOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Auftrag %s bearbeitet", order.OrderNumber))
+
+	return nil
+}
+
+// ConfirmCustomerOrder bestätigt einen Auftrag im Entwurf und reserviert für jede Position
+// die bestellte Menge. Reicht der Bestand für eine Position nicht aus, werden die bereits
+// angelegten Reservierungen wieder freigegeben und der Auftrag bleibt ein Entwurf.
+func (s *CustomerOrderService) ConfirmCustomerOrder(id string, userID primitive.ObjectID, userName string) error {
+	order, err := s.customerOrderRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Auftrag nicht gefunden: %v", err)
+	}
+	if !order.IsEditable() {
+		return repository.ErrCustomerOrderStatus
+	}
+
+	var reserved []primitive.ObjectID
+	release := func() {
+		for _, reservationID := range reserved {
+			if _, err := s.reservationService.closeReservation(reservationID, model.ReservationStatusReleased); err != nil {
+				log.Printf("Auftrag %s: Reservierung %s konnte nicht freigegeben werden: %v",
+					order.OrderNumber, reservationID.Hex(), err)
+			}
+		}
+	}
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		reservation, err := s.reservationService.CreateReservation(
+			line.ArticleID.Hex(),
+			line.Quantity,
+			"Kundenauftrag "+order.CustomerName,
+			order.OrderNumber,
+			time.Time{},
+			userID,
+			userName,
+		)
+		if err != nil {
+			release()
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+		reserved = append(reserved, reservation.ID)
+		line.ReservationID = reservation.ID
+	}
+
+	if err := s.customerOrderRepo.Confirm(order); err != nil {
+		release()
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeCustomerOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Auftrag %s bestätigt, Bestand reserviert", order.OrderNumber))
+
+	return nil
+}
+
+// CancelCustomerOrder storniert einen Auftrag und gibt die Reservierungen seiner Positionen frei
+func (s *CustomerOrderService) CancelCustomerOrder(id string, userID primitive.ObjectID, userName string) error {
+	order, err := s.customerOrderRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Auftrag nicht gefunden: %v", err)
+	}
+
+	err = s.customerOrderRepo.UpdateStatus(
+		order.ID,
+		[]model.CustomerOrderStatus{model.CustomerOrderStatusDraft, model.CustomerOrderStatusConfirmed},
+		model.CustomerOrderStatusCancelled,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Lines {
+		reservationID := order.Lines[i].ReservationID
+		if reservationID.IsZero() {
+			continue
+		}
+		// Manuell freigegebene Reservierungen sind bereits erledigt
+		_, err := s.reservationService.closeReservation(reservationID, model.ReservationStatusReleased)
+		if err != nil && err != repository.ErrReservationNotActive {
+			log.Printf("Auftrag %s: Reservierung %s konnte nicht freigegeben werden: %v",
+				order.OrderNumber, reservationID.Hex(), err)
+		}
+	}
+
+	s.logActivity(model.ActivityTypeCustomerOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Auftrag %s storniert", order.OrderNumber))
+
+	return nil
+}
+
+// BuildPickingList verteilt die Positionen eines Auftrags auf die Lagerorte mit verfügbarem
+// Bestand und sortiert die Entnahmen nach dem Pfad des Lagerorts (LocationRepository.GetLocationPath),
+// sodass das Lager in einem Durchgang abgelaufen werden kann. Je Position werden die
+// Lagerorte in Pfadreihenfolge ausgeschöpft; Bestand ohne Lagerort kommt zuletzt. Ware in
+// Quarantäne, gesperrte und beschädigte Ware wird nicht kommissioniert.
+func (s *CustomerOrderService) BuildPickingList(order *model.CustomerOrder) (*PickingList, error) {
+	type levelKey struct {
+		articleID  primitive.ObjectID
+		locationID primitive.ObjectID
+	}
+
+	list := &PickingList{}
+	paths := make(map[primitive.ObjectID]string)
+	used := make(map[levelKey]float64) // Bereits verplante Mengen bei mehreren Positionen desselben Artikels
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+
+		article, err := s.articleRepo.FindByID(line.ArticleID.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Artikel %s nicht gefunden: %v", line.ArticleNumber, err)
+		}
+
+		levels, err := s.stockLevelRepo.FindByArticleID(line.ArticleID)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden der Bestände: %v", err)
+		}
+		for _, level := range levels {
+			if _, exists := paths[level.LocationID]; exists || level.IsUnassigned() {
+				continue
+			}
+			path, err := s.locationRepo.GetLocationPath(level.LocationID.Hex())
+			if err != nil {
+				return nil, fmt.Errorf("Fehler beim Laden des Lagerorts: %v", err)
+			}
+			paths[level.LocationID] = path
+		}
+		sort.Slice(levels, func(a, b int) bool {
+			if levels[a].IsUnassigned() != levels[b].IsUnassigned() {
+				return levels[b].IsUnassigned()
+			}
+			return paths[levels[a].LocationID] < paths[levels[b].LocationID]
+		})
+
+		remaining := line.Quantity
+		for _, level := range levels {
+			if remaining <= pickEpsilon {
+				break
+			}
+			key := levelKey{line.ArticleID, level.LocationID}
+			available := level.GetAvailableQuantity() - used[key]
+			if available <= pickEpsilon {
+				continue
+			}
+
+			quantity := math.Min(available, remaining)
+			used[key] += quantity
+			remaining -= quantity
+
+			path := paths[level.LocationID]
+			if level.IsUnassigned() {
+				path = model.UnassignedLocationName
+			}
+			list.Entries = append(list.Entries, &PickListEntry{
+				LineIndex:            i,
+				ArticleID:            line.ArticleID,
+				ArticleNumber:        line.ArticleNumber,
+				ArticleName:          line.ArticleName,
+				Unit:                 line.Unit,
+				LocationID:           level.LocationID,
+				LocationPath:         path,
+				Quantity:             quantity,
+				SerialNumberRequired: article.SerialNumberRequired,
+			})
+		}
+
+		if remaining > pickEpsilon {
+			list.Shortages = append(list.Shortages, PickShortage{
+				LineIndex:     i,
+				ArticleNumber: line.ArticleNumber,
+				ArticleName:   line.ArticleName,
+				Unit:          line.Unit,
+				Quantity:      remaining,
+			})
+		}
+	}
+
+	sort.SliceStable(list.Entries, func(a, b int) bool {
+		ea, eb := list.Entries[a], list.Entries[b]
+		if ea.LocationID.IsZero() != eb.LocationID.IsZero() {
+			return eb.LocationID.IsZero()
+		}
+		if ea.LocationPath != eb.LocationPath {
+			return ea.LocationPath < eb.LocationPath
+		}
+		return ea.ArticleNumber < eb.ArticleNumber
+	})
+
+	return list, nil
+}
+
+// ShipCustomerOrder bestätigt die Kommissionierung eines Auftrags: Für jede Entnahme wird ein
+// Warenausgang auf die Reservierung der Position gebucht, danach gilt der Auftrag als
+// versendet. Die Entnahmen müssen die bestellten Mengen vollständig abdecken.
+//
+// Der Auftrag wird vor dem Buchen auf versendet gesetzt, damit er nicht doppelt kommissioniert
+// wird. Schlägt eine Buchung fehl, werden die bereits gebuchten Ausgänge storniert, ihre
+// Reservierungen wiederhergestellt und der Auftrag zurück auf bestätigt gesetzt.
+func (s *CustomerOrderService) ShipCustomerOrder(
+	id string,
+	picks []PickInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	order, err := s.customerOrderRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Auftrag nicht gefunden: %v", err)
+	}
+	if !order.CanShip() {
+		return ErrCustomerOrderNotConfirmed
+	}
+
+	if err := s.checkPicks(order, picks); err != nil {
+		return err
+	}
+
+	shippedAt := time.Now()
+	err = s.customerOrderRepo.UpdateStatus(
+		order.ID,
+		[]model.CustomerOrderStatus{model.CustomerOrderStatusConfirmed},
+		model.CustomerOrderStatusShipped,
+		bson.M{"shippedAt": shippedAt},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Rücknahme bei einem Fehler: gebuchte Ausgänge stornieren, Reservierungen
+	// wiederherstellen, Auftrag zurücksetzen
+	var posted []*model.Transaction
+	rollback := func() {
+		for _, transaction := range posted {
+			if _, err := s.stockService.ReverseTransaction(transaction.ID.Hex(), "Kommissionierung abgebrochen", userID, userName); err != nil {
+				log.Printf("Auftrag %s: Buchung %s konnte nicht storniert werden: %v",
+					order.OrderNumber, transaction.ID.Hex(), err)
+				continue
+			}
+			s.reservationService.reopen(transaction.ReservationID, transaction.Quantity)
+			s.reservationService.revertReserved(transaction.ArticleID, transaction.Quantity)
+		}
+		err := s.customerOrderRepo.UpdateStatus(
+			order.ID,
+			[]model.CustomerOrderStatus{model.CustomerOrderStatusShipped},
+			model.CustomerOrderStatusConfirmed,
+			bson.M{"shippedAt": time.Time{}},
+		)
+		if err != nil {
+			log.Printf("Auftrag %s konnte nicht zurückgesetzt werden: %v", order.OrderNumber, err)
+		}
+	}
+
+	for _, pick := range picks {
+		line := &order.Lines[pick.LineIndex]
+
+		// Ohne Stückpreis geht die Ware zum Einstandswert ab; der Verkaufspreis bleibt am Auftrag
+		transaction := &model.Transaction{
+			Type:            model.TransactionTypeStockOut,
+			ArticleID:       line.ArticleID,
+			Quantity:        pick.Quantity,
+			Reason:          "Versand an " + order.CustomerName,
+			Reference:       order.OrderNumber,
+			UserID:          userID,
+			UserName:        userName,
+			Timestamp:       shippedAt,
+			LocationID:      pick.LocationID,
+			SerialNumbers:   pick.SerialNumbers,
+			ReservationID:   line.ReservationID,
+			CustomerOrderID: order.ID,
+		}
+		if err := s.stockService.PostTransaction(transaction); err != nil {
+			rollback()
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+		posted = append(posted, transaction)
+	}
+
+	s.logActivity(model.ActivityTypeCustomerOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Auftrag %s kommissioniert und versendet", order.OrderNumber))
+
+	return nil
+}
+
+// checkPicks prüft vor dem Buchen, ob die Entnahmen die Positionen genau abdecken und
+// seriennummernpflichtige Artikel vollständig erfasst sind, damit ein Auftrag nicht erst
+// teilweise gebucht wird
+func (s *CustomerOrderService) checkPicks(order *model.CustomerOrder, picks []PickInput) error {
+	picked := make([]float64, len(order.Lines))
+	for _, pick := range picks {
+		if pick.LineIndex < 0 || pick.LineIndex >= len(order.Lines) || pick.Quantity <= 0 {
+			return ErrCustomerOrderPick
+		}
+		picked[pick.LineIndex] += pick.Quantity
+	}
+
+	articles := make(map[primitive.ObjectID]*model.Article)
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if math.Abs(picked[i]-line.Quantity) > pickEpsilon {
+			return fmt.Errorf("%w (%s)", ErrCustomerOrderPick, line.ArticleNumber)
+		}
+
+		article, err := s.articleRepo.FindByID(line.ArticleID.Hex())
+		if err != nil {
+			return fmt.Errorf("Artikel %s nicht gefunden: %v", line.ArticleNumber, err)
+		}
+		articles[line.ArticleID] = article
+	}
+
+	for _, pick := range picks {
+		line := &order.Lines[pick.LineIndex]
+		if !articles[line.ArticleID].SerialNumberRequired {
+			continue
+		}
+		if err := checkSerialCount(pick.SerialNumbers, pick.Quantity); err != nil {
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+	}
+
+	return nil
+}
+
+// prepare prüft Kunde und Positionen und ergänzt sie um Stammdaten
+func (s *CustomerOrderService) prepare(order *model.CustomerOrder, lines []CustomerOrderLineInput) error {
+	if order.CustomerName == "" {
+		return ErrCustomerOrderCustomer
+	}
+
+	order.Lines = order.Lines[:0]
+	for _, input := range lines {
+		if input.ArticleID == "" || input.Quantity <= 0 {
+			continue
+		}
+
+		article, err := s.articleRepo.FindByID(input.ArticleID)
+		if err != nil || !article.IsActive {
+			return ErrCustomerOrderLine
+		}
+
+		unitPrice := input.UnitPrice
+		if unitPrice == 0 {
+			unitPrice = article.SalesPriceGross
+		}
+
+		order.Lines = append(order.Lines, model.CustomerOrderLine{
+			ArticleID:     article.ID,
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Unit:          article.Unit,
+			Quantity:      input.Quantity,
+			UnitPrice:     unitPrice,
+		})
+	}
+	if len(order.Lines) == 0 {
+		return ErrCustomerOrderNoLines
+	}
+
+	return nil
+}
+
+// logActivity protokolliert eine Änderung an einem Auftrag
+func (s *CustomerOrderService) logActivity(
+	activityType model.ActivityType,
+	order *model.CustomerOrder,
+	userID primitive.ObjectID,
+	userName, description string,
+) {
+	_, _ = s.activityRepo.LogActivity(
+		activityType,
+		userID,
+		userName,
+		order.ID,
+		"customer_order",
+		order.OrderNumber,
+		description,
+		0,
+	)
+}
@@ -0,0 +1,520 @@
+// backend/service/cycle_count_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCycleCountScope wird zurückgegeben, wenn der Umfang einer Zählung ungültig ist
+var ErrCycleCountScope = errors.New("Bitte ein Lager, einen Lagerort oder eine Warengruppe auswählen")
+
+// ErrCycleCountEmpty wird zurückgegeben, wenn im gewählten Umfang kein Bestand liegt
+var ErrCycleCountEmpty = errors.New("Im gewählten Bereich liegt kein Bestand, der gezählt werden könnte")
+
+// ErrCycleCountQuantity wird zurückgegeben, wenn eine Zählmenge ungültig ist
+var ErrCycleCountQuantity = errors.New("Ungültige Zählmenge")
+
+// ErrCycleCountNoCounts wird zurückgegeben, wenn auf einem Zählblatt keine Menge erfasst wurde
+var ErrCycleCountNoCounts = errors.New("Bitte mindestens eine Zählmenge erfassen")
+
+// ErrCycleCountNothingCounted wird zurückgegeben, wenn eine Zählung ohne gezählte Position abgeschlossen werden soll
+var ErrCycleCountNothingCounted = errors.New("Es wurde noch keine Position gezählt")
+
+// ErrCycleCountNegative wird zurückgegeben, wenn eine Differenz den frei verwendbaren Bestand am Lagerort negativ machen würde
+var ErrCycleCountNegative = errors.New("Die Differenz würde den frei verwendbaren Bestand am Lagerort negativ machen, da seit der Zählung Ware entnommen oder zurückgehalten wurde")
+
+// ErrCycleCountCorrected wird zurückgegeben, wenn der Buchbestand zum Zählzeitpunkt nicht bestimmt
+// werden kann, weil zwischen dem Anlegen der Zählung und der Zählung eine Journalkorrektur gebucht wurde
+var ErrCycleCountCorrected = errors.New("Seit dem Anlegen der Zählung wurde der Bestand per Journalkorrektur geändert. Bitte die Zählung abbrechen und neu anlegen")
+
+// CycleCountInput enthält die Angaben zum Anlegen einer Zählung
+type CycleCountInput struct {
+	Scope      model.CycleCountScope
+	LocationID primitive.ObjectID // Lager bzw. Lagerort bei ortsbezogenen Zählungen
+	Category   string             // Warengruppe bei Zählung nach Warengruppe
+	BlindCount bool
+	Notes      string
+}
+
+// CycleCountPostingInput enthält die Angaben, die zum Buchen der Differenz einer
+// chargen- oder seriennummernpflichtigen Position nötig sind
+type CycleCountPostingInput struct {
+	LotNumber     string    // Charge für Mehrmengen bzw. gezielte Entnahme bei Fehlmengen
+	ExpiryDate    time.Time // Mindesthaltbarkeit einer neuen Charge
+	SerialNumbers []string  // Gefundene bzw. fehlende Seriennummern
+}
+
+// CycleCountService verwaltet Inventurzählungen von der Erfassung bis zur Freigabe
+type CycleCountService struct {
+	cycleCountRepo  *repository.CycleCountRepository
+	articleRepo     *repository.ArticleRepository
+	locationRepo    *repository.LocationRepository
+	stockLevelRepo  *repository.StockLevelRepository
+	transactionRepo *repository.TransactionRepository
+	activityRepo    *repository.ActivityRepository
+	stockService    *StockService
+}
+
+// NewCycleCountService erstellt einen neuen CycleCountService
+func NewCycleCountService() *CycleCountService {
+	return &CycleCountService{
+		cycleCountRepo:  repository.NewCycleCountRepository(),
+		articleRepo:     repository.NewArticleRepository(),
+		locationRepo:    repository.NewLocationRepository(),
+		stockLevelRepo:  repository.NewStockLevelRepository(),
+		transactionRepo: repository.NewTransactionRepository(),
+		activityRepo:    repository.NewActivityRepository(),
+		stockService:    NewStockService(),
+	}
+}
+
+// CreateCycleCount legt eine Zählung an und hält für jeden Artikel und Lagerort im gewählten
+// Umfang den aktuellen Bestand als Sollmenge fest
+func (s *CycleCountService) CreateCycleCount(
+	input CycleCountInput,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.CycleCount, error) {
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Lagerorte: %v", err)
+	}
+
+	// Der Anlagezeitpunkt liegt vor dem Festhalten der Sollmengen, damit keine Buchung dazwischen
+	// beim Ermitteln des Buchbestands zum Zählzeitpunkt fehlt
+	count := &model.CycleCount{
+		CreatedAt:     time.Now(),
+		Scope:         input.Scope,
+		BlindCount:    input.BlindCount,
+		Notes:         input.Notes,
+		Status:        model.CycleCountStatusCounting,
+		CreatedByID:   userID,
+		CreatedByName: userName,
+	}
+
+	var levels []*model.StockLevel
+	switch input.Scope {
+	case model.CycleCountScopeWarehouse, model.CycleCountScopeLocation:
+		location, exists := locationMap[input.LocationID]
+		if !exists || (input.Scope == model.CycleCountScopeWarehouse && !location.ParentID.IsZero()) {
+			return nil, ErrCycleCountScope
+		}
+		count.LocationID = location.ID
+		count.ScopeName = location.GetFullPath(locationMap)
+
+		levels, err = s.stockLevelRepo.FindByLocationIDs(collectSubtree(locationMap, location.ID))
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden der Bestände: %v", err)
+		}
+	case model.CycleCountScopeCategory:
+		if input.Category == "" {
+			return nil, ErrCycleCountScope
+		}
+		count.Category = input.Category
+		count.ScopeName = input.Category
+
+		articles, err := s.articleRepo.FindByCategory(input.Category)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden der Artikel: %v", err)
+		}
+		for _, article := range articles {
+			articleLevels, err := s.stockLevelRepo.FindByArticleID(article.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Fehler beim Laden der Bestände: %v", err)
+			}
+			levels = append(levels, articleLevels...)
+		}
+	default:
+		return nil, ErrCycleCountScope
+	}
+
+	articles := make(map[primitive.ObjectID]*model.Article)
+	for _, level := range levels {
+		article, exists := articles[level.ArticleID]
+		if !exists {
+			article, err = s.articleRepo.FindByID(level.ArticleID.Hex())
+			if err != nil {
+				// Bestände gelöschter Artikel werden nicht gezählt
+				continue
+			}
+			articles[level.ArticleID] = article
+		}
+		if !article.IsActive {
+			continue
+		}
+
+		path := model.UnassignedLocationName
+		if location, exists := locationMap[level.LocationID]; exists {
+			path = location.GetFullPath(locationMap)
+		}
+
+		count.Items = append(count.Items, model.CycleCountItem{
+			ArticleID:        article.ID,
+			ArticleNumber:    article.ArticleNumber,
+			ArticleName:      article.ShortName,
+			Unit:             article.Unit,
+			LocationID:       level.LocationID,
+			LocationPath:     path,
+			ExpectedQuantity: level.Quantity,
+			UnitPrice:        article.GetAverageCost(),
+		})
+	}
+	if len(count.Items) == 0 {
+		return nil, ErrCycleCountEmpty
+	}
+
+	// Zählblätter in Laufreihenfolge durch das Lager
+	sort.Slice(count.Items, func(i, j int) bool {
+		a, b := count.Items[i], count.Items[j]
+		if a.LocationID.IsZero() != b.LocationID.IsZero() {
+			return b.LocationID.IsZero()
+		}
+		if a.LocationPath != b.LocationPath {
+			return a.LocationPath < b.LocationPath
+		}
+		return a.ArticleNumber < b.ArticleNumber
+	})
+
+	if err := s.cycleCountRepo.Create(count); err != nil {
+		return nil, fmt.Errorf("Fehler beim Speichern der Zählung: %v", err)
+	}
+
+	s.logActivity(model.ActivityTypeCycleCountCreated, count, userID, userName,
+		fmt.Sprintf("Zählung %s für %s angelegt (%d Positionen)", count.CountNumber, count.GetDisplayScope(), len(count.Items)))
+
+	return count, nil
+}
+
+// RecordCounts erfasst die Zählmengen eines Zählers. Die Schlüssel sind die Indizes der
+// Positionen; mehrere Zähler können dieselbe Position zählen, maßgeblich ist die letzte Zählung.
+func (s *CycleCountService) RecordCounts(
+	id string,
+	quantities map[int]float64,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	count, err := s.cycleCountRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Zählung nicht gefunden: %v", err)
+	}
+	if !count.IsCounting() {
+		return repository.ErrCycleCountStatus
+	}
+	if len(quantities) == 0 {
+		return ErrCycleCountNoCounts
+	}
+
+	now := time.Now()
+	entries := make(map[int]model.CycleCountEntry, len(quantities))
+	for index, quantity := range quantities {
+		if index < 0 || index >= len(count.Items) || quantity < 0 || math.IsNaN(quantity) {
+			return ErrCycleCountQuantity
+		}
+		entries[index] = model.CycleCountEntry{
+			CounterID:   userID,
+			CounterName: userName,
+			Quantity:    quantity,
+			CountedAt:   now,
+		}
+	}
+
+	return s.cycleCountRepo.AddCounts(count.ID, entries)
+}
+
+// CloseCounting beendet die Erfassung und legt die Differenzen zur Freigabe vor
+func (s *CycleCountService) CloseCounting(id string, userID primitive.ObjectID, userName string) error {
+	count, err := s.cycleCountRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Zählung nicht gefunden: %v", err)
+	}
+	if count.GetCountedItems() == 0 {
+		return ErrCycleCountNothingCounted
+	}
+
+	err = s.cycleCountRepo.UpdateStatus(
+		count.ID,
+		[]model.CycleCountStatus{model.CycleCountStatusCounting},
+		model.CycleCountStatusReview,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeCycleCountUpdated, count, userID, userName,
+		fmt.Sprintf("Zählung %s abgeschlossen, %d von %d Positionen gezählt",
+			count.CountNumber, count.GetCountedItems(), len(count.Items)))
+
+	return nil
+}
+
+// ReopenCounting öffnet eine zur Freigabe vorgelegte Zählung wieder, z.B. für Nachzählungen
+func (s *CycleCountService) ReopenCounting(id string, userID primitive.ObjectID, userName string) error {
+	count, err := s.cycleCountRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Zählung nicht gefunden: %v", err)
+	}
+	if !count.CanCancel() {
+		return repository.ErrCycleCountStatus
+	}
+
+	err = s.cycleCountRepo.UpdateStatus(
+		count.ID,
+		[]model.CycleCountStatus{model.CycleCountStatusReview},
+		model.CycleCountStatusCounting,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeCycleCountUpdated, count, userID, userName,
+		fmt.Sprintf("Zählung %s für Nachzählungen wieder geöffnet", count.CountNumber))
+
+	return nil
+}
+
+// CancelCycleCount bricht eine Zählung ab, ohne Differenzen zu buchen
+func (s *CycleCountService) CancelCycleCount(id string, userID primitive.ObjectID, userName string) error {
+	count, err := s.cycleCountRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Zählung nicht gefunden: %v", err)
+	}
+	if !count.CanCancel() {
+		return repository.ErrCycleCountStatus
+	}
+
+	err = s.cycleCountRepo.UpdateStatus(
+		count.ID,
+		[]model.CycleCountStatus{model.CycleCountStatusCounting, model.CycleCountStatusReview},
+		model.CycleCountStatusCancelled,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeCycleCountUpdated, count, userID, userName,
+		fmt.Sprintf("Zählung %s abgebrochen", count.CountNumber))
+
+	return nil
+}
+
+// ApproveCycleCount gibt die Zählung frei: Jede Differenz wird als Inventur gebucht, bei
+// allen gezählten Artikeln wird das Inventurdatum gesetzt. Nicht gezählte Positionen bleiben
+// unverändert.
+//
+// Die Differenz wird auf den aktuellen Bestand am Lagerort gebucht, damit Bewegungen seit dem
+// Anlegen der Zählung erhalten bleiben. Jede Position wird nach ihrer Buchung als freigegeben
+// vermerkt; schlägt eine Buchung fehl, kehrt die Zählung zur Freigabe zurück und eine erneute
+// Freigabe bucht nur die übrigen Positionen.
+func (s *CycleCountService) ApproveCycleCount(
+	id string,
+	postings map[int]CycleCountPostingInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	count, err := s.cycleCountRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Zählung nicht gefunden: %v", err)
+	}
+	if !count.CanApprove() {
+		return repository.ErrCycleCountStatus
+	}
+
+	// Differenzen zum Buchbestand zum Zählzeitpunkt ermitteln sowie Chargen und Seriennummern
+	// vorab prüfen, damit die Freigabe nicht unterwegs scheitert
+	articles := make(map[primitive.ObjectID]*model.Article)
+	variances := make(map[int]float64)
+	for i := range count.Items {
+		item := &count.Items[i]
+		if !item.IsCounted() || item.IsPosted() {
+			continue
+		}
+
+		movements, err := s.transactionRepo.FindByArticleInPeriod(item.ArticleID, count.CreatedAt, item.GetCountedAt())
+		if err != nil {
+			return fmt.Errorf("Fehler beim Laden der Buchungen: %v", err)
+		}
+		variance, err := bookVariance(item, movements)
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", item.ArticleNumber, item.LocationPath, err)
+		}
+		variances[i] = variance
+
+		article, exists := articles[item.ArticleID]
+		if !exists {
+			article, err = s.articleRepo.FindByID(item.ArticleID.Hex())
+			if err != nil {
+				return fmt.Errorf("Artikel %s nicht gefunden: %v", item.ArticleNumber, err)
+			}
+			articles[item.ArticleID] = article
+		}
+		if math.Abs(variance) < lotEpsilon {
+			continue
+		}
+
+		posting := postings[i]
+		if article.LotTracked && variance > 0 && posting.LotNumber == "" {
+			return fmt.Errorf("%s (%s): %w", item.ArticleNumber, item.LocationPath, ErrLotRequired)
+		}
+		if article.SerialNumberRequired {
+			if err := checkSerialCount(posting.SerialNumbers, math.Abs(variance)); err != nil {
+				return fmt.Errorf("%s (%s): %w", item.ArticleNumber, item.LocationPath, err)
+			}
+		}
+	}
+
+	// Zählung vor dem Buchen beanspruchen, damit sie nicht doppelt freigegeben wird
+	approvedAt := time.Now()
+	err = s.cycleCountRepo.UpdateStatus(
+		count.ID,
+		[]model.CycleCountStatus{model.CycleCountStatusReview},
+		model.CycleCountStatusApproved,
+		bson.M{"approvedById": userID, "approvedBy": userName, "approvedAt": approvedAt},
+	)
+	if err != nil {
+		return err
+	}
+
+	fail := func(item *model.CycleCountItem, err error) error {
+		resetErr := s.cycleCountRepo.UpdateStatus(
+			count.ID,
+			[]model.CycleCountStatus{model.CycleCountStatusApproved},
+			model.CycleCountStatusReview,
+			nil,
+		)
+		if resetErr != nil {
+			log.Printf("Zählung %s konnte nicht zurückgesetzt werden: %v", count.CountNumber, resetErr)
+		}
+		return fmt.Errorf("%s (%s): %w", item.ArticleNumber, item.LocationPath, err)
+	}
+
+	posted := 0
+	var postedValue float64
+	for i := range count.Items {
+		item := &count.Items[i]
+		if !item.IsCounted() || item.IsPosted() {
+			continue
+		}
+
+		transactionID := primitive.NilObjectID
+		if math.Abs(variances[i]) >= lotEpsilon {
+			transaction, err := s.postVariance(count, item, variances[i], postings[i], userID, userName, approvedAt)
+			if err != nil {
+				return fail(item, err)
+			}
+			transactionID = transaction.ID
+			posted++
+			postedValue += variances[i] * item.UnitPrice
+		} else if err := s.articleRepo.MarkStockTaken(item.ArticleID, approvedAt); err != nil {
+			log.Printf("Inventurdatum für Artikel %s konnte nicht gesetzt werden: %v", item.ArticleID.Hex(), err)
+		}
+
+		if err := s.cycleCountRepo.MarkItemPosted(count.ID, i, approvedAt, transactionID); err != nil {
+			// Die Buchung ist erfolgt; ohne Vermerk würde eine erneute Freigabe doppelt buchen
+			log.Printf("Zählung %s: Freigabe der Position %d konnte nicht vermerkt werden: %v", count.CountNumber, i, err)
+		}
+	}
+
+	s.logActivity(model.ActivityTypeCycleCountUpdated, count, userID, userName,
+		fmt.Sprintf("Zählung %s freigegeben, %d Differenzen gebucht (%.2f €)",
+			count.CountNumber, posted, postedValue))
+
+	return nil
+}
+
+// bookVariance gibt die Differenz zwischen gezählter Menge und Buchbestand zum Zählzeitpunkt
+// zurück. Der Buchbestand ist die Sollmenge zuzüglich der Bewegungen am Lagerort zwischen dem
+// Anlegen der Zählung und der letzten Zählung der Position.
+func bookVariance(item *model.CycleCountItem, movements []*model.Transaction) (float64, error) {
+	book := item.ExpectedQuantity
+	for _, movement := range movements {
+		if movement.Type == model.TransactionTypeCorrection {
+			return 0, ErrCycleCountCorrected
+		}
+		book += movement.GetLocationDelta(item.LocationID)
+	}
+	return item.GetCountedQuantity() - book, nil
+}
+
+// postVariance bucht die Differenz einer Position zum Buchbestand zum Zählzeitpunkt als
+// Inventur. Gebucht wird die Differenz selbst, sodass Bewegungen nach der Zählung erhalten bleiben.
+func (s *CycleCountService) postVariance(
+	count *model.CycleCount,
+	item *model.CycleCountItem,
+	variance float64,
+	posting CycleCountPostingInput,
+	userID primitive.ObjectID,
+	userName string,
+	timestamp time.Time,
+) (*model.Transaction, error) {
+	transaction := &model.Transaction{
+		Type:          model.TransactionTypeInventory,
+		ArticleID:     item.ArticleID,
+		Quantity:      variance,
+		Reason:        "Inventurzählung " + count.CountNumber,
+		Reference:     count.CountNumber,
+		UserID:        userID,
+		UserName:      userName,
+		Timestamp:     timestamp,
+		LocationID:    item.LocationID,
+		LotNumber:     posting.LotNumber,
+		ExpiryDate:    posting.ExpiryDate,
+		SerialNumbers: posting.SerialNumbers,
+		Notes: fmt.Sprintf("Soll %g %s, Buchbestand zum Zählzeitpunkt %g %s, gezählt %g %s",
+			item.ExpectedQuantity, item.Unit, item.GetCountedQuantity()-variance, item.Unit,
+			item.GetCountedQuantity(), item.Unit),
+	}
+	err := s.stockService.PostStockDifference(transaction)
+	if err == repository.ErrInsufficientStock {
+		return nil, ErrCycleCountNegative
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// collectSubtree gibt die ID eines Lagerorts und aller untergeordneten Lagerorte zurück
+func collectSubtree(locationMap map[primitive.ObjectID]*model.Location, rootID primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{rootID}
+	for i := 0; i < len(ids); i++ {
+		for _, location := range locationMap {
+			if location.ParentID == ids[i] {
+				ids = append(ids, location.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// logActivity protokolliert eine Änderung an einer Zählung
+func (s *CycleCountService) logActivity(
+	activityType model.ActivityType,
+	count *model.CycleCount,
+	userID primitive.ObjectID,
+	userName, description string,
+) {
+	_, _ = s.activityRepo.LogActivity(
+		activityType,
+		userID,
+		userName,
+		count.ID,
+		"cycle_count",
+		count.CountNumber,
+		description,
+		0,
+	)
+}
@@ -0,0 +1,130 @@
+// backend/service/cycle_count_service_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBookVariance(t *testing.T) {
+	location := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		expected  float64
+		counted   float64
+		movements []*model.Transaction
+		want      float64
+		wantErr   error
+	}{
+		{
+			name:     "ohne Bewegungen",
+			expected: 10,
+			counted:  8,
+			want:     -2,
+		},
+		{
+			name:     "Entnahme vor der Zählung wird nicht doppelt gebucht",
+			expected: 10,
+			counted:  7,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeStockOut, Quantity: 3, LocationID: location},
+			},
+			want: 0,
+		},
+		{
+			name:     "Zugang vor der Zählung",
+			expected: 10,
+			counted:  14,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeStockIn, Quantity: 5, LocationID: location},
+			},
+			want: -1,
+		},
+		{
+			name:     "Bewegungen an anderen Lagerorten zählen nicht",
+			expected: 10,
+			counted:  10,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeStockOut, Quantity: 4, LocationID: other},
+				{Type: model.TransactionTypeInventory, Quantity: 2, LocationID: other},
+			},
+			want: 0,
+		},
+		{
+			name:     "Umlagerung vom und zum Lagerort",
+			expected: 10,
+			counted:  7,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeTransfer, Quantity: 5, SourceLocationID: location, TargetLocationID: other},
+				{Type: model.TransactionTypeTransfer, Quantity: 2, SourceLocationID: other, TargetLocationID: location},
+			},
+			want: 0,
+		},
+		{
+			name:     "Storno einer Umlagerung",
+			expected: 10,
+			counted:  12,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeReversal, Quantity: 2, SourceLocationID: other, TargetLocationID: location},
+			},
+			want: 0,
+		},
+		{
+			name:     "Inventuren und Stornos enthalten die Differenz",
+			expected: 10,
+			counted:  9,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeInventory, Quantity: -2, LocationID: location},
+				{Type: model.TransactionTypeReversal, Quantity: 1, LocationID: location},
+			},
+			want: 0,
+		},
+		{
+			name:     "Statuswechsel verändern den Bestand am Lagerort nicht",
+			expected: 10,
+			counted:  10,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeStatusChange, Quantity: 4, LocationID: location,
+					StockStatus: model.StockStatusAvailable, TargetStockStatus: model.StockStatusBlocked},
+			},
+			want: 0,
+		},
+		{
+			name:     "Journalkorrektur verhindert die Freigabe",
+			expected: 10,
+			counted:  10,
+			movements: []*model.Transaction{
+				{Type: model.TransactionTypeCorrection, Quantity: 2, LocationID: location},
+			},
+			wantErr: ErrCycleCountCorrected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &model.CycleCountItem{
+				LocationID:       location,
+				ExpectedQuantity: tt.expected,
+				Counts:           []model.CycleCountEntry{{Quantity: tt.counted}},
+			}
+			got, err := bookVariance(item, tt.movements)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("bookVariance() = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
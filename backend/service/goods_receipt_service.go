@@ -0,0 +1,273 @@
+// backend/service/goods_receipt_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrGoodsReceiptOrderNotOpen wird zurückgegeben, wenn zu einer Bestellung keine Lieferung mehr erwartet wird
+var ErrGoodsReceiptOrderNotOpen = errors.New("Für diese Bestellung kann kein Wareneingang gebucht werden")
+
+// ErrGoodsReceiptEmpty wird zurückgegeben, wenn ein Wareneingang keine gelieferte Menge enthält
+var ErrGoodsReceiptEmpty = errors.New("Bitte mindestens eine gelieferte Menge erfassen")
+
+// ErrGoodsReceiptQuantity wird zurückgegeben, wenn eine Position ungültig ist oder doppelt erfasst wurde
+var ErrGoodsReceiptQuantity = errors.New("Ungültige Liefermenge")
+
+// ErrGoodsReceiptReason wird zurückgegeben, wenn eine abweichende Liefermenge nicht begründet ist
+var ErrGoodsReceiptReason = errors.New("Bitte einen Grund für die abweichende Liefermenge angeben")
+
+// ErrGoodsReceiptCloseReason wird zurückgegeben, wenn eine Bestellung ohne Begründung mit offenen Mengen abgeschlossen werden soll
+var ErrGoodsReceiptCloseReason = errors.New("Bitte in den Bemerkungen begründen, warum die offenen Mengen nicht mehr geliefert werden")
+
+// ErrGoodsReceiptNotReversible wird zurückgegeben, wenn eine einzelne Buchung eines Wareneingangs storniert werden soll
+var ErrGoodsReceiptNotReversible = errors.New("Buchungen eines Wareneingangs können nicht einzeln storniert werden; zurückgehende Ware bitte über eine Lieferantenretoure buchen")
+
+// receiptEpsilon gleicht Rundungsfehler beim Vergleich mit der erwarteten Menge aus
+const receiptEpsilon = 1e-9
+
+// GoodsReceiptLineInput ist die erfasste Liefermenge zu einer Bestellposition
+type GoodsReceiptLineInput struct {
+	LineIndex     int // Index der Position in der Bestellung
+	Quantity      float64
+	Reason        string
+	LocationID    primitive.ObjectID
+	LotNumber     string
+	ExpiryDate    time.Time
+	SerialNumbers []string
+}
+
+// GoodsReceiptInput enthält die Erfassung eines Wareneingangs
+type GoodsReceiptInput struct {
+	DeliveryNote string
+	Notes        string
+	CloseOrder   bool // Bestellung abschließen, auch wenn noch Mengen offen sind
+	Lines        []GoodsReceiptLineInput
+}
+
+// GoodsReceiptService bucht Wareneingänge zu Bestellungen
+type GoodsReceiptService struct {
+	goodsReceiptRepo  *repository.GoodsReceiptRepository
+	purchaseOrderRepo *repository.PurchaseOrderRepository
+	articleRepo       *repository.ArticleRepository
+	activityRepo      *repository.ActivityRepository
+	stockService      *StockService
+}
+
+// NewGoodsReceiptService erstellt einen neuen GoodsReceiptService
+func NewGoodsReceiptService() *GoodsReceiptService {
+	return &GoodsReceiptService{
+		goodsReceiptRepo:  repository.NewGoodsReceiptRepository(),
+		purchaseOrderRepo: repository.NewPurchaseOrderRepository(),
+		articleRepo:       repository.NewArticleRepository(),
+		activityRepo:      repository.NewActivityRepository(),
+		stockService:      NewStockService(),
+	}
+}
+
+// PostGoodsReceipt bucht einen Wareneingang zu einer offenen Bestellung. Für jede gelieferte
+// Position wird ein Wareneingang über PostTransaction gebucht; anschließend stehen die
+// gelieferten Mengen und der Status in der Bestellung.
+//
+// Die gelieferten Mengen werden zuerst versioniert in der Bestellung vermerkt, damit
+// gleichzeitige Wareneingänge zur selben Bestellung einander nicht überschreiben. Schlägt eine
+// Buchung fehl, werden die bereits gebuchten Eingänge storniert und die Bestellung
+// zurückgesetzt.
+func (s *GoodsReceiptService) PostGoodsReceipt(
+	orderID string,
+	input GoodsReceiptInput,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.GoodsReceipt, error) {
+	order, err := s.purchaseOrderRepo.FindByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("Bestellung nicht gefunden: %v", err)
+	}
+	if !order.IsOpen() {
+		return nil, ErrGoodsReceiptOrderNotOpen
+	}
+
+	receipt := &model.GoodsReceipt{
+		ID:              primitive.NewObjectID(),
+		PurchaseOrderID: order.ID,
+		OrderNumber:     order.OrderNumber,
+		SupplierID:      order.SupplierID,
+		SupplierName:    order.SupplierName,
+		DeliveryNote:    input.DeliveryNote,
+		Notes:           input.Notes,
+		ClosedOrder:     input.CloseOrder,
+		UserID:          userID,
+		UserName:        userName,
+		ReceivedAt:      time.Now(),
+	}
+
+	// Gelieferte Mengen auf einer Kopie der Bestellung vermerken
+	updated := *order
+	updated.Lines = append([]model.PurchaseOrderLine(nil), order.Lines...)
+
+	seen := make(map[int]bool, len(input.Lines))
+	for _, in := range input.Lines {
+		if in.LineIndex < 0 || in.LineIndex >= len(updated.Lines) || seen[in.LineIndex] || in.Quantity < 0 {
+			return nil, ErrGoodsReceiptQuantity
+		}
+		seen[in.LineIndex] = true
+		if in.Quantity == 0 {
+			continue
+		}
+
+		line := &updated.Lines[in.LineIndex]
+		expected := line.GetOpenQuantity()
+		if math.Abs(in.Quantity-expected) > receiptEpsilon && in.Reason == "" {
+			return nil, fmt.Errorf("%w (%s)", ErrGoodsReceiptReason, line.ArticleNumber)
+		}
+
+		if err := s.checkLine(line, in); err != nil {
+			return nil, err
+		}
+
+		line.ReceivedQuantity += in.Quantity
+		receipt.Lines = append(receipt.Lines, model.GoodsReceiptLine{
+			LineIndex:        in.LineIndex,
+			ArticleID:        line.ArticleID,
+			ArticleNumber:    line.ArticleNumber,
+			ArticleName:      line.ArticleName,
+			Unit:             line.Unit,
+			ExpectedQuantity: expected,
+			Quantity:         in.Quantity,
+			Reason:           in.Reason,
+			LocationID:       in.LocationID,
+			LotNumber:        in.LotNumber,
+			ExpiryDate:       in.ExpiryDate,
+			SerialNumbers:    in.SerialNumbers,
+		})
+	}
+	if len(receipt.Lines) == 0 {
+		return nil, ErrGoodsReceiptEmpty
+	}
+
+	updated.Status = updated.GetReceiptStatus()
+	if input.CloseOrder && updated.Status != model.PurchaseOrderStatusReceived {
+		if input.Notes == "" {
+			return nil, ErrGoodsReceiptCloseReason
+		}
+		updated.Status = model.PurchaseOrderStatusReceived
+	}
+
+	receipt.ReceiptNumber, err = s.goodsReceiptRepo.NextReceiptNumber()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Vergeben der Wareneingangsnummer: %v", err)
+	}
+
+	if err := s.purchaseOrderRepo.UpdateReceipt(&updated); err != nil {
+		return nil, err
+	}
+
+	// Rücknahme bei einem Fehler: gebuchte Eingänge stornieren, Bestellung zurücksetzen
+	var posted []*model.Transaction
+	rollback := func() {
+		for _, transaction := range posted {
+			if _, err := s.stockService.ReverseTransaction(transaction.ID.Hex(), "Wareneingang abgebrochen", userID, userName); err != nil {
+				log.Printf("Wareneingang %s: Buchung %s konnte nicht storniert werden: %v",
+					receipt.ReceiptNumber, transaction.ID.Hex(), err)
+			}
+		}
+		previous := *order
+		previous.Version = updated.Version
+		if err := s.purchaseOrderRepo.UpdateReceipt(&previous); err != nil {
+			log.Printf("Wareneingang %s: Bestellung %s konnte nicht zurückgesetzt werden: %v",
+				receipt.ReceiptNumber, order.OrderNumber, err)
+		}
+	}
+
+	reference := fmt.Sprintf("%s / %s", order.OrderNumber, receipt.ReceiptNumber)
+	if input.DeliveryNote != "" {
+		reference += " / Lieferschein " + input.DeliveryNote
+	}
+
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+
+		reason := "Wareneingang"
+		if line.Reason != "" {
+			reason = "Wareneingang: " + line.Reason
+		}
+
+		// Gebucht wird in der Bestelleinheit mit dem Umrechnungsfaktor der Bestellposition
+		orderLine := &order.Lines[line.LineIndex]
+		transaction := &model.Transaction{
+			Type:           model.TransactionTypeStockIn,
+			ArticleID:      line.ArticleID,
+			InputUnit:      orderLine.Unit,
+			InputQuantity:  line.Quantity,
+			InputUnitPrice: orderLine.UnitPrice,
+			InputFactor:    orderLine.GetUnitFactor(),
+			Reason:         reason,
+			Reference:      reference,
+			UserID:         userID,
+			UserName:       userName,
+			Timestamp:      receipt.ReceivedAt,
+			LocationID:     line.LocationID,
+			LotNumber:      line.LotNumber,
+			ExpiryDate:     line.ExpiryDate,
+			SerialNumbers:  line.SerialNumbers,
+			GoodsReceiptID: receipt.ID,
+		}
+		if err := s.stockService.PostTransaction(transaction); err != nil {
+			rollback()
+			return nil, fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+		posted = append(posted, transaction)
+
+		line.TransactionID = transaction.ID
+		line.LocationName = transaction.LocationName
+	}
+
+	if err := s.goodsReceiptRepo.Create(receipt); err != nil {
+		rollback()
+		return nil, fmt.Errorf("Fehler beim Speichern des Wareneingangs: %v", err)
+	}
+
+	description := fmt.Sprintf("Wareneingang %s zu Bestellung %s (%d Positionen)",
+		receipt.ReceiptNumber, order.OrderNumber, len(receipt.Lines))
+	_, _ = s.activityRepo.LogActivity(
+		model.ActivityTypeGoodsReceived,
+		userID,
+		userName,
+		receipt.ID,
+		"goods_receipt",
+		receipt.ReceiptNumber,
+		description,
+		0,
+	)
+
+	return receipt, nil
+}
+
+// checkLine prüft Charge und Seriennummern einer Position vor dem Buchen, damit ein
+// unvollständiger Wareneingang gar nicht erst teilweise gebucht wird
+func (s *GoodsReceiptService) checkLine(line *model.PurchaseOrderLine, in GoodsReceiptLineInput) error {
+	article, err := s.articleRepo.FindByID(line.ArticleID.Hex())
+	if err != nil {
+		return fmt.Errorf("Artikel %s nicht gefunden: %v", line.ArticleNumber, err)
+	}
+	if in.LocationID.IsZero() {
+		return fmt.Errorf("%s: %w", line.ArticleNumber, ErrLocationRequired)
+	}
+	if article.LotTracked && in.LotNumber == "" {
+		return fmt.Errorf("%s: %w", line.ArticleNumber, ErrLotRequired)
+	}
+	if article.SerialNumberRequired {
+		if err := checkSerialCount(in.SerialNumbers, in.Quantity*line.GetUnitFactor()); err != nil {
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+	}
+	return nil
+}
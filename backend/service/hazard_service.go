@@ -0,0 +1,292 @@
+// backend/service/hazard_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrHazardClassUnknown wird zurückgegeben, wenn eine Gefahrgutklasse nicht im Gefahrgutkatalog steht
+var ErrHazardClassUnknown = errors.New("Die Gefahrgutklasse steht nicht im Gefahrgutkatalog")
+
+// ErrHazardNotAllowed wird zurückgegeben, wenn ein Lagerort für die Gefahrgutklasse eines Artikels nicht freigegeben ist
+var ErrHazardNotAllowed = errors.New("Der Lagerort ist für diese Gefahrgutklasse nicht freigegeben")
+
+// ErrHazardSegregation wird zurückgegeben, wenn am Lagerort bereits Gefahrgut einer unverträglichen Klasse liegt
+var ErrHazardSegregation = errors.New("Am Lagerort liegt Gefahrgut einer unverträglichen Klasse")
+
+// HazardViolationKind unterscheidet die Verstöße im Gefahrgutbericht
+type HazardViolationKind string
+
+const (
+	HazardViolationNotAllowed  HazardViolationKind = "not_allowed" // Bestand auf einem nicht freigegebenen Lagerort
+	HazardViolationSegregation HazardViolationKind = "segregation" // Unverträgliche Klassen am selben Lagerort
+	HazardViolationAssignment  HazardViolationKind = "assignment"  // Standardlagerort nicht freigegeben
+)
+
+// GetDisplayName gibt die Bezeichnung des Verstoßes zurück
+func (k HazardViolationKind) GetDisplayName() string {
+	switch k {
+	case HazardViolationNotAllowed:
+		return "Nicht freigegeben"
+	case HazardViolationSegregation:
+		return "Zusammenlagerung"
+	case HazardViolationAssignment:
+		return "Standardlagerort"
+	default:
+		return string(k)
+	}
+}
+
+// GetStatusClass gibt eine CSS-Klasse für die Art des Verstoßes zurück
+func (k HazardViolationKind) GetStatusClass() string {
+	switch k {
+	case HazardViolationNotAllowed:
+		return "bg-red-100 text-red-800"
+	case HazardViolationSegregation:
+		return "bg-orange-100 text-orange-800"
+	default:
+		return "bg-yellow-100 text-yellow-800"
+	}
+}
+
+// HazardViolation ist ein Verstoß gegen die Gefahrgutregeln im aktuellen Bestand oder in der
+// Stammdatenzuordnung eines Artikels. Bei Zusammenlagerungsverstößen nennt Conflict* den
+// Artikel der unverträglichen Klasse.
+type HazardViolation struct {
+	Kind          HazardViolationKind `json:"kind"`
+	LocationID    string              `json:"locationId"`
+	LocationPath  string              `json:"locationPath"`
+	ArticleID     string              `json:"articleId"`
+	ArticleNumber string              `json:"articleNumber"`
+	ArticleName   string              `json:"articleName"`
+	HazardClass   string              `json:"hazardClass"`
+	Quantity      float64             `json:"quantity"` // Bestand am Lagerort, 0 bei Standardlagerorten ohne Bestand
+	Unit          string              `json:"unit"`
+
+	ConflictArticleID     string  `json:"conflictArticleId,omitempty"`
+	ConflictArticleNumber string  `json:"conflictArticleNumber,omitempty"`
+	ConflictArticleName   string  `json:"conflictArticleName,omitempty"`
+	ConflictHazardClass   string  `json:"conflictHazardClass,omitempty"`
+	ConflictQuantity      float64 `json:"conflictQuantity,omitempty"`
+	ConflictUnit          string  `json:"conflictUnit,omitempty"`
+}
+
+// HazardService prüft die Lagerung von Gefahrgut gegen die Freigaben der Lagerorte und die
+// Zusammenlagerungsregeln des Gefahrgutkatalogs
+type HazardService struct {
+	hazardRepo     *repository.HazardClassRepository
+	articleRepo    *repository.ArticleRepository
+	locationRepo   *repository.LocationRepository
+	stockLevelRepo *repository.StockLevelRepository
+}
+
+// NewHazardService erstellt einen neuen HazardService
+func NewHazardService() *HazardService {
+	return &HazardService{
+		hazardRepo:     repository.NewHazardClassRepository(),
+		articleRepo:    repository.NewArticleRepository(),
+		locationRepo:   repository.NewLocationRepository(),
+		stockLevelRepo: repository.NewStockLevelRepository(),
+	}
+}
+
+// CheckClass prüft, ob eine Gefahrgutklasse im Katalog steht; Artikel ohne Klasse sind kein Gefahrgut
+func (s *HazardService) CheckClass(code string) error {
+	if code == "" {
+		return nil
+	}
+
+	exists, err := s.hazardRepo.ExistsByCode(code)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden des Gefahrgutkatalogs: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrHazardClassUnknown, code)
+	}
+
+	return nil
+}
+
+// CheckStorage prüft, ob ein Artikel an einem Lagerort gelagert werden darf: Der Lagerort muss
+// die Gefahrgutklasse des Artikels freigeben, und dort darf kein Bestand einer Klasse liegen,
+// die laut Katalog nicht mit ihr zusammengelagert werden darf. Artikel ohne Gefahrgutklasse
+// und Bestand ohne Lagerort werden nicht geprüft.
+func (s *HazardService) CheckStorage(article *model.Article, locationID primitive.ObjectID) error {
+	if article.HazardClass == "" || locationID.IsZero() {
+		return nil
+	}
+
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden der Lagerorte: %v", err)
+	}
+	location, exists := locationMap[locationID]
+	if !exists {
+		// Unbekannte Lagerorte weist die Prüfung des Lagerorts selbst zurück
+		return nil
+	}
+	path := location.GetFullPath(locationMap)
+
+	if !location.AllowsHazardClass(article.HazardClass, locationMap) {
+		return fmt.Errorf("%w: Klasse %s in %s", ErrHazardNotAllowed, article.HazardClass, path)
+	}
+
+	catalog, err := s.hazardRepo.FindCatalog()
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden des Gefahrgutkatalogs: %v", err)
+	}
+	levels, err := s.stockLevelRepo.FindByLocationIDs([]primitive.ObjectID{locationID})
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden der Bestände am Lagerort: %v", err)
+	}
+
+	for _, level := range levels {
+		if level.ArticleID == article.ID || level.Quantity <= 0 {
+			continue
+		}
+		other, err := s.articleRepo.FindByID(level.ArticleID.Hex())
+		if err != nil {
+			continue
+		}
+		if model.HazardClassesIncompatible(catalog, article.HazardClass, other.HazardClass) {
+			return fmt.Errorf("%w: Klasse %s darf nicht zusammen mit %s (Klasse %s) in %s gelagert werden",
+				ErrHazardSegregation, article.HazardClass, other.ArticleNumber, other.HazardClass, path)
+		}
+	}
+
+	return nil
+}
+
+// GenerateComplianceReport ermittelt alle aktuellen Verstöße gegen die Gefahrgutregeln:
+// Bestand auf Lagerorten, die seine Klasse nicht freigeben, Bestände unverträglicher Klassen
+// am selben Lagerort und Gefahrgutartikel, deren Standardlagerort ihre Klasse nicht freigibt.
+// Solche Verstöße entstehen z.B. durch spätere Änderungen an Freigaben, Regeln oder
+// Gefahrgutklassen oder durch Stornos.
+func (s *HazardService) GenerateComplianceReport() (map[string]interface{}, error) {
+	catalog, err := s.hazardRepo.FindCatalog()
+	if err != nil {
+		return nil, err
+	}
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return nil, err
+	}
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	levels, err := s.stockLevelRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hazardous := make(map[primitive.ObjectID]*model.Article)
+	for _, article := range articles {
+		if article.HazardClass != "" {
+			hazardous[article.ID] = article
+		}
+	}
+
+	// Gefahrgutbestände je Lagerort
+	byLocation := make(map[primitive.ObjectID][]*model.StockLevel)
+	for _, level := range levels {
+		if level.LocationID.IsZero() || level.Quantity <= 0 {
+			continue
+		}
+		if _, exists := hazardous[level.ArticleID]; !exists {
+			continue
+		}
+		byLocation[level.LocationID] = append(byLocation[level.LocationID], level)
+	}
+
+	violations := []*HazardViolation{}
+	affected := make(map[string]bool)
+	counts := make(map[HazardViolationKind]int)
+	add := func(violation *HazardViolation) {
+		violations = append(violations, violation)
+		affected[violation.LocationID] = true
+		counts[violation.Kind]++
+	}
+
+	for locationID, locationLevels := range byLocation {
+		location, exists := locationMap[locationID]
+		if !exists {
+			continue
+		}
+		path := location.GetFullPath(locationMap)
+
+		for i, level := range locationLevels {
+			article := hazardous[level.ArticleID]
+			if !location.AllowsHazardClass(article.HazardClass, locationMap) {
+				add(newHazardViolation(HazardViolationNotAllowed, location, path, article, level.Quantity))
+			}
+
+			// Jedes Paar nur einmal melden
+			for _, otherLevel := range locationLevels[i+1:] {
+				other := hazardous[otherLevel.ArticleID]
+				if !model.HazardClassesIncompatible(catalog, article.HazardClass, other.HazardClass) {
+					continue
+				}
+				violation := newHazardViolation(HazardViolationSegregation, location, path, article, level.Quantity)
+				violation.ConflictArticleID = other.ID.Hex()
+				violation.ConflictArticleNumber = other.ArticleNumber
+				violation.ConflictArticleName = other.ShortName
+				violation.ConflictHazardClass = other.HazardClass
+				violation.ConflictQuantity = otherLevel.Quantity
+				violation.ConflictUnit = other.Unit
+				add(violation)
+			}
+		}
+	}
+
+	// Standardlagerorte, auf die der Artikel nicht eingelagert werden dürfte
+	for _, article := range hazardous {
+		location, exists := locationMap[article.StorageLocationID]
+		if !exists || location.AllowsHazardClass(article.HazardClass, locationMap) {
+			continue
+		}
+		add(newHazardViolation(HazardViolationAssignment, location, location.GetFullPath(locationMap), article, 0))
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].LocationPath != violations[j].LocationPath {
+			return violations[i].LocationPath < violations[j].LocationPath
+		}
+		if violations[i].ArticleNumber != violations[j].ArticleNumber {
+			return violations[i].ArticleNumber < violations[j].ArticleNumber
+		}
+		return violations[i].ConflictArticleNumber < violations[j].ConflictArticleNumber
+	})
+
+	return map[string]interface{}{
+		"violations":        violations,
+		"notAllowedCount":   counts[HazardViolationNotAllowed],
+		"segregationCount":  counts[HazardViolationSegregation],
+		"assignmentCount":   counts[HazardViolationAssignment],
+		"locationCount":     len(affected),
+		"hazardousArticles": len(hazardous),
+		"generatedAt":       time.Now(),
+	}, nil
+}
+
+// newHazardViolation erstellt einen Verstoß für einen Artikel an einem Lagerort
+func newHazardViolation(kind HazardViolationKind, location *model.Location, path string, article *model.Article, quantity float64) *HazardViolation {
+	return &HazardViolation{
+		Kind:          kind,
+		LocationID:    location.ID.Hex(),
+		LocationPath:  path,
+		ArticleID:     article.ID.Hex(),
+		ArticleNumber: article.ArticleNumber,
+		ArticleName:   article.ShortName,
+		HazardClass:   article.HazardClass,
+		Quantity:      quantity,
+		Unit:          article.Unit,
+	}
+}
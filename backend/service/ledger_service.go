@@ -0,0 +1,184 @@
+// backend/service/ledger_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LedgerService prüft, ob die Artikelbestände mit dem Buchungsjournal übereinstimmen
+type LedgerService struct {
+	articleRepo     *repository.ArticleRepository
+	transactionRepo *repository.TransactionRepository
+	stockLevelRepo  *repository.StockLevelRepository
+	stockService    *StockService
+}
+
+// NewLedgerService erstellt einen neuen LedgerService
+func NewLedgerService() *LedgerService {
+	return &LedgerService{
+		articleRepo:     repository.NewArticleRepository(),
+		transactionRepo: repository.NewTransactionRepository(),
+		stockLevelRepo:  repository.NewStockLevelRepository(),
+		stockService:    NewStockService(),
+	}
+}
+
+// LedgerMismatch beschreibt einen Artikel, dessen Bestand nicht durch Buchungen belegt ist
+type LedgerMismatch struct {
+	ArticleID        string  `json:"articleId"`
+	ArticleNumber    string  `json:"articleNumber"`
+	ArticleName      string  `json:"articleName"`
+	Unit             string  `json:"unit"`
+	StockCurrent     float64 `json:"stockCurrent"`     // Bestand laut Artikel
+	LedgerStock      float64 `json:"ledgerStock"`      // Summe aller gebuchten Bestandsveränderungen
+	LevelStock       float64 `json:"levelStock"`       // Summe der Lagerplatzbestände
+	TransactionCount int     `json:"transactionCount"` // Anzahl der Buchungen
+
+	// Aufteilung der Differenz zwischen Artikel- und Journalbestand nach Ursache
+	OpeningStock    float64 `json:"openingStock"`    // Bestand vor der ersten Buchung
+	UnbookedChanges float64 `json:"unbookedChanges"` // Änderungen zwischen zwei Buchungen
+	ChangeAfterLast float64 `json:"changeAfterLast"` // Änderung nach der letzten Buchung
+	Corrected       float64 `json:"corrected"`       // Bereits durch Journalkorrekturen ausgeglichen
+}
+
+// GetDifference gibt die Differenz zwischen Artikelbestand und Journalbestand zurück
+func (m *LedgerMismatch) GetDifference() float64 {
+	return m.StockCurrent - m.LedgerStock
+}
+
+// GetLevelDifference gibt die Differenz zwischen Lagerplatzbeständen und Artikelbestand zurück
+func (m *LedgerMismatch) GetLevelDifference() float64 {
+	return m.LevelStock - m.StockCurrent
+}
+
+// HasLedgerDifference prüft, ob der Artikelbestand vom Journalbestand abweicht
+func (m *LedgerMismatch) HasLedgerDifference() bool {
+	return math.Abs(m.GetDifference()) >= lotEpsilon
+}
+
+// HasLevelDifference prüft, ob die Lagerplatzbestände vom Artikelbestand abweichen
+func (m *LedgerMismatch) HasLevelDifference() bool {
+	return math.Abs(m.GetLevelDifference()) >= lotEpsilon
+}
+
+// GetCauses beschreibt die Ursachen der Abweichung
+func (m *LedgerMismatch) GetCauses() []string {
+	var causes []string
+	if m.HasLedgerDifference() {
+		if m.TransactionCount == 0 {
+			causes = append(causes, fmt.Sprintf("Bestand von %g ohne jede Buchung", m.StockCurrent))
+		}
+		if math.Abs(m.OpeningStock) >= lotEpsilon {
+			causes = append(causes, fmt.Sprintf("Anfangsbestand von %g ohne Buchung", m.OpeningStock))
+		}
+		if math.Abs(m.UnbookedChanges) >= lotEpsilon {
+			causes = append(causes, fmt.Sprintf("Ungebuchte Änderungen von %g zwischen Buchungen", m.UnbookedChanges))
+		}
+		if math.Abs(m.ChangeAfterLast) >= lotEpsilon {
+			causes = append(causes, fmt.Sprintf("Ungebuchte Änderung von %g nach der letzten Buchung", m.ChangeAfterLast))
+		}
+		if math.Abs(m.Corrected) >= lotEpsilon {
+			causes = append(causes, fmt.Sprintf("Davon bereits durch Journalkorrekturen ausgeglichen: %g", m.Corrected))
+		}
+	}
+	if m.HasLevelDifference() {
+		causes = append(causes, fmt.Sprintf("Lagerplatzbestände weichen um %g ab", m.GetLevelDifference()))
+	}
+	return causes
+}
+
+// LedgerCheckResult ist das Ergebnis einer Konsistenzprüfung
+type LedgerCheckResult struct {
+	CheckedAt    time.Time         `json:"checkedAt"`
+	ArticleCount int               `json:"articleCount"`
+	Mismatches   []*LedgerMismatch `json:"mismatches"`
+}
+
+// IsConsistent prüft, ob keine Abweichungen gefunden wurden
+func (r *LedgerCheckResult) IsConsistent() bool {
+	return len(r.Mismatches) == 0
+}
+
+// CheckConsistency vergleicht für jeden Artikel den gespeicherten Bestand mit der Summe seiner
+// Buchungen und mit der Summe seiner Lagerplatzbestände. Die Differenz zum Journal wird nach
+// Ursachen aufgeteilt: Anfangsbestand ohne Buchung, Lücken zwischen dem Bestand nach einer
+// Buchung und vor der nächsten sowie Änderungen nach der letzten Buchung, abzüglich der bereits
+// gebuchten Journalkorrekturen.
+func (s *LedgerService) CheckConsistency() (*LedgerCheckResult, error) {
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Artikel: %v", err)
+	}
+
+	summaries, err := s.transactionRepo.SummarizeLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Auswerten des Buchungsjournals: %v", err)
+	}
+
+	levels, err := s.stockLevelRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Lagerplatzbestände: %v", err)
+	}
+	levelStock := make(map[primitive.ObjectID]float64)
+	for _, level := range levels {
+		levelStock[level.ArticleID] += level.Quantity
+	}
+
+	result := &LedgerCheckResult{
+		CheckedAt:    time.Now(),
+		ArticleCount: len(articles),
+		Mismatches:   []*LedgerMismatch{},
+	}
+	for _, article := range articles {
+		mismatch := compareLedger(article, summaries[article.ID], levelStock[article.ID])
+		if mismatch.HasLedgerDifference() || mismatch.HasLevelDifference() {
+			result.Mismatches = append(result.Mismatches, mismatch)
+		}
+	}
+
+	sort.Slice(result.Mismatches, func(i, j int) bool {
+		return result.Mismatches[i].ArticleNumber < result.Mismatches[j].ArticleNumber
+	})
+
+	return result, nil
+}
+
+// compareLedger stellt den Bestand eines Artikels seinem Journal und seinen Lagerplatzbeständen
+// gegenüber. summary ist nil, wenn es für den Artikel keine Buchungen gibt.
+func compareLedger(article *model.Article, summary *model.LedgerSummary, levelStock float64) *LedgerMismatch {
+	mismatch := &LedgerMismatch{
+		ArticleID:     article.ID.Hex(),
+		ArticleNumber: article.ArticleNumber,
+		ArticleName:   article.ShortName,
+		Unit:          article.Unit,
+		StockCurrent:  article.StockCurrent,
+		LevelStock:    levelStock,
+	}
+	if summary != nil {
+		mismatch.LedgerStock = summary.MovementSum
+		mismatch.TransactionCount = summary.Count
+		mismatch.OpeningStock = summary.FirstOldStock
+		mismatch.UnbookedChanges = summary.GetChainGap()
+		mismatch.Corrected = summary.CorrectionSum
+		mismatch.ChangeAfterLast = article.StockCurrent - summary.LastNewStock
+	}
+	return mismatch
+}
+
+// Repair bucht eine Journalkorrektur auf den angegebenen richtigen Bestand
+func (s *LedgerService) Repair(
+	articleID string,
+	stock float64,
+	reason, notes string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Transaction, error) {
+	return s.stockService.PostCorrection(articleID, stock, reason, notes, userID, userName)
+}
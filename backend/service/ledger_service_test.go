@@ -0,0 +1,100 @@
+// backend/service/ledger_service_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"testing"
+)
+
+func TestCompareLedger(t *testing.T) {
+	tests := []struct {
+		name       string
+		stock      float64
+		summary    *model.LedgerSummary
+		levelStock float64
+
+		wantLedgerDifference float64
+		wantLevelDifference  float64
+		wantUnbooked         float64
+		wantAfterLast        float64
+		wantCauses           int
+	}{
+		{
+			name:       "Bestand durch Journal und Lagerplätze belegt",
+			stock:      12,
+			summary:    &model.LedgerSummary{Count: 3, MovementSum: 12, FirstOldStock: 0, LastNewStock: 12},
+			levelStock: 12,
+		},
+		{
+			name:                 "Bestand ohne jede Buchung",
+			stock:                5,
+			levelStock:           5,
+			wantLedgerDifference: 5,
+			wantCauses:           1,
+		},
+		{
+			name:                 "Anfangsbestand ohne Buchung",
+			stock:                25,
+			summary:              &model.LedgerSummary{Count: 2, MovementSum: 5, FirstOldStock: 20, LastNewStock: 25},
+			levelStock:           25,
+			wantLedgerDifference: 20,
+			wantCauses:           1,
+		},
+		{
+			name:                 "ungebuchte Änderung zwischen Buchungen",
+			stock:                12,
+			summary:              &model.LedgerSummary{Count: 2, MovementSum: 7, FirstOldStock: 0, LastNewStock: 12},
+			levelStock:           12,
+			wantLedgerDifference: 5,
+			wantUnbooked:         5,
+			wantCauses:           1,
+		},
+		{
+			name:                 "ungebuchte Änderung nach der letzten Buchung",
+			stock:                15,
+			summary:              &model.LedgerSummary{Count: 3, MovementSum: 12, FirstOldStock: 0, LastNewStock: 12},
+			levelStock:           15,
+			wantLedgerDifference: 3,
+			wantAfterLast:        3,
+			wantCauses:           1,
+		},
+		{
+			name:         "durch Journalkorrektur ausgeglichene Lücke",
+			stock:        12,
+			summary:      &model.LedgerSummary{Count: 3, MovementSum: 12, CorrectionSum: 5, FirstOldStock: 0, LastNewStock: 12},
+			levelStock:   12,
+			wantUnbooked: 5,
+		},
+		{
+			name:                "Lagerplatzbestände weichen vom Artikelbestand ab",
+			stock:               12,
+			summary:             &model.LedgerSummary{Count: 3, MovementSum: 12, FirstOldStock: 0, LastNewStock: 12},
+			levelStock:          10,
+			wantLevelDifference: -2,
+			wantCauses:          1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := &model.Article{StockCurrent: tt.stock}
+			mismatch := compareLedger(article, tt.summary, tt.levelStock)
+
+			if got := mismatch.GetDifference(); got != tt.wantLedgerDifference {
+				t.Errorf("Differenz zum Journal = %v, erwartet %v", got, tt.wantLedgerDifference)
+			}
+			if got := mismatch.GetLevelDifference(); got != tt.wantLevelDifference {
+				t.Errorf("Differenz der Lagerplätze = %v, erwartet %v", got, tt.wantLevelDifference)
+			}
+			if mismatch.UnbookedChanges != tt.wantUnbooked {
+				t.Errorf("Änderungen zwischen Buchungen = %v, erwartet %v", mismatch.UnbookedChanges, tt.wantUnbooked)
+			}
+			if mismatch.ChangeAfterLast != tt.wantAfterLast {
+				t.Errorf("Änderung nach der letzten Buchung = %v, erwartet %v", mismatch.ChangeAfterLast, tt.wantAfterLast)
+			}
+			if causes := mismatch.GetCauses(); len(causes) != tt.wantCauses {
+				t.Errorf("Ursachen = %q, erwartet %d", causes, tt.wantCauses)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// backend/service/purchase_order_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrPurchaseOrderSupplier wird zurückgegeben, wenn der Lieferant einer Bestellung fehlt oder unbekannt ist
+var ErrPurchaseOrderSupplier = errors.New("Bitte einen gültigen Lieferanten auswählen")
+
+// ErrPurchaseOrderNoLines wird zurückgegeben, wenn eine Bestellung keine Positionen enthält
+var ErrPurchaseOrderNoLines = errors.New("Die Bestellung muss mindestens eine Position mit Menge größer als null enthalten")
+
+// ErrPurchaseOrderLine wird zurückgegeben, wenn eine Position einen unbekannten Artikel enthält
+var ErrPurchaseOrderLine = errors.New("Die Bestellung enthält einen unbekannten Artikel")
+
+// ErrPurchaseOrderQuantity wird zurückgegeben, wenn eine Menge die Mindestbestellmenge oder die Verpackungseinheit der Bezugsquelle verletzt
+var ErrPurchaseOrderQuantity = errors.New("Die Menge unterschreitet die Mindestbestellmenge oder ist kein Vielfaches der Verpackungseinheit")
+
+// ErrPurchaseOrderNotEditable wird zurückgegeben, wenn eine bereits versendete Bestellung geändert werden soll
+var ErrPurchaseOrderNotEditable = errors.New("Nur Bestellungen im Entwurf können bearbeitet werden")
+
+// PurchaseOrderLineInput ist eine erfasste Bestellposition vor der Ergänzung um Artikeldaten
+type PurchaseOrderLineInput struct {
+	ArticleID string
+	Quantity  float64 // Menge in der Bestelleinheit der Bezugsquelle bzw. der Einkaufseinheit des Artikels
+	UnitPrice float64 // 0 = heute gültiger Preis der Bezugsquelle bzw. Einkaufspreis des Artikels
+}
+
+// PurchaseOrderService verwaltet Bestellungen bei Lieferanten
+type PurchaseOrderService struct {
+	purchaseOrderRepo   *repository.PurchaseOrderRepository
+	articleRepo         *repository.ArticleRepository
+	supplierRepo        *repository.SupplierRepository
+	supplierArticleRepo *repository.SupplierArticleRepository
+	activityRepo        *repository.ActivityRepository
+}
+
+// NewPurchaseOrderService erstellt einen neuen PurchaseOrderService
+func NewPurchaseOrderService() *PurchaseOrderService {
+	return &PurchaseOrderService{
+		purchaseOrderRepo:   repository.NewPurchaseOrderRepository(),
+		articleRepo:         repository.NewArticleRepository(),
+		supplierRepo:        repository.NewSupplierRepository(),
+		supplierArticleRepo: repository.NewSupplierArticleRepository(),
+		activityRepo:        repository.NewActivityRepository(),
+	}
+}
+
+// CreatePurchaseOrder legt eine Bestellung als Entwurf an. Ohne Liefertermin wird er aus
+// dem Bestelldatum und der längsten Lieferzeit der bestellten Artikel berechnet.
+func (s *PurchaseOrderService) CreatePurchaseOrder(
+	order *model.PurchaseOrder,
+	lines []PurchaseOrderLineInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	if err := s.prepare(order, lines); err != nil {
+		return err
+	}
+
+	order.Status = model.PurchaseOrderStatusDraft
+	order.CreatedByID = userID
+	order.CreatedByName = userName
+
+	if err := s.purchaseOrderRepo.Create(order); err != nil {
+		return fmt.Errorf("Fehler beim Speichern der Bestellung: %v", err)
+	}
+
+	s.logActivity(model.ActivityTypePurchaseOrderCreated, order, userID, userName,
+		fmt.Sprintf("Bestellung %s bei %s angelegt", order.OrderNumber, order.SupplierName))
+
+	return nil
+}
+
+// UpdatePurchaseOrder speichert geänderte Kopfdaten und Positionen einer Bestellung im Entwurf
+func (s *PurchaseOrderService) UpdatePurchaseOrder(
+	order *model.PurchaseOrder,
+	lines []PurchaseOrderLineInput,
+	userID primitive.ObjectID,
+	userName string,
+) error {
+	if !order.IsEditable() {
+		return ErrPurchaseOrderNotEditable
+	}
+	if err := s.prepare(order, lines); err != nil {
+		return err
+	}
+
+	if err := s.purchaseOrderRepo.Update(order); err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypePurchaseOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Bestellung %s bearbeitet", order.OrderNumber))
+
+	return nil
+}
+
+// SendPurchaseOrder markiert eine Bestellung im Entwurf als an den Lieferanten versendet.
+// Ab dann zählen ihre Mengen als bestellt.
+func (s *PurchaseOrderService) SendPurchaseOrder(id string, userID primitive.ObjectID, userName string) error {
+	order, err := s.purchaseOrderRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Bestellung nicht gefunden: %v", err)
+	}
+
+	err = s.purchaseOrderRepo.UpdateStatus(
+		order.ID,
+		[]model.PurchaseOrderStatus{model.PurchaseOrderStatusDraft},
+		model.PurchaseOrderStatusSent,
+		bson.M{"sentAt": time.Now()},
+	)
+	if err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypePurchaseOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Bestellung %s an %s versendet", order.OrderNumber, order.SupplierName))
+
+	return nil
+}
+
+// CancelPurchaseOrder storniert eine Bestellung. Bereits gelieferte Mengen bleiben erhalten,
+// offene Mengen gelten nicht mehr als bestellt.
+func (s *PurchaseOrderService) CancelPurchaseOrder(id string, userID primitive.ObjectID, userName string) error {
+	order, err := s.purchaseOrderRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Bestellung nicht gefunden: %v", err)
+	}
+
+	err = s.purchaseOrderRepo.UpdateStatus(
+		order.ID,
+		[]model.PurchaseOrderStatus{
+			model.PurchaseOrderStatusDraft,
+			model.PurchaseOrderStatusSent,
+			model.PurchaseOrderStatusPartiallyReceived,
+		},
+		model.PurchaseOrderStatusCancelled,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypePurchaseOrderUpdated, order, userID, userName,
+		fmt.Sprintf("Bestellung %s storniert", order.OrderNumber))
+
+	return nil
+}
+
+// GetQuantityOnOrder gibt die noch nicht gelieferte Menge eines Artikels aus offenen Bestellungen
+// in Lagereinheiten zurück
+func (s *PurchaseOrderService) GetQuantityOnOrder(articleID primitive.ObjectID) (float64, error) {
+	orders, err := s.purchaseOrderRepo.FindOpenByArticleID(articleID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, order := range orders {
+		for i := range order.Lines {
+			if order.Lines[i].ArticleID == articleID {
+				total += order.Lines[i].GetOpenBaseQuantity()
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// DefaultExpectedDate berechnet den erwarteten Liefertermin aus dem Bestelldatum und der
+// längsten Lieferzeit der Positionen
+func DefaultExpectedDate(orderDate time.Time, leadTimeDays []int) time.Time {
+	days := 0
+	for _, leadTime := range leadTimeDays {
+		if leadTime > days {
+			days = leadTime
+		}
+	}
+	return orderDate.AddDate(0, 0, days)
+}
+
+// prepare prüft Lieferant und Positionen und ergänzt sie um Stammdaten. Ist der Lieferant eine
+// Bezugsquelle des Artikels, gelten deren Bestelleinheit, Artikelnummer, Lieferzeit und Preis,
+// und die Menge muss Mindestbestellmenge und Verpackungseinheit einhalten. Sonst wird in der
+// Einkaufseinheit des Artikels bestellt. Der Umrechnungsfaktor wird in der Position
+// festgehalten, damit spätere Änderungen am Artikel offene Bestellungen nicht verfälschen.
+func (s *PurchaseOrderService) prepare(order *model.PurchaseOrder, lines []PurchaseOrderLineInput) error {
+	supplier, err := s.supplierRepo.FindByID(order.SupplierID.Hex())
+	if err != nil {
+		return ErrPurchaseOrderSupplier
+	}
+	order.SupplierName = supplier.Name
+
+	order.Lines = order.Lines[:0]
+	var leadTimeDays []int
+	for _, input := range lines {
+		if input.ArticleID == "" || input.Quantity <= 0 {
+			continue
+		}
+
+		article, err := s.articleRepo.FindByID(input.ArticleID)
+		if err != nil {
+			return ErrPurchaseOrderLine
+		}
+
+		unit := article.GetPurchaseUnit()
+		supplierArticleNumber := article.SupplierArticleNumber
+		leadTime := article.DeliveryTimeInDays
+		defaultPrice := 0.0
+		supplierArticle, err := s.supplierArticleRepo.FindBySupplierAndArticle(supplier.ID, article.ID)
+		if err == nil {
+			if _, ok := article.GetUnitFactor(supplierArticle.Unit); ok {
+				unit = supplierArticle.Unit
+			}
+			supplierArticleNumber = supplierArticle.SupplierArticleNumber
+			leadTime = supplierArticle.LeadTimeDays
+			defaultPrice = supplierArticle.GetCurrentPrice()
+			if !supplierArticle.IsValidOrderQuantity(input.Quantity) {
+				conditions := fmt.Sprintf("Mindestbestellmenge %g %s", supplierArticle.MinimumOrderQuantity, unit)
+				if supplierArticle.PackSize > 0 {
+					conditions += fmt.Sprintf(", Verpackungseinheit %g %s", supplierArticle.PackSize, unit)
+				}
+				return fmt.Errorf("%w: %s (%s)", ErrPurchaseOrderQuantity, article.ArticleNumber, conditions)
+			}
+		}
+		leadTimeDays = append(leadTimeDays, leadTime)
+
+		factor, _ := article.GetUnitFactor(unit)
+		unitPrice := input.UnitPrice
+		if unitPrice == 0 {
+			unitPrice = defaultPrice
+		}
+		if unitPrice == 0 {
+			unitPrice = article.GetPurchasePriceFor(unit)
+		}
+
+		order.Lines = append(order.Lines, model.PurchaseOrderLine{
+			ArticleID:             article.ID,
+			ArticleNumber:         article.ArticleNumber,
+			ArticleName:           article.ShortName,
+			SupplierArticleNumber: supplierArticleNumber,
+			Unit:                  unit,
+			Quantity:              input.Quantity,
+			UnitPrice:             unitPrice,
+			UnitFactor:            factor,
+			BaseUnit:              article.Unit,
+		})
+	}
+	if len(order.Lines) == 0 {
+		return ErrPurchaseOrderNoLines
+	}
+
+	if order.OrderDate.IsZero() {
+		order.OrderDate = time.Now()
+	}
+	if order.ExpectedDate.IsZero() {
+		order.ExpectedDate = DefaultExpectedDate(order.OrderDate, leadTimeDays)
+	}
+
+	return nil
+}
+
+// logActivity protokolliert eine Änderung an einer Bestellung
+func (s *PurchaseOrderService) logActivity(
+	activityType model.ActivityType,
+	order *model.PurchaseOrder,
+	userID primitive.ObjectID,
+	userName, description string,
+) {
+	_, _ = s.activityRepo.LogActivity(
+		activityType,
+		userID,
+		userName,
+		order.ID,
+		"purchase_order",
+		order.OrderNumber,
+		description,
+		0,
+	)
+}
@@ -0,0 +1,314 @@
+// backend/service/replenishment_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrProposalNoSelection wird zurückgegeben, wenn aus den Bestellvorschlägen nichts ausgewählt wurde
+var ErrProposalNoSelection = errors.New("Bitte mindestens einen Bestellvorschlag mit einer Menge größer als null auswählen")
+
+// ErrProposalWithoutSupplier wird zurückgegeben, wenn ein ausgewählter Artikel keinen Lieferanten hat
+var ErrProposalWithoutSupplier = errors.New("Für den Artikel ist kein Lieferant hinterlegt")
+
+// usageWindowDays ist der Zeitraum, aus dessen Warenausgängen der Tagesverbrauch ermittelt wird
+const usageWindowDays = 90
+
+// ReorderProposal ist der Bestellvorschlag für einen Artikel
+type ReorderProposal struct {
+	Article      *model.Article
+	Available    float64 // Bestand abzüglich Reservierungen
+	OnOrder      float64 // Offene Mengen aus versendeten Bestellungen
+	Projected    float64 // Verfügbarer Bestand zuzüglich bestellter Mengen
+	DailyUsage   float64 // Durchschnittlicher Tagesverbrauch
+	ReorderPoint float64 // Mindestbestand zuzüglich Verbrauch während der Lieferzeit
+	Quantity     float64 // Vorgeschlagene Bestellmenge
+
+	Source  *SupplyOption   // Gewählte Bezugsquelle, nil bei Artikeln ohne Bezugsquellen
+	Sources []*SupplyOption // Alle Bezugsquellen aktiver Lieferanten, die gewählte zuerst
+}
+
+// GetTotal gibt den Nettowert des Vorschlags zurück: mit Bezugsquelle zu deren Preis für die
+// gerundete Bestellmenge, sonst zum Einkaufspreis des Artikels
+func (p *ReorderProposal) GetTotal() float64 {
+	if p.Source != nil && p.Source.UnitPrice > 0 {
+		return p.Source.GetTotal()
+	}
+	return p.Quantity * p.Article.PurchasePriceNet
+}
+
+// SupplierProposal fasst die Bestellvorschläge eines Lieferanten zusammen
+type SupplierProposal struct {
+	SupplierID   primitive.ObjectID // Leer bei Artikeln ohne Lieferanten
+	SupplierName string
+	Proposals    []*ReorderProposal
+}
+
+// GetTotal gibt den Nettowert aller Vorschläge des Lieferanten zurück
+func (p *SupplierProposal) GetTotal() float64 {
+	var total float64
+	for _, proposal := range p.Proposals {
+		total += proposal.GetTotal()
+	}
+	return total
+}
+
+// ProposalSelection ist ein zur Bestellung ausgewählter, gegebenenfalls geänderter Vorschlag
+type ProposalSelection struct {
+	ArticleID         string
+	SupplierArticleID string  // Gewählte Bezugsquelle, leer = nach der Strategie wählen
+	Quantity          float64 // Menge in Lagereinheiten
+}
+
+// ReplenishmentService berechnet Bestellvorschläge und setzt sie in Bestellungen um
+type ReplenishmentService struct {
+	articleRepo            *repository.ArticleRepository
+	supplierRepo           *repository.SupplierRepository
+	supplierArticleRepo    *repository.SupplierArticleRepository
+	transactionRepo        *repository.TransactionRepository
+	purchaseOrderRepo      *repository.PurchaseOrderRepository
+	purchaseOrderService   *PurchaseOrderService
+	supplierArticleService *SupplierArticleService
+}
+
+// NewReplenishmentService erstellt einen neuen ReplenishmentService
+func NewReplenishmentService() *ReplenishmentService {
+	return &ReplenishmentService{
+		articleRepo:            repository.NewArticleRepository(),
+		supplierRepo:           repository.NewSupplierRepository(),
+		supplierArticleRepo:    repository.NewSupplierArticleRepository(),
+		transactionRepo:        repository.NewTransactionRepository(),
+		purchaseOrderRepo:      repository.NewPurchaseOrderRepository(),
+		purchaseOrderService:   NewPurchaseOrderService(),
+		supplierArticleService: NewSupplierArticleService(),
+	}
+}
+
+// ComputeProposals berechnet die Bestellvorschläge aller aktiven Artikel mit Mindestbestand
+// und gruppiert sie nach dem Lieferanten der nach der Strategie gewählten Bezugsquelle.
+// Artikel ohne Bezugsquelle werden dem Lieferanten des Artikels zugeordnet; Artikel ganz ohne
+// Lieferanten stehen in einer eigenen Gruppe am Ende.
+func (s *ReplenishmentService) ComputeProposals(strategy SourceStrategy) ([]*SupplierProposal, error) {
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	onOrder, err := s.purchaseOrderRepo.SumOnOrderByArticle()
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.transactionRepo.SumStockOutByArticle(time.Now().AddDate(0, 0, -usageWindowDays))
+	if err != nil {
+		return nil, err
+	}
+	suppliers, err := s.supplierRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	supplierNames := make(map[primitive.ObjectID]string, len(suppliers))
+	activeSuppliers := make(map[primitive.ObjectID]bool, len(suppliers))
+	for _, supplier := range suppliers {
+		supplierNames[supplier.ID] = supplier.Name
+		activeSuppliers[supplier.ID] = supplier.IsActive
+	}
+	allSupplierArticles, err := s.supplierArticleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	supplierArticles := make(map[primitive.ObjectID][]*model.SupplierArticle)
+	for _, supplierArticle := range allSupplierArticles {
+		supplierArticles[supplierArticle.ArticleID] = append(supplierArticles[supplierArticle.ArticleID], supplierArticle)
+	}
+
+	groups := make(map[primitive.ObjectID]*SupplierProposal)
+	for _, article := range articles {
+		if !article.IsActive || article.MinimumStock <= 0 {
+			continue
+		}
+
+		proposal := ProposeReorder(article, onOrder[article.ID], usage[article.ID]/usageWindowDays)
+		if proposal == nil {
+			continue
+		}
+
+		supplierID := article.SupplierID
+		proposal.Sources = RankSupplyOptions(article, supplierArticles[article.ID], activeSuppliers, proposal.Quantity, strategy)
+		if len(proposal.Sources) > 0 {
+			proposal.Source = proposal.Sources[0]
+			supplierID = proposal.Source.SupplierArticle.SupplierID
+		}
+		if _, exists := supplierNames[supplierID]; !exists {
+			supplierID = primitive.NilObjectID
+		}
+		group, exists := groups[supplierID]
+		if !exists {
+			group = &SupplierProposal{SupplierID: supplierID, SupplierName: supplierNames[supplierID]}
+			groups[supplierID] = group
+		}
+		group.Proposals = append(group.Proposals, proposal)
+	}
+
+	result := make([]*SupplierProposal, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Proposals, func(i, j int) bool {
+			return group.Proposals[i].Article.ArticleNumber < group.Proposals[j].Article.ArticleNumber
+		})
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SupplierID.IsZero() != result[j].SupplierID.IsZero() {
+			return result[j].SupplierID.IsZero()
+		}
+		return result[i].SupplierName < result[j].SupplierName
+	})
+
+	return result, nil
+}
+
+// ProposeReorder berechnet den Bestellvorschlag für einen Artikel oder gibt nil zurück, wenn
+// nicht bestellt werden muss.
+//
+// Bestellt wird, sobald der verfügbare Bestand zuzüglich bestellter Mengen den Bestellpunkt
+// erreicht: den Mindestbestand zuzüglich des erwarteten Verbrauchs während der Lieferzeit.
+// Mit Maximalbestand wird bis zu diesem aufgefüllt, sonst in Vielfachen der Bestellmenge,
+// bis der Bestellpunkt wieder überschritten ist. Die Bestellmenge gilt auch als Mindestmenge.
+func ProposeReorder(article *model.Article, onOrder, dailyUsage float64) *ReorderProposal {
+	proposal := &ReorderProposal{
+		Article:    article,
+		Available:  article.GetAvailableStock(),
+		OnOrder:    onOrder,
+		DailyUsage: dailyUsage,
+	}
+	proposal.Projected = proposal.Available + onOrder
+	proposal.ReorderPoint = article.MinimumStock + math.Ceil(dailyUsage*float64(article.DeliveryTimeInDays))
+
+	if proposal.Projected > proposal.ReorderPoint {
+		return nil
+	}
+
+	shortfall := proposal.ReorderPoint - proposal.Projected
+	switch {
+	case article.MaximumStock > proposal.ReorderPoint:
+		proposal.Quantity = article.MaximumStock - proposal.Projected
+	case article.ReorderQuantity > 0:
+		proposal.Quantity = (math.Floor(shortfall/article.ReorderQuantity) + 1) * article.ReorderQuantity
+	default:
+		proposal.Quantity = shortfall
+	}
+	if proposal.Quantity < article.ReorderQuantity {
+		proposal.Quantity = article.ReorderQuantity
+	}
+
+	if proposal.Quantity <= 0 {
+		return nil
+	}
+	return proposal
+}
+
+// CreatePurchaseOrders legt aus den ausgewählten Vorschlägen je Lieferant eine Bestellung im
+// Entwurf an. Bestellt wird bei der ausgewählten Bezugsquelle oder, ohne Auswahl, bei der nach
+// der Strategie gewählten; die Mengen in Lagereinheiten werden auf volle Bestelleinheiten,
+// die Mindestbestellmenge und die Verpackungseinheit aufgerundet. Artikel ohne Bezugsquelle
+// werden beim Lieferanten des Artikels in der Einkaufseinheit bestellt. Tritt ein Fehler auf,
+// werden die bis dahin angelegten Bestellungen mit dem Fehler zurückgegeben.
+func (s *ReplenishmentService) CreatePurchaseOrders(
+	selections []ProposalSelection,
+	strategy SourceStrategy,
+	userID primitive.ObjectID,
+	userName string,
+) ([]*model.PurchaseOrder, error) {
+	var supplierIDs []primitive.ObjectID
+	lines := make(map[primitive.ObjectID][]PurchaseOrderLineInput)
+	for _, selection := range selections {
+		if selection.Quantity <= 0 {
+			continue
+		}
+
+		article, err := s.articleRepo.FindByID(selection.ArticleID)
+		if err != nil {
+			return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
+		}
+
+		source, err := s.selectSource(article, selection, strategy)
+		if err != nil {
+			return nil, err
+		}
+
+		supplierID := article.SupplierID
+		quantity := toPurchaseUnits(article, selection.Quantity)
+		if source != nil {
+			supplierID = source.SupplierArticle.SupplierID
+			quantity = source.Quantity
+		}
+		if supplierID.IsZero() {
+			return nil, fmt.Errorf("%w: %s", ErrProposalWithoutSupplier, article.ArticleNumber)
+		}
+
+		if _, exists := lines[supplierID]; !exists {
+			supplierIDs = append(supplierIDs, supplierID)
+		}
+		lines[supplierID] = append(lines[supplierID], PurchaseOrderLineInput{
+			ArticleID: selection.ArticleID,
+			Quantity:  quantity,
+		})
+	}
+	if len(supplierIDs) == 0 {
+		return nil, ErrProposalNoSelection
+	}
+
+	orders := make([]*model.PurchaseOrder, 0, len(supplierIDs))
+	for _, supplierID := range supplierIDs {
+		order := &model.PurchaseOrder{
+			SupplierID: supplierID,
+			Notes:      "Aus Bestellvorschlag erzeugt",
+		}
+		if err := s.purchaseOrderService.CreatePurchaseOrder(order, lines[supplierID], userID, userName); err != nil {
+			return orders, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// selectSource gibt die ausgewählte oder nach der Strategie gewählte Bezugsquelle eines
+// Vorschlags zurück, nil wenn der Artikel keine Bezugsquelle bei einem aktiven Lieferanten hat
+func (s *ReplenishmentService) selectSource(article *model.Article, selection ProposalSelection, strategy SourceStrategy) (*SupplyOption, error) {
+	if selection.SupplierArticleID != "" {
+		supplierArticle, err := s.supplierArticleRepo.FindByID(selection.SupplierArticleID)
+		if err != nil || supplierArticle.ArticleID != article.ID {
+			return nil, fmt.Errorf("Bezugsquelle für Artikel %s nicht gefunden", article.ArticleNumber)
+		}
+		option := NewSupplyOption(article, supplierArticle, selection.Quantity)
+		if option == nil {
+			return nil, fmt.Errorf("%w: %s", ErrSupplierArticleUnit, article.ArticleNumber)
+		}
+		return option, nil
+	}
+
+	options, err := s.supplierArticleService.GetSupplyOptions(article, selection.Quantity, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+	return options[0], nil
+}
+
+// toPurchaseUnits rechnet eine Menge in Lagereinheiten in volle Einkaufseinheiten des Artikels um
+func toPurchaseUnits(article *model.Article, quantity float64) float64 {
+	factor, _ := article.GetUnitFactor(article.GetPurchaseUnit())
+	if factor == 1 {
+		return quantity
+	}
+	return math.Ceil(quantity/factor - lotEpsilon)
+}
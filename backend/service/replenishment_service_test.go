@@ -0,0 +1,164 @@
+// backend/service/replenishment_service_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"testing"
+)
+
+func TestProposeReorder(t *testing.T) {
+	tests := []struct {
+		name       string
+		article    model.Article
+		onOrder    float64
+		dailyUsage float64
+
+		wantProposal     bool
+		wantReorderPoint float64
+		wantQuantity     float64
+	}{
+		{
+			name:         "über dem Bestellpunkt",
+			article:      model.Article{StockCurrent: 20, MinimumStock: 10},
+			wantProposal: false,
+		},
+		{
+			name:         "bestellte Mengen zählen mit",
+			article:      model.Article{StockCurrent: 5, MinimumStock: 10},
+			onOrder:      8,
+			wantProposal: false,
+		},
+		{
+			name:         "am Bestellpunkt ohne Fehlmenge und Bestellmenge",
+			article:      model.Article{StockCurrent: 10, MinimumStock: 10},
+			wantProposal: false,
+		},
+		{
+			name:             "Auffüllen bis zum Maximalbestand",
+			article:          model.Article{StockCurrent: 5, MinimumStock: 10, MaximumStock: 50, ReorderQuantity: 5},
+			onOrder:          5,
+			wantProposal:     true,
+			wantReorderPoint: 10,
+			wantQuantity:     40,
+		},
+		{
+			name:             "Verbrauch während der Lieferzeit wird aufgerundet",
+			article:          model.Article{StockCurrent: 3, MinimumStock: 10, DeliveryTimeInDays: 5, ReorderQuantity: 5},
+			dailyUsage:       1.2,
+			wantProposal:     true,
+			wantReorderPoint: 16,
+			wantQuantity:     15,
+		},
+		{
+			name:             "Vielfaches der Bestellmenge über den Bestellpunkt",
+			article:          model.Article{StockCurrent: 0, MinimumStock: 10, ReorderQuantity: 5},
+			wantProposal:     true,
+			wantReorderPoint: 10,
+			wantQuantity:     15,
+		},
+		{
+			name:             "Maximalbestand unter dem Bestellpunkt wird ignoriert",
+			article:          model.Article{StockCurrent: 12, MinimumStock: 10, MaximumStock: 12, DeliveryTimeInDays: 5, ReorderQuantity: 4},
+			dailyUsage:       1,
+			wantProposal:     true,
+			wantReorderPoint: 15,
+			wantQuantity:     4,
+		},
+		{
+			name:             "Bestellmenge gilt als Mindestmenge",
+			article:          model.Article{StockCurrent: 8, MinimumStock: 10, MaximumStock: 11, ReorderQuantity: 6},
+			wantProposal:     true,
+			wantReorderPoint: 10,
+			wantQuantity:     6,
+		},
+		{
+			name:             "ohne Bestellmenge nur die Fehlmenge",
+			article:          model.Article{StockCurrent: 20, StockReserved: 12, MinimumStock: 10},
+			wantProposal:     true,
+			wantReorderPoint: 10,
+			wantQuantity:     2,
+		},
+		{
+			name:             "zurückgehaltener Bestand ist nicht verfügbar",
+			article:          model.Article{StockCurrent: 20, StockQuarantine: 15, MinimumStock: 10, ReorderQuantity: 10},
+			wantProposal:     true,
+			wantReorderPoint: 10,
+			wantQuantity:     10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := tt.article
+			proposal := ProposeReorder(&article, tt.onOrder, tt.dailyUsage)
+
+			if !tt.wantProposal {
+				if proposal != nil {
+					t.Fatalf("Vorschlag über %v erhalten, erwartet keinen", proposal.Quantity)
+				}
+				return
+			}
+			if proposal == nil {
+				t.Fatal("kein Vorschlag erhalten")
+			}
+			if proposal.ReorderPoint != tt.wantReorderPoint {
+				t.Errorf("Bestellpunkt = %v, erwartet %v", proposal.ReorderPoint, tt.wantReorderPoint)
+			}
+			if proposal.Quantity != tt.wantQuantity {
+				t.Errorf("Menge = %v, erwartet %v", proposal.Quantity, tt.wantQuantity)
+			}
+		})
+	}
+}
+
+func TestToPurchaseUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		article  model.Article
+		quantity float64
+		want     float64
+	}{
+		{
+			name:     "ohne Einkaufseinheit",
+			article:  model.Article{Unit: "Stk"},
+			quantity: 7.5,
+			want:     7.5,
+		},
+		{
+			name: "auf volle Einkaufseinheiten aufgerundet",
+			article: model.Article{Unit: "Stk", PurchaseUnit: "Karton",
+				UnitConversions: []model.UnitConversion{{Unit: "Karton", Factor: 12}}},
+			quantity: 25,
+			want:     3,
+		},
+		{
+			name: "genau volle Einkaufseinheiten",
+			article: model.Article{Unit: "Stk", PurchaseUnit: "Karton",
+				UnitConversions: []model.UnitConversion{{Unit: "Karton", Factor: 12}}},
+			quantity: 36,
+			want:     3,
+		},
+		{
+			name: "Rundungsfehler ergeben keine zusätzliche Einheit",
+			article: model.Article{Unit: "kg", PurchaseUnit: "Sack",
+				UnitConversions: []model.UnitConversion{{Unit: "Sack", Factor: 0.1}}},
+			quantity: 0.3,
+			want:     3,
+		},
+		{
+			name:     "Einkaufseinheit ohne Umrechnung gilt als Lagereinheit",
+			article:  model.Article{Unit: "Stk", PurchaseUnit: "Karton"},
+			quantity: 25,
+			want:     25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := tt.article
+			if got := toPurchaseUnits(&article, tt.quantity); got != tt.want {
+				t.Errorf("toPurchaseUnits(%v) = %v, erwartet %v", tt.quantity, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,14 +4,27 @@ package service
 import (
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"errors"
+	"math"
+	"sort"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrInvalidPeriod wird zurückgegeben, wenn das Ende eines Berichtszeitraums nicht nach dessen Beginn liegt
+var ErrInvalidPeriod = errors.New("Das Ende des Zeitraums muss nach dem Beginn liegen")
+
 // ReportService bietet Funktionen für Lagerberichte
 type ReportService struct {
 	articleRepo     *repository.ArticleRepository
 	transactionRepo *repository.TransactionRepository
 	supplierRepo    *repository.SupplierRepository
+	lotRepo         *repository.LotRepository
+	locationRepo    *repository.LocationRepository
+
+	valuationService *ValuationService
 }
 
 // NewReportService erstellt einen neuen ReportService
@@ -20,13 +33,17 @@ func NewReportService() *ReportService {
 		articleRepo:     repository.NewArticleRepository(),
 		transactionRepo: repository.NewTransactionRepository(),
 		supplierRepo:    repository.NewSupplierRepository(),
+		lotRepo:         repository.NewLotRepository(),
+		locationRepo:    repository.NewLocationRepository(),
+
+		valuationService: NewValuationService(),
 	}
 }
 
 // GenerateStockValueReport erstellt einen Bericht über den Lagerwert
 func (s *ReportService) GenerateStockValueReport() (map[string]interface{}, error) {
 	// Gesamtwert des Lagerbestands
-	totalStockValue, err := s.articleRepo.CalculateTotalStockValue()
+	totalStockValue, err := s.valuationService.CalculateTotalStockValue()
 	if err != nil {
 		return nil, err
 	}
@@ -56,38 +73,500 @@ func (s *ReportService) GenerateStockValueReport() (map[string]interface{}, erro
 	}, nil
 }
 
-// GenerateInventoryTurnoverReport erstellt einen Bericht über die Lagerumschlagshäufigkeit
+// TurnoverEntry ist die Umschlagskennzahl eines Artikels im Berichtszeitraum
+type TurnoverEntry struct {
+	ArticleID        string  `json:"articleId"`
+	ArticleNumber    string  `json:"articleNumber"`
+	ArticleName      string  `json:"articleName"`
+	Category         string  `json:"category"`
+	Unit             string  `json:"unit"`
+	OpeningStock     float64 `json:"openingStock"`     // Bestand zu Beginn des Zeitraums
+	ClosingStock     float64 `json:"closingStock"`     // Bestand am Ende des Zeitraums
+	AverageStock     float64 `json:"averageStock"`     // Zeitgewichteter durchschnittlicher Bestand
+	AverageValue     float64 `json:"averageValue"`     // Durchschnittlicher Bestand zum Durchschnittspreis
+	Consumption      float64 `json:"consumption"`      // Nicht stornierte Warenausgänge
+	ConsumptionValue float64 `json:"consumptionValue"` // Warenausgänge zu ihren Stückpreisen
+	TurnoverRate     float64 `json:"turnoverRate"`     // Verbrauch / durchschnittlicher Bestand
+	DaysOfSupply     float64 `json:"daysOfSupply"`     // Reichweite des Endbestands in Tagen, -1 ohne Verbrauch
+}
+
+// TurnoverCategory fasst die Umschlagskennzahlen einer Warengruppe wertmäßig zusammen
+type TurnoverCategory struct {
+	Name             string  `json:"name"`
+	Count            int     `json:"count"`
+	AverageValue     float64 `json:"averageValue"`
+	ConsumptionValue float64 `json:"consumptionValue"`
+	TurnoverRate     float64 `json:"turnoverRate"`
+}
+
+// GenerateInventoryTurnoverReport erstellt einen Bericht über die Lagerumschlagshäufigkeit.
+// Der durchschnittliche Bestand wird aus dem Buchungsjournal zeitgewichtet über den Zeitraum
+// ermittelt, der Verbrauch aus den nicht stornierten Warenausgängen. Warengruppen und die
+// Gesamtkennzahl werden wertmäßig berechnet, da sich Mengen verschiedener Einheiten nicht
+// addieren lassen.
 func (s *ReportService) GenerateInventoryTurnoverReport(startDate, endDate time.Time) (map[string]interface{}, error) {
-	// Dieser Bericht würde zusätzliche Daten im TransactionRepository erfordern
-	// und ist hier nur als Platzhalter implementiert
+	// Zukünftige Zeiträume zählen nicht zum Durchschnitt
+	now := time.Now()
+	if endDate.After(now) {
+		endDate = now
+	}
+	if !endDate.After(startDate) {
+		return nil, ErrInvalidPeriod
+	}
+	duration := endDate.Sub(startDate)
+	days := duration.Hours() / 24
+
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.transactionRepo.FindInPeriod(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	lastBefore, err := s.transactionRepo.FindLastPerArticleUntil(startDate)
+	if err != nil {
+		return nil, err
+	}
+	firstAfter, err := s.transactionRepo.FindFirstPerArticleAfter(endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	byArticle := make(map[primitive.ObjectID][]*model.Transaction)
+	for _, transaction := range transactions {
+		byArticle[transaction.ArticleID] = append(byArticle[transaction.ArticleID], transaction)
+	}
+
+	entries := []TurnoverEntry{}
+	categoryTotals := make(map[string]*TurnoverCategory)
+	var totalAverageValue, totalConsumptionValue float64
+	for _, article := range articles {
+		if article.CreatedAt.After(endDate) {
+			continue
+		}
+
+		booked := byArticle[article.ID]
+
+		// Anfangsbestand aus der ersten Buchung im Zeitraum bzw. der letzten davor
+		opening := article.StockCurrent
+		switch {
+		case len(booked) > 0:
+			opening = booked[0].OldStock
+		case lastBefore[article.ID] != nil:
+			opening = lastBefore[article.ID].NewStock
+		case firstAfter[article.ID] != nil:
+			opening = firstAfter[article.ID].OldStock
+		}
+
+		averageStock, level, consumption, consumptionValue := integrateStock(opening, booked, startDate, endDate)
+		if averageStock < lotEpsilon && consumption < lotEpsilon {
+			continue
+		}
+
+		entry := TurnoverEntry{
+			ArticleID:        article.ID.Hex(),
+			ArticleNumber:    article.ArticleNumber,
+			ArticleName:      article.ShortName,
+			Category:         article.Category,
+			Unit:             article.Unit,
+			OpeningStock:     opening,
+			ClosingStock:     level,
+			AverageStock:     averageStock,
+			AverageValue:     averageStock * article.GetAverageCost(),
+			Consumption:      consumption,
+			ConsumptionValue: consumptionValue,
+			DaysOfSupply:     -1,
+		}
+		if averageStock > lotEpsilon {
+			entry.TurnoverRate = consumption / averageStock
+		}
+		if consumption > lotEpsilon {
+			entry.DaysOfSupply = math.Max(level, 0) / (consumption / days)
+		}
+		entries = append(entries, entry)
+
+		category := article.Category
+		if category == "" {
+			category = "Ohne Kategorie"
+		}
+		if categoryTotals[category] == nil {
+			categoryTotals[category] = &TurnoverCategory{Name: category}
+		}
+		categoryTotals[category].Count++
+		categoryTotals[category].AverageValue += entry.AverageValue
+		categoryTotals[category].ConsumptionValue += entry.ConsumptionValue
+		totalAverageValue += entry.AverageValue
+		totalConsumptionValue += entry.ConsumptionValue
+	}
+
+	// Schnelldreher zuerst
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].TurnoverRate != entries[j].TurnoverRate {
+			return entries[i].TurnoverRate > entries[j].TurnoverRate
+		}
+		return entries[i].ArticleNumber < entries[j].ArticleNumber
+	})
+
+	categories := make([]TurnoverCategory, 0, len(categoryTotals))
+	for _, category := range categoryTotals {
+		if category.AverageValue > lotEpsilon {
+			category.TurnoverRate = category.ConsumptionValue / category.AverageValue
+		}
+		categories = append(categories, *category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	var turnoverRate float64
+	if totalAverageValue > lotEpsilon {
+		turnoverRate = totalConsumptionValue / totalAverageValue
+	}
 
 	return map[string]interface{}{
 		"period": map[string]string{
 			"start": startDate.Format("02.01.2006"),
 			"end":   endDate.Format("02.01.2006"),
 		},
-		"turnoverRate": 0.0, // Beispielwert
-		"generatedAt":  time.Now(),
+		"days":             math.Round(days),
+		"entries":          entries,
+		"categories":       categories,
+		"averageValue":     totalAverageValue,
+		"consumptionValue": totalConsumptionValue,
+		"turnoverRate":     turnoverRate,
+		"generatedAt":      now,
 	}, nil
 }
 
+// integrateStock integriert den Bestand eines Artikels ausgehend vom Anfangsbestand über die
+// nach Zeit sortierten Buchungen des Zeitraums und gibt den zeitgewichteten durchschnittlichen
+// Bestand, den Endbestand sowie Menge und Wert der nicht stornierten Warenausgänge zurück
+func integrateStock(opening float64, booked []*model.Transaction, startDate, endDate time.Time) (float64, float64, float64, float64) {
+	level, since := opening, startDate
+	var area, consumption, consumptionValue float64
+	for _, transaction := range booked {
+		area += level * transaction.Timestamp.Sub(since).Seconds()
+		level, since = transaction.NewStock, transaction.Timestamp
+
+		if transaction.Type == model.TransactionTypeStockOut && !transaction.IsReversed() {
+			consumption += transaction.Quantity
+			consumptionValue += transaction.Quantity * transaction.UnitPrice
+		}
+	}
+	area += level * endDate.Sub(since).Seconds()
+
+	averageStock := math.Max(area/endDate.Sub(startDate).Seconds(), 0)
+	return averageStock, level, consumption, consumptionValue
+}
+
 // GenerateLowStockReport erstellt einen Bericht über Artikel unter Mindestbestand
 func (s *ReportService) GenerateLowStockReport() ([]*model.Article, error) {
 	return s.articleRepo.FindLowStock(0) // 0 = keine Begrenzung
 }
 
-// GenerateStockMovementReport erstellt einen Bericht über Bestandsbewegungen
+// MovementDimension bestimmt, wonach der Bewegungsbericht gruppiert wird
+type MovementDimension string
+
+const (
+	MovementByType     MovementDimension = "type"     // Transaktionstyp
+	MovementByArticle  MovementDimension = "article"  // Artikel
+	MovementByCategory MovementDimension = "category" // Warengruppe
+	MovementByUser     MovementDimension = "user"     // Benutzer
+	MovementByLocation MovementDimension = "location" // Lagerort
+)
+
+// ErrInvalidMovementDimension wird zurückgegeben, wenn nach einem unbekannten Merkmal gruppiert werden soll
+var ErrInvalidMovementDimension = errors.New("Ungültige Gruppierung für den Bewegungsbericht")
+
+// movementUncategorized ist die Anzeige für Artikel ohne Warengruppe
+const movementUncategorized = "Ohne Kategorie"
+
+// GenerateStockMovementReport erstellt einen Bericht über die Bestandsbewegungen im Zeitraum,
+// summiert nach Transaktionstyp, Artikel, Warengruppe, Benutzer und Lagerort. Zu- und Abgänge
+// sind mit den Stückpreisen der Buchungen bewertet.
 func (s *ReportService) GenerateStockMovementReport(startDate, endDate time.Time) (map[string]interface{}, error) {
-	// Dieser Bericht würde zusätzliche Daten im TransactionRepository erfordern
-	// und ist hier nur als Platzhalter implementiert
+	byType, err := s.transactionRepo.SumMovements(startDate, endDate, "type")
+	if err != nil {
+		return nil, err
+	}
+	byArticle, err := s.transactionRepo.SumMovements(startDate, endDate, "articleId")
+	if err != nil {
+		return nil, err
+	}
+	byUser, err := s.transactionRepo.SumMovements(startDate, endDate, "userId")
+	if err != nil {
+		return nil, err
+	}
+	byLocation, err := s.transactionRepo.SumMovements(startDate, endDate, "locationId")
+	if err != nil {
+		return nil, err
+	}
+
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	articleByID := make(map[string]*model.Article, len(articles))
+	for _, article := range articles {
+		articleByID[article.ID.Hex()] = article
+	}
+
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return nil, err
+	}
+
+	// Anzeigenamen ergänzen und Summen bilden
+	var transactionCount int
+	var quantityIn, quantityOut, valueIn, valueOut float64
+	for _, total := range byType {
+		total.Name = (&model.Transaction{Type: model.TransactionType(total.Key)}).GetDisplayType()
+		transactionCount += total.Count
+		quantityIn += total.QuantityIn
+		quantityOut += total.QuantityOut
+		valueIn += total.ValueIn
+		valueOut += total.ValueOut
+	}
+
+	// Warengruppen aus den Artikelsummen ableiten
+	categoryTotals := make(map[string]*model.MovementTotal)
+	for _, total := range byArticle {
+		category := ""
+		if article, exists := articleByID[total.Key]; exists {
+			total.Name = article.ArticleNumber + " " + article.ShortName
+			category = article.Category
+		}
+
+		if categoryTotals[category] == nil {
+			name := category
+			if name == "" {
+				name = movementUncategorized
+			}
+			categoryTotals[category] = &model.MovementTotal{Key: category, Name: name}
+		}
+		categoryTotal := categoryTotals[category]
+		categoryTotal.Count += total.Count
+		categoryTotal.QuantityIn += total.QuantityIn
+		categoryTotal.QuantityOut += total.QuantityOut
+		categoryTotal.ValueIn += total.ValueIn
+		categoryTotal.ValueOut += total.ValueOut
+	}
+	byCategory := make([]*model.MovementTotal, 0, len(categoryTotals))
+	for _, total := range categoryTotals {
+		byCategory = append(byCategory, total)
+	}
+
+	for _, total := range byLocation {
+		total.Name = s.locationName(total.Key, total.Name, locationMap)
+	}
+
+	for _, totals := range [][]*model.MovementTotal{byArticle, byCategory, byUser, byLocation} {
+		sortMovementTotals(totals)
+	}
 
 	return map[string]interface{}{
 		"period": map[string]string{
 			"start": startDate.Format("02.01.2006"),
 			"end":   endDate.Format("02.01.2006"),
 		},
-		"stockInTotal":  0.0, // Beispielwert
-		"stockOutTotal": 0.0, // Beispielwert
-		"generatedAt":   time.Now(),
+		"byType":           byType,
+		"byArticle":        byArticle,
+		"byCategory":       byCategory,
+		"byUser":           byUser,
+		"byLocation":       byLocation,
+		"transactionCount": transactionCount,
+		"stockInTotal":     quantityIn,
+		"stockOutTotal":    quantityOut,
+		"valueInTotal":     valueIn,
+		"valueOutTotal":    valueOut,
+		"generatedAt":      time.Now(),
+	}, nil
+}
+
+// FindMovementTransactions findet die Buchungen hinter einer Summe des Bewegungsberichts und
+// gibt außerdem die Gesamtzahl der Treffer und den Anzeigenamen der Gruppe zurück
+func (s *ReportService) FindMovementTransactions(
+	startDate, endDate time.Time,
+	dimension MovementDimension,
+	key string,
+	limit int,
+) ([]*model.Transaction, int64, string, error) {
+	var filter bson.M
+	var label string
+
+	switch dimension {
+	case MovementByType:
+		filter = bson.M{"type": key}
+		label = (&model.Transaction{Type: model.TransactionType(key)}).GetDisplayType()
+	case MovementByArticle, MovementByUser:
+		objID, err := primitive.ObjectIDFromHex(key)
+		if err != nil {
+			return nil, 0, "", ErrInvalidMovementDimension
+		}
+		if dimension == MovementByUser {
+			filter = bson.M{"userId": objID}
+			break
+		}
+		filter = bson.M{"articleId": objID}
+		if article, err := s.articleRepo.FindByID(key); err == nil {
+			label = article.ArticleNumber + " " + article.ShortName
+		}
+	case MovementByCategory:
+		articles, err := s.articleRepo.FindAll()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		articleIDs := []primitive.ObjectID{}
+		for _, article := range articles {
+			if article.Category == key {
+				articleIDs = append(articleIDs, article.ID)
+			}
+		}
+		filter = bson.M{"articleId": bson.M{"$in": articleIDs}}
+		label = key
+		if label == "" {
+			label = movementUncategorized
+		}
+	case MovementByLocation:
+		locationMap, err := s.locationRepo.BuildLocationTree()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		label = s.locationName(key, "", locationMap)
+
+		if key == "" {
+			filter = bson.M{"locationId": bson.M{"$exists": false}, "sourceLocationId": bson.M{"$exists": false}}
+			break
+		}
+		objID, err := primitive.ObjectIDFromHex(key)
+		if err != nil {
+			return nil, 0, "", ErrInvalidMovementDimension
+		}
+		filter = bson.M{"$or": bson.A{
+			bson.M{"locationId": objID},
+			bson.M{"sourceLocationId": objID},
+			bson.M{"targetLocationId": objID},
+		}}
+	default:
+		return nil, 0, "", ErrInvalidMovementDimension
+	}
+
+	transactions, total, err := s.transactionRepo.FindMovements(startDate, endDate, filter, limit)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	if dimension == MovementByUser && len(transactions) > 0 {
+		label = transactions[0].UserName
+	}
+
+	return transactions, total, label, nil
+}
+
+// locationName gibt den Pfad eines Lagerorts zurück; gelöschte Lagerorte behalten den Namen aus der Buchung
+func (s *ReportService) locationName(key, fallback string, locationMap map[primitive.ObjectID]*model.Location) string {
+	if key == "" {
+		return model.UnassignedLocationName
+	}
+	if objID, err := primitive.ObjectIDFromHex(key); err == nil {
+		if location, exists := locationMap[objID]; exists {
+			return location.GetFullPath(locationMap)
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return key
+}
+
+// sortMovementTotals sortiert Bewegungssummen nach Anzahl der Buchungen, die meisten zuerst
+func sortMovementTotals(totals []*model.MovementTotal) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Count != totals[j].Count {
+			return totals[i].Count > totals[j].Count
+		}
+		return totals[i].Name < totals[j].Name
+	})
+}
+
+// ExpiringLotEntry ist ein Eintrag im Bericht über ablaufende Chargen
+type ExpiringLotEntry struct {
+	Lot           *model.Lot
+	ArticleID     string
+	ArticleNumber string
+	ArticleName   string
+	Unit          string
+	LocationPath  string
+	DaysLeft      int     // Tage bis zum Ablauf, negativ bei abgelaufenen Chargen
+	Expired       bool    // Charge ist bereits abgelaufen
+	Value         float64 // Bestandswert der Charge zum Durchschnittspreis
+}
+
+// GenerateExpiringLotsReport erstellt einen Bericht über Chargen, die innerhalb der
+// angegebenen Anzahl von Tagen ablaufen oder bereits abgelaufen sind
+func (s *ReportService) GenerateExpiringLotsReport(days int) (map[string]interface{}, error) {
+	now := time.Now()
+	lots, err := s.lotRepo.FindExpiringBefore(now.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	articleByID := make(map[primitive.ObjectID]*model.Article, len(articles))
+	for _, article := range articles {
+		articleByID[article.ID] = article
+	}
+
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ExpiringLotEntry{}
+	var expiredCount int
+	var expiredValue, expiringValue float64
+	for _, lot := range lots {
+		article, exists := articleByID[lot.ArticleID]
+		if !exists {
+			continue
+		}
+
+		entry := ExpiringLotEntry{
+			Lot:           lot,
+			ArticleID:     article.ID.Hex(),
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Unit:          article.Unit,
+			LocationPath:  model.UnassignedLocationName,
+			DaysLeft:      lot.DaysUntilExpiry(now),
+			Expired:       lot.IsExpired(now),
+			Value:         lot.Quantity * article.GetAverageCost(),
+		}
+		if location, exists := locationMap[lot.LocationID]; exists {
+			entry.LocationPath = location.GetFullPath(locationMap)
+		}
+
+		if entry.Expired {
+			expiredCount++
+			expiredValue += entry.Value
+		} else {
+			expiringValue += entry.Value
+		}
+		entries = append(entries, entry)
+	}
+
+	return map[string]interface{}{
+		"days":          days,
+		"lots":          entries,
+		"expiredCount":  expiredCount,
+		"expiringCount": len(entries) - expiredCount,
+		"expiredValue":  expiredValue,
+		"expiringValue": expiringValue,
+		"generatedAt":   now,
 	}, nil
 }
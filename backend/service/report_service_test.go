@@ -0,0 +1,93 @@
+// backend/service/report_service_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIntegrateStock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+	day := func(n int) time.Time { return start.AddDate(0, 0, n) }
+
+	tests := []struct {
+		name                 string
+		opening              float64
+		booked               []*model.Transaction
+		wantAverage          float64
+		wantClosing          float64
+		wantConsumption      float64
+		wantConsumptionValue float64
+	}{
+		{
+			name:        "ohne Buchungen",
+			opening:     20,
+			wantAverage: 20,
+			wantClosing: 20,
+		},
+		{
+			name:    "Zugang in der Mitte",
+			opening: 10,
+			booked: []*model.Transaction{
+				{Type: model.TransactionTypeStockIn, Quantity: 20, OldStock: 10, NewStock: 30, Timestamp: day(5)},
+			},
+			wantAverage: 20,
+			wantClosing: 30,
+		},
+		{
+			name:    "Ausgänge werden zeitgewichtet und als Verbrauch gezählt",
+			opening: 40,
+			booked: []*model.Transaction{
+				{Type: model.TransactionTypeStockOut, Quantity: 10, UnitPrice: 2, OldStock: 40, NewStock: 30, Timestamp: day(2)},
+				{Type: model.TransactionTypeStockOut, Quantity: 20, UnitPrice: 3, OldStock: 30, NewStock: 10, Timestamp: day(6)},
+			},
+			wantAverage:          (40*2 + 30*4 + 10*4) / 10.0,
+			wantClosing:          10,
+			wantConsumption:      30,
+			wantConsumptionValue: 80,
+		},
+		{
+			name:    "stornierte Ausgänge sind kein Verbrauch",
+			opening: 10,
+			booked: []*model.Transaction{
+				{Type: model.TransactionTypeStockOut, Quantity: 5, UnitPrice: 2, OldStock: 10, NewStock: 5, Timestamp: day(4), ReversedBy: primitive.NewObjectID()},
+				{Type: model.TransactionTypeReversal, Quantity: 5, OldStock: 5, NewStock: 10, Timestamp: day(6)},
+			},
+			wantAverage: (10*4 + 5*2 + 10*4) / 10.0,
+			wantClosing: 10,
+		},
+		{
+			name:    "negativer Durchschnitt zählt als null",
+			opening: -5,
+			booked: []*model.Transaction{
+				{Type: model.TransactionTypeStockIn, Quantity: 2, OldStock: -5, NewStock: -3, Timestamp: day(5)},
+			},
+			wantAverage: 0,
+			wantClosing: -3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			average, closing, consumption, consumptionValue := integrateStock(tt.opening, tt.booked, start, end)
+
+			if math.Abs(average-tt.wantAverage) > 1e-9 {
+				t.Errorf("Durchschnittsbestand = %v, erwartet %v", average, tt.wantAverage)
+			}
+			if closing != tt.wantClosing {
+				t.Errorf("Endbestand = %v, erwartet %v", closing, tt.wantClosing)
+			}
+			if consumption != tt.wantConsumption {
+				t.Errorf("Verbrauch = %v, erwartet %v", consumption, tt.wantConsumption)
+			}
+			if math.Abs(consumptionValue-tt.wantConsumptionValue) > 1e-9 {
+				t.Errorf("Verbrauchswert = %v, erwartet %v", consumptionValue, tt.wantConsumptionValue)
+			}
+		})
+	}
+}
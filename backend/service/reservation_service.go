@@ -0,0 +1,250 @@
+// backend/service/reservation_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidReservationQuantity wird zurückgegeben, wenn keine positive Menge reserviert werden soll
+var ErrInvalidReservationQuantity = errors.New("Bitte eine Menge größer als null reservieren")
+
+// ErrReservationExpiry wird zurückgegeben, wenn das Ablaufdatum einer neuen Reservierung bereits erreicht ist
+var ErrReservationExpiry = errors.New("Das Ablaufdatum der Reservierung muss in der Zukunft liegen")
+
+// ErrReservationExceedsStock wird zurückgegeben, wenn der verfügbare Bestand für eine Reservierung nicht ausreicht
+var ErrReservationExceedsStock = errors.New("Der verfügbare Bestand reicht für diese Reservierung nicht aus")
+
+// systemUserName wird bei automatischen Vorgängen ohne angemeldeten Benutzer protokolliert
+const systemUserName = "System"
+
+// ReservationService verwaltet Reservierungen und den daraus berechneten reservierten Bestand
+type ReservationService struct {
+	reservationRepo *repository.ReservationRepository
+	articleRepo     *repository.ArticleRepository
+	activityRepo    *repository.ActivityRepository
+}
+
+// NewReservationService erstellt einen neuen ReservationService
+func NewReservationService() *ReservationService {
+	return &ReservationService{
+		reservationRepo: repository.NewReservationRepository(),
+		articleRepo:     repository.NewArticleRepository(),
+		activityRepo:    repository.NewActivityRepository(),
+	}
+}
+
+// CreateReservation reserviert eine Menge eines Artikels. Die Reservierung wird nur angelegt,
+// wenn der nicht reservierte Bestand dafür ausreicht.
+func (s *ReservationService) CreateReservation(
+	articleID string,
+	quantity float64,
+	reason, reference string,
+	expiresAt time.Time,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Reservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidReservationQuantity
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return nil, ErrReservationExpiry
+	}
+
+	article, err := s.articleRepo.FindByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
+	}
+
+	// Abgelaufene Reservierungen dürfen keinen Bestand mehr blockieren
+	s.ReleaseExpiredForArticle(article.ID)
+
+	if err := s.articleRepo.IncrementReserved(article.ID, quantity, false); err != nil {
+		if err == repository.ErrInsufficientStock {
+			return nil, ErrReservationExceedsStock
+		}
+		return nil, fmt.Errorf("Fehler beim Reservieren des Bestands: %v", err)
+	}
+
+	reservation := &model.Reservation{
+		ArticleID:   article.ID,
+		ArticleName: article.ShortName,
+		Quantity:    quantity,
+		Reason:      reason,
+		Reference:   reference,
+		OwnerID:     userID,
+		OwnerName:   userName,
+		ExpiresAt:   expiresAt,
+		Status:      model.ReservationStatusActive,
+	}
+	if err := s.reservationRepo.Create(reservation); err != nil {
+		s.revertReserved(article.ID, -quantity)
+		return nil, fmt.Errorf("Fehler beim Speichern der Reservierung: %v", err)
+	}
+
+	_, _ = s.activityRepo.LogActivity(
+		model.ActivityTypeStockReserved,
+		userID,
+		userName,
+		article.ID,
+		"article",
+		article.ShortName,
+		fmt.Sprintf("Reservierung: %g %s%s", quantity, article.Unit, formatReservationReference(reference)),
+		quantity,
+	)
+
+	return reservation, nil
+}
+
+// ReleaseReservation gibt eine aktive Reservierung manuell frei
+func (s *ReservationService) ReleaseReservation(id string, userID primitive.ObjectID, userName string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("Reservierung nicht gefunden: %v", err)
+	}
+
+	reservation, err := s.closeReservation(objID, model.ReservationStatusReleased)
+	if err != nil {
+		return err
+	}
+
+	_, _ = s.activityRepo.LogActivity(
+		model.ActivityTypeReservationReleased,
+		userID,
+		userName,
+		reservation.ArticleID,
+		"article",
+		reservation.ArticleName,
+		fmt.Sprintf("Reservierung freigegeben: %g%s", reservation.GetOpenQuantity(), formatReservationReference(reservation.Reference)),
+		reservation.GetOpenQuantity(),
+	)
+
+	return nil
+}
+
+// ReleaseExpired gibt alle abgelaufenen Reservierungen frei und gibt deren Anzahl zurück
+func (s *ReservationService) ReleaseExpired() (int, error) {
+	return s.releaseExpired(primitive.NilObjectID)
+}
+
+// ReleaseExpiredForArticle gibt die abgelaufenen Reservierungen eines Artikels frei. Fehler
+// werden nur protokolliert, da die Freigabe beim nächsten Durchlauf erneut versucht wird.
+func (s *ReservationService) ReleaseExpiredForArticle(articleID primitive.ObjectID) {
+	if _, err := s.releaseExpired(articleID); err != nil {
+		log.Printf("Abgelaufene Reservierungen für Artikel %s konnten nicht freigegeben werden: %v", articleID.Hex(), err)
+	}
+}
+
+// StartExpiryWorker gibt abgelaufene Reservierungen im angegebenen Intervall im Hintergrund frei
+func (s *ReservationService) StartExpiryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if released, err := s.ReleaseExpired(); err != nil {
+				log.Printf("Abgelaufene Reservierungen konnten nicht freigegeben werden: %v", err)
+			} else if released > 0 {
+				log.Printf("%d abgelaufene Reservierungen freigegeben", released)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// releaseExpired gibt abgelaufene Reservierungen frei, bei gesetzter articleID nur die dieses Artikels
+func (s *ReservationService) releaseExpired(articleID primitive.ObjectID) (int, error) {
+	expired, err := s.reservationRepo.FindExpired(articleID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, candidate := range expired {
+		reservation, err := s.closeReservation(candidate.ID, model.ReservationStatusExpired)
+		if err == repository.ErrReservationNotActive {
+			// Zwischenzeitlich erfüllt oder von einem anderen Vorgang freigegeben
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+
+		_, _ = s.activityRepo.LogActivity(
+			model.ActivityTypeReservationReleased,
+			primitive.NilObjectID,
+			systemUserName,
+			reservation.ArticleID,
+			"article",
+			reservation.ArticleName,
+			fmt.Sprintf("Reservierung abgelaufen: %g%s", reservation.GetOpenQuantity(), formatReservationReference(reservation.Reference)),
+			reservation.GetOpenQuantity(),
+		)
+	}
+
+	return released, nil
+}
+
+// closeReservation beendet eine aktive Reservierung und gibt ihre offene Menge frei
+func (s *ReservationService) closeReservation(id primitive.ObjectID, status model.ReservationStatus) (*model.Reservation, error) {
+	reservation, err := s.reservationRepo.Close(id, status)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.articleRepo.IncrementReserved(reservation.ArticleID, -reservation.GetOpenQuantity(), true); err != nil {
+		return nil, fmt.Errorf("Fehler beim Freigeben des reservierten Bestands: %v", err)
+	}
+
+	return reservation, nil
+}
+
+// consume verbucht einen Warenausgang auf eine Reservierung und gibt die davon gedeckte Menge
+// frei. Die zurückgegebene Funktion nimmt beides wieder zurück.
+func (s *ReservationService) consume(reservationID, articleID primitive.ObjectID, quantity float64) (func(), error) {
+	taken, err := s.reservationRepo.Consume(reservationID, articleID, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.articleRepo.IncrementReserved(articleID, -taken, true); err != nil {
+		s.reopen(reservationID, taken)
+		return nil, fmt.Errorf("Fehler beim Freigeben des reservierten Bestands: %v", err)
+	}
+
+	revert := func() {
+		s.reopen(reservationID, taken)
+		s.revertReserved(articleID, taken)
+	}
+
+	return revert, nil
+}
+
+// reopen nimmt eine auf die Reservierung verbuchte Ausgabe zurück
+func (s *ReservationService) reopen(reservationID primitive.ObjectID, quantity float64) {
+	if err := s.reservationRepo.Reopen(reservationID, quantity); err != nil {
+		log.Printf("Reservierung %s konnte nicht zurückgenommen werden: %v", reservationID.Hex(), err)
+	}
+}
+
+// revertReserved nimmt eine Änderung des reservierten Bestands zurück
+func (s *ReservationService) revertReserved(articleID primitive.ObjectID, delta float64) {
+	if err := s.articleRepo.IncrementReserved(articleID, delta, true); err != nil {
+		log.Printf("Reservierter Bestand für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+	}
+}
+
+// formatReservationReference ergänzt eine Beschreibung um die Referenz der Reservierung
+func formatReservationReference(reference string) string {
+	if reference == "" {
+		return ""
+	}
+	return " (" + reference + ")"
+}
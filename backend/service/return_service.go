@@ -0,0 +1,590 @@
+// backend/service/return_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrReturnSource wird zurückgegeben, wenn sich der Beleg nicht als Grundlage einer Retoure eignet
+var ErrReturnSource = errors.New("Zu diesem Beleg kann keine Retoure angelegt werden")
+
+// ErrReturnEmpty wird zurückgegeben, wenn keine Rückgabemenge erfasst wurde
+var ErrReturnEmpty = errors.New("Bitte mindestens eine zurückgegebene Menge erfassen")
+
+// ErrReturnQuantity wird zurückgegeben, wenn mehr zurückgegeben werden soll als noch offen ist
+var ErrReturnQuantity = errors.New("Die Rückgabemenge übersteigt die noch nicht zurückgegebene Menge")
+
+// ErrReturnPartner wird zurückgegeben, wenn bei einer Kundenretoure der Kunde fehlt
+var ErrReturnPartner = errors.New("Bitte den Kunden angeben")
+
+// ErrReturnNotOpen wird zurückgegeben, wenn eine bereits geprüfte oder stornierte Retoure bearbeitet werden soll
+var ErrReturnNotOpen = errors.New("Die Retoure ist nicht mehr offen")
+
+// ErrReturnCondition wird zurückgegeben, wenn für eine Position kein zulässiges Prüfergebnis gewählt wurde
+var ErrReturnCondition = errors.New("Bitte für jede Position ein zulässiges Prüfergebnis wählen")
+
+// ErrReturnNotReversible wird zurückgegeben, wenn eine einzelne Buchung einer Retoure storniert werden soll
+var ErrReturnNotReversible = errors.New("Buchungen einer Retoure können nicht einzeln storniert werden")
+
+// ErrReversalReturned wird zurückgegeben, wenn eine Buchung storniert werden soll, zu der bereits eine Retoure erfasst ist
+var ErrReversalReturned = errors.New("Die Buchung kann nicht storniert werden, da zu ihr bereits eine Retoure erfasst ist")
+
+// returnEpsilon gleicht Rundungsfehler beim Vergleich mit der offenen Menge aus
+const returnEpsilon = 1e-9
+
+// ReturnCandidate ist eine Position des Originalbelegs, die zurückgegeben werden kann
+type ReturnCandidate struct {
+	Line     model.ReturnLine // Vorbelegte Position mit Artikel, Einheit und Vorschlägen für die Prüfung
+	Returned float64          // In anderen Retouren bereits zurückgegebene Menge
+}
+
+// GetReturnable gibt die noch zurückgebbare Menge zurück
+func (c *ReturnCandidate) GetReturnable() float64 {
+	if returnable := c.Line.OriginalQuantity - c.Returned; returnable > 0 {
+		return returnable
+	}
+	return 0
+}
+
+// ReturnLineInput ist die erfasste Rückgabemenge zu einer Position des Originalbelegs
+type ReturnLineInput struct {
+	CandidateIndex int // Index der Position in den Kandidaten
+	Quantity       float64
+	Reason         string
+}
+
+// ReturnInput enthält die Erfassung einer Retoure
+type ReturnInput struct {
+	PartnerName       string
+	ExternalReference string
+	Notes             string
+	Lines             []ReturnLineInput
+}
+
+// ReturnAssessmentInput ist das Ergebnis der Zustandsprüfung einer Position
+type ReturnAssessmentInput struct {
+	Condition     model.ReturnCondition
+	LocationID    primitive.ObjectID
+	StockStatus   model.StockStatus // Nur bei Rücksendungen: Bestandsstatus der zurückgehenden Ware
+	LotNumber     string
+	ExpiryDate    time.Time
+	SerialNumbers []string
+}
+
+// ReturnService verwaltet Kundenretouren und Rücksendungen an Lieferanten
+type ReturnService struct {
+	returnRepo        *repository.ReturnRepository
+	transactionRepo   *repository.TransactionRepository
+	goodsReceiptRepo  *repository.GoodsReceiptRepository
+	customerOrderRepo *repository.CustomerOrderRepository
+	articleRepo       *repository.ArticleRepository
+	activityRepo      *repository.ActivityRepository
+	stockService      *StockService
+}
+
+// NewReturnService erstellt einen neuen ReturnService
+func NewReturnService() *ReturnService {
+	return &ReturnService{
+		returnRepo:        repository.NewReturnRepository(),
+		transactionRepo:   repository.NewTransactionRepository(),
+		goodsReceiptRepo:  repository.NewGoodsReceiptRepository(),
+		customerOrderRepo: repository.NewCustomerOrderRepository(),
+		articleRepo:       repository.NewArticleRepository(),
+		activityRepo:      repository.NewActivityRepository(),
+		stockService:      NewStockService(),
+	}
+}
+
+// FindByID findet eine Retoure anhand ihrer ID
+func (s *ReturnService) FindByID(id string) (*model.ReturnCase, error) {
+	return s.returnRepo.FindByID(id)
+}
+
+// FindAll findet alle Retouren, optional gefiltert nach Art und Status
+func (s *ReturnService) FindAll(returnType model.ReturnType, status model.ReturnStatus) ([]*model.ReturnCase, error) {
+	return s.returnRepo.FindAll(returnType, status)
+}
+
+// FindBySource findet die Retouren zu einem Kundenauftrag oder Wareneingang
+func (s *ReturnService) FindBySource(sourceID primitive.ObjectID) ([]*model.ReturnCase, error) {
+	return s.returnRepo.FindBySource(sourceID)
+}
+
+// PrepareCustomerReturn stellt die Warenausgänge zusammen, auf die sich eine Kundenretoure
+// beziehen kann: alle Versandbuchungen eines Kundenauftrags oder ein einzelner Warenausgang.
+// Zurückgegeben werden der Kopf der Retoure und die Positionen mit ihrer offenen Menge.
+func (s *ReturnService) PrepareCustomerReturn(orderID, transactionID string) (*model.ReturnCase, []*ReturnCandidate, error) {
+	header := &model.ReturnCase{Type: model.ReturnTypeCustomer}
+
+	var transactions []*model.Transaction
+	if orderID != "" {
+		order, err := s.customerOrderRepo.FindByID(orderID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Auftrag nicht gefunden: %v", err)
+		}
+		transactions, err = s.transactionRepo.FindShipmentsByCustomerOrderID(order.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		header.CustomerOrderID = order.ID
+		header.SourceNumber = order.OrderNumber
+		header.PartnerName = order.CustomerName
+		header.ExternalReference = order.CustomerReference
+	} else {
+		transaction, err := s.transactionRepo.FindByID(transactionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Buchung nicht gefunden: %v", err)
+		}
+		if transaction.Type != model.TransactionTypeStockOut || transaction.IsReversed() ||
+			!transaction.AssemblyID.IsZero() || !transaction.ReturnID.IsZero() {
+			return nil, nil, ErrReturnSource
+		}
+		transactions = []*model.Transaction{transaction}
+		header.SourceNumber = transaction.Reference
+		if !transaction.CustomerOrderID.IsZero() {
+			if order, err := s.customerOrderRepo.FindByID(transaction.CustomerOrderID.Hex()); err == nil {
+				header.CustomerOrderID = order.ID
+				header.SourceNumber = order.OrderNumber
+				header.PartnerName = order.CustomerName
+				header.ExternalReference = order.CustomerReference
+			}
+		}
+	}
+	if len(transactions) == 0 {
+		return nil, nil, ErrReturnSource
+	}
+
+	articles := make(map[primitive.ObjectID]*model.Article)
+	candidates := make([]*ReturnCandidate, 0, len(transactions))
+	for _, transaction := range transactions {
+		article, ok := articles[transaction.ArticleID]
+		if !ok {
+			var err error
+			article, err = s.articleRepo.FindByID(transaction.ArticleID.Hex())
+			if err != nil {
+				return nil, nil, fmt.Errorf("Artikel %s nicht gefunden: %v", transaction.ArticleName, err)
+			}
+			articles[transaction.ArticleID] = article
+		}
+
+		// Zurückgenommen wird in der Lagereinheit; Charge und Seriennummern des Ausgangs
+		// sind die Vorschläge für die Prüfung
+		line := model.ReturnLine{
+			ArticleID:             article.ID,
+			ArticleNumber:         article.ArticleNumber,
+			ArticleName:           article.ShortName,
+			Unit:                  article.Unit,
+			Factor:                1,
+			OriginalTransactionID: transaction.ID,
+			OriginalQuantity:      transaction.Quantity,
+			LocationID:            article.StorageLocationID,
+			LotNumber:             transaction.LotNumber,
+			SerialNumbers:         transaction.SerialNumbers,
+		}
+		if len(transaction.Lots) == 1 {
+			line.LotNumber = transaction.Lots[0].LotNumber
+			line.ExpiryDate = transaction.Lots[0].ExpiryDate
+		}
+		candidates = append(candidates, &ReturnCandidate{Line: line})
+	}
+
+	if err := s.loadReturned(candidates); err != nil {
+		return nil, nil, err
+	}
+	return header, candidates, nil
+}
+
+// PrepareSupplierReturn stellt die Positionen eines Wareneingangs zusammen, die an den
+// Lieferanten zurückgeschickt werden können. Zurückgeschickt wird in der Bestelleinheit mit
+// dem Umrechnungsfaktor, mit dem der Wareneingang gebucht wurde.
+func (s *ReturnService) PrepareSupplierReturn(receiptID string) (*model.ReturnCase, []*ReturnCandidate, error) {
+	receipt, err := s.goodsReceiptRepo.FindByID(receiptID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Wareneingang nicht gefunden: %v", err)
+	}
+
+	header := &model.ReturnCase{
+		Type:           model.ReturnTypeSupplier,
+		PartnerName:    receipt.SupplierName,
+		SupplierID:     receipt.SupplierID,
+		GoodsReceiptID: receipt.ID,
+		SourceNumber:   receipt.ReceiptNumber,
+	}
+
+	candidates := make([]*ReturnCandidate, 0, len(receipt.Lines))
+	for _, receiptLine := range receipt.Lines {
+		transaction, err := s.transactionRepo.FindByID(receiptLine.TransactionID.Hex())
+		if err != nil || transaction.IsReversed() {
+			continue
+		}
+
+		factor := 1.0
+		if transaction.HasInputUnit() && transaction.InputFactor > 0 {
+			factor = transaction.InputFactor
+		}
+		candidates = append(candidates, &ReturnCandidate{Line: model.ReturnLine{
+			ArticleID:             receiptLine.ArticleID,
+			ArticleNumber:         receiptLine.ArticleNumber,
+			ArticleName:           receiptLine.ArticleName,
+			Unit:                  receiptLine.Unit,
+			Factor:                factor,
+			OriginalTransactionID: transaction.ID,
+			OriginalQuantity:      receiptLine.Quantity,
+			LocationID:            receiptLine.LocationID,
+			LotNumber:             receiptLine.LotNumber,
+			ExpiryDate:            receiptLine.ExpiryDate,
+			SerialNumbers:         receiptLine.SerialNumbers,
+		}})
+	}
+	if len(candidates) == 0 {
+		return nil, nil, ErrReturnSource
+	}
+
+	if err := s.loadReturned(candidates); err != nil {
+		return nil, nil, err
+	}
+	return header, candidates, nil
+}
+
+// loadReturned ergänzt die Kandidaten um die in anderen Retouren bereits zurückgegebenen Mengen
+func (s *ReturnService) loadReturned(candidates []*ReturnCandidate) error {
+	ids := make([]primitive.ObjectID, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.Line.OriginalTransactionID
+	}
+
+	returned, err := s.returnRepo.SumReturnedQuantities(ids, primitive.NilObjectID)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		candidate.Returned = returned[candidate.Line.OriginalTransactionID]
+	}
+	return nil
+}
+
+// CreateReturn legt eine offene Retoure zu den vorbereiteten Positionen an. Gebucht wird
+// erst mit der Zustandsprüfung; bis dahin ist die Ware nur angekündigt.
+func (s *ReturnService) CreateReturn(
+	header *model.ReturnCase,
+	candidates []*ReturnCandidate,
+	input ReturnInput,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.ReturnCase, error) {
+	returnCase := *header
+	if returnCase.Type == model.ReturnTypeCustomer && input.PartnerName != "" {
+		returnCase.PartnerName = input.PartnerName
+	}
+	if returnCase.PartnerName == "" {
+		return nil, ErrReturnPartner
+	}
+	if input.ExternalReference != "" {
+		returnCase.ExternalReference = input.ExternalReference
+	}
+	returnCase.Notes = input.Notes
+	returnCase.CreatedByID = userID
+	returnCase.CreatedByName = userName
+
+	seen := make(map[int]bool, len(input.Lines))
+	for _, in := range input.Lines {
+		if in.CandidateIndex < 0 || in.CandidateIndex >= len(candidates) || seen[in.CandidateIndex] || in.Quantity < 0 {
+			return nil, ErrReturnQuantity
+		}
+		seen[in.CandidateIndex] = true
+		if in.Quantity == 0 {
+			continue
+		}
+
+		candidate := candidates[in.CandidateIndex]
+		if in.Quantity > candidate.GetReturnable()+returnEpsilon {
+			return nil, fmt.Errorf("%w (%s)", ErrReturnQuantity, candidate.Line.ArticleNumber)
+		}
+
+		line := candidate.Line
+		line.Quantity = in.Quantity
+		line.Reason = in.Reason
+		// Seriennummern nur vorschlagen, wenn alle Stücke zurückkommen
+		if in.Quantity < line.OriginalQuantity {
+			line.SerialNumbers = nil
+		}
+		returnCase.Lines = append(returnCase.Lines, line)
+	}
+	if len(returnCase.Lines) == 0 {
+		return nil, ErrReturnEmpty
+	}
+
+	if err := s.returnRepo.Create(&returnCase); err != nil {
+		return nil, fmt.Errorf("Fehler beim Speichern der Retoure: %v", err)
+	}
+
+	s.logActivity(model.ActivityTypeReturnCreated, &returnCase, userID, userName,
+		fmt.Sprintf("%s %s angelegt (%s, %d Positionen)", returnCase.GetDisplayType(),
+			returnCase.ReturnNumber, returnCase.PartnerName, len(returnCase.Lines)))
+
+	return &returnCase, nil
+}
+
+// PostReturn schließt die Zustandsprüfung einer offenen Retoure ab und bucht jede Position
+// entsprechend ihrem Prüfergebnis:
+//
+//   - Wieder einlagern: Zugang am gewählten Lagerplatz zum Einstandspreis des ursprünglichen
+//     Warenausgangs
+//   - Quarantäne: Zugang wie beim Einlagern, aber in den Bestandsstatus Quarantäne, sodass die
+//     Ware bis zur Klärung nicht ausgegeben werden kann
+//   - Verschrotten (Kunde): Zugang als beschädigte Ware und sofortiger Abgang am gewählten
+//     Lagerplatz, damit die Rücknahme in der Bestandsführung nachvollziehbar bleibt
+//   - An Lieferanten zurück und Verschrotten (Lieferant): Warenausgang vom gewählten Lagerplatz
+//     aus dem gewählten Bestandsstatus, z.B. aus der Quarantäne nach einer Wareneingangsprüfung
+//
+// Die Retoure wird vor der ersten Buchung als abgeschlossen markiert, damit sie nicht doppelt
+// gebucht wird. Schlägt eine Buchung fehl, werden die bereits gebuchten storniert und die
+// Retoure wieder geöffnet.
+func (s *ReturnService) PostReturn(
+	id string,
+	assessments []ReturnAssessmentInput,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.ReturnCase, error) {
+	returnCase, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("Retoure nicht gefunden: %v", err)
+	}
+	if !returnCase.IsOpen() {
+		return nil, ErrReturnNotOpen
+	}
+	if len(assessments) != len(returnCase.Lines) {
+		return nil, ErrReturnCondition
+	}
+
+	// Prüfergebnisse übernehmen und vor der ersten Buchung prüfen
+	lines := append([]model.ReturnLine(nil), returnCase.Lines...)
+	for i := range lines {
+		line := &lines[i]
+		in := assessments[i]
+		if !in.Condition.IsAllowedFor(returnCase.Type) {
+			return nil, fmt.Errorf("%w (%s)", ErrReturnCondition, line.ArticleNumber)
+		}
+		if in.LocationID.IsZero() {
+			return nil, fmt.Errorf("%s: %w", line.ArticleNumber, ErrLocationRequired)
+		}
+		if returnCase.Type != model.ReturnTypeSupplier || in.StockStatus == model.StockStatusAvailable {
+			in.StockStatus = ""
+		}
+		if in.StockStatus != "" && !in.StockStatus.IsValid() {
+			return nil, fmt.Errorf("%s: %w", line.ArticleNumber, ErrInvalidStockStatus)
+		}
+
+		article, err := s.articleRepo.FindByID(line.ArticleID.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Artikel %s nicht gefunden: %v", line.ArticleNumber, err)
+		}
+		if returnCase.Type == model.ReturnTypeCustomer && article.LotTracked && in.LotNumber == "" {
+			return nil, fmt.Errorf("%s: %w", line.ArticleNumber, ErrLotRequired)
+		}
+		if article.SerialNumberRequired {
+			if err := checkSerialCount(in.SerialNumbers, line.GetBaseQuantity()); err != nil {
+				return nil, fmt.Errorf("%s: %w", line.ArticleNumber, err)
+			}
+		} else {
+			in.SerialNumbers = nil
+		}
+
+		line.Condition = in.Condition
+		line.LocationID = in.LocationID
+		line.StockStatus = in.StockStatus
+		line.LotNumber = in.LotNumber
+		line.ExpiryDate = in.ExpiryDate
+		line.SerialNumbers = in.SerialNumbers
+		line.TransactionIDs = nil
+	}
+
+	completedAt := time.Now()
+	if err := s.returnRepo.UpdateStatus(returnCase.ID, model.ReturnStatusOpen, model.ReturnStatusCompleted, bson.M{
+		"completedAt":     completedAt,
+		"completedByName": userName,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Rücknahme bei einem Fehler: gebuchte Bewegungen stornieren, Retoure wieder öffnen
+	var posted []*model.Transaction
+	rollback := func() {
+		for i := len(posted) - 1; i >= 0; i-- {
+			transaction := posted[i]
+			if _, err := s.stockService.reverse(transaction, "Retoure abgebrochen", userID, userName); err != nil {
+				log.Printf("Retoure %s: Buchung %s konnte nicht storniert werden: %v",
+					returnCase.ReturnNumber, transaction.ID.Hex(), err)
+			}
+		}
+		if err := s.returnRepo.UpdateStatus(returnCase.ID, model.ReturnStatusCompleted, model.ReturnStatusOpen, bson.M{
+			"completedAt":     time.Time{},
+			"completedByName": "",
+		}); err != nil {
+			log.Printf("Retoure %s konnte nicht wieder geöffnet werden: %v", returnCase.ReturnNumber, err)
+		}
+	}
+
+	reference := returnCase.ReturnNumber
+	if returnCase.SourceNumber != "" {
+		reference += " / " + returnCase.SourceNumber
+	}
+
+	post := func(line *model.ReturnLine, transaction *model.Transaction) error {
+		transaction.ArticleID = line.ArticleID
+		transaction.LocationID = line.LocationID
+		transaction.SerialNumbers = line.SerialNumbers
+		transaction.Reference = reference
+		transaction.UserID = userID
+		transaction.UserName = userName
+		transaction.Timestamp = completedAt
+		transaction.ReturnID = returnCase.ID
+		if err := s.stockService.PostTransaction(transaction); err != nil {
+			return fmt.Errorf("%s: %w", line.ArticleNumber, err)
+		}
+		posted = append(posted, transaction)
+
+		line.TransactionIDs = append(line.TransactionIDs, transaction.ID)
+		line.LocationName = transaction.LocationName
+		return nil
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		reason := fmt.Sprintf("Retoure: %s", line.Condition.GetDisplayName())
+		if line.Reason != "" {
+			reason += " (" + line.Reason + ")"
+		}
+
+		if returnCase.Type == model.ReturnTypeSupplier {
+			// Zurückgeschickt wird in der Einheit, in der geliefert wurde
+			out := &model.Transaction{
+				Type:          model.TransactionTypeStockOut,
+				InputUnit:     line.Unit,
+				InputQuantity: line.Quantity,
+				InputFactor:   line.Factor,
+				Reason:        reason,
+				LotNumber:     line.LotNumber,
+				StockStatus:   line.StockStatus,
+			}
+			if err := post(line, out); err != nil {
+				rollback()
+				return nil, err
+			}
+			continue
+		}
+
+		unitPrice, err := s.returnCost(line)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		in := &model.Transaction{
+			Type:       model.TransactionTypeStockIn,
+			Quantity:   line.GetBaseQuantity(),
+			UnitPrice:  unitPrice,
+			Reason:     reason,
+			LotNumber:  line.LotNumber,
+			ExpiryDate: line.ExpiryDate,
+		}
+		switch line.Condition {
+		case model.ReturnConditionQuarantine:
+			in.StockStatus = model.StockStatusQuarantine
+		case model.ReturnConditionScrap:
+			in.StockStatus = model.StockStatusDamaged
+		}
+		if err := post(line, in); err != nil {
+			rollback()
+			return nil, err
+		}
+
+		if line.Condition == model.ReturnConditionScrap {
+			out := &model.Transaction{
+				Type:        model.TransactionTypeStockOut,
+				Quantity:    in.Quantity,
+				Reason:      "Verschrottung " + returnCase.ReturnNumber,
+				LotNumber:   line.LotNumber,
+				StockStatus: model.StockStatusDamaged,
+			}
+			if err := post(line, out); err != nil {
+				rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.returnRepo.UpdateLines(returnCase.ID, lines); err != nil {
+		rollback()
+		return nil, fmt.Errorf("Fehler beim Speichern der Retoure: %v", err)
+	}
+	returnCase.Lines = lines
+	returnCase.Status = model.ReturnStatusCompleted
+	returnCase.CompletedAt = completedAt
+	returnCase.CompletedByName = userName
+
+	s.logActivity(model.ActivityTypeReturnUpdated, returnCase, userID, userName,
+		fmt.Sprintf("%s %s geprüft und gebucht", returnCase.GetDisplayType(), returnCase.ReturnNumber))
+
+	return returnCase, nil
+}
+
+// returnCost ermittelt den Einstandspreis einer zurückgenommenen Position: den
+// Durchschnittspreis zum Zeitpunkt des ursprünglichen Warenausgangs, ersatzweise den
+// aktuellen Durchschnittspreis des Artikels
+func (s *ReturnService) returnCost(line *model.ReturnLine) (float64, error) {
+	if original, err := s.transactionRepo.FindByID(line.OriginalTransactionID.Hex()); err == nil && original.AverageCost > 0 {
+		return original.AverageCost, nil
+	}
+
+	article, err := s.articleRepo.FindByID(line.ArticleID.Hex())
+	if err != nil {
+		return 0, fmt.Errorf("Artikel %s nicht gefunden: %v", line.ArticleNumber, err)
+	}
+	return article.GetAverageCost(), nil
+}
+
+// CancelReturn storniert eine offene Retoure; gebucht wurde für sie noch nichts
+func (s *ReturnService) CancelReturn(id string, userID primitive.ObjectID, userName string) error {
+	returnCase, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("Retoure nicht gefunden: %v", err)
+	}
+	if !returnCase.IsOpen() {
+		return ErrReturnNotOpen
+	}
+
+	if err := s.returnRepo.UpdateStatus(returnCase.ID, model.ReturnStatusOpen, model.ReturnStatusCancelled, nil); err != nil {
+		return err
+	}
+
+	s.logActivity(model.ActivityTypeReturnUpdated, returnCase, userID, userName,
+		fmt.Sprintf("%s %s storniert", returnCase.GetDisplayType(), returnCase.ReturnNumber))
+	return nil
+}
+
+// logActivity protokolliert eine Aktivität zu einer Retoure
+func (s *ReturnService) logActivity(
+	activityType model.ActivityType,
+	returnCase *model.ReturnCase,
+	userID primitive.ObjectID,
+	userName, description string,
+) {
+	_, _ = s.activityRepo.LogActivity(
+		activityType,
+		userID,
+		userName,
+		returnCase.ID,
+		"return",
+		returnCase.ReturnNumber,
+		description,
+		0,
+	)
+}
@@ -0,0 +1,192 @@
+// backend/service/stock_correction.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCorrectionReasonRequired wird zurückgegeben, wenn eine Journalkorrektur ohne Begründung gebucht werden soll
+var ErrCorrectionReasonRequired = errors.New("Für eine Journalkorrektur muss eine Begründung angegeben werden")
+
+// ErrCorrectionNotReversible wird zurückgegeben, wenn eine Journalkorrektur storniert werden soll
+var ErrCorrectionNotReversible = errors.New("Journalkorrekturen können nicht storniert werden")
+
+// ErrStockChangeTracked wird zurückgegeben, wenn der Bestand eines chargen- oder
+// seriennummernpflichtigen Artikels ohne Angabe der Chargen bzw. Seriennummern geändert werden soll
+var ErrStockChangeTracked = errors.New("Der Bestand chargen- oder seriennummernpflichtiger Artikel kann nur über Buchungen mit Chargen- bzw. Seriennummernangabe geändert werden")
+
+// ChangeStock bucht eine Bestandsänderung um delta am angegebenen Lagerort als Zu- bzw.
+// Abgang. Sie dient Stellen wie dem Artikelformular, die nur den neuen Gesamtbestand kennen.
+// Gebucht wird die Differenz, sodass zwischenzeitliche Bewegungen erhalten bleiben; Abgänge
+// dürfen wie jede Entnahme nur verfügbaren Bestand verbrauchen. Bewertet wird die Änderung
+// zum Durchschnittspreis, damit sie den Durchschnittspreis nicht verschiebt.
+func (s *StockService) ChangeStock(
+	article *model.Article,
+	locationID primitive.ObjectID,
+	delta float64,
+	reason string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Transaction, error) {
+	if article.LotTracked || article.SerialNumberRequired {
+		return nil, ErrStockChangeTracked
+	}
+
+	transaction := &model.Transaction{
+		Type:       model.TransactionTypeStockIn,
+		ArticleID:  article.ID,
+		Quantity:   delta,
+		UnitPrice:  article.GetAverageCost(),
+		LocationID: locationID,
+		Reason:     reason,
+		UserID:     userID,
+		UserName:   userName,
+	}
+	if delta < 0 {
+		transaction.Type = model.TransactionTypeStockOut
+		transaction.Quantity = -delta
+	}
+
+	if err := s.PostTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// PostCorrection bucht eine Journalkorrektur, nach der die Summe aller Buchungen eines Artikels
+// dem angegebenen Bestand entspricht. Der alte Bestand der Korrektur ist der Bestand laut
+// Journal, der neue der festgestellte richtige Bestand.
+//
+// Weicht der richtige Bestand vom gespeicherten Artikelbestand ab, wird dieser ebenfalls
+// angepasst. Die Summe der Lagerplatzbestände wird am Standardlagerort des Artikels auf den
+// richtigen Bestand gebracht.
+func (s *StockService) PostCorrection(
+	articleID string,
+	stock float64,
+	reason, notes string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Transaction, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrCorrectionReasonRequired
+	}
+
+	article, err := s.articleRepo.FindByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
+	}
+
+	summary, err := s.transactionRepo.SummarizeLedgerByArticle(article.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Auswerten des Buchungsjournals: %v", err)
+	}
+	var ledgerStock float64
+	if summary != nil {
+		ledgerStock = summary.MovementSum
+	}
+
+	// Ohne Chargen- bzw. Seriennummernangabe darf sich nur das Journal, nicht der Bestand ändern
+	delta := stock - article.StockCurrent
+	if math.Abs(delta) >= lotEpsilon && (article.LotTracked || article.SerialNumberRequired) {
+		return nil, ErrStockChangeTracked
+	}
+
+	transaction := &model.Transaction{
+		ID:          primitive.NewObjectID(),
+		Type:        model.TransactionTypeCorrection,
+		ArticleID:   article.ID,
+		ArticleName: article.ShortName,
+		Quantity:    stock - ledgerStock,
+		OldStock:    ledgerStock,
+		NewStock:    stock,
+		UnitPrice:   article.GetAverageCost(),
+		AverageCost: article.GetAverageCost(),
+		LocationID:  article.StorageLocationID,
+		Reason:      reason,
+		Notes:       notes,
+		UserID:      userID,
+		UserName:    userName,
+		Timestamp:   time.Now(),
+	}
+	if err := s.resolveLocation(transaction); err != nil {
+		return nil, err
+	}
+
+	var undo []func()
+	revert := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	// Artikelbestand und FIFO-Kostenschichten an den richtigen Bestand anpassen
+	if math.Abs(delta) >= lotEpsilon {
+		if _, _, err := s.articleRepo.IncrementStock(article.ID, delta, true); err != nil {
+			return nil, fmt.Errorf("Fehler beim Aktualisieren des Artikelbestands: %v", err)
+		}
+		undo = append(undo, func() {
+			if _, _, err := s.articleRepo.IncrementStock(article.ID, -delta, true); err != nil {
+				log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", article.ID.Hex(), err)
+			}
+		})
+
+		var undoLayers func()
+		if delta > 0 {
+			undoLayers, err = s.addCostLayers(transaction, nil, delta)
+		} else {
+			undoLayers, err = s.consumeCostLayers(transaction, nil, -delta)
+		}
+		if err != nil {
+			revert()
+			return nil, err
+		}
+		undo = append(undo, undoLayers)
+	}
+
+	// Lagerplatzbestände am Standardlagerort ausgleichen
+	levels, err := s.stockLevelRepo.FindByArticleID(article.ID)
+	if err != nil {
+		revert()
+		return nil, fmt.Errorf("Fehler beim Laden der Lagerplatzbestände: %v", err)
+	}
+	var levelStock float64
+	for _, level := range levels {
+		levelStock += level.Quantity
+	}
+	if levelDelta := stock - levelStock; math.Abs(levelDelta) >= lotEpsilon {
+		if _, _, err := s.stockLevelRepo.Increment(article.ID, article.StorageLocationID, levelDelta, true); err != nil {
+			revert()
+			return nil, fmt.Errorf("Fehler beim Aktualisieren des Lagerplatzbestands: %v", err)
+		}
+		undo = append(undo, func() { s.revertLevel(article.ID, article.StorageLocationID, -levelDelta) })
+	}
+
+	if err := s.transactionRepo.Create(transaction); err != nil {
+		revert()
+		return nil, fmt.Errorf("Fehler beim Speichern der Transaktion: %v", err)
+	}
+
+	_, _ = s.activityRepo.LogActivity(
+		model.ActivityTypeStockAdjusted,
+		transaction.UserID,
+		transaction.UserName,
+		article.ID,
+		"article",
+		article.ShortName,
+		fmt.Sprintf("%s: Journalbestand %g %s auf %g %s korrigiert (%s)", transaction.GetDisplayType(),
+			ledgerStock, article.Unit, stock, article.Unit, reason),
+		transaction.Quantity,
+	)
+
+	return transaction, nil
+}
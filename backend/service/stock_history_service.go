@@ -0,0 +1,240 @@
+// backend/service/stock_history_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockHistoryService rekonstruiert Bestände zu früheren Zeitpunkten aus dem Buchungsjournal
+type StockHistoryService struct {
+	articleRepo     *repository.ArticleRepository
+	transactionRepo *repository.TransactionRepository
+}
+
+// NewStockHistoryService erstellt einen neuen StockHistoryService
+func NewStockHistoryService() *StockHistoryService {
+	return &StockHistoryService{
+		articleRepo:     repository.NewArticleRepository(),
+		transactionRepo: repository.NewTransactionRepository(),
+	}
+}
+
+// StockAsOfEntry ist der Bestand eines Artikels zu einem Zeitpunkt
+type StockAsOfEntry struct {
+	ArticleID     string  `json:"articleId"`
+	ArticleNumber string  `json:"articleNumber"`
+	ArticleName   string  `json:"articleName"`
+	Category      string  `json:"category"`
+	Unit          string  `json:"unit"`
+	Quantity      float64 `json:"quantity"`
+	UnitCost      float64 `json:"unitCost"` // Gleitender Durchschnittspreis zum Zeitpunkt
+	Value         float64 `json:"value"`
+}
+
+// StockComparisonEntry stellt den Bestand eines Artikels zu zwei Zeitpunkten gegenüber
+type StockComparisonEntry struct {
+	ArticleID     string  `json:"articleId"`
+	ArticleNumber string  `json:"articleNumber"`
+	ArticleName   string  `json:"articleName"`
+	Category      string  `json:"category"`
+	Unit          string  `json:"unit"`
+	QuantityFrom  float64 `json:"quantityFrom"`
+	QuantityTo    float64 `json:"quantityTo"`
+	ValueFrom     float64 `json:"valueFrom"`
+	ValueTo       float64 `json:"valueTo"`
+}
+
+// GetQuantityDifference gibt die Bestandsveränderung zwischen den beiden Zeitpunkten zurück
+func (e *StockComparisonEntry) GetQuantityDifference() float64 {
+	return e.QuantityTo - e.QuantityFrom
+}
+
+// GetValueDifference gibt die Wertveränderung zwischen den beiden Zeitpunkten zurück
+func (e *StockComparisonEntry) GetValueDifference() float64 {
+	return e.ValueTo - e.ValueFrom
+}
+
+// stockAsOf ermittelt Bestand und Durchschnittspreis eines Artikels zu einem Zeitpunkt. last ist
+// die letzte Buchung bis zum Zeitpunkt, next die erste danach (jeweils nil, falls keine
+// existiert). Ohne Buchung davor gilt der Bestand vor der nächsten Buchung, ohne jede Buchung
+// der aktuelle Bestand. Buchungen ohne gespeicherten Durchschnittspreis stammen aus der Zeit
+// vor der Bewertung; dann gilt der aktuelle Durchschnittspreis.
+func stockAsOf(article *model.Article, last, next *model.Transaction) (float64, float64) {
+	quantity, averageCost := article.StockCurrent, article.GetAverageCost()
+	switch {
+	case last != nil:
+		quantity = last.NewStock
+		if last.AverageCost > 0 {
+			averageCost = last.AverageCost
+		}
+	case next != nil:
+		quantity = next.OldStock
+	}
+	return quantity, averageCost
+}
+
+// GetArticleStockAsOf gibt den Bestand eines Artikels zum angegebenen Zeitpunkt zurück
+func (s *StockHistoryService) GetArticleStockAsOf(articleID string, at time.Time) (*StockAsOfEntry, error) {
+	article, err := s.articleRepo.FindByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
+	}
+
+	entry := newStockAsOfEntry(article, 0, 0)
+	if article.CreatedAt.After(at) {
+		return entry, nil
+	}
+
+	last, err := s.transactionRepo.FindLastByArticleUntil(article.ID, at)
+	if err != nil {
+		return nil, err
+	}
+	var next *model.Transaction
+	if last == nil {
+		next, err = s.transactionRepo.FindFirstByArticleAfter(article.ID, at)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	quantity, averageCost := stockAsOf(article, last, next)
+	return newStockAsOfEntry(article, quantity, averageCost), nil
+}
+
+// GetStockAsOf rekonstruiert den Bestand aller Artikel zum angegebenen Zeitpunkt mit Wert zum
+// damaligen Durchschnittspreis. Artikel ohne Bestand werden nicht aufgeführt.
+func (s *StockHistoryService) GetStockAsOf(at time.Time) (map[string]interface{}, error) {
+	snapshot, articles, err := s.snapshot(at)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []StockAsOfEntry{}
+	var totalValue float64
+	for _, article := range articles {
+		entry, exists := snapshot[article.ID]
+		if !exists || math.Abs(entry.Quantity) < lotEpsilon {
+			continue
+		}
+		entries = append(entries, *entry)
+		totalValue += entry.Value
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ArticleNumber < entries[j].ArticleNumber
+	})
+
+	return map[string]interface{}{
+		"at":           at,
+		"entries":      entries,
+		"articleCount": len(entries),
+		"totalValue":   totalValue,
+		"generatedAt":  time.Now(),
+	}, nil
+}
+
+// CompareStockAsOf stellt die Bestände aller Artikel zu zwei Zeitpunkten gegenüber. Artikel,
+// die zu beiden Zeitpunkten keinen Bestand hatten, werden nicht aufgeführt.
+func (s *StockHistoryService) CompareStockAsOf(from, to time.Time) (map[string]interface{}, error) {
+	before, articles, err := s.snapshot(from)
+	if err != nil {
+		return nil, err
+	}
+	after, _, err := s.snapshot(to)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []StockComparisonEntry{}
+	var valueFrom, valueTo float64
+	var changed int
+	for _, article := range articles {
+		entry := StockComparisonEntry{
+			ArticleID:     article.ID.Hex(),
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Category:      article.Category,
+			Unit:          article.Unit,
+		}
+		if snapshot, exists := before[article.ID]; exists {
+			entry.QuantityFrom, entry.ValueFrom = snapshot.Quantity, snapshot.Value
+		}
+		if snapshot, exists := after[article.ID]; exists {
+			entry.QuantityTo, entry.ValueTo = snapshot.Quantity, snapshot.Value
+		}
+
+		if math.Abs(entry.QuantityFrom) < lotEpsilon && math.Abs(entry.QuantityTo) < lotEpsilon {
+			continue
+		}
+		if math.Abs(entry.GetQuantityDifference()) >= lotEpsilon {
+			changed++
+		}
+
+		entries = append(entries, entry)
+		valueFrom += entry.ValueFrom
+		valueTo += entry.ValueTo
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ArticleNumber < entries[j].ArticleNumber
+	})
+
+	return map[string]interface{}{
+		"from":         from,
+		"to":           to,
+		"entries":      entries,
+		"changedCount": changed,
+		"valueFrom":    valueFrom,
+		"valueTo":      valueTo,
+		"generatedAt":  time.Now(),
+	}, nil
+}
+
+// snapshot ermittelt den Bestand aller zum Zeitpunkt bereits angelegten Artikel
+func (s *StockHistoryService) snapshot(at time.Time) (map[primitive.ObjectID]*StockAsOfEntry, []*model.Article, error) {
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lastBefore, err := s.transactionRepo.FindLastPerArticleUntil(at)
+	if err != nil {
+		return nil, nil, err
+	}
+	firstAfter, err := s.transactionRepo.FindFirstPerArticleAfter(at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot := make(map[primitive.ObjectID]*StockAsOfEntry, len(articles))
+	for _, article := range articles {
+		if article.CreatedAt.After(at) {
+			continue
+		}
+		quantity, averageCost := stockAsOf(article, lastBefore[article.ID], firstAfter[article.ID])
+		snapshot[article.ID] = newStockAsOfEntry(article, quantity, averageCost)
+	}
+
+	return snapshot, articles, nil
+}
+
+// newStockAsOfEntry erstellt den Eintrag eines Artikels mit Bestand und Durchschnittspreis
+func newStockAsOfEntry(article *model.Article, quantity, averageCost float64) *StockAsOfEntry {
+	return &StockAsOfEntry{
+		ArticleID:     article.ID.Hex(),
+		ArticleNumber: article.ArticleNumber,
+		ArticleName:   article.ShortName,
+		Category:      article.Category,
+		Unit:          article.Unit,
+		Quantity:      quantity,
+		UnitCost:      averageCost,
+		Value:         quantity * averageCost,
+	}
+}
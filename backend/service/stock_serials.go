@@ -0,0 +1,209 @@
+// backend/service/stock_serials.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// ErrSerialCount wird zurückgegeben, wenn die Anzahl der Seriennummern nicht zur Menge passt
+var ErrSerialCount = errors.New("Für seriennummernpflichtige Artikel muss je Stück genau eine Seriennummer angegeben werden")
+
+// ErrSerialNotAvailable wird zurückgegeben, wenn eine Seriennummer nicht am Lagerort im Bestand ist
+var ErrSerialNotAvailable = errors.New("Seriennummer ist an diesem Lagerort nicht im Bestand")
+
+// ErrSerialInStock wird zurückgegeben, wenn eine eingehende Seriennummer bereits im Bestand ist
+var ErrSerialInStock = errors.New("Seriennummer ist bereits im Bestand")
+
+// ErrSerialMoved wird zurückgegeben, wenn eine Seriennummer seit der zu stornierenden Buchung weiterbewegt wurde
+var ErrSerialMoved = errors.New("Seriennummer wurde seit der Buchung weiterbewegt")
+
+// ErrSerialTrackingStock wird zurückgegeben, wenn die Seriennummernpflicht für einen Artikel mit
+// Bestand eingeführt werden soll, für dessen Stücke keine Seriennummern erfasst sind
+var ErrSerialTrackingStock = errors.New("Die Seriennummernpflicht kann nur für Artikel ohne Bestand eingeführt werden, da für den vorhandenen Bestand keine Seriennummern erfasst sind")
+
+// checkSerialCount prüft, ob für jedes Stück genau eine, eindeutige Seriennummer angegeben ist
+func checkSerialCount(serials []string, quantity float64) error {
+	if quantity != math.Trunc(quantity) || float64(len(serials)) != quantity {
+		return ErrSerialCount
+	}
+
+	seen := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		if serial == "" || seen[serial] {
+			return ErrSerialCount
+		}
+		seen[serial] = true
+	}
+
+	return nil
+}
+
+// bookSerials führt die Seriennummern eines seriennummernpflichtigen Artikels entsprechend
+// der Buchung fort: Zugänge legen Seriennummern an oder nehmen ausgegebene Stücke zurück,
+// Warenausgänge geben Stücke aus, Korrekturen und Inventuren buchen Stücke aus (verschrottet)
+// oder ein, Umlagerungen verschieben sie. Stornos setzen die Stücke auf den Zustand vor der
+// Originalbuchung zurück.
+func (s *StockService) bookSerials(article *model.Article, transaction *model.Transaction, isTransfer bool) (func(), error) {
+	if !article.SerialNumberRequired {
+		return func() {}, nil
+	}
+
+	quantity := math.Abs(transaction.GetStockDelta())
+	if isTransfer {
+		quantity = transaction.Quantity
+	}
+	if err := checkSerialCount(transaction.SerialNumbers, quantity); err != nil {
+		return nil, err
+	}
+
+	var undo []func()
+	revert := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	for _, serialNumber := range transaction.SerialNumbers {
+		current, err := s.serialRepo.FindByArticleAndNumber(article.ID, serialNumber)
+		if err != nil {
+			revert()
+			return nil, fmt.Errorf("Fehler beim Laden der Seriennummer %s: %v", serialNumber, err)
+		}
+
+		undoStep, err := s.bookSerial(article, transaction, isTransfer, serialNumber, current)
+		if err != nil {
+			revert()
+			return nil, err
+		}
+		undo = append(undo, undoStep)
+	}
+
+	return revert, nil
+}
+
+// bookSerial führt eine einzelne Seriennummer fort und gibt die Rücknahme der Änderung zurück
+func (s *StockService) bookSerial(
+	article *model.Article,
+	transaction *model.Transaction,
+	isTransfer bool,
+	serialNumber string,
+	current *model.SerialNumber,
+) (func(), error) {
+	event := model.SerialEvent{
+		TransactionID:   transaction.ID,
+		TransactionType: transaction.Type,
+		LocationID:      transaction.LocationID,
+		LocationName:    transaction.LocationName,
+		UserID:          transaction.UserID,
+		UserName:        transaction.UserName,
+		Reference:       transaction.Reference,
+		Timestamp:       transaction.Timestamp,
+	}
+
+	// Storno: Zustand vor der Originalbuchung wiederherstellen
+	if transaction.Type == model.TransactionTypeReversal {
+		if current == nil || current.LastTransactionID != transaction.ReversalOf {
+			return nil, fmt.Errorf("%w: %s", ErrSerialMoved, serialNumber)
+		}
+		if len(current.History) < 2 {
+			// Das Stück wurde erst durch die Originalbuchung angelegt
+			if err := s.serialRepo.Delete(current.ID); err != nil {
+				return nil, err
+			}
+			return func() { s.restoreSerial(current) }, nil
+		}
+
+		previous := current.History[len(current.History)-2]
+		event.Status = previous.Status
+		event.LocationID = previous.LocationID
+		event.LocationName = previous.LocationName
+		return s.transitionSerial(current, event)
+	}
+
+	delta := transaction.GetStockDelta()
+	switch {
+	case isTransfer:
+		if current == nil || !current.IsAvailable() || current.LocationID != transaction.SourceLocationID {
+			return nil, fmt.Errorf("%w: %s", ErrSerialNotAvailable, serialNumber)
+		}
+		event.Status = current.Status
+		event.LocationID = transaction.TargetLocationID
+		event.LocationName = transaction.TargetLocationName
+		return s.transitionSerial(current, event)
+
+	case delta > 0:
+		if current == nil {
+			event.Status = model.SerialStatusInStock
+			serial := &model.SerialNumber{
+				ArticleID:         article.ID,
+				ArticleName:       article.ShortName,
+				SerialNumber:      serialNumber,
+				Status:            event.Status,
+				LocationID:        event.LocationID,
+				LocationName:      event.LocationName,
+				LastTransactionID: transaction.ID,
+				History:           []model.SerialEvent{event},
+			}
+			if err := s.serialRepo.Create(serial); err != nil {
+				if err == repository.ErrSerialConflict {
+					return nil, fmt.Errorf("%w: %s", ErrSerialInStock, serialNumber)
+				}
+				return nil, err
+			}
+			return func() {
+				if err := s.serialRepo.Delete(serial.ID); err != nil {
+					log.Printf("Seriennummer %s konnte nicht zurückgenommen werden: %v", serialNumber, err)
+				}
+			}, nil
+		}
+
+		if current.IsAvailable() {
+			return nil, fmt.Errorf("%w: %s", ErrSerialInStock, serialNumber)
+		}
+		// Ausgegebene Stücke kommen als Rückgabe zurück, verschrottete werden wieder eingebucht
+		event.Status = model.SerialStatusInStock
+		if current.Status == model.SerialStatusIssued {
+			event.Status = model.SerialStatusReturned
+		}
+		return s.transitionSerial(current, event)
+
+	default:
+		if current == nil || !current.IsAvailable() || current.LocationID != transaction.LocationID {
+			return nil, fmt.Errorf("%w: %s", ErrSerialNotAvailable, serialNumber)
+		}
+		// Ausgegebene Stücke verlassen das Lager, Korrekturen buchen sie als verschrottet aus
+		event.Status = model.SerialStatusScrapped
+		if transaction.Type == model.TransactionTypeStockOut {
+			event.Status = model.SerialStatusIssued
+		}
+		event.LocationID = current.LocationID
+		event.LocationName = current.LocationName
+		return s.transitionSerial(current, event)
+	}
+}
+
+// transitionSerial überführt eine Seriennummer in einen neuen Zustand
+func (s *StockService) transitionSerial(current *model.SerialNumber, event model.SerialEvent) (func(), error) {
+	if err := s.serialRepo.Transition(current, event); err != nil {
+		if err == repository.ErrSerialConflict {
+			return nil, fmt.Errorf("%w: %s", ErrSerialNotAvailable, current.SerialNumber)
+		}
+		return nil, err
+	}
+
+	return func() { s.restoreSerial(current) }, nil
+}
+
+// restoreSerial stellt den vorherigen Zustand einer Seriennummer wieder her
+func (s *StockService) restoreSerial(previous *model.SerialNumber) {
+	previous.UpdatedAt = time.Now()
+	if err := s.serialRepo.Restore(previous); err != nil {
+		log.Printf("Seriennummer %s konnte nicht zurückgenommen werden: %v", previous.SerialNumber, err)
+	}
+}
@@ -4,7 +4,10 @@ package service
 import (
 	"StockFlow/backend/model"
 	"StockFlow/backend/repository"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +18,16 @@ type StockService struct {
 	articleRepo     *repository.ArticleRepository
 	transactionRepo *repository.TransactionRepository
 	activityRepo    *repository.ActivityRepository
+	locationRepo    *repository.LocationRepository
+	stockLevelRepo  *repository.StockLevelRepository
+	lotRepo         *repository.LotRepository
+	serialRepo      *repository.SerialNumberRepository
+	costLayerRepo   *repository.CostLayerRepository
+	returnRepo      *repository.ReturnRepository
+
+	reservationService *ReservationService
+	valuationService   *ValuationService
+	hazardService      *HazardService
 }
 
 // NewStockService erstellt einen neuen StockService
@@ -23,9 +36,47 @@ func NewStockService() *StockService {
 		articleRepo:     repository.NewArticleRepository(),
 		transactionRepo: repository.NewTransactionRepository(),
 		activityRepo:    repository.NewActivityRepository(),
+		locationRepo:    repository.NewLocationRepository(),
+		stockLevelRepo:  repository.NewStockLevelRepository(),
+		lotRepo:         repository.NewLotRepository(),
+		serialRepo:      repository.NewSerialNumberRepository(),
+		costLayerRepo:   repository.NewCostLayerRepository(),
+		returnRepo:      repository.NewReturnRepository(),
+
+		reservationService: NewReservationService(),
+		valuationService:   NewValuationService(),
+		hazardService:      NewHazardService(),
 	}
 }
 
+// ErrInvalidTransactionType wird zurückgegeben, wenn ein unbekannter Transaktionstyp gebucht werden soll
+var ErrInvalidTransactionType = errors.New("Ungültiger Transaktionstyp")
+
+// ErrInvalidTransferLocations wird zurückgegeben, wenn Quell- oder Ziellagerort einer Umlagerung ungültig sind
+var ErrInvalidTransferLocations = errors.New("Ungültige Lagerorte für die Umlagerung")
+
+// ErrLocationRequired wird zurückgegeben, wenn eine Buchung keinen gültigen Lagerort nennt
+var ErrLocationRequired = errors.New("Bitte einen gültigen Lagerplatz angeben")
+
+// ErrLotRequired wird zurückgegeben, wenn bei einem chargenpflichtigen Artikel die Charge fehlt
+var ErrLotRequired = errors.New("Für chargenpflichtige Artikel muss eine Chargennummer angegeben werden")
+
+// ErrInvalidStockStatus wird zurückgegeben, wenn der Bestandsstatus einer Buchung ungültig ist
+var ErrInvalidStockStatus = errors.New("Ungültiger Bestandsstatus für diese Buchung")
+
+// ErrStockBelowHeld wird zurückgegeben, wenn eine Korrektur oder Inventur den Bestand an einem
+// Lagerort unter die dort zurückgehaltene Menge senken würde
+var ErrStockBelowHeld = errors.New("Der Bestand am Lagerort darf nicht unter die Menge in Quarantäne, gesperrt oder beschädigt fallen; bitte zuerst den Bestandsstatus ändern")
+
+// lotEpsilon gleicht Rundungsfehler bei der Verteilung auf Chargen aus
+const lotEpsilon = 1e-9
+
+// ErrReversalNotAllowed wird zurückgegeben, wenn eine Stornobuchung selbst storniert werden soll
+var ErrReversalNotAllowed = errors.New("Stornobuchungen können nicht storniert werden")
+
+// ErrReversalSuperseded wird zurückgegeben, wenn nach der Buchung bereits eine Inventur erfolgt ist
+var ErrReversalSuperseded = errors.New("Die Buchung wurde durch eine spätere Inventur überholt und kann nicht storniert werden")
+
 // PerformStockAdjustment führt eine Bestandsanpassung durch
 func (s *StockService) PerformStockAdjustment(
 	articleID string,
@@ -35,88 +86,934 @@ func (s *StockService) PerformStockAdjustment(
 	userID primitive.ObjectID,
 	userName string,
 ) (*model.Transaction, error) {
-	// Artikel abrufen
-	article, err := s.articleRepo.FindByID(articleID)
+	objID, err := primitive.ObjectIDFromHex(articleID)
 	if err != nil {
 		return nil, fmt.Errorf("Artikel nicht gefunden: %v", err)
 	}
 
-	// Alten Bestand speichern
-	oldStock := article.StockCurrent
+	transaction := &model.Transaction{
+		Type:      transactionType,
+		ArticleID: objID,
+		Quantity:  quantity,
+		Reason:    reason,
+		Reference: reference,
+		UserID:    userID,
+		UserName:  userName,
+		Notes:     notes,
+	}
+
+	if err := s.PostTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// PostTransaction ist der zentrale Buchungsweg für Lagerbewegungen. Der Bestand am
+// betroffenen Lagerort und der Gesamtbestand des Artikels werden atomar angepasst, sodass
+// gleichzeitige Buchungen keine Änderungen verlieren, und die Buchung anschließend im
+// Journal gespeichert. Schlägt ein späterer Schritt fehl, werden die bereits gebuchten
+// Bestandsänderungen zurückgenommen.
+//
+// Bei Wareneingang und -ausgang enthält transaction.Quantity die Bewegungsmenge, bei
+// Korrektur und Inventur den neuen Bestand am Lagerort (als Differenz bucht sie
+// PostStockDifference). Nach der Buchung enthält Quantity bei Korrektur und Inventur die
+// Differenz zum alten Bestand. Ist transaction.InputUnit gesetzt, wird die Menge
+// stattdessen aus InputQuantity in die Lagereinheit umgerechnet.
+//
+// Ein- und Ausgänge bewegen verfügbaren Bestand, sofern transaction.StockStatus keinen
+// anderen Bestandsstatus nennt. Statuswechsel verschieben Bestand an einem Lagerort von
+// StockStatus nach TargetStockStatus und verändern den Gesamtbestand nicht.
+func (s *StockService) PostTransaction(transaction *model.Transaction) error {
+	return s.postTransaction(transaction, false)
+}
+
+// PostStockDifference bucht eine Korrektur oder Inventur als Differenz: transaction.Quantity
+// enthält die Bestandsänderung am Lagerort statt des neuen Bestands. Anders als beim Setzen
+// des neuen Bestands gehen dabei Bewegungen nicht verloren, die seit der Ermittlung der
+// Differenz gebucht wurden. Der frei verwendbare Bestand am Lagerort darf nicht unter null fallen.
+func (s *StockService) PostStockDifference(transaction *model.Transaction) error {
+	switch transaction.Type {
+	case model.TransactionTypeAdjust, model.TransactionTypeInventory:
+		return s.postTransaction(transaction, true)
+	default:
+		return ErrInvalidTransactionType
+	}
+}
+
+// postTransaction bucht eine Lagerbewegung; bei Korrektur und Inventur gibt difference an, ob
+// transaction.Quantity die Differenz oder den neuen Bestand am Lagerort enthält
+func (s *StockService) postTransaction(transaction *model.Transaction, difference bool) error {
+	// Artikel abrufen
+	article, err := s.articleRepo.FindByID(transaction.ArticleID.Hex())
+	if err != nil {
+		return fmt.Errorf("Artikel nicht gefunden: %v", err)
+	}
+
+	// In einer weiteren Einheit erfasste Mengen und Preise in die Lagereinheit umrechnen
+	if err := applyInputUnit(article, transaction); err != nil {
+		return err
+	}
+
+	// Storno einer Umlagerung: Ware zurück an den ursprünglichen Lagerort
+	isTransfer := transaction.Type == model.TransactionTypeTransfer ||
+		(transaction.Type == model.TransactionTypeReversal && !transaction.SourceLocationID.IsZero())
+
+	// Bestandsstatus prüfen; Storno eines Statuswechsels: Ware zurück in den bisherigen Status
+	if err := checkStockStatus(transaction); err != nil {
+		return err
+	}
+	isStatusChange := transaction.IsStatusChange()
+
+	// Lagerorte prüfen
+	switch {
+	case transaction.Type == model.TransactionTypeReversal && transaction.ReversalOf.IsZero():
+		// Stornobuchungen entstehen nur über ReverseTransaction und enthalten die Bestandsdifferenz
+		return ErrInvalidTransactionType
+	case isTransfer:
+		if err := s.resolveTransferLocations(transaction); err != nil {
+			return err
+		}
+	default:
+		if err := s.resolveLocation(transaction); err != nil {
+			return err
+		}
+	}
+
+	// Wareneingänge chargenpflichtiger Artikel benötigen eine Chargennummer
+	if article.LotTracked && transaction.Type == model.TransactionTypeStockIn && transaction.LotNumber == "" {
+		return ErrLotRequired
+	}
+
+	// Gefahrgut nur auf freigegebene Lagerplätze und nicht neben unverträgliche Klassen einlagern.
+	// Korrekturen und Inventuren werden nur geprüft, wenn sie den Bestand erhöhen, damit Bestand
+	// an einem unzulässigen Ort noch abgebaut werden kann. Stornos stellen einen früheren Bestand
+	// wieder her und werden nicht geprüft.
+	switch transaction.Type {
+	case model.TransactionTypeStockIn:
+		err = s.hazardService.CheckStorage(article, transaction.LocationID)
+	case model.TransactionTypeTransfer:
+		err = s.hazardService.CheckStorage(article, transaction.TargetLocationID)
+	case model.TransactionTypeAdjust, model.TransactionTypeInventory:
+		var increases bool
+		increases, err = s.increasesStock(article, transaction, difference)
+		if err == nil && increases {
+			err = s.hazardService.CheckStorage(article, transaction.LocationID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Bei Ein-, Ausgängen und Umlagerungen steht die Stückzahl schon vor der Buchung fest
+	if article.SerialNumberRequired {
+		switch transaction.Type {
+		case model.TransactionTypeStockIn, model.TransactionTypeStockOut, model.TransactionTypeTransfer:
+			if err := checkSerialCount(transaction.SerialNumbers, transaction.Quantity); err != nil {
+				return err
+			}
+		}
+	}
 
-	// Neuen Bestand berechnen basierend auf dem Transaktionstyp
-	var newStock float64
-	switch transactionType {
+	// Bestand atomar anpassen; revert nimmt die Änderungen bei einem späteren Fehler zurück
+	var oldStock, newStock float64
+	var revert func()
+	switch transaction.Type {
 	case model.TransactionTypeStockIn:
-		newStock = oldStock + quantity
+		oldStock, newStock, revert, err = s.bookStock(article.ID, transaction.LocationID, transaction.Quantity, transaction.StockStatus)
 	case model.TransactionTypeStockOut:
-		newStock = oldStock - quantity
-		// Prüfen, ob genügend Bestand vorhanden ist
-		if newStock < 0 {
-			return nil, fmt.Errorf("Nicht genügend Bestand vorhanden")
+		if transaction.StockStatus.IsHeld() {
+			// Zurückgehaltene Ware, z.B. beschädigte zur Verschrottung, ist nie reserviert
+			oldStock, newStock, revert, err = s.bookStock(article.ID, transaction.LocationID, -transaction.Quantity, transaction.StockStatus)
+			break
+		}
+		oldStock, newStock, revert, err = s.withdrawStock(transaction)
+	case model.TransactionTypeReversal:
+		if isTransfer {
+			oldStock, revert, err = s.moveStock(article.ID, transaction.SourceLocationID, transaction.TargetLocationID, transaction.Quantity)
+			newStock = oldStock
+			break
+		}
+		if isStatusChange {
+			oldStock, revert, err = s.changeStatus(transaction)
+			newStock = oldStock
+			break
 		}
+		oldStock, newStock, revert, err = s.bookStock(article.ID, transaction.LocationID, transaction.Quantity, transaction.StockStatus)
+	case model.TransactionTypeStatusChange:
+		// Ein Statuswechsel verändert den Gesamtbestand nicht
+		oldStock, revert, err = s.changeStatus(transaction)
+		newStock = oldStock
+	case model.TransactionTypeTransfer:
+		// Eine Umlagerung verändert den Gesamtbestand nicht
+		oldStock, revert, err = s.moveStock(article.ID, transaction.SourceLocationID, transaction.TargetLocationID, transaction.Quantity)
+		newStock = oldStock
 	case model.TransactionTypeAdjust, model.TransactionTypeInventory:
-		// Bei Anpassung/Inventur ist die Menge bereits der neue Bestand
-		newStock = quantity
+		if difference {
+			oldStock, newStock, revert, err = s.changeStock(article.ID, transaction.LocationID, transaction.Quantity)
+			break
+		}
+		// Bei Anpassung/Inventur ist die Menge bereits der neue Bestand am Lagerort
+		var delta float64
+		delta, oldStock, newStock, revert, err = s.setStock(article.ID, transaction.LocationID, transaction.Quantity)
 		// Anpassen der Menge für die Transaktion (Differenz zum alten Bestand)
-		quantity = newStock - oldStock
+		transaction.Quantity = delta
 	default:
-		return nil, fmt.Errorf("Ungültiger Transaktionstyp")
+		return ErrInvalidTransactionType
 	}
 
-	// Neue Transaktion erstellen
-	transaction := &model.Transaction{
-		ID:          primitive.NewObjectID(),
-		Type:        transactionType,
-		ArticleID:   article.ID,
-		ArticleName: article.ShortName,
-		Quantity:    quantity,
-		OldStock:    oldStock,
-		NewStock:    newStock,
-		UnitPrice:   article.PurchasePriceNet,
-		Reason:      reason,
-		Reference:   reference,
-		UserID:      userID,
-		UserName:    userName,
-		Timestamp:   time.Now(),
-		Notes:       notes,
+	if err != nil {
+		switch err {
+		case repository.ErrInsufficientStock, repository.ErrStockReserved, repository.ErrStockHeld,
+			repository.ErrReservationNotActive, repository.ErrReservationConflict, ErrStockBelowHeld:
+			return err
+		}
+		return fmt.Errorf("Fehler beim Aktualisieren des Artikelbestands: %v", err)
 	}
 
-	// Transaktion speichern
-	err = s.transactionRepo.Create(transaction)
+	// Buchung vervollständigen
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+	if transaction.Timestamp.IsZero() {
+		transaction.Timestamp = time.Now()
+	}
+	if transaction.UnitPrice == 0 {
+		// Zugänge ohne Preisangabe zum Einkaufspreis, alle übrigen Bewegungen zum Durchschnittspreis
+		transaction.UnitPrice = article.GetAverageCost()
+		if transaction.Type == model.TransactionTypeStockIn {
+			transaction.UnitPrice = article.PurchasePriceNet
+		}
+	}
+	transaction.ArticleName = article.ShortName
+	transaction.OldStock = oldStock
+	transaction.NewStock = newStock
+
+	// Chargen, Seriennummern und Bewertung buchen
+	revertLots, err := s.bookLots(article, transaction, isTransfer)
 	if err != nil {
-		return nil, fmt.Errorf("Fehler beim Speichern der Transaktion: %v", err)
+		revert()
+		return err
+	}
+	revertSerials, err := s.bookSerials(article, transaction, isTransfer)
+	if err != nil {
+		revertLots()
+		revert()
+		return err
+	}
+	revertValuation, err := s.bookValuation(article, transaction, isTransfer)
+	if err != nil {
+		revertSerials()
+		revertLots()
+		revert()
+		return err
 	}
 
-	// Artikelbestand aktualisieren
-	article.StockCurrent = newStock
-	if transactionType == model.TransactionTypeInventory {
-		article.LastStockTakeDate = time.Now()
+	// Transaktion speichern
+	if err := s.transactionRepo.Create(transaction); err != nil {
+		// Bestandsänderung zurücknehmen, damit Journal und Bestände nicht auseinanderlaufen
+		revertValuation()
+		revertSerials()
+		revertLots()
+		revert()
+		return fmt.Errorf("Fehler beim Speichern der Transaktion: %v", err)
 	}
 
-	err = s.articleRepo.Update(article)
-	if err != nil {
-		return nil, fmt.Errorf("Fehler beim Aktualisieren des Artikelbestands: %v", err)
+	if transaction.Type == model.TransactionTypeInventory {
+		if err := s.articleRepo.MarkStockTaken(article.ID, transaction.Timestamp); err != nil {
+			log.Printf("Inventurdatum für Artikel %s konnte nicht gesetzt werden: %v", article.ID.Hex(), err)
+		}
 	}
 
 	// Aktivität loggen
 	activityType := model.ActivityTypeStockAdjusted
-	if transactionType == model.TransactionTypeInventory {
+	switch transaction.Type {
+	case model.TransactionTypeInventory:
 		activityType = model.ActivityTypeStockTaking
+	case model.TransactionTypeReversal:
+		activityType = model.ActivityTypeStockReversed
+	}
+
+	description := fmt.Sprintf("%s: %g %s in %s (neu: %g)", transaction.GetDisplayType(), transaction.Quantity, article.Unit,
+		transaction.LocationName, newStock)
+	if isTransfer {
+		description = fmt.Sprintf("%s: %g %s von %s nach %s", transaction.GetDisplayType(), transaction.Quantity, article.Unit,
+			transaction.SourceLocationName, transaction.TargetLocationName)
+	}
+	if isStatusChange {
+		description = fmt.Sprintf("%s: %g %s in %s von %s nach %s", transaction.GetDisplayType(), transaction.Quantity, article.Unit,
+			transaction.LocationName, transaction.StockStatus.GetDisplayName(), transaction.TargetStockStatus.GetDisplayName())
 	}
 
 	_, _ = s.activityRepo.LogActivity(
 		activityType,
-		userID,
-		userName,
+		transaction.UserID,
+		transaction.UserName,
 		article.ID,
 		"article",
 		article.ShortName,
-		fmt.Sprintf("%s: %g %s (neu: %g)", transaction.GetDisplayType(), quantity, article.Unit, newStock),
-		quantity,
+		description,
+		transaction.Quantity,
 	)
 
-	return transaction, nil
+	return nil
+}
+
+// bookStock verändert den Bestand an einem Lagerort und den Gesamtbestand des Artikels um
+// delta. Entnahmen werden nur gebucht, wenn am Lagerort genug Bestand vorhanden ist und sie
+// den verfügbaren Bestand nicht unter die offenen Reservierungen drücken, z.B. beim Storno
+// eines Zugangs. Nennt status einen zurückgehaltenen Bestandsstatus, wird der Bestand in
+// diesem Status gebucht.
+func (s *StockService) bookStock(articleID, locationID primitive.ObjectID, delta float64, status model.StockStatus) (float64, float64, func(), error) {
+	if status.IsHeld() {
+		return s.bookHeldStock(articleID, locationID, delta, status)
+	}
+
+	if delta < 0 {
+		// Abgelaufene Reservierungen dürfen die Entnahme nicht mehr blockieren
+		s.reservationService.ReleaseExpiredForArticle(articleID)
+	}
+
+	if _, _, err := s.stockLevelRepo.Increment(articleID, locationID, delta, false); err != nil {
+		return 0, 0, nil, err
+	}
+
+	var oldStock, newStock float64
+	var err error
+	if delta < 0 {
+		oldStock, newStock, err = s.articleRepo.WithdrawAvailableStock(articleID, -delta)
+	} else {
+		oldStock, newStock, err = s.articleRepo.IncrementStock(articleID, delta, false)
+	}
+	if err != nil {
+		s.revertLevel(articleID, locationID, -delta)
+		return 0, 0, nil, err
+	}
+
+	revert := func() {
+		s.revertLevel(articleID, locationID, -delta)
+		if _, _, err := s.articleRepo.IncrementStock(articleID, -delta, true); err != nil {
+			log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	return oldStock, newStock, revert, nil
+}
+
+// bookHeldStock verändert den Bestand in einem zurückgehaltenen Bestandsstatus an einem
+// Lagerort und beim Artikel um delta
+func (s *StockService) bookHeldStock(articleID, locationID primitive.ObjectID, delta float64, status model.StockStatus) (float64, float64, func(), error) {
+	if _, _, err := s.stockLevelRepo.IncrementHeld(articleID, locationID, status, delta, false); err != nil {
+		return 0, 0, nil, err
+	}
+
+	revertLevel := func() {
+		if _, _, err := s.stockLevelRepo.IncrementHeld(articleID, locationID, status, -delta, true); err != nil {
+			log.Printf("Lagerplatzbestand für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	oldStock, newStock, err := s.articleRepo.IncrementHeldStock(articleID, status, delta, false)
+	if err != nil {
+		revertLevel()
+		return 0, 0, nil, err
+	}
+
+	revert := func() {
+		revertLevel()
+		if _, _, err := s.articleRepo.IncrementHeldStock(articleID, status, -delta, true); err != nil {
+			log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	return oldStock, newStock, revert, nil
+}
+
+// changeStatus verschiebt die Menge einer Buchung an ihrem Lagerort von StockStatus nach
+// TargetStockStatus, am Lagerort und beim Artikel, und gibt den dabei gelesenen, unveränderten
+// Gesamtbestand des Artikels zurück
+func (s *StockService) changeStatus(transaction *model.Transaction) (float64, func(), error) {
+	articleID, locationID, quantity := transaction.ArticleID, transaction.LocationID, transaction.Quantity
+	from, to := transaction.StockStatus, transaction.TargetStockStatus
+
+	if !from.IsHeld() {
+		// Abgelaufene Reservierungen dürfen den Statuswechsel nicht mehr blockieren
+		s.reservationService.ReleaseExpiredForArticle(articleID)
+	}
+
+	if err := s.stockLevelRepo.ChangeStatus(articleID, locationID, from, to, quantity); err != nil {
+		return 0, nil, err
+	}
+
+	stock, err := s.articleRepo.ChangeStockStatus(articleID, from, to, quantity)
+	if err != nil {
+		s.revertStatus(articleID, locationID, from, to, quantity)
+		return 0, nil, err
+	}
+
+	revert := func() {
+		s.revertStatus(articleID, locationID, from, to, quantity)
+		if _, err := s.articleRepo.ChangeStockStatus(articleID, to, from, quantity); err != nil {
+			log.Printf("Statuswechsel für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	return stock, revert, nil
+}
+
+// revertStatus nimmt einen Statuswechsel an einem Lagerort zurück
+func (s *StockService) revertStatus(articleID, locationID primitive.ObjectID, from, to model.StockStatus, quantity float64) {
+	if err := s.stockLevelRepo.ChangeStatus(articleID, locationID, to, from, quantity); err != nil {
+		log.Printf("Statuswechsel am Lagerplatz für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+	}
+}
+
+// checkStockStatus prüft den Bestandsstatus einer Buchung. Verfügbare Ware wird bei Ein- und
+// Ausgängen ohne Status gebucht; zurückgehaltene Ware kann nur zu- oder abgehen, nicht
+// reserviert, umgelagert oder gezählt werden. Statuswechsel nennen beide Status.
+func checkStockStatus(transaction *model.Transaction) error {
+	if transaction.Type == model.TransactionTypeStatusChange ||
+		(transaction.Type == model.TransactionTypeReversal && transaction.TargetStockStatus != "") {
+		if transaction.StockStatus == "" {
+			transaction.StockStatus = model.StockStatusAvailable
+		}
+		if !transaction.StockStatus.IsValid() || !transaction.TargetStockStatus.IsValid() ||
+			transaction.StockStatus == transaction.TargetStockStatus {
+			return ErrInvalidStockStatus
+		}
+		return nil
+	}
+
+	if transaction.TargetStockStatus != "" {
+		return ErrInvalidStockStatus
+	}
+	if transaction.StockStatus == model.StockStatusAvailable {
+		transaction.StockStatus = ""
+	}
+	if transaction.StockStatus == "" {
+		return nil
+	}
+
+	switch transaction.Type {
+	case model.TransactionTypeStockIn, model.TransactionTypeStockOut, model.TransactionTypeReversal:
+		if !transaction.StockStatus.IsValid() || !transaction.ReservationID.IsZero() || !transaction.SourceLocationID.IsZero() {
+			return ErrInvalidStockStatus
+		}
+		return nil
+	default:
+		return ErrInvalidStockStatus
+	}
+}
+
+// withdrawStock bucht einen Warenausgang. Reservierter Bestand bleibt dabei unangetastet, es
+// sei denn, der Ausgang erfüllt die in transaction.ReservationID angegebene Reservierung:
+// Deren offene Menge wird zuerst freigegeben und steht damit für die Entnahme zur Verfügung.
+// Ein Storno des Ausgangs stellt die Reservierung nicht wieder her.
+func (s *StockService) withdrawStock(transaction *model.Transaction) (float64, float64, func(), error) {
+	articleID, locationID, quantity := transaction.ArticleID, transaction.LocationID, transaction.Quantity
+
+	// Abgelaufene Reservierungen dürfen die Entnahme nicht mehr blockieren
+	s.reservationService.ReleaseExpiredForArticle(articleID)
+
+	revertReservation := func() {}
+	if !transaction.ReservationID.IsZero() {
+		var err error
+		revertReservation, err = s.reservationService.consume(transaction.ReservationID, articleID, quantity)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	if _, _, err := s.stockLevelRepo.Increment(articleID, locationID, -quantity, false); err != nil {
+		revertReservation()
+		return 0, 0, nil, err
+	}
+
+	oldStock, newStock, err := s.articleRepo.WithdrawAvailableStock(articleID, quantity)
+	if err != nil {
+		s.revertLevel(articleID, locationID, quantity)
+		revertReservation()
+		return 0, 0, nil, err
+	}
+
+	revert := func() {
+		s.revertLevel(articleID, locationID, quantity)
+		if _, _, err := s.articleRepo.IncrementStock(articleID, quantity, true); err != nil {
+			log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+		revertReservation()
+	}
+
+	return oldStock, newStock, revert, nil
+}
+
+// setStock setzt den Bestand an einem Lagerort auf einen absoluten Wert und passt den
+// Gesamtbestand des Artikels um die Differenz an
+func (s *StockService) setStock(articleID, locationID primitive.ObjectID, quantity float64) (float64, float64, float64, func(), error) {
+	// Zurückgehaltene Ware bleibt in ihrem Status; sie muss dazu am Lagerort vorhanden sein
+	held, err := s.stockLevelRepo.FindHeldQuantity(articleID, locationID)
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	if quantity < held {
+		return 0, 0, 0, nil, ErrStockBelowHeld
+	}
+
+	oldLevel, err := s.stockLevelRepo.Set(articleID, locationID, quantity)
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	delta := quantity - oldLevel
+
+	oldStock, newStock, err := s.articleRepo.IncrementStock(articleID, delta, true)
+	if err != nil {
+		s.revertLevel(articleID, locationID, -delta)
+		return 0, 0, 0, nil, err
+	}
+
+	revert := func() {
+		s.revertLevel(articleID, locationID, -delta)
+		if _, _, err := s.articleRepo.IncrementStock(articleID, -delta, true); err != nil {
+			log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	return delta, oldStock, newStock, revert, nil
+}
+
+// increasesStock prüft, ob eine Korrektur oder Inventur eines Gefahrgutartikels den Bestand am
+// Lagerort erhöht; ist difference gesetzt, enthält transaction.Quantity die Differenz, sonst
+// den neuen Bestand
+func (s *StockService) increasesStock(article *model.Article, transaction *model.Transaction, difference bool) (bool, error) {
+	if article.HazardClass == "" {
+		return false, nil
+	}
+	if difference {
+		return transaction.Quantity > 0, nil
+	}
+
+	levels, err := s.stockLevelRepo.FindByArticleID(article.ID)
+	if err != nil {
+		return false, fmt.Errorf("Fehler beim Laden der Lagerplatzbestände: %v", err)
+	}
+	var current float64
+	for _, level := range levels {
+		if level.LocationID == transaction.LocationID {
+			current = level.Quantity
+		}
+	}
+	return transaction.Quantity > current, nil
+}
+
+// changeStock verändert den Bestand an einem Lagerort und den Gesamtbestand des Artikels bei
+// Korrekturen und Inventuren um delta. Der frei verwendbare Bestand am Lagerort darf dabei nicht
+// unter null fallen; Reservierungen werden nicht berücksichtigt, da die Differenz tatsächlich
+// besteht.
+func (s *StockService) changeStock(articleID, locationID primitive.ObjectID, delta float64) (float64, float64, func(), error) {
+	if _, _, err := s.stockLevelRepo.Increment(articleID, locationID, delta, false); err != nil {
+		return 0, 0, nil, err
+	}
+
+	oldStock, newStock, err := s.articleRepo.IncrementStock(articleID, delta, true)
+	if err != nil {
+		s.revertLevel(articleID, locationID, -delta)
+		return 0, 0, nil, err
+	}
+
+	revert := func() {
+		s.revertLevel(articleID, locationID, -delta)
+		if _, _, err := s.articleRepo.IncrementStock(articleID, -delta, true); err != nil {
+			log.Printf("Bestandsänderung für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+		}
+	}
+
+	return oldStock, newStock, revert, nil
+}
+
+// moveStock verschiebt Bestand zwischen zwei Lagerorten, ohne den Gesamtbestand zu verändern,
+// und gibt den nach der Umlagerung gelesenen Gesamtbestand des Artikels zurück
+func (s *StockService) moveStock(articleID, sourceID, targetID primitive.ObjectID, quantity float64) (float64, func(), error) {
+	if _, _, err := s.stockLevelRepo.Increment(articleID, sourceID, -quantity, false); err != nil {
+		return 0, nil, err
+	}
+
+	if _, _, err := s.stockLevelRepo.Increment(articleID, targetID, quantity, false); err != nil {
+		s.revertLevel(articleID, sourceID, quantity)
+		return 0, nil, err
+	}
+
+	revert := func() {
+		s.revertLevel(articleID, targetID, -quantity)
+		s.revertLevel(articleID, sourceID, quantity)
+	}
+
+	// Alt- und Neubestand der Buchung aus dem aktuellen Artikel, nicht aus dem zu Beginn geladenen
+	stock, err := s.articleRepo.GetStockCurrent(articleID)
+	if err != nil {
+		revert()
+		return 0, nil, err
+	}
+
+	return stock, revert, nil
+}
+
+// revertLevel nimmt eine Bestandsänderung an einem Lagerort zurück
+func (s *StockService) revertLevel(articleID, locationID primitive.ObjectID, delta float64) {
+	if _, _, err := s.stockLevelRepo.Increment(articleID, locationID, delta, true); err != nil {
+		log.Printf("Lagerplatzbestand für Artikel %s konnte nicht zurückgenommen werden: %v", articleID.Hex(), err)
+	}
+}
+
+// OpenLots erfasst den Bestand eines Artikels, der noch keiner Charge zugeordnet ist, je
+// Lagerort in einer Eröffnungscharge. Beim Einführen der Chargenpflicht bleibt vorhandener
+// Bestand so weiter entnehmbar.
+func (s *StockService) OpenLots(articleID primitive.ObjectID) error {
+	levels, err := s.stockLevelRepo.FindByArticleID(articleID)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden der Lagerplatzbestände: %v", err)
+	}
+	if _, err := s.lotRepo.OpenStock(articleID, levels); err != nil {
+		return fmt.Errorf("Fehler beim Anlegen der Eröffnungscharge: %v", err)
+	}
+	return nil
+}
+
+// bookLots verteilt die Bestandsveränderung eines chargenpflichtigen Artikels auf seine
+// Chargen und hält die bewegten Chargenmengen in transaction.Lots fest. Zugänge werden auf
+// die angegebene Charge gebucht, Entnahmen standardmäßig nach FEFO (zuerst ablaufende Charge
+// zuerst) oder aus der manuell gewählten Charge. Stornos bewegen genau die Chargen der
+// Originalbuchung, die ReverseTransaction in transaction.Lots vorgibt.
+func (s *StockService) bookLots(article *model.Article, transaction *model.Transaction, isTransfer bool) (func(), error) {
+	if !article.LotTracked {
+		return func() {}, nil
+	}
+
+	if isTransfer {
+		allocations, err := s.takeLots(article.ID, transaction.SourceLocationID, transaction.Quantity, transaction.LotNumber, transaction.Lots)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.putLots(article.ID, transaction.TargetLocationID, allocations); err != nil {
+			s.adjustLots(article.ID, transaction.SourceLocationID, allocations, 1)
+			return nil, err
+		}
+
+		transaction.Lots = allocations
+		return func() {
+			s.adjustLots(article.ID, transaction.TargetLocationID, allocations, -1)
+			s.adjustLots(article.ID, transaction.SourceLocationID, allocations, 1)
+		}, nil
+	}
+
+	delta := transaction.GetStockDelta()
+	switch {
+	case delta > 0:
+		allocations := transaction.Lots
+		if len(allocations) == 0 {
+			if transaction.LotNumber == "" {
+				return nil, ErrLotRequired
+			}
+			allocations = []model.LotAllocation{{
+				LotNumber:  transaction.LotNumber,
+				ExpiryDate: transaction.ExpiryDate,
+				Quantity:   delta,
+			}}
+		}
+		if err := s.putLots(article.ID, transaction.LocationID, allocations); err != nil {
+			return nil, err
+		}
+
+		transaction.Lots = allocations
+		return func() { s.adjustLots(article.ID, transaction.LocationID, allocations, -1) }, nil
+	case delta < 0:
+		allocations, err := s.takeLots(article.ID, transaction.LocationID, -delta, transaction.LotNumber, transaction.Lots)
+		if err != nil {
+			return nil, err
+		}
+
+		transaction.Lots = allocations
+		return func() { s.adjustLots(article.ID, transaction.LocationID, allocations, 1) }, nil
+	}
+
+	return func() {}, nil
+}
+
+// takeLots entnimmt eine Menge aus den Chargen eines Lagerorts. Sind Chargenmengen
+// vorgegeben, werden genau diese entnommen; bei angegebener Chargennummer nur aus dieser
+// Charge, sonst nach FEFO.
+func (s *StockService) takeLots(
+	articleID, locationID primitive.ObjectID,
+	quantity float64,
+	lotNumber string,
+	exact []model.LotAllocation,
+) ([]model.LotAllocation, error) {
+	if len(exact) > 0 {
+		for i, allocation := range exact {
+			_, err := s.lotRepo.Increment(articleID, locationID, allocation.LotNumber, allocation.ExpiryDate, -allocation.Quantity, false)
+			if err != nil {
+				s.adjustLots(articleID, locationID, exact[:i], 1)
+				return nil, err
+			}
+		}
+		return exact, nil
+	}
+
+	if lotNumber != "" {
+		lot, err := s.lotRepo.Increment(articleID, locationID, lotNumber, time.Time{}, -quantity, false)
+		if err != nil {
+			return nil, err
+		}
+		return []model.LotAllocation{{LotNumber: lot.LotNumber, ExpiryDate: lot.ExpiryDate, Quantity: quantity}}, nil
+	}
+
+	lots, err := s.lotRepo.FindAvailable(articleID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden der Chargen: %v", err)
+	}
+
+	var allocations []model.LotAllocation
+	remaining := quantity
+	for _, lot := range lots {
+		if remaining <= lotEpsilon {
+			break
+		}
+
+		take := math.Min(remaining, lot.Quantity)
+		_, err := s.lotRepo.Increment(articleID, locationID, lot.LotNumber, lot.ExpiryDate, -take, false)
+		if err == repository.ErrInsufficientLotStock {
+			// Die Charge wurde zwischenzeitlich von einer anderen Buchung entnommen
+			continue
+		}
+		if err != nil {
+			s.adjustLots(articleID, locationID, allocations, 1)
+			return nil, err
+		}
+
+		allocations = append(allocations, model.LotAllocation{LotNumber: lot.LotNumber, ExpiryDate: lot.ExpiryDate, Quantity: take})
+		remaining -= take
+	}
+
+	if remaining > lotEpsilon {
+		s.adjustLots(articleID, locationID, allocations, 1)
+		return nil, repository.ErrInsufficientLotStock
+	}
+
+	return allocations, nil
+}
+
+// putLots bucht Chargenmengen auf einen Lagerort und legt fehlende Chargen an
+func (s *StockService) putLots(articleID, locationID primitive.ObjectID, allocations []model.LotAllocation) error {
+	for i, allocation := range allocations {
+		_, err := s.lotRepo.Increment(articleID, locationID, allocation.LotNumber, allocation.ExpiryDate, allocation.Quantity, true)
+		if err != nil {
+			s.adjustLots(articleID, locationID, allocations[:i], -1)
+			return fmt.Errorf("Fehler beim Buchen der Charge %s: %v", allocation.LotNumber, err)
+		}
+	}
+	return nil
+}
+
+// adjustLots nimmt Chargenbewegungen zurück; sign gibt die Richtung der Korrektur an
+func (s *StockService) adjustLots(articleID, locationID primitive.ObjectID, allocations []model.LotAllocation, sign float64) {
+	for _, allocation := range allocations {
+		_, err := s.lotRepo.Increment(articleID, locationID, allocation.LotNumber, allocation.ExpiryDate, sign*allocation.Quantity, true)
+		if err != nil {
+			log.Printf("Chargenbestand %s für Artikel %s konnte nicht zurückgenommen werden: %v", allocation.LotNumber, articleID.Hex(), err)
+		}
+	}
+}
+
+// resolveLocation prüft den Lagerort einer Buchung und übernimmt dessen vollständigen Pfad.
+// Zugänge müssen auf einen aktiven Lagerplatz ohne Unterebenen gebucht werden; Entnahmen,
+// Korrekturen und Inventuren sind auch für Bestand ohne Lagerort (Altdaten) möglich.
+func (s *StockService) resolveLocation(transaction *model.Transaction) error {
+	if transaction.LocationID.IsZero() {
+		if transaction.Type == model.TransactionTypeStockIn {
+			return ErrLocationRequired
+		}
+		transaction.LocationName = model.UnassignedLocationName
+		return nil
+	}
+
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden der Lagerorte: %v", err)
+	}
+
+	location, exists := locationMap[transaction.LocationID]
+	if !exists {
+		return ErrLocationRequired
+	}
+	if transaction.Type == model.TransactionTypeStockIn && (!location.IsActive || hasChildLocations(locationMap, location.ID)) {
+		return ErrLocationRequired
+	}
+
+	transaction.LocationName = location.GetFullPath(locationMap)
+	return nil
+}
+
+// hasChildLocations prüft, ob ein Lagerort untergeordnete Lagerorte besitzt
+func hasChildLocations(locationMap map[primitive.ObjectID]*model.Location, locationID primitive.ObjectID) bool {
+	for _, loc := range locationMap {
+		if loc.ParentID == locationID {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTransferLocations prüft Quell- und Ziellagerort einer Umlagerung und übernimmt
+// deren vollständige Pfade in die Buchung
+func (s *StockService) resolveTransferLocations(transaction *model.Transaction) error {
+	if transaction.SourceLocationID.IsZero() || transaction.TargetLocationID.IsZero() ||
+		transaction.SourceLocationID == transaction.TargetLocationID {
+		return ErrInvalidTransferLocations
+	}
+
+	locationMap, err := s.locationRepo.BuildLocationTree()
+	if err != nil {
+		return fmt.Errorf("Fehler beim Laden der Lagerorte: %v", err)
+	}
+
+	// Aus inaktiven Lagerorten darf noch ausgelagert werden, eingelagert wird nur auf aktive Lagerplätze
+	source, exists := locationMap[transaction.SourceLocationID]
+	if !exists {
+		return ErrInvalidTransferLocations
+	}
+	target, exists := locationMap[transaction.TargetLocationID]
+	if !exists || !target.IsActive || hasChildLocations(locationMap, target.ID) {
+		return ErrInvalidTransferLocations
+	}
+
+	transaction.SourceLocationName = source.GetFullPath(locationMap)
+	transaction.TargetLocationName = target.GetFullPath(locationMap)
+	return nil
+}
+
+// CheckReversible prüft, ob eine Buchung storniert werden darf, und gibt andernfalls den Grund zurück
+func (s *StockService) CheckReversible(original *model.Transaction) error {
+	if original.Type == model.TransactionTypeReversal {
+		return ErrReversalNotAllowed
+	}
+	if original.Type == model.TransactionTypeCorrection {
+		return ErrCorrectionNotReversible
+	}
+	if !original.AssemblyID.IsZero() {
+		return ErrAssemblyNotReversible
+	}
+	if !original.ReturnID.IsZero() {
+		return ErrReturnNotReversible
+	}
+	if !original.GoodsReceiptID.IsZero() {
+		return ErrGoodsReceiptNotReversible
+	}
+	if !original.CustomerOrderID.IsZero() {
+		return ErrShipmentNotReversible
+	}
+	if original.IsReversed() {
+		return repository.ErrAlreadyReversed
+	}
+
+	// Retournierte Ware ist bereits zurückgebucht; ein Storno würde sie ein zweites Mal bewegen
+	returned, err := s.returnRepo.SumReturnedQuantities([]primitive.ObjectID{original.ID}, primitive.NilObjectID)
+	if err != nil {
+		return err
+	}
+	if returned[original.ID] > 0 {
+		return ErrReversalReturned
+	}
+
+	// Eine spätere Inventur an einem betroffenen Lagerort hat den Bestand dort neu festgestellt;
+	// ein Storno würde ihn verfälschen
+	locationIDs := []primitive.ObjectID{original.LocationID}
+	if original.Type == model.TransactionTypeTransfer {
+		locationIDs = []primitive.ObjectID{original.SourceLocationID, original.TargetLocationID}
+	}
+	superseded, err := s.transactionRepo.HasInventoryAfter(original.ArticleID, locationIDs, original.Timestamp)
+	if err != nil {
+		return err
+	}
+	if superseded {
+		return ErrReversalSuperseded
+	}
+
+	return nil
+}
+
+// ReverseTransaction storniert eine Buchung durch eine Gegenbuchung, die auf das Original
+// verweist und dessen Bestandsveränderung zurücknimmt
+func (s *StockService) ReverseTransaction(
+	transactionID string,
+	reason string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Transaction, error) {
+	original, err := s.transactionRepo.FindByID(transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("Transaktion nicht gefunden: %v", err)
+	}
+
+	if err := s.CheckReversible(original); err != nil {
+		return nil, err
+	}
+
+	return s.reverse(original, reason, userID, userName)
+}
+
+// reverse bucht die Gegenbuchung zu einer Buchung ohne die Prüfungen aus CheckReversible.
+// Vorgänge wie die Montage nutzen sie, um ihre eigenen Buchungen bei einem Fehler zurückzunehmen.
+func (s *StockService) reverse(
+	original *model.Transaction,
+	reason string,
+	userID primitive.ObjectID,
+	userName string,
+) (*model.Transaction, error) {
+	reversal := &model.Transaction{
+		ID:            primitive.NewObjectID(),
+		Type:          model.TransactionTypeReversal,
+		ArticleID:     original.ArticleID,
+		Quantity:      -original.GetStockDelta(),
+		LocationID:    original.LocationID,
+		Lots:          original.Lots,
+		SerialNumbers: original.SerialNumbers,
+		UnitPrice:     original.UnitPrice,
+		Reason:        reason,
+		Reference:     original.Reference,
+		UserID:        userID,
+		UserName:      userName,
+		Notes:         fmt.Sprintf("Storno der Buchung vom %s", original.Timestamp.Format("02.01.2006 15:04")),
+		ReversalOf:    original.ID,
+	}
+
+	// Ein- und Ausgänge zurückgehaltener Ware werden im selben Bestandsstatus storniert,
+	// ein Statuswechsel durch den Wechsel zurück in den bisherigen Status
+	reversal.StockStatus = original.StockStatus
+	if original.IsStatusChange() {
+		reversal.Quantity = original.Quantity
+		reversal.StockStatus = original.TargetStockStatus
+		reversal.TargetStockStatus = original.StockStatus
+	}
+
+	// Eine Umlagerung wird durch die Umlagerung in Gegenrichtung storniert
+	if original.Type == model.TransactionTypeTransfer {
+		reversal.Quantity = original.Quantity
+		reversal.SourceLocationID = original.TargetLocationID
+		reversal.TargetLocationID = original.SourceLocationID
+	}
+
+	// Original zuerst markieren, damit parallele Stornos derselben Buchung scheitern
+	if err := s.transactionRepo.MarkReversed(original.ID, reversal.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.PostTransaction(reversal); err != nil {
+		if unmarkErr := s.transactionRepo.UnmarkReversed(original.ID, reversal.ID); unmarkErr != nil {
+			log.Printf("Storno-Markierung für Transaktion %s konnte nicht entfernt werden: %v", original.ID.Hex(), unmarkErr)
+		}
+		return nil, err
+	}
+
+	return reversal, nil
 }
 
 // CheckLowStockArticles prüft, ob Artikel unter Mindestbestand sind
@@ -139,7 +1036,7 @@ func (s *StockService) GetStockStatus() (map[string]interface{}, error) {
 	}
 
 	// Gesamtwert des Lagers
-	totalStockValue, err := s.articleRepo.CalculateTotalStockValue()
+	totalStockValue, err := s.valuationService.CalculateTotalStockValue()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,45 @@
+// backend/service/stock_units.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"errors"
+)
+
+// ErrUnknownUnit wird zurückgegeben, wenn für die erfasste Einheit beim Artikel keine Umrechnung hinterlegt ist
+var ErrUnknownUnit = errors.New("Für die Einheit ist beim Artikel keine Umrechnung hinterlegt")
+
+// applyInputUnit rechnet eine in einer beliebigen Einheit des Artikels erfasste Buchung in die
+// Lagereinheit um. Ein bereits gesetzter Faktor (z.B. aus einer Bestellposition) hat Vorrang
+// vor der aktuellen Umrechnung des Artikels. Ohne Stückpreis wird ein Zugang zum hinterlegten
+// Einkaufspreis der erfassten Einheit bewertet.
+func applyInputUnit(article *model.Article, transaction *model.Transaction) error {
+	if !transaction.HasInputUnit() {
+		return nil
+	}
+
+	factor := transaction.InputFactor
+	if factor <= 0 {
+		var ok bool
+		factor, ok = article.GetUnitFactor(transaction.InputUnit)
+		if !ok {
+			return ErrUnknownUnit
+		}
+	}
+
+	transaction.Quantity = transaction.InputQuantity * factor
+	if transaction.InputUnitPrice > 0 {
+		transaction.UnitPrice = transaction.InputUnitPrice / factor
+	} else if conversion := article.GetUnitConversion(transaction.InputUnit); conversion != nil &&
+		transaction.Type == model.TransactionTypeStockIn && conversion.PurchasePriceNet > 0 && transaction.UnitPrice == 0 {
+		transaction.UnitPrice = conversion.PurchasePriceNet / factor
+	}
+
+	// In der Lagereinheit erfasste Mengen brauchen keine Angaben zur Umrechnung
+	if transaction.InputUnit == article.Unit && factor == 1 {
+		transaction.InputUnit, transaction.InputQuantity, transaction.InputUnitPrice = "", 0, 0
+		return nil
+	}
+	transaction.InputFactor = factor
+	return nil
+}
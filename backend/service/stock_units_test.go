@@ -0,0 +1,117 @@
+// backend/service/stock_units_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"math"
+	"testing"
+)
+
+func TestApplyInputUnit(t *testing.T) {
+	article := &model.Article{
+		Unit:             "Stk",
+		PurchasePriceNet: 1,
+		UnitConversions: []model.UnitConversion{
+			{Unit: "Karton", Factor: 12, PurchasePriceNet: 9.6},
+			{Unit: "Palette", Factor: 480},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		transaction model.Transaction
+		wantErr     error
+
+		wantQuantity   float64
+		wantUnitPrice  float64
+		wantInputUnit  string
+		wantFactor     float64
+		wantInputPrice float64
+	}{
+		{
+			name:         "ohne erfasste Einheit unverändert",
+			transaction:  model.Transaction{Type: model.TransactionTypeStockIn, Quantity: 5},
+			wantQuantity: 5,
+		},
+		{
+			name:          "Zugang in Kartons zum hinterlegten Einkaufspreis",
+			transaction:   model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Karton", InputQuantity: 3},
+			wantQuantity:  36,
+			wantUnitPrice: 0.8,
+			wantInputUnit: "Karton",
+			wantFactor:    12,
+		},
+		{
+			name: "erfasster Stückpreis je Einheit",
+			transaction: model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Karton", InputQuantity: 2,
+				InputUnitPrice: 6},
+			wantQuantity:   24,
+			wantUnitPrice:  0.5,
+			wantInputUnit:  "Karton",
+			wantFactor:     12,
+			wantInputPrice: 6,
+		},
+		{
+			name:          "Entnahme wird nicht zum Einkaufspreis bewertet",
+			transaction:   model.Transaction{Type: model.TransactionTypeStockOut, InputUnit: "Karton", InputQuantity: 1},
+			wantQuantity:  12,
+			wantInputUnit: "Karton",
+			wantFactor:    12,
+		},
+		{
+			name:          "Einheit ohne Einkaufspreis",
+			transaction:   model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Palette", InputQuantity: 1},
+			wantQuantity:  480,
+			wantInputUnit: "Palette",
+			wantFactor:    480,
+		},
+		{
+			name: "gesetzter Faktor hat Vorrang",
+			transaction: model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Karton", InputQuantity: 2,
+				InputFactor: 10},
+			wantQuantity:  20,
+			wantUnitPrice: 0.96,
+			wantInputUnit: "Karton",
+			wantFactor:    10,
+		},
+		{
+			name:         "Lagereinheit braucht keine Umrechnungsangaben",
+			transaction:  model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Stk", InputQuantity: 7},
+			wantQuantity: 7,
+		},
+		{
+			name:        "unbekannte Einheit",
+			transaction: model.Transaction{Type: model.TransactionTypeStockIn, InputUnit: "Fass", InputQuantity: 1},
+			wantErr:     ErrUnknownUnit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction := tt.transaction
+			err := applyInputUnit(article, &transaction)
+			if err != tt.wantErr {
+				t.Fatalf("Fehler = %v, erwartet %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if math.Abs(transaction.Quantity-tt.wantQuantity) > lotEpsilon {
+				t.Errorf("Menge = %v, erwartet %v", transaction.Quantity, tt.wantQuantity)
+			}
+			if math.Abs(transaction.UnitPrice-tt.wantUnitPrice) > lotEpsilon {
+				t.Errorf("Stückpreis = %v, erwartet %v", transaction.UnitPrice, tt.wantUnitPrice)
+			}
+			if transaction.InputUnit != tt.wantInputUnit {
+				t.Errorf("Einheit = %q, erwartet %q", transaction.InputUnit, tt.wantInputUnit)
+			}
+			if transaction.InputFactor != tt.wantFactor {
+				t.Errorf("Faktor = %v, erwartet %v", transaction.InputFactor, tt.wantFactor)
+			}
+			if transaction.InputUnitPrice != tt.wantInputPrice {
+				t.Errorf("erfasster Stückpreis = %v, erwartet %v", transaction.InputUnitPrice, tt.wantInputPrice)
+			}
+		})
+	}
+}
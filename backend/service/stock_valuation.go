@@ -0,0 +1,258 @@
+// backend/service/stock_valuation.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"fmt"
+	"log"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// costLayerAttempts begrenzt die Versuche, einen Abgang bei gleichzeitigen Buchungen auf die
+// Kostenschichten zu verteilen
+const costLayerAttempts = 3
+
+// bookValuation führt die Bewertung eines Artikels entsprechend der Buchung fort. Beide
+// Verfahren werden immer gepflegt, damit ein Wechsel des Bewertungsverfahrens ohne Neuaufbau
+// möglich ist:
+//
+//   - Der gleitende Durchschnittspreis ändert sich nur durch Wareneingänge und deren Storno.
+//     Alle übrigen Bewegungen werden zum aktuellen Durchschnittspreis bewertet.
+//   - Zugänge legen eine FIFO-Kostenschicht an, Abgänge verbrauchen die ältesten Schichten.
+//     Ein Storno nimmt den Verbrauch bzw. die Schicht der Originalbuchung zurück.
+//
+// Fehlende Kostenschichten (z.B. bei negativem Bestand) blockieren keine Buchung; der nicht
+// gedeckte Abgang bleibt dann unbewertet.
+func (s *StockService) bookValuation(article *model.Article, transaction *model.Transaction, isTransfer bool) (func(), error) {
+	transaction.AverageCost = article.GetAverageCost()
+
+	// Umlagerungen verändern weder Menge noch Wert des Artikels
+	delta := transaction.GetStockDelta()
+	if isTransfer || math.Abs(delta) < lotEpsilon {
+		return func() {}, nil
+	}
+
+	var original *model.Transaction
+	if !transaction.ReversalOf.IsZero() {
+		var err error
+		original, err = s.transactionRepo.FindByID(transaction.ReversalOf.Hex())
+		if err != nil {
+			return nil, fmt.Errorf("Originalbuchung nicht gefunden: %v", err)
+		}
+	}
+
+	var undo []func()
+	revert := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	// Gleitender Durchschnitt: Wareneingang bzw. Storno eines Wareneingangs
+	if transaction.Type == model.TransactionTypeStockIn ||
+		(original != nil && original.Type == model.TransactionTypeStockIn) {
+		previous := article.AverageCost
+		averageCost, err := s.articleRepo.ApplyReceiptCost(article.ID, delta, transaction.UnitPrice)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Aktualisieren des Durchschnittspreises: %v", err)
+		}
+		transaction.AverageCost = averageCost
+		undo = append(undo, func() {
+			if err := s.articleRepo.SetAverageCost(article.ID, previous); err != nil {
+				log.Printf("Durchschnittspreis für Artikel %s konnte nicht zurückgesetzt werden: %v", article.ID.Hex(), err)
+			}
+		})
+	}
+
+	// FIFO-Kostenschichten
+	var undoLayers func()
+	var err error
+	if delta > 0 {
+		undoLayers, err = s.addCostLayers(transaction, original, delta)
+	} else {
+		undoLayers, err = s.consumeCostLayers(transaction, original, -delta)
+	}
+	if err != nil {
+		revert()
+		return nil, err
+	}
+	undo = append(undo, undoLayers)
+
+	return revert, nil
+}
+
+// addCostLayers bucht einen Zugang in die Kostenschichten. Der Storno eines Abgangs gibt die
+// Menge an die Schichten zurück, aus denen der Abgang entnommen hat; alles Übrige bildet eine
+// neue Schicht zum Stückpreis der Buchung.
+func (s *StockService) addCostLayers(transaction, original *model.Transaction, quantity float64) (func(), error) {
+	var undo []func()
+	revert := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	if original != nil {
+		layers, err := s.costLayerRepo.FindConsumedBy(original.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden der Kostenschichten: %v", err)
+		}
+
+		for _, layer := range layers {
+			consumed := consumedBy(layer, original.ID)
+			restore := math.Min(consumed, quantity)
+			if restore < lotEpsilon {
+				continue
+			}
+
+			if err := s.costLayerRepo.Restore(layer.ID, transaction.ID, restore, transaction.Timestamp); err != nil {
+				revert()
+				return nil, fmt.Errorf("Fehler beim Aktualisieren der Kostenschichten: %v", err)
+			}
+			layerID := layer.ID
+			undo = append(undo, func() { s.removeConsumption(layerID, transaction.ID, -restore) })
+			quantity -= restore
+		}
+	}
+
+	if quantity < lotEpsilon {
+		return revert, nil
+	}
+
+	layer := &model.CostLayer{
+		ArticleID:     transaction.ArticleID,
+		TransactionID: transaction.ID,
+		ReceivedAt:    transaction.Timestamp,
+		Quantity:      quantity,
+		Remaining:     quantity,
+		UnitCost:      transaction.UnitPrice,
+	}
+	if err := s.costLayerRepo.Create(layer); err != nil {
+		revert()
+		return nil, fmt.Errorf("Fehler beim Anlegen der Kostenschicht: %v", err)
+	}
+	undo = append(undo, func() {
+		if err := s.costLayerRepo.Delete(layer.ID); err != nil {
+			log.Printf("Kostenschicht %s konnte nicht gelöscht werden: %v", layer.ID.Hex(), err)
+		}
+	})
+
+	return revert, nil
+}
+
+// consumeCostLayers verbraucht die ältesten Kostenschichten für einen Abgang. Der Storno eines
+// Zugangs verbraucht zuerst die Schicht, die der Zugang angelegt hat. Hat eine parallele
+// Buchung eine Schicht inzwischen verbraucht, wird der Rest neu auf die Schichten verteilt.
+func (s *StockService) consumeCostLayers(transaction, original *model.Transaction, quantity float64) (func(), error) {
+	var undo []func()
+	revert := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+
+	for attempt := 0; attempt < costLayerAttempts && quantity >= lotEpsilon; attempt++ {
+		layers, err := s.costLayerRepo.FindOpen(transaction.ArticleID)
+		if err != nil {
+			revert()
+			return nil, fmt.Errorf("Fehler beim Laden der Kostenschichten: %v", err)
+		}
+
+		var own *model.CostLayer
+		if original != nil {
+			own, err = s.costLayerRepo.FindByTransactionID(original.ID)
+			if err != nil && err != mongo.ErrNoDocuments {
+				revert()
+				return nil, fmt.Errorf("Fehler beim Laden der Kostenschichten: %v", err)
+			}
+		}
+
+		takes, _ := allocateCostLayers(layers, own, quantity)
+		conflict := false
+		for _, take := range takes {
+			ok, err := s.costLayerRepo.Consume(take.layer.ID, transaction.ID, take.quantity, transaction.Timestamp)
+			if err != nil {
+				revert()
+				return nil, fmt.Errorf("Fehler beim Aktualisieren der Kostenschichten: %v", err)
+			}
+			if !ok {
+				// Eine parallele Buchung hat die Schicht inzwischen verbraucht
+				conflict = true
+				continue
+			}
+
+			layerID, quantityTaken := take.layer.ID, take.quantity
+			undo = append(undo, func() { s.removeConsumption(layerID, transaction.ID, quantityTaken) })
+			quantity -= take.quantity
+		}
+		if !conflict {
+			break
+		}
+	}
+
+	if quantity >= lotEpsilon {
+		log.Printf("Abgang %s für Artikel %s ist in Höhe von %g nicht durch Kostenschichten gedeckt",
+			transaction.ID.Hex(), transaction.ArticleID.Hex(), quantity)
+	}
+
+	return revert, nil
+}
+
+// costLayerTake ist die Menge, die ein Abgang aus einer Kostenschicht entnimmt
+type costLayerTake struct {
+	layer    *model.CostLayer
+	quantity float64
+}
+
+// allocateCostLayers verteilt eine Abgangsmenge nach FIFO auf die nach Zugang sortierten
+// offenen Kostenschichten. Ist own gesetzt, wird diese Schicht zuerst verbraucht. Zurückgegeben
+// werden die Entnahmen und die Menge, die keine Schicht mehr deckt.
+func allocateCostLayers(layers []*model.CostLayer, own *model.CostLayer, quantity float64) ([]costLayerTake, float64) {
+	if own != nil {
+		ordered := []*model.CostLayer{own}
+		for _, layer := range layers {
+			if layer.ID != own.ID {
+				ordered = append(ordered, layer)
+			}
+		}
+		layers = ordered
+	}
+
+	var takes []costLayerTake
+	for _, layer := range layers {
+		if quantity < lotEpsilon {
+			break
+		}
+
+		take := math.Min(layer.Remaining, quantity)
+		if take < lotEpsilon {
+			continue
+		}
+
+		takes = append(takes, costLayerTake{layer: layer, quantity: take})
+		quantity -= take
+	}
+
+	return takes, math.Max(quantity, 0)
+}
+
+// removeConsumption nimmt den Verbrauch einer Buchung aus einer Kostenschicht zurück
+func (s *StockService) removeConsumption(layerID, transactionID primitive.ObjectID, quantity float64) {
+	if err := s.costLayerRepo.RemoveConsumption(layerID, transactionID, quantity); err != nil {
+		log.Printf("Verbrauch der Kostenschicht %s konnte nicht zurückgenommen werden: %v", layerID.Hex(), err)
+	}
+}
+
+// consumedBy gibt die Menge zurück, die eine Buchung netto aus einer Kostenschicht entnommen hat
+func consumedBy(layer *model.CostLayer, transactionID primitive.ObjectID) float64 {
+	var total float64
+	for _, consumption := range layer.Consumptions {
+		if consumption.TransactionID == transactionID {
+			total += consumption.Quantity
+		}
+	}
+	return total
+}
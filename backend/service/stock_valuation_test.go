@@ -0,0 +1,119 @@
+// backend/service/stock_valuation_test.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAllocateCostLayers(t *testing.T) {
+	oldest := &model.CostLayer{ID: primitive.NewObjectID(), Remaining: 5, UnitCost: 2}
+	middle := &model.CostLayer{ID: primitive.NewObjectID(), Remaining: 10, UnitCost: 3}
+	newest := &model.CostLayer{ID: primitive.NewObjectID(), Remaining: 8, UnitCost: 4}
+	empty := &model.CostLayer{ID: primitive.NewObjectID(), Remaining: 0, UnitCost: 1}
+
+	tests := []struct {
+		name          string
+		layers        []*model.CostLayer
+		own           *model.CostLayer
+		quantity      float64
+		wantLayers    []*model.CostLayer
+		wantTakes     []float64
+		wantUncovered float64
+	}{
+		{
+			name:       "aus der ältesten Schicht",
+			layers:     []*model.CostLayer{oldest, middle, newest},
+			quantity:   3,
+			wantLayers: []*model.CostLayer{oldest},
+			wantTakes:  []float64{3},
+		},
+		{
+			name:       "über mehrere Schichten",
+			layers:     []*model.CostLayer{oldest, middle, newest},
+			quantity:   17,
+			wantLayers: []*model.CostLayer{oldest, middle, newest},
+			wantTakes:  []float64{5, 10, 2},
+		},
+		{
+			name:       "verbrauchte Schichten werden übersprungen",
+			layers:     []*model.CostLayer{empty, middle},
+			quantity:   4,
+			wantLayers: []*model.CostLayer{middle},
+			wantTakes:  []float64{4},
+		},
+		{
+			name:          "nicht gedeckter Rest",
+			layers:        []*model.CostLayer{oldest, middle},
+			quantity:      20,
+			wantLayers:    []*model.CostLayer{oldest, middle},
+			wantTakes:     []float64{5, 10},
+			wantUncovered: 5,
+		},
+		{
+			name:          "ohne Schichten",
+			quantity:      4,
+			wantUncovered: 4,
+		},
+		{
+			name:       "Storno verbraucht zuerst die eigene Schicht",
+			layers:     []*model.CostLayer{oldest, middle, newest},
+			own:        newest,
+			quantity:   10,
+			wantLayers: []*model.CostLayer{newest, oldest},
+			wantTakes:  []float64{8, 2},
+		},
+		{
+			name:       "eigene Schicht bereits verbraucht",
+			layers:     []*model.CostLayer{oldest, middle},
+			own:        empty,
+			quantity:   6,
+			wantLayers: []*model.CostLayer{oldest, middle},
+			wantTakes:  []float64{5, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			takes, uncovered := allocateCostLayers(tt.layers, tt.own, tt.quantity)
+
+			if len(takes) != len(tt.wantTakes) {
+				t.Fatalf("%d Entnahmen, erwartet %d", len(takes), len(tt.wantTakes))
+			}
+			for i, take := range takes {
+				if take.layer != tt.wantLayers[i] {
+					t.Errorf("Entnahme %d aus Schicht %s, erwartet %s", i, take.layer.ID.Hex(), tt.wantLayers[i].ID.Hex())
+				}
+				if math.Abs(take.quantity-tt.wantTakes[i]) > lotEpsilon {
+					t.Errorf("Entnahme %d = %v, erwartet %v", i, take.quantity, tt.wantTakes[i])
+				}
+			}
+			if math.Abs(uncovered-tt.wantUncovered) > lotEpsilon {
+				t.Errorf("nicht gedeckt = %v, erwartet %v", uncovered, tt.wantUncovered)
+			}
+		})
+	}
+}
+
+func TestConsumedBy(t *testing.T) {
+	outbound := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	layer := &model.CostLayer{
+		Consumptions: []model.CostLayerConsumption{
+			{TransactionID: outbound, Quantity: 6},
+			{TransactionID: other, Quantity: 3},
+			{TransactionID: outbound, Quantity: -2},
+		},
+	}
+
+	if got := consumedBy(layer, outbound); got != 4 {
+		t.Errorf("consumedBy = %v, erwartet 4", got)
+	}
+	if got := consumedBy(layer, primitive.NewObjectID()); got != 0 {
+		t.Errorf("consumedBy ohne Verbrauch = %v, erwartet 0", got)
+	}
+}
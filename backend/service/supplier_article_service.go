@@ -0,0 +1,260 @@
+// backend/service/supplier_article_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrSupplierArticleSupplier wird zurückgegeben, wenn der Lieferant einer Bezugsquelle fehlt oder unbekannt ist
+var ErrSupplierArticleSupplier = errors.New("Bitte einen gültigen Lieferanten auswählen")
+
+// ErrSupplierArticleArticle wird zurückgegeben, wenn der Artikel einer Bezugsquelle fehlt oder unbekannt ist
+var ErrSupplierArticleArticle = errors.New("Bitte einen gültigen Artikel auswählen")
+
+// ErrSupplierArticleUnit wird zurückgegeben, wenn die Bestelleinheit keine Einheit des Artikels ist
+var ErrSupplierArticleUnit = errors.New("Die Bestelleinheit muss eine Einheit des Artikels sein")
+
+// ErrSupplierArticleQuantity wird zurückgegeben, wenn Mindestbestellmenge, Verpackungseinheit oder Lieferzeit negativ sind
+var ErrSupplierArticleQuantity = errors.New("Mindestbestellmenge, Verpackungseinheit und Lieferzeit dürfen nicht negativ sein")
+
+// ErrSupplierPriceDate wird zurückgegeben, wenn ein neuer Preis vor dem zuletzt erfassten Preis gelten soll
+var ErrSupplierPriceDate = errors.New("Ein neuer Preis kann nicht vor dem zuletzt erfassten Preis gültig werden")
+
+// ErrSupplierPriceInvalid wird zurückgegeben, wenn ein negativer Preis erfasst wird
+var ErrSupplierPriceInvalid = errors.New("Der Preis darf nicht negativ sein")
+
+// SourceStrategy legt fest, nach welcher Regel die Bezugsquelle für eine Nachbestellung gewählt wird
+type SourceStrategy string
+
+const (
+	SourceStrategyPreferred SourceStrategy = "preferred" // Bevorzugte Bezugsquelle, sonst die günstigste
+	SourceStrategyCheapest  SourceStrategy = "cheapest"  // Günstigste Bezugsquelle für die benötigte Menge
+)
+
+// ParseSourceStrategy wandelt eine Eingabe in eine Strategie um; unbekannte Werte ergeben die bevorzugte Quelle
+func ParseSourceStrategy(value string) SourceStrategy {
+	if SourceStrategy(value) == SourceStrategyCheapest {
+		return SourceStrategyCheapest
+	}
+	return SourceStrategyPreferred
+}
+
+// SupplyOption ist eine Bezugsquelle mit der für einen Bedarf bestellten Menge und ihrem Preis
+type SupplyOption struct {
+	SupplierArticle *model.SupplierArticle
+	Quantity        float64 // Bestellmenge in der Bestelleinheit, gerundet auf Mindestmenge und Verpackungseinheit
+	UnitPrice       float64 // Heute gültiger Preis je Bestelleinheit (0 = kein Preis hinterlegt)
+	Factor          float64 // Lagereinheiten je Bestelleinheit
+}
+
+// GetTotal gibt den Nettowert der Bestellmenge zurück
+func (o *SupplyOption) GetTotal() float64 {
+	return o.Quantity * o.UnitPrice
+}
+
+// GetBaseQuantity gibt die Bestellmenge in Lagereinheiten zurück
+func (o *SupplyOption) GetBaseQuantity() float64 {
+	return o.Quantity * o.Factor
+}
+
+// NewSupplyOption berechnet für einen Bedarf in Lagereinheiten die Bestellmenge bei einer
+// Bezugsquelle. Gibt nil zurück, wenn die Bestelleinheit keine Einheit des Artikels ist.
+func NewSupplyOption(article *model.Article, supplierArticle *model.SupplierArticle, baseQuantity float64) *SupplyOption {
+	factor, ok := article.GetUnitFactor(supplierArticle.Unit)
+	if !ok {
+		return nil
+	}
+	quantity := math.Ceil(baseQuantity/factor - lotEpsilon)
+	if factor == 1 {
+		quantity = baseQuantity
+	}
+	return &SupplyOption{
+		SupplierArticle: supplierArticle,
+		Quantity:        supplierArticle.RoundOrderQuantity(quantity),
+		UnitPrice:       supplierArticle.GetCurrentPrice(),
+		Factor:          factor,
+	}
+}
+
+// RankSupplyOptions ordnet die Bezugsquellen eines Artikels für einen Bedarf in Lagereinheiten.
+// Berücksichtigt werden nur Bezugsquellen aktiver Lieferanten. Die erste Quelle ist die nach
+// der Strategie gewählte: die bevorzugte oder die mit dem niedrigsten Wert für die gerundete
+// Bestellmenge. Quellen ohne Preis folgen am Ende, bei Gleichstand entscheiden die
+// Kennzeichnung als bevorzugt, die kürzere Lieferzeit und der Name des Lieferanten.
+func RankSupplyOptions(
+	article *model.Article,
+	supplierArticles []*model.SupplierArticle,
+	activeSuppliers map[primitive.ObjectID]bool,
+	baseQuantity float64,
+	strategy SourceStrategy,
+) []*SupplyOption {
+	options := make([]*SupplyOption, 0, len(supplierArticles))
+	for _, supplierArticle := range supplierArticles {
+		if supplierArticle.ArticleID != article.ID || !activeSuppliers[supplierArticle.SupplierID] {
+			continue
+		}
+		if option := NewSupplyOption(article, supplierArticle, baseQuantity); option != nil {
+			options = append(options, option)
+		}
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if strategy == SourceStrategyPreferred && a.SupplierArticle.IsPreferred != b.SupplierArticle.IsPreferred {
+			return a.SupplierArticle.IsPreferred
+		}
+		if (a.UnitPrice > 0) != (b.UnitPrice > 0) {
+			return a.UnitPrice > 0
+		}
+		if math.Abs(a.GetTotal()-b.GetTotal()) >= 0.005 {
+			return a.GetTotal() < b.GetTotal()
+		}
+		if a.SupplierArticle.IsPreferred != b.SupplierArticle.IsPreferred {
+			return a.SupplierArticle.IsPreferred
+		}
+		if a.SupplierArticle.LeadTimeDays != b.SupplierArticle.LeadTimeDays {
+			return a.SupplierArticle.LeadTimeDays < b.SupplierArticle.LeadTimeDays
+		}
+		return a.SupplierArticle.SupplierName < b.SupplierArticle.SupplierName
+	})
+
+	return options
+}
+
+// SupplierArticleService verwaltet die Bezugsquellen der Artikel und ihre Preishistorie
+type SupplierArticleService struct {
+	supplierArticleRepo *repository.SupplierArticleRepository
+	articleRepo         *repository.ArticleRepository
+	supplierRepo        *repository.SupplierRepository
+}
+
+// NewSupplierArticleService erstellt einen neuen SupplierArticleService
+func NewSupplierArticleService() *SupplierArticleService {
+	return &SupplierArticleService{
+		supplierArticleRepo: repository.NewSupplierArticleRepository(),
+		articleRepo:         repository.NewArticleRepository(),
+		supplierRepo:        repository.NewSupplierRepository(),
+	}
+}
+
+// SaveSupplierArticle prüft eine neue oder geänderte Bezugsquelle, übernimmt Lieferanten- und
+// Artikeldaten und speichert sie. Ein Preis größer als 0 wird ab validFrom in die Preishistorie
+// aufgenommen. Wird die Bezugsquelle bevorzugt, verlieren die übrigen Quellen des Artikels
+// diese Kennzeichnung.
+func (s *SupplierArticleService) SaveSupplierArticle(
+	supplierArticle *model.SupplierArticle,
+	price float64,
+	validFrom time.Time,
+	notes string,
+	userName string,
+) error {
+	supplier, err := s.supplierRepo.FindByID(supplierArticle.SupplierID.Hex())
+	if err != nil {
+		return ErrSupplierArticleSupplier
+	}
+	article, err := s.articleRepo.FindByID(supplierArticle.ArticleID.Hex())
+	if err != nil {
+		return ErrSupplierArticleArticle
+	}
+
+	supplierArticle.SupplierName = supplier.Name
+	supplierArticle.ArticleNumber = article.ArticleNumber
+	supplierArticle.ArticleName = article.ShortName
+	if supplierArticle.Unit == "" {
+		supplierArticle.Unit = article.GetPurchaseUnit()
+	}
+	if _, ok := article.GetUnitFactor(supplierArticle.Unit); !ok {
+		return ErrSupplierArticleUnit
+	}
+	if supplierArticle.MinimumOrderQuantity < 0 || supplierArticle.PackSize < 0 || supplierArticle.LeadTimeDays < 0 {
+		return ErrSupplierArticleQuantity
+	}
+	if price < 0 {
+		return ErrSupplierPriceInvalid
+	}
+	if price > 0 {
+		if err := addSupplierPrice(supplierArticle, price, validFrom, notes, userName); err != nil {
+			return err
+		}
+	}
+
+	if supplierArticle.ID.IsZero() {
+		err = s.supplierArticleRepo.Create(supplierArticle)
+	} else {
+		err = s.supplierArticleRepo.Update(supplierArticle)
+	}
+	if err != nil {
+		return err
+	}
+
+	if supplierArticle.IsPreferred {
+		return s.supplierArticleRepo.ClearPreferred(supplierArticle.ArticleID, supplierArticle.ID)
+	}
+	return nil
+}
+
+// GetSupplyOptions gibt die nach der Strategie geordneten Bezugsquellen eines Artikels für einen
+// Bedarf in Lagereinheiten zurück
+func (s *SupplierArticleService) GetSupplyOptions(article *model.Article, baseQuantity float64, strategy SourceStrategy) ([]*SupplyOption, error) {
+	supplierArticles, err := s.supplierArticleRepo.FindByArticleID(article.ID)
+	if err != nil {
+		return nil, err
+	}
+	activeSuppliers, err := s.findActiveSuppliers()
+	if err != nil {
+		return nil, err
+	}
+	return RankSupplyOptions(article, supplierArticles, activeSuppliers, baseQuantity, strategy), nil
+}
+
+// findActiveSuppliers gibt die IDs aller aktiven Lieferanten zurück
+func (s *SupplierArticleService) findActiveSuppliers() (map[primitive.ObjectID]bool, error) {
+	suppliers, err := s.supplierRepo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[primitive.ObjectID]bool, len(suppliers))
+	for _, supplier := range suppliers {
+		active[supplier.ID] = true
+	}
+	return active, nil
+}
+
+// addSupplierPrice nimmt einen Preis ab dem angegebenen Tag in die Preishistorie auf. Der
+// bisher letzte Preis gilt bis zu diesem Tag. Gilt er ab demselben Tag, wird er ersetzt.
+func addSupplierPrice(supplierArticle *model.SupplierArticle, price float64, validFrom time.Time, notes, userName string) error {
+	if validFrom.IsZero() {
+		validFrom = time.Now()
+	}
+	validFrom = time.Date(validFrom.Year(), validFrom.Month(), validFrom.Day(), 0, 0, 0, 0, validFrom.Location())
+
+	entry := model.SupplierPrice{
+		PurchasePriceNet: price,
+		ValidFrom:        validFrom,
+		ChangedBy:        userName,
+		ChangedAt:        time.Now(),
+		Notes:            notes,
+	}
+
+	last := supplierArticle.GetLatestPrice()
+	switch {
+	case last == nil:
+		supplierArticle.Prices = append(supplierArticle.Prices, entry)
+	case validFrom.Before(last.ValidFrom):
+		return ErrSupplierPriceDate
+	case validFrom.Equal(last.ValidFrom):
+		*last = entry
+	default:
+		last.ValidTo = validFrom
+		supplierArticle.Prices = append(supplierArticle.Prices, entry)
+	}
+
+	return nil
+}
@@ -0,0 +1,405 @@
+// backend/service/supplier_scorecard_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Gewichtung der Kennzahlen in der Gesamtbewertung eines Lieferanten (Summe 100 Punkte)
+const (
+	scoreWeightOnTime   = 40.0 // Termintreue
+	scoreWeightQuantity = 30.0 // Mengentreue
+	scoreWeightLeadTime = 20.0 // Einhaltung der zugesagten Lieferzeit
+	scoreWeightPrice    = 10.0 // Preisstabilität
+
+	// scorePriceIncreaseLimit ist die durchschnittliche Preiserhöhung, ab der es keine Punkte
+	// für Preisstabilität mehr gibt
+	scorePriceIncreaseLimit = 0.10
+)
+
+// SupplierScorecard bewertet die Lieferungen eines Lieferanten in einem Zeitraum
+type SupplierScorecard struct {
+	SupplierID   primitive.ObjectID `json:"supplierId"`
+	SupplierCode string             `json:"supplierCode"`
+	SupplierName string             `json:"supplierName"`
+	IsActive     bool               `json:"isActive"`
+
+	Deliveries int `json:"deliveries"` // Wareneingänge mit nicht stornierten Buchungen
+	Orders     int `json:"orders"`     // Bestellungen, zu denen geliefert wurde
+	Lines      int `json:"lines"`      // Gelieferte Positionen
+
+	OnTimeDeliveries int     `json:"onTimeDeliveries"` // Lieferungen bis zum erwarteten Liefertermin
+	OnTimeRate       float64 `json:"onTimeRate"`       // Anteil pünktlicher Lieferungen (0..1)
+
+	PromisedLeadTime float64 `json:"promisedLeadTime"` // Ø zugesagte Lieferzeit in Tagen laut Stammdaten
+	ActualLeadTime   float64 `json:"actualLeadTime"`   // Ø tatsächliche Lieferzeit in Tagen ab Bestelldatum
+	LateDays         float64 `json:"lateDays"`         // Ø Verspätung gegenüber dem Liefertermin (nur verspätete)
+
+	ExactLines       int     `json:"exactLines"`       // Positionen genau in der erwarteten Menge
+	QuantityAccuracy float64 `json:"quantityAccuracy"` // 1 - Ø relative Mengenabweichung je Position (0..1)
+
+	PriceChanges       int     `json:"priceChanges"`       // Preisänderungen im Zeitraum laut Preishistorie
+	AveragePriceChange float64 `json:"averagePriceChange"` // Ø relative Preisänderung (z.B. 0.05 = +5 %)
+
+	Score float64 `json:"score"` // Gesamtbewertung 0..100, nur mit Lieferungen im Zeitraum
+	Rank  int     `json:"rank"`  // Rang in der Bewertung, 0 ohne Lieferungen
+}
+
+// HasDeliveries prüft, ob im Zeitraum Lieferungen bewertet werden konnten
+func (s *SupplierScorecard) HasDeliveries() bool {
+	return s.Deliveries > 0
+}
+
+// GetLeadTimeDeviation gibt die Abweichung der tatsächlichen von der zugesagten Lieferzeit in Tagen zurück
+func (s *SupplierScorecard) GetLeadTimeDeviation() float64 {
+	return s.ActualLeadTime - s.PromisedLeadTime
+}
+
+// GetOnTimePercent gibt die Termintreue in Prozent zurück
+func (s *SupplierScorecard) GetOnTimePercent() float64 {
+	return s.OnTimeRate * 100
+}
+
+// GetQuantityAccuracyPercent gibt die Mengentreue in Prozent zurück
+func (s *SupplierScorecard) GetQuantityAccuracyPercent() float64 {
+	return s.QuantityAccuracy * 100
+}
+
+// GetAveragePriceChangePercent gibt die durchschnittliche Preisänderung in Prozent zurück
+func (s *SupplierScorecard) GetAveragePriceChangePercent() float64 {
+	return s.AveragePriceChange * 100
+}
+
+// SupplierScorecardService bewertet Lieferanten anhand ihrer Wareneingänge
+type SupplierScorecardService struct {
+	supplierRepo        *repository.SupplierRepository
+	supplierArticleRepo *repository.SupplierArticleRepository
+	articleRepo         *repository.ArticleRepository
+	transactionRepo     *repository.TransactionRepository
+	goodsReceiptRepo    *repository.GoodsReceiptRepository
+	purchaseOrderRepo   *repository.PurchaseOrderRepository
+}
+
+// NewSupplierScorecardService erstellt einen neuen SupplierScorecardService
+func NewSupplierScorecardService() *SupplierScorecardService {
+	return &SupplierScorecardService{
+		supplierRepo:        repository.NewSupplierRepository(),
+		supplierArticleRepo: repository.NewSupplierArticleRepository(),
+		articleRepo:         repository.NewArticleRepository(),
+		transactionRepo:     repository.NewTransactionRepository(),
+		goodsReceiptRepo:    repository.NewGoodsReceiptRepository(),
+		purchaseOrderRepo:   repository.NewPurchaseOrderRepository(),
+	}
+}
+
+// GenerateSupplierReport erstellt die Rangliste aller Lieferanten für einen Zeitraum
+func (s *SupplierScorecardService) GenerateSupplierReport(startDate, endDate time.Time) (map[string]interface{}, error) {
+	scorecards, err := s.RankSuppliers(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rated := 0
+	deliveries := 0
+	onTime := 0
+	for _, scorecard := range scorecards {
+		if scorecard.HasDeliveries() {
+			rated++
+			deliveries += scorecard.Deliveries
+			onTime += scorecard.OnTimeDeliveries
+		}
+	}
+	onTimeRate := 0.0
+	if deliveries > 0 {
+		onTimeRate = float64(onTime) / float64(deliveries)
+	}
+
+	return map[string]interface{}{
+		"period": map[string]string{
+			"start": startDate.Format("02.01.2006"),
+			"end":   endDate.Format("02.01.2006"),
+		},
+		"days":          math.Round(endDate.Sub(startDate).Hours() / 24),
+		"entries":       scorecards,
+		"ratedCount":    rated,
+		"deliveries":    deliveries,
+		"onTimePercent": onTimeRate * 100,
+	}, nil
+}
+
+// GetScorecard bewertet einen einzelnen Lieferanten im Zeitraum. Der Rang bezieht sich auf
+// alle Lieferanten.
+func (s *SupplierScorecardService) GetScorecard(supplierID primitive.ObjectID, startDate, endDate time.Time) (*SupplierScorecard, error) {
+	scorecards, err := s.RankSuppliers(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, scorecard := range scorecards {
+		if scorecard.SupplierID == supplierID {
+			return scorecard, nil
+		}
+	}
+	return &SupplierScorecard{SupplierID: supplierID}, nil
+}
+
+// RankSuppliers bewertet alle Lieferanten anhand der nicht stornierten Zugangsbuchungen aus
+// Wareneingängen im Zeitraum und ordnet sie nach ihrer Gesamtbewertung. Lieferanten ohne
+// Lieferungen im Zeitraum folgen ohne Bewertung am Ende.
+//
+// Bewertet werden je Wareneingang die Termintreue (geliefert bis zum erwarteten Liefertermin
+// der Bestellung) und die tatsächliche Lieferzeit ab Bestelldatum gegenüber der zugesagten
+// Lieferzeit laut Stammdaten: der Lieferzeit der Bezugsquelle oder, ohne Bezugsquelle, der
+// Lieferzeit des Artikels (längste Lieferzeit der gelieferten Artikel). Die Mengentreue
+// ergibt sich je Position aus der Abweichung der gelieferten von der erwarteten Menge, die
+// Preisänderungen aus der Preishistorie der Bezugsquellen.
+func (s *SupplierScorecardService) RankSuppliers(startDate, endDate time.Time) ([]*SupplierScorecard, error) {
+	if !endDate.After(startDate) {
+		return nil, ErrInvalidPeriod
+	}
+
+	suppliers, err := s.supplierRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	scorecards := make(map[primitive.ObjectID]*SupplierScorecard, len(suppliers))
+	result := make([]*SupplierScorecard, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		scorecard := &SupplierScorecard{
+			SupplierID:   supplier.ID,
+			SupplierCode: supplier.SupplierCode,
+			SupplierName: supplier.Name,
+			IsActive:     supplier.IsActive,
+		}
+		scorecards[supplier.ID] = scorecard
+		result = append(result, scorecard)
+	}
+
+	// Zugangsbuchungen aus Wareneingängen und die zugehörigen Belege
+	transactions, err := s.transactionRepo.FindGoodsReceiptTransactionsInPeriod(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	booked := make(map[primitive.ObjectID]bool, len(transactions))
+	var receiptIDs []primitive.ObjectID
+	seenReceipts := make(map[primitive.ObjectID]bool)
+	for _, transaction := range transactions {
+		booked[transaction.ID] = true
+		if !seenReceipts[transaction.GoodsReceiptID] {
+			seenReceipts[transaction.GoodsReceiptID] = true
+			receiptIDs = append(receiptIDs, transaction.GoodsReceiptID)
+		}
+	}
+
+	receipts, err := s.goodsReceiptRepo.FindByIDs(receiptIDs)
+	if err != nil {
+		return nil, err
+	}
+	var orderIDs []primitive.ObjectID
+	seenOrders := make(map[primitive.ObjectID]bool)
+	for _, receipt := range receipts {
+		if !seenOrders[receipt.PurchaseOrderID] {
+			seenOrders[receipt.PurchaseOrderID] = true
+			orderIDs = append(orderIDs, receipt.PurchaseOrderID)
+		}
+	}
+	orders, err := s.purchaseOrderRepo.FindByIDs(orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	ordersByID := make(map[primitive.ObjectID]*model.PurchaseOrder, len(orders))
+	for _, order := range orders {
+		ordersByID[order.ID] = order
+	}
+
+	// Zugesagte Lieferzeiten aus Bezugsquellen und Artikeln
+	supplierArticles, err := s.supplierArticleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	leadTimes, err := s.findPromisedLeadTimes(supplierArticles)
+	if err != nil {
+		return nil, err
+	}
+
+	type totals struct {
+		orders                           map[primitive.ObjectID]bool
+		promised, actual, late, accuracy float64
+		lateDeliveries                   int
+	}
+	sums := make(map[primitive.ObjectID]*totals)
+
+	for _, receipt := range receipts {
+		scorecard := scorecards[receipt.SupplierID]
+		order := ordersByID[receipt.PurchaseOrderID]
+		if scorecard == nil || order == nil {
+			continue
+		}
+
+		sum := sums[receipt.SupplierID]
+		if sum == nil {
+			sum = &totals{orders: make(map[primitive.ObjectID]bool)}
+			sums[receipt.SupplierID] = sum
+		}
+
+		promised := 0
+		lines := 0
+		for i := range receipt.Lines {
+			line := &receipt.Lines[i]
+			if !booked[line.TransactionID] {
+				continue
+			}
+			lines++
+
+			if math.Abs(line.GetDeviation()) < lotEpsilon {
+				scorecard.ExactLines++
+				sum.accuracy++
+			} else if line.ExpectedQuantity > 0 {
+				sum.accuracy += 1 - math.Min(math.Abs(line.GetDeviation())/line.ExpectedQuantity, 1)
+			}
+
+			if days := leadTimes.get(receipt.SupplierID, line.ArticleID); days > promised {
+				promised = days
+			}
+		}
+		if lines == 0 {
+			continue
+		}
+
+		scorecard.Deliveries++
+		scorecard.Lines += lines
+		sum.orders[order.ID] = true
+
+		received := truncateToDay(receipt.ReceivedAt)
+		sum.promised += float64(promised)
+		sum.actual += received.Sub(truncateToDay(order.OrderDate)).Hours() / 24
+		if expected := truncateToDay(order.ExpectedDate); received.After(expected) {
+			sum.late += received.Sub(expected).Hours() / 24
+			sum.lateDeliveries++
+		} else {
+			scorecard.OnTimeDeliveries++
+		}
+	}
+
+	// Preisänderungen laut Preishistorie der Bezugsquellen
+	priceSums := make(map[primitive.ObjectID]float64)
+	for _, supplierArticle := range supplierArticles {
+		scorecard := scorecards[supplierArticle.SupplierID]
+		if scorecard == nil {
+			continue
+		}
+		for i := 1; i < len(supplierArticle.Prices); i++ {
+			price := supplierArticle.Prices[i]
+			previous := supplierArticle.Prices[i-1].PurchasePriceNet
+			if price.ValidFrom.Before(startDate) || price.ValidFrom.After(endDate) || previous <= 0 {
+				continue
+			}
+			scorecard.PriceChanges++
+			priceSums[supplierArticle.SupplierID] += (price.PurchasePriceNet - previous) / previous
+		}
+	}
+
+	for _, scorecard := range result {
+		if scorecard.PriceChanges > 0 {
+			scorecard.AveragePriceChange = priceSums[scorecard.SupplierID] / float64(scorecard.PriceChanges)
+		}
+
+		sum := sums[scorecard.SupplierID]
+		if sum == nil || scorecard.Deliveries == 0 {
+			continue
+		}
+		deliveries := float64(scorecard.Deliveries)
+		scorecard.Orders = len(sum.orders)
+		scorecard.OnTimeRate = float64(scorecard.OnTimeDeliveries) / deliveries
+		scorecard.PromisedLeadTime = sum.promised / deliveries
+		scorecard.ActualLeadTime = sum.actual / deliveries
+		scorecard.QuantityAccuracy = sum.accuracy / float64(scorecard.Lines)
+		if sum.lateDeliveries > 0 {
+			scorecard.LateDays = sum.late / float64(sum.lateDeliveries)
+		}
+		scorecard.Score = computeSupplierScore(scorecard)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].HasDeliveries() != result[j].HasDeliveries() {
+			return result[i].HasDeliveries()
+		}
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		if result[i].Deliveries != result[j].Deliveries {
+			return result[i].Deliveries > result[j].Deliveries
+		}
+		return result[i].SupplierName < result[j].SupplierName
+	})
+	for i, scorecard := range result {
+		if scorecard.HasDeliveries() {
+			scorecard.Rank = i + 1
+		}
+	}
+
+	return result, nil
+}
+
+// computeSupplierScore berechnet die Gesamtbewertung aus Termintreue, Mengentreue, dem
+// Verhältnis von zugesagter zu tatsächlicher Lieferzeit und der Preisstabilität. Preissenkungen
+// und Lieferungen schneller als zugesagt ergeben die volle Punktzahl.
+func computeSupplierScore(scorecard *SupplierScorecard) float64 {
+	leadTime := 1.0
+	if scorecard.ActualLeadTime > scorecard.PromisedLeadTime && scorecard.ActualLeadTime > 0 {
+		leadTime = scorecard.PromisedLeadTime / scorecard.ActualLeadTime
+	}
+	price := 1 - math.Min(math.Max(scorecard.AveragePriceChange, 0)/scorePriceIncreaseLimit, 1)
+
+	score := scoreWeightOnTime*scorecard.OnTimeRate +
+		scoreWeightQuantity*scorecard.QuantityAccuracy +
+		scoreWeightLeadTime*leadTime +
+		scoreWeightPrice*price
+	return math.Round(score*10) / 10
+}
+
+// promisedLeadTimes enthält die zugesagten Lieferzeiten je Bezugsquelle und je Artikel
+type promisedLeadTimes struct {
+	bySource  map[[2]primitive.ObjectID]int
+	byArticle map[primitive.ObjectID]int
+}
+
+// get gibt die Lieferzeit der Bezugsquelle oder, ohne Bezugsquelle, die des Artikels zurück
+func (l *promisedLeadTimes) get(supplierID, articleID primitive.ObjectID) int {
+	if days, ok := l.bySource[[2]primitive.ObjectID{supplierID, articleID}]; ok {
+		return days
+	}
+	return l.byArticle[articleID]
+}
+
+// findPromisedLeadTimes ermittelt die Lieferzeiten der Bezugsquellen und lädt die aller Artikel
+func (s *SupplierScorecardService) findPromisedLeadTimes(supplierArticles []*model.SupplierArticle) (*promisedLeadTimes, error) {
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	leadTimes := &promisedLeadTimes{
+		bySource:  make(map[[2]primitive.ObjectID]int, len(supplierArticles)),
+		byArticle: make(map[primitive.ObjectID]int, len(articles)),
+	}
+	for _, supplierArticle := range supplierArticles {
+		leadTimes.bySource[[2]primitive.ObjectID{supplierArticle.SupplierID, supplierArticle.ArticleID}] = supplierArticle.LeadTimeDays
+	}
+	for _, article := range articles {
+		leadTimes.byArticle[article.ID] = article.DeliveryTimeInDays
+	}
+
+	return leadTimes, nil
+}
+
+// truncateToDay gibt den Beginn des Tages in lokaler Zeit zurück
+func truncateToDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,207 @@
+// backend/service/valuation_service.go
+package service
+
+import (
+	"StockFlow/backend/model"
+	"StockFlow/backend/repository"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidValuationMethod wird zurückgegeben, wenn ein unbekanntes Bewertungsverfahren gewählt wird
+var ErrInvalidValuationMethod = errors.New("Ungültiges Bewertungsverfahren")
+
+// ValuationService bewertet den Lagerbestand nach dem eingestellten Verfahren
+type ValuationService struct {
+	settingsRepo    *repository.SettingsRepository
+	articleRepo     *repository.ArticleRepository
+	transactionRepo *repository.TransactionRepository
+	costLayerRepo   *repository.CostLayerRepository
+}
+
+// NewValuationService erstellt einen neuen ValuationService
+func NewValuationService() *ValuationService {
+	return &ValuationService{
+		settingsRepo:    repository.NewSettingsRepository(),
+		articleRepo:     repository.NewArticleRepository(),
+		transactionRepo: repository.NewTransactionRepository(),
+		costLayerRepo:   repository.NewCostLayerRepository(),
+	}
+}
+
+// GetValuationMethod gibt das eingestellte Bewertungsverfahren zurück
+func (s *ValuationService) GetValuationMethod() (model.ValuationMethod, error) {
+	settings, err := s.settingsRepo.Get()
+	if err != nil {
+		return "", err
+	}
+	return settings.ValuationMethod, nil
+}
+
+// SetValuationMethod stellt das Bewertungsverfahren um. Beide Verfahren werden bei jeder
+// Buchung fortgeführt, daher wirkt die Umstellung sofort und auch rückwirkend in Berichten.
+func (s *ValuationService) SetValuationMethod(method model.ValuationMethod, userID primitive.ObjectID, userName string) error {
+	if !method.IsValid() {
+		return ErrInvalidValuationMethod
+	}
+
+	settings, err := s.settingsRepo.Get()
+	if err != nil {
+		return err
+	}
+
+	settings.ValuationMethod = method
+	settings.UpdatedByID = userID
+	settings.UpdatedByName = userName
+	return s.settingsRepo.Save(settings)
+}
+
+// CalculateTotalStockValue berechnet den aktuellen Lagerwert nach dem eingestellten Verfahren
+func (s *ValuationService) CalculateTotalStockValue() (float64, error) {
+	method, err := s.GetValuationMethod()
+	if err != nil {
+		return 0, err
+	}
+
+	if method == model.ValuationMethodFIFO {
+		return s.costLayerRepo.SumRemainingValue()
+	}
+	return s.articleRepo.CalculateTotalStockValue()
+}
+
+// ValuationEntry ist die Bewertung eines Artikels im Bewertungsbericht
+type ValuationEntry struct {
+	ArticleID     string
+	ArticleNumber string
+	ArticleName   string
+	Category      string
+	Unit          string
+	Quantity      float64 // Bestand zum Stichtag
+	UnitCost      float64 // Durchschnittlicher Wert je Einheit
+	Value         float64
+}
+
+// ValuationCategory fasst die Bewertung einer Warengruppe zusammen
+type ValuationCategory struct {
+	Name  string
+	Count int
+	Value float64
+}
+
+// GenerateValuationReport bewertet den Lagerbestand zum Ende des Stichtags. Der Bestand ergibt
+// sich aus der letzten Buchung bis zum Stichtag bzw. der ersten Buchung danach. Beim gleitenden
+// Durchschnitt gilt der Durchschnittspreis nach der letzten Buchung bis zum Stichtag, bei FIFO
+// der Wert der zum Stichtag offenen Kostenschichten. Bestand, der vor Einführung der Bewertung
+// gebucht wurde und daher nicht abgedeckt ist, wird zum aktuellen Durchschnittspreis bewertet.
+func (s *ValuationService) GenerateValuationReport(cutoff time.Time, method model.ValuationMethod) (map[string]interface{}, error) {
+	if !method.IsValid() {
+		return nil, ErrInvalidValuationMethod
+	}
+
+	articles, err := s.articleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	lastBefore, err := s.transactionRepo.FindLastPerArticleUntil(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	firstAfter, err := s.transactionRepo.FindFirstPerArticleAfter(cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	// FIFO: offene Menge und Wert der Kostenschichten je Artikel zum Stichtag
+	layerQuantity := make(map[primitive.ObjectID]float64)
+	layerValue := make(map[primitive.ObjectID]float64)
+	if method == model.ValuationMethodFIFO {
+		layers, err := s.costLayerRepo.FindReceivedUntil(cutoff)
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range layers {
+			remaining := layer.GetRemainingAt(cutoff)
+			if remaining <= 0 {
+				continue
+			}
+			layerQuantity[layer.ArticleID] += remaining
+			layerValue[layer.ArticleID] += remaining * layer.UnitCost
+		}
+	}
+
+	entries := []ValuationEntry{}
+	categoryTotals := make(map[string]*ValuationCategory)
+	var totalValue float64
+	for _, article := range articles {
+		if article.CreatedAt.After(cutoff) {
+			continue
+		}
+
+		quantity, averageCost := stockAsOf(article, lastBefore[article.ID], firstAfter[article.ID])
+		if quantity <= lotEpsilon {
+			continue
+		}
+
+		value := quantity * averageCost
+		if method == model.ValuationMethodFIFO {
+			// Übersteigen die offenen Schichten den Bestand, werden sie anteilig bewertet
+			covered := math.Min(layerQuantity[article.ID], quantity)
+			value = (quantity - covered) * averageCost
+			if covered > 0 {
+				value += layerValue[article.ID] * covered / layerQuantity[article.ID]
+			}
+		}
+
+		entry := ValuationEntry{
+			ArticleID:     article.ID.Hex(),
+			ArticleNumber: article.ArticleNumber,
+			ArticleName:   article.ShortName,
+			Category:      article.Category,
+			Unit:          article.Unit,
+			Quantity:      quantity,
+			UnitCost:      value / quantity,
+			Value:         value,
+		}
+		entries = append(entries, entry)
+		totalValue += value
+
+		category := article.Category
+		if category == "" {
+			category = "Ohne Kategorie"
+		}
+		if categoryTotals[category] == nil {
+			categoryTotals[category] = &ValuationCategory{Name: category}
+		}
+		categoryTotals[category].Count++
+		categoryTotals[category].Value += value
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Category != entries[j].Category {
+			return entries[i].Category < entries[j].Category
+		}
+		return entries[i].ArticleNumber < entries[j].ArticleNumber
+	})
+
+	categories := make([]ValuationCategory, 0, len(categoryTotals))
+	for _, category := range categoryTotals {
+		categories = append(categories, *category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
+	return map[string]interface{}{
+		"cutoff":      cutoff,
+		"method":      method,
+		"entries":     entries,
+		"categories":  categories,
+		"totalValue":  totalValue,
+		"generatedAt": time.Now(),
+	}, nil
+}
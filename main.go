@@ -1,9 +1,14 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -12,10 +17,14 @@ import (
 	"StockFlow/backend"
 	"StockFlow/backend/db"
 	"StockFlow/backend/repository"
+	"StockFlow/backend/service"
 	"StockFlow/backend/utils"
 )
 
 func main() {
+	checkLedger := flag.Bool("check-ledger", false, "Artikelbestände gegen das Buchungsjournal prüfen und beenden")
+	flag.Parse()
+
 	// Set Gin to release mode in production
 	// gin.SetMode(gin.ReleaseMode)
 	gin.SetMode(gin.DebugMode)
@@ -26,6 +35,13 @@ func main() {
 	}
 	defer db.DisconnectDB()
 
+	// Nur die Konsistenzprüfung ausführen; der Exit-Code zeigt an, ob Abweichungen bestehen
+	if *checkLedger {
+		exitCode := runLedgerCheck()
+		db.DisconnectDB()
+		os.Exit(exitCode)
+	}
+
 	initRepo := repository.NewInitRepository()
 	if err := initRepo.InitializeDatabase(); err != nil {
 		log.Printf("Warnung: Datenbank konnte nicht vollständig initialisiert werden: %v", err)
@@ -39,6 +55,9 @@ func main() {
 		log.Println("Admin-Benutzer wurde überprüft/erstellt")
 	}
 
+	// Abgelaufene Reservierungen regelmäßig freigeben
+	service.NewReservationService().StartExpiryWorker(time.Minute)
+
 	// Upload-Verzeichnis erstellen, falls es nicht existiert
 	if err := utils.EnsureUploadDirExists(); err != nil {
 		log.Printf("Warnung: Upload-Verzeichnis konnte nicht erstellt werden: %v", err)
@@ -63,6 +82,37 @@ func main() {
 	}
 }
 
+// runLedgerCheck gibt alle Abweichungen zwischen Artikelbeständen und Buchungsjournal aus und
+// liefert 1 zurück, wenn es Abweichungen gibt, bzw. 2, wenn die Prüfung fehlschlägt
+func runLedgerCheck() int {
+	result, err := service.NewLedgerService().CheckConsistency()
+	if err != nil {
+		log.Printf("Fehler bei der Prüfung des Buchungsjournals: %v", err)
+		return 2
+	}
+
+	fmt.Printf("%d Artikel geprüft, %d Abweichungen\n", result.ArticleCount, len(result.Mismatches))
+	if result.IsConsistent() {
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "\nArtikelnr.\tBezeichnung\tArtikelbestand\tJournal\tLagerplätze\tUrsachen")
+	for _, mismatch := range result.Mismatches {
+		fmt.Fprintf(writer, "%s\t%s\t%g\t%g\t%g\t%s\n",
+			mismatch.ArticleNumber,
+			mismatch.ArticleName,
+			mismatch.StockCurrent,
+			mismatch.LedgerStock,
+			mismatch.LevelStock,
+			strings.Join(mismatch.GetCauses(), "; "),
+		)
+	}
+	writer.Flush()
+
+	return 1
+}
+
 func setupRouter() *gin.Engine {
 	// Create a default gin router with Logger and Recovery middleware
 	router := gin.Default()